@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"fiatjaf.com/nostr"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Relays that gate reading behind NIP-42 close a room subscription with
+// "auth-required:". The pool answers that once per subscription through its
+// AuthRequiredHandler and resubscribes; when that AUTH fails (typically
+// because the challenge hasn't arrived yet) the relay is left closed. The
+// subscribe commands therefore forward every CLOSED to the update loop,
+// which authenticates with retries and reopens the room's subscription.
+
+// authReadRetries is how often a room is resubscribed after authenticating
+// to a relay that closed its subscription for auth-required.
+const authReadRetries = 2
+
+// subClosedMsg reports that a relay closed a room subscription.
+type subClosedMsg struct {
+	rooms  []string // rooms the subscription served (several for combined ones)
+	relay  string
+	reason string
+	authed bool // the pool authenticated and resubscribed on its own
+}
+
+// relayAuthedMsg is returned after authenticating to a relay for rooms.
+type relayAuthedMsg struct {
+	rooms []string
+	relay string
+	err   error
+}
+
+// forwardClosed sends the CLOSED notices of a subscription to out until ctx
+// ends.
+func forwardClosed(ctx context.Context, closed <-chan nostr.RelayClosed, rooms []string, out chan<- subClosedMsg) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case c := <-closed:
+			msg := subClosedMsg{rooms: rooms, reason: c.Reason, authed: c.HandledAuth}
+			if c.Relay != nil {
+				msg.relay = c.Relay.URL
+			}
+			select {
+			case out <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// waitForSubClosed returns the next CLOSED notice of any room subscription.
+func waitForSubClosed(out <-chan subClosedMsg) tea.Cmd {
+	return func() tea.Msg {
+		return <-out
+	}
+}
+
+// isAuthRequired reports whether a CLOSED reason asks for NIP-42 auth.
+func isAuthRequired(reason string) bool {
+	return strings.HasPrefix(reason, "auth-required:")
+}
+
+// authRelayCmd authenticates to relayURL, retrying until the relay's
+// challenge is answered or relayAuthTimeout passes.
+func authRelayCmd(pool *nostr.Pool, relayURL string, rooms []string, sign func(context.Context, *nostr.Event) error) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), relayAuthTimeout)
+		defer cancel()
+		r, err := pool.EnsureRelay(relayURL)
+		if err != nil {
+			return relayAuthedMsg{rooms: rooms, relay: relayURL, err: err}
+		}
+		err = authenticate(ctx, r, sign)
+		return relayAuthedMsg{rooms: rooms, relay: relayURL, err: err}
+	}
+}
+
+func (m *model) handleSubClosed(msg subClosedMsg) (tea.Model, tea.Cmd) {
+	log.Printf("subClosedMsg: %s closed %v: %q (authed=%v)", msg.relay, msg.rooms, msg.reason, msg.authed)
+	next := waitForSubClosed(m.subClosed)
+	switch {
+	case msg.authed:
+		for _, room := range msg.rooms {
+			if key := room + "\t" + msg.relay; !m.authReads[key] {
+				m.authReads[key] = true
+				m.addRoomSystemMsg(room, fmt.Sprintf("%s requires authentication to read; authenticated (NIP-42)", msg.relay))
+			}
+		}
+		return m, next
+	case isAuthRequired(msg.reason):
+		var retry []string
+		for _, room := range msg.rooms {
+			key := room + "\t" + msg.relay
+			if m.authPending[key] {
+				continue // another filter of the same subscription
+			}
+			if m.authRetries[key] < authReadRetries {
+				m.authRetries[key]++
+				m.authPending[key] = true
+				retry = append(retry, room)
+			} else {
+				m.addRoomSystemMsg(room, fmt.Sprintf("%s refused to let us read after authenticating: %s", msg.relay, msg.reason))
+			}
+		}
+		if len(retry) == 0 {
+			return m, next
+		}
+		for _, room := range retry {
+			m.addRoomSystemMsg(room, fmt.Sprintf("%s requires authentication to read; authenticating (NIP-42)...", msg.relay))
+		}
+		return m, tea.Batch(next, authRelayCmd(m.pool, msg.relay, retry, m.kr.SignEvent))
+	}
+	for _, room := range msg.rooms {
+		m.addRoomSystemMsg(room, fmt.Sprintf("%s closed the subscription: %s", msg.relay, msg.reason))
+	}
+	return m, next
+}
+
+// resetAuthRetries forgets the AUTH retries spent on room and relay once the
+// relay delivers events to it again, so that an auth-required after a later
+// reconnect gets the full retries. They aren't reset when AUTH itself
+// succeeds: a relay that accepts AUTH but keeps refusing to serve us would
+// loop forever.
+func (m *model) resetAuthRetries(room, relay string) {
+	delete(m.authRetries, room+"\t"+relay)
+}
+
+// handleRelayAuthed reopens the subscriptions of rooms once the relay
+// accepted our AUTH.
+func (m *model) handleRelayAuthed(msg relayAuthedMsg) (tea.Model, tea.Cmd) {
+	for _, room := range msg.rooms {
+		delete(m.authPending, room+"\t"+msg.relay)
+	}
+	if msg.err != nil {
+		log.Printf("relayAuthedMsg: %s: %v", msg.relay, msg.err)
+		for _, room := range msg.rooms {
+			m.addRoomSystemMsg(room, fmt.Sprintf("authentication to %s failed: %v", msg.relay, msg.err))
+		}
+		return m, nil
+	}
+	var cmds []tea.Cmd
+	for _, room := range msg.rooms {
+		sub, ok := m.roomSubs[room]
+		if !ok {
+			continue // closed meanwhile
+		}
+		m.addRoomSystemMsg(room, fmt.Sprintf("authenticated to %s, reading again", msg.relay))
+		m.cancelRoomSub(room)
+		var reconnect tea.Msg = channelReconnectMsg{channelID: room}
+		if sub.kind == SidebarGroup {
+			reconnect = groupReconnectMsg{groupKey: room}
+		}
+		cmds = append(cmds, func() tea.Msg { return reconnect })
+	}
+	return m, tea.Batch(cmds...)
+}
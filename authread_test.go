@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/keyer"
+)
+
+func TestForwardClosed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	closed := make(chan nostr.RelayClosed)
+	out := make(chan subClosedMsg, 1)
+	done := make(chan struct{})
+	go func() {
+		forwardClosed(ctx, closed, []string{"ch0"}, out)
+		close(done)
+	}()
+
+	closed <- nostr.RelayClosed{Reason: "auth-required: please", HandledAuth: true}
+	got := <-out
+	if got.reason != "auth-required: please" || !got.authed || len(got.rooms) != 1 || got.rooms[0] != "ch0" {
+		t.Errorf("got %+v", got)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("forwardClosed didn't stop when the context ended")
+	}
+}
+
+func TestHandleSubClosed(t *testing.T) {
+	m := newTestModel(2, 0, 0)
+	m.authReads = make(map[string]bool)
+	m.authRetries = make(map[string]int)
+	m.authPending = make(map[string]bool)
+	m.kr = keyer.NewPlainKeySigner(nostr.Generate())
+	last := func(room string) string {
+		msgs := m.msgs[room]
+		if len(msgs) == 0 {
+			return ""
+		}
+		return msgs[len(msgs)-1].Content
+	}
+
+	// Handled by the pool: reported once per room and relay.
+	m.handleSubClosed(subClosedMsg{rooms: []string{"ch0"}, relay: "wss://a", reason: "auth-required: x", authed: true})
+	m.handleSubClosed(subClosedMsg{rooms: []string{"ch0"}, relay: "wss://a", reason: "auth-required: x", authed: true})
+	if n := len(m.msgs["ch0"]); n != 1 {
+		t.Errorf("got %d notices, want 1", n)
+	}
+
+	// Not handled: we authenticate, once while in flight.
+	m.handleSubClosed(subClosedMsg{rooms: []string{"ch1"}, relay: "wss://a", reason: "auth-required: x"})
+	m.handleSubClosed(subClosedMsg{rooms: []string{"ch1"}, relay: "wss://a", reason: "auth-required: x"})
+	if got := m.authRetries["ch1\twss://a"]; got != 1 {
+		t.Errorf("retries = %d, want 1", got)
+	}
+	m.handleRelayAuthed(relayAuthedMsg{rooms: []string{"ch1"}, relay: "wss://a", err: errors.New("timeout")})
+	if !strings.Contains(last("ch1"), "failed") {
+		t.Errorf("last notice = %q", last("ch1"))
+	}
+
+	// Retries are capped.
+	for i := 0; i < authReadRetries; i++ {
+		m.handleSubClosed(subClosedMsg{rooms: []string{"ch1"}, relay: "wss://a", reason: "auth-required: x"})
+		m.handleRelayAuthed(relayAuthedMsg{rooms: []string{"ch1"}, relay: "wss://a"})
+	}
+	if !strings.Contains(last("ch1"), "refused") {
+		t.Errorf("last notice = %q, want the relay reported as refusing", last("ch1"))
+	}
+
+	// Once the relay delivers events again, a later auth-required gets
+	// fresh retries.
+	m.resetAuthRetries("ch1", "wss://a")
+	m.handleSubClosed(subClosedMsg{rooms: []string{"ch1"}, relay: "wss://a", reason: "auth-required: x"})
+	if !strings.Contains(last("ch1"), "authenticating") {
+		t.Errorf("last notice = %q, want a new authentication attempt", last("ch1"))
+	}
+
+	// Other reasons are only reported.
+	m.handleSubClosed(subClosedMsg{rooms: []string{"ch0"}, relay: "wss://a", reason: "restricted: members only"})
+	if !strings.Contains(last("ch0"), "restricted: members only") {
+		t.Errorf("last notice = %q", last("ch0"))
+	}
+}
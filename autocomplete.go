@@ -39,30 +39,18 @@ func (m *model) updateSuggestions() {
 	switch {
 	case len(tokens) == 1 && !trailingSpace:
 		// Partial top-level command: /he → /help
-		commands := []string{"/channel", "/join", "/dm", "/me", "/room", "/delete", "/group", "/invite", "/leave", "/help"}
 		prefix := strings.ToLower(tokens[0])
-		for _, c := range commands {
+		for _, c := range commandNames() {
 			if strings.HasPrefix(c, prefix) && c != prefix {
 				suggestions = append(suggestions, c)
 			}
 		}
 
-	case strings.ToLower(tokens[0]) == "/channel":
-		subcommands := []string{"create"}
-		switch {
-		case len(tokens) == 1 && trailingSpace:
-			suggestions = subcommands
-		case len(tokens) == 2 && !trailingSpace:
-			prefix := strings.ToLower(tokens[1])
-			for _, sc := range subcommands {
-				if strings.HasPrefix(sc, prefix) && sc != prefix {
-					suggestions = append(suggestions, sc)
-				}
-			}
-		}
+	case commandSubcommands[strings.ToLower(tokens[0])] != nil:
+		suggestions = subcommandSuggestions(tokens, trailingSpace, commandSubcommands[strings.ToLower(tokens[0])])
 
 	case strings.ToLower(tokens[0]) == "/group":
-		subcommands := []string{"create", "set", "user", "name", "about", "picture"}
+		subcommands := []string{"create", "set", "user", "name", "about", "picture", "mirror", "invite"}
 		switch {
 		case len(tokens) == 1 && trailingSpace:
 			// "/group " → show all subcommands
@@ -81,8 +69,8 @@ func (m *model) updateSuggestions() {
 			switch sub {
 			case "set":
 				suggestions = []string{"open", "closed"}
-			case "user":
-				suggestions = []string{"add"}
+			case "user", "mirror":
+				suggestions = []string{"add", "remove"}
 			}
 		case len(tokens) == 3 && !trailingSpace:
 			sub := strings.ToLower(tokens[1])
@@ -95,8 +83,8 @@ func (m *model) updateSuggestions() {
 						suggestions = append(suggestions, o)
 					}
 				}
-			case "user":
-				options := []string{"add"}
+			case "user", "mirror":
+				options := []string{"add", "remove"}
 				prefix := strings.ToLower(tokens[2])
 				for _, o := range options {
 					if strings.HasPrefix(o, prefix) && o != prefix {
@@ -106,6 +94,20 @@ func (m *model) updateSuggestions() {
 			}
 		}
 
+	case strings.ToLower(tokens[0]) == "/draft":
+		// "/draft <partial>" → filter saved draft names
+		if (len(tokens) == 1 && trailingSpace) || (len(tokens) == 2 && !trailingSpace) {
+			partial := ""
+			if len(tokens) == 2 {
+				partial = tokens[1]
+			}
+			for _, name := range sortedDraftNames(m.drafts) {
+				if partial == "" || (strings.HasPrefix(strings.ToLower(name), strings.ToLower(partial)) && name != partial) {
+					suggestions = append(suggestions, name)
+				}
+			}
+		}
+
 	case strings.ToLower(tokens[0]) == "/join":
 		// "/join <partial>" → filter channel names and invite links
 		if (len(tokens) == 1 && trailingSpace) || (len(tokens) == 2 && !trailingSpace) {
@@ -129,8 +131,10 @@ func (m *model) updateSuggestions() {
 			}
 		}
 
-	case strings.ToLower(tokens[0]) == "/dm" || strings.ToLower(tokens[0]) == "/invite":
-		// "/dm <partial>" or "/invite <partial>" → filter contact display names
+	case strings.ToLower(tokens[0]) == "/dm" || strings.ToLower(tokens[0]) == "/invite" ||
+		strings.ToLower(tokens[0]) == "/follow" || strings.ToLower(tokens[0]) == "/unfollow" ||
+		strings.ToLower(tokens[0]) == "/whois":
+		// "/dm <partial>", "/invite <partial>", etc. → filter contact display names
 		if (len(tokens) == 1 && trailingSpace) || (len(tokens) == 2 && !trailingSpace) {
 			partial := ""
 			if len(tokens) == 2 {
@@ -160,6 +164,36 @@ func (m *model) updateSuggestions() {
 	m.acSuggestions = suggestions
 }
 
+// commandSubcommands lists the subcommands completed after commands that
+// take one. /group, whose subcommands have options of their own, is
+// completed separately.
+var commandSubcommands = map[string][]string{
+	"/channel": {"create"},
+	"/import":  {"contacts", "rooms"},
+	"/filter":  {"contacts", "all", "since", "limit", "reset"},
+	"/thread":  {"list", "new", "open", "close"},
+}
+
+// subcommandSuggestions completes the subcommand after a command: all of
+// subs once a space follows the command, those matching the partial second
+// token while it is typed.
+func subcommandSuggestions(tokens []string, trailingSpace bool, subs []string) []string {
+	switch {
+	case len(tokens) == 1 && trailingSpace:
+		return subs
+	case len(tokens) == 2 && !trailingSpace:
+		var suggestions []string
+		prefix := strings.ToLower(tokens[1])
+		for _, sc := range subs {
+			if strings.HasPrefix(sc, prefix) && sc != prefix {
+				suggestions = append(suggestions, sc)
+			}
+		}
+		return suggestions
+	}
+	return nil
+}
+
 // acceptSuggestion replaces the partial token in input with the selected suggestion.
 func (m *model) acceptSuggestion() {
 	if len(m.acSuggestions) == 0 {
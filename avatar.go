@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// avatarWidth is the cell width of a rendered avatar (initial plus padding).
+const avatarWidth = 3
+
+var avatarStyle = lipgloss.NewStyle().
+	Foreground(colorStatusBg).
+	Bold(true)
+
+// avatarInitial picks the avatar character for a display name: the first
+// letter or digit, upper-cased. Names with none (emoji only, punctuation)
+// get "?".
+func avatarInitial(name string) string {
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return string(unicode.ToUpper(r))
+		}
+	}
+	return "?"
+}
+
+// renderAvatar renders name's initial on pubkey's author color, padded to
+// avatarWidth cells (wide initials such as CJK take the right padding).
+func renderAvatar(name, pubkey string) string {
+	initial := avatarInitial(name)
+	pad := max(avatarWidth-1-lipgloss.Width(initial), 0)
+	return avatarStyle.Background(colorForPubkey(pubkey)).Render(" " + initial + strings.Repeat(" ", pad))
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+func TestAvatarInitial(t *testing.T) {
+	tests := map[string]string{
+		"alice":    "A",
+		"émile":    "É",
+		"🚀 rocket": "R",
+		"_bob":     "B",
+		"42":       "4",
+		"🔥🔥":       "?",
+		"":         "?",
+		"李雷":       "李",
+	}
+	for name, want := range tests {
+		if got := avatarInitial(name); got != want {
+			t.Errorf("avatarInitial(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestRenderAvatarWidth(t *testing.T) {
+	for _, name := range []string{"alice", "李雷", "🔥"} {
+		if w := lipgloss.Width(renderAvatar(name, "ab")); w != avatarWidth {
+			t.Errorf("renderAvatar(%q) is %d cells wide, want %d", name, w, avatarWidth)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"fiatjaf.com/nostr"
+)
+
+// defaultAwayMessage is sent by /away when no message is given.
+const defaultAwayMessage = "I'm away right now and will reply when I'm back."
+
+// setAway handles /away [--all] [message]: auto-reply once per peer to new
+// DMs until /back. Only contacts get the reply unless --all is given.
+func (m *model) setAway(arg string) (tea.Model, tea.Cmd) {
+	m.awayAll = false
+	if rest, ok := strings.CutPrefix(arg, "--all"); ok && (rest == "" || rest[0] == ' ') {
+		m.awayAll = true
+		arg = strings.TrimSpace(rest)
+	}
+	if arg == "" {
+		arg = defaultAwayMessage
+	}
+	m.awayMsg = arg
+	m.awaySince = nostr.Now()
+	m.awayReplied = make(map[string]bool)
+	who := "contact"
+	if m.awayAll {
+		who = "person"
+	}
+	m.addSystemMsg(fmt.Sprintf("away: new DMs get one auto-reply per %s: %q (/back to stop)", who, arg))
+	return m, nil
+}
+
+// setBack handles /back.
+func (m *model) setBack() (tea.Model, tea.Cmd) {
+	if m.awayMsg == "" {
+		m.addSystemMsg("you are not away")
+		return m, nil
+	}
+	m.addSystemMsg(fmt.Sprintf("welcome back — auto-replied to %d people", len(m.awayReplied)))
+	m.awayMsg = ""
+	m.awayReplied = nil
+	return m, nil
+}
+
+// autoReplyText is what an away auto-reply says.
+func autoReplyText(awayMsg string) string {
+	return "[auto-reply] " + awayMsg
+}
+
+// maybeAutoReply returns a command sending the away message to the sender
+// of cm, at most once per peer while away. Our own messages (including
+// echoes from other devices), history from before /away, and muted
+// messages are skipped, as are senders who weren't contacts before this
+// message (newPeer) unless /away --all: replying would confirm to any
+// stranger or spammer that the key is in use.
+func (m *model) maybeAutoReply(cm ChatMessage, newPeer bool) tea.Cmd {
+	if m.awayMsg == "" || cm.IsMine || cm.Timestamp < m.awaySince || m.awayReplied[cm.PubKey] {
+		return nil
+	}
+	if m.isMutedMessage(cm) || !m.awayAll && (newPeer || !m.isContact(cm.PubKey)) {
+		return nil
+	}
+	m.awayReplied[cm.PubKey] = true
+	log.Printf("away: auto-replying to %s", shortPK(cm.PubKey))
+	return sendDM(m.pool, m.relays, cm.PubKey, autoReplyText(m.awayMsg), nil, m.keys, m.kr)
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestMaybeAutoReply(t *testing.T) {
+	m := newTestModel(0, 0, 1)
+	incoming := ChatMessage{PubKey: "pk0", Content: "hi", Timestamp: nostr.Now()}
+
+	if m.maybeAutoReply(incoming, false) != nil {
+		t.Fatal("auto-replied while not away")
+	}
+
+	m.setAway("")
+	if m.awayMsg != defaultAwayMessage {
+		t.Fatalf("awayMsg = %q, want default", m.awayMsg)
+	}
+	m.awaySince = incoming.Timestamp
+
+	mine := incoming
+	mine.IsMine = true
+	if m.maybeAutoReply(mine, false) != nil {
+		t.Error("auto-replied to our own message")
+	}
+	old := incoming
+	old.Timestamp = incoming.Timestamp - 60
+	if m.maybeAutoReply(old, false) != nil {
+		t.Error("auto-replied to a message from before /away")
+	}
+	if m.maybeAutoReply(incoming, false) == nil {
+		t.Fatal("no auto-reply to first message from peer")
+	}
+	if m.maybeAutoReply(incoming, false) != nil {
+		t.Error("auto-replied twice to the same peer")
+	}
+	stranger := incoming
+	stranger.PubKey = "pk1"
+	if m.maybeAutoReply(stranger, false) != nil {
+		t.Error("auto-replied to a non-contact without --all")
+	}
+
+	m.setBack()
+	if m.awayMsg != "" || m.maybeAutoReply(incoming, false) != nil {
+		t.Error("still auto-replying after /back")
+	}
+}
+
+func TestMaybeAutoReplyFilters(t *testing.T) {
+	m := newTestModel(0, 0, 2)
+	m.mutedWords = map[string]bool{"casino": true}
+	m.setAway("")
+	m.awaySince = 0
+
+	if m.maybeAutoReply(ChatMessage{PubKey: "pk0", Content: "best casino bonus", Timestamp: 1}, false) != nil {
+		t.Error("auto-replied to a muted message")
+	}
+	if m.maybeAutoReply(ChatMessage{PubKey: "pk1", Content: "hi", Timestamp: 1}, true) != nil {
+		t.Error("auto-replied to a first-time sender without --all")
+	}
+
+	m.setAway("--all brb")
+	m.awaySince = 0
+	if m.awayMsg != "brb" || !m.awayAll {
+		t.Fatalf("awayMsg=%q awayAll=%v, want brb and --all", m.awayMsg, m.awayAll)
+	}
+	if m.maybeAutoReply(ChatMessage{PubKey: "stranger", Content: "hi", Timestamp: 1}, true) == nil {
+		t.Error("no auto-reply to a stranger with --all")
+	}
+}
@@ -0,0 +1,590 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+// A backup is a gzipped tar of the files directly in the config directory
+// (config, contacts, drafts, last_dm_seen, ...) plus the key file. With a
+// passphrase the archive is sealed with XChaCha20-Poly1305 under a scrypt
+// key, like NIP-49 does for the key itself. Without one the key file, old
+// keys kept by /nsec-rotate, and secret config values (digest_api_key and
+// relay tokens) are left out.
+
+// backupMagic starts an encrypted backup; it is followed by the scrypt salt,
+// the nonce, and the sealed archive.
+const backupMagic = "nitrous-backup-v1\n"
+
+const backupSaltLen = 16
+
+// backupKeyName is the archive name of the key file. A restore writes it to
+// private_key_file, wherever that is.
+const backupKeyName = "private_key_file"
+
+// secretConfigKeys are config options left out of unencrypted backups.
+// Options inside a table are named table.key.
+var secretConfigKeys = []string{"digest_api_key", "relay.token"}
+
+// inlineTokenRe matches the token of an inline [[relay]] table, as in
+// relay = [{ url = "...", token = "..." }].
+var inlineTokenRe = regexp.MustCompile(`\btoken\s*=\s*("[^"]*"|'[^']*')`)
+
+// backupFile is one file of a backup, named relative to the config
+// directory.
+type backupFile struct {
+	Name string
+	Mode os.FileMode
+	Data []byte
+}
+
+// passPrompt asks for a backup or key passphrase without echoing it.
+type passPrompt struct {
+	action  string // "backup", "restore" or "nsec-rotate"
+	path    string
+	force   bool   // /restore --force
+	migrate bool   // /nsec-rotate <code> migrate
+	first   string // the passphrase typed once, while /backup or /nsec-rotate asks to repeat it
+	input   textinput.Model
+}
+
+// pendingRestore is a read backup waiting for y/n before it is written.
+type pendingRestore struct {
+	path      string
+	files     []backupFile
+	conflicts []string
+}
+
+type backupDoneMsg struct {
+	path      string
+	files     int
+	encrypted bool
+	err       error
+}
+
+type restoreReadMsg struct {
+	path  string
+	files []backupFile
+	force bool
+	err   error
+}
+
+type restoreDoneMsg struct {
+	files int
+	err   error
+}
+
+// collectBackup reads the files to back up from dir. The key file at
+// keyPath, and the old keys /nsec-rotate left next to it, are included only
+// withSecrets, the key also when it lives elsewhere, and secret config
+// values are otherwise redacted.
+func collectBackup(dir, cfgName, keyPath string, withSecrets bool) ([]backupFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []backupFile
+	add := func(path, name string) error {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if name == cfgName && !withSecrets {
+			data = redactConfigSecrets(data)
+		}
+		files = append(files, backupFile{Name: name, Mode: info.Mode().Perm(), Data: data})
+		return nil
+	}
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		if !e.Type().IsRegular() || e.Name() == backupKeyName || (keyPath != "" && sameFile(path, keyPath)) {
+			continue
+		}
+		if !withSecrets && isOldKeyFile(e.Name(), keyPath) {
+			continue
+		}
+		if err := add(path, e.Name()); err != nil {
+			return nil, err
+		}
+	}
+	if withSecrets && keyPath != "" {
+		if err := add(keyPath, backupKeyName); err != nil {
+			return nil, fmt.Errorf("key file: %w", err)
+		}
+	}
+	return files, nil
+}
+
+// isOldKeyFile reports whether name is a key replaced by /nsec-rotate,
+// which keeps it as <keyfile>.old-<time>.
+func isOldKeyFile(name, keyPath string) bool {
+	return keyPath != "" && strings.HasPrefix(name, filepath.Base(keyPath)+".old-")
+}
+
+// sameFile reports whether a and b name the same existing file.
+func sameFile(a, b string) bool {
+	ia, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	ib, err := os.Stat(b)
+	return err == nil && os.SameFile(ia, ib)
+}
+
+// redactConfigSecrets comments out the secretConfigKeys assignments of a
+// TOML config and blanks relay tokens given as inline tables.
+func redactConfigSecrets(data []byte) []byte {
+	lines := strings.Split(string(data), "\n")
+	table := ""
+	relayDepth := 0 // open brackets of a relay = [...] assignment
+	for i, l := range lines {
+		t := strings.TrimSpace(l)
+		if relayDepth > 0 {
+			lines[i] = inlineTokenRe.ReplaceAllString(l, `token = ""`)
+			relayDepth += strings.Count(t, "[") - strings.Count(t, "]")
+			continue
+		}
+		if strings.HasPrefix(t, "[") {
+			header, _, _ := strings.Cut(t, "#")
+			table = strings.Trim(strings.TrimSpace(header), "[] ")
+			continue
+		}
+		key, val, ok := strings.Cut(t, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		name := key
+		if table != "" {
+			name = table + "." + key
+		}
+		switch {
+		case slices.Contains(secretConfigKeys, name):
+			indent := l[:len(l)-len(strings.TrimLeft(l, " \t"))]
+			lines[i] = indent + "# " + key + " left out of the unencrypted backup"
+		case table == "" && key == "relay":
+			lines[i] = inlineTokenRe.ReplaceAllString(l, `token = ""`)
+			relayDepth = strings.Count(val, "[") - strings.Count(val, "]")
+		}
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// packBackup writes files as a gzipped tar.
+func packBackup(files []backupFile) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		hdr := &tar.Header{Name: f.Name, Mode: int64(f.Mode), Size: int64(len(f.Data)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(f.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// unpackBackup reads the files of a gzipped tar. Only plain file names are
+// accepted, so a restore can't write outside the config directory.
+func unpackBackup(data []byte) ([]backupFile, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("not a nitrous backup: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	var files []backupFile
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg || hdr.Name != filepath.Base(hdr.Name) || hdr.Name == ".." {
+			return nil, fmt.Errorf("unexpected entry %q", hdr.Name)
+		}
+		body, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		mode := os.FileMode(hdr.Mode).Perm()
+		if mode == 0 {
+			mode = 0600 // a file we couldn't read back after restoring is no use
+		}
+		files = append(files, backupFile{Name: hdr.Name, Mode: mode, Data: body})
+	}
+}
+
+// backupKey derives the sealing key from a passphrase.
+func backupKey(pass string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(pass), salt, 1<<keyEncryptionLogN, 8, 1, chacha20poly1305.KeySize)
+}
+
+// sealBackup encrypts an archive with pass.
+func sealBackup(archive []byte, pass string) ([]byte, error) {
+	salt := make([]byte, backupSaltLen)
+	nonce := make([]byte, chacha20poly1305.NonceSizeX)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key, err := backupKey(pass, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	out := append([]byte(backupMagic), salt...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, archive, []byte(backupMagic)), nil
+}
+
+// isEncryptedBackup reports whether data was sealed by sealBackup.
+func isEncryptedBackup(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(backupMagic))
+}
+
+// openBackup decrypts a sealed backup.
+func openBackup(data []byte, pass string) ([]byte, error) {
+	rest := data[len(backupMagic):]
+	if len(rest) < backupSaltLen+chacha20poly1305.NonceSizeX {
+		return nil, errors.New("backup is truncated")
+	}
+	salt, nonce := rest[:backupSaltLen], rest[backupSaltLen:backupSaltLen+chacha20poly1305.NonceSizeX]
+	key, err := backupKey(pass, salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := aead.Open(nil, nonce, rest[len(salt)+len(nonce):], []byte(backupMagic))
+	if err != nil {
+		return nil, errors.New("wrong passphrase or damaged backup")
+	}
+	return archive, nil
+}
+
+// restorePath returns where the backup file name is restored: the key to
+// keyPath, everything else into dir.
+func restorePath(dir, keyPath, name string) string {
+	if name == backupKeyName && keyPath != "" {
+		return keyPath
+	}
+	return filepath.Join(dir, name)
+}
+
+// restoreConflicts lists the files of a backup that already exist.
+func restoreConflicts(dir, keyPath string, files []backupFile) []string {
+	var existing []string
+	for _, f := range files {
+		if _, err := os.Stat(restorePath(dir, keyPath, f.Name)); err == nil {
+			existing = append(existing, f.Name)
+		}
+	}
+	return existing
+}
+
+// backupCmd writes a backup of dir to path, sealed with pass unless it is
+// empty.
+func backupCmd(dir, cfgName, keyPath, path, pass string) tea.Cmd {
+	return func() tea.Msg {
+		files, err := collectBackup(dir, cfgName, keyPath, pass != "")
+		if err != nil {
+			return backupDoneMsg{path: path, err: err}
+		}
+		data, err := packBackup(files)
+		if err == nil && pass != "" {
+			data, err = sealBackup(data, pass)
+		}
+		if err == nil {
+			err = os.WriteFile(path, data, 0600)
+		}
+		return backupDoneMsg{path: path, files: len(files), encrypted: pass != "", err: err}
+	}
+}
+
+// restoreReadCmd reads and, with a passphrase, decrypts the backup at path.
+func restoreReadCmd(path, pass string, force bool) tea.Cmd {
+	return func() tea.Msg {
+		data, err := os.ReadFile(path)
+		if err == nil && isEncryptedBackup(data) {
+			data, err = openBackup(data, pass)
+		}
+		if err != nil {
+			return restoreReadMsg{path: path, err: err}
+		}
+		files, err := unpackBackup(data)
+		return restoreReadMsg{path: path, files: files, force: force, err: err}
+	}
+}
+
+// restoreCmd writes the files of a backup into dir, and the key to keyPath.
+func restoreCmd(dir, keyPath string, files []backupFile) tea.Cmd {
+	return func() tea.Msg {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return restoreDoneMsg{err: err}
+		}
+		for i, f := range files {
+			if f.Name == backupKeyName && keyPath == "" {
+				return restoreDoneMsg{files: i, err: fmt.Errorf("the backup has a key, but private_key_file isn't set")}
+			}
+			if err := writeFileMode(restorePath(dir, keyPath, f.Name), f.Data, f.Mode); err != nil {
+				return restoreDoneMsg{files: i, err: err}
+			}
+		}
+		return restoreDoneMsg{files: len(files)}
+	}
+}
+
+// writeFileMode replaces path with data and mode. It writes a temporary file
+// and renames it, so the mode also applies when path already exists.
+func writeFileMode(path string, data []byte, mode os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".restore-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Chmod(mode); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// keyPath returns the resolved private_key_file, or "" when it isn't set.
+func (m *model) keyPath() string {
+	if m.cfg.PrivateKeyFile == "" {
+		return ""
+	}
+	return expandKeyPath(m.cfg.PrivateKeyFile)
+}
+
+// configDir returns the directory holding the config and its state files.
+func (m *model) configDir() string {
+	return filepath.Dir(configPath(m.cfgFlagPath))
+}
+
+// openPassPrompt shows the passphrase prompt for action.
+func (m *model) openPassPrompt(action, path string, force bool) {
+	in := textinput.New()
+	in.EchoMode = textinput.EchoPassword
+	in.EchoCharacter = '•'
+	in.Width = 40
+	in.Focus()
+	m.passPrompt = &passPrompt{action: action, path: path, force: force, input: in}
+}
+
+// backupCommand handles /backup <path>.
+func (m *model) backupCommand(arg string) (tea.Model, tea.Cmd) {
+	if arg == "" {
+		m.addSystemMsg("usage: /backup <path>")
+		return m, nil
+	}
+	m.openPassPrompt("backup", expandKeyPath(arg), false)
+	return m, textinput.Blink
+}
+
+// restoreCommand handles /restore [--force] <path>.
+func (m *model) restoreCommand(arg string) (tea.Model, tea.Cmd) {
+	path, force := strings.CutPrefix(arg, "--force ")
+	path = expandKeyPath(strings.TrimSpace(path))
+	if path == "" || path == "--force" {
+		m.addSystemMsg("usage: /restore [--force] <path>")
+		return m, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		m.addSystemMsg("restore: " + err.Error())
+		return m, nil
+	}
+	head := make([]byte, len(backupMagic))
+	n, _ := io.ReadFull(f, head)
+	_ = f.Close()
+	if !isEncryptedBackup(head[:n]) {
+		return m, restoreReadCmd(path, "", force)
+	}
+	m.openPassPrompt("restore", path, force)
+	return m, textinput.Blink
+}
+
+// handlePassPromptKey edits the passphrase; enter submits and esc cancels.
+// /backup and /nsec-rotate ask twice for a non-empty passphrase; the new key
+// of /nsec-rotate must have one.
+func (m *model) handlePassPromptKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.passPrompt
+	switch msg.String() {
+	case "esc":
+		m.passPrompt = nil
+		m.addSystemMsg(p.action + " cancelled")
+		return m, nil
+	case "enter":
+		pass := p.input.Value()
+		if p.action == "restore" {
+			m.passPrompt = nil
+			return m, restoreReadCmd(p.path, pass, p.force)
+		}
+		if pass == "" && p.action == "nsec-rotate" {
+			return m, nil
+		}
+		if pass != "" && p.first == "" {
+			p.first = pass
+			p.input.Reset()
+			return m, nil
+		}
+		m.passPrompt = nil
+		if pass != p.first {
+			m.addSystemMsg(p.action + " cancelled: the passphrases don't match")
+			return m, nil
+		}
+		if p.action == "nsec-rotate" {
+			return m.finishRotate(p.migrate, pass)
+		}
+		m.addSystemMsg("writing backup to " + p.path + " …")
+		return m, backupCmd(m.configDir(), filepath.Base(configPath(m.cfgFlagPath)), m.keyPath(), p.path, pass)
+	}
+	var cmd tea.Cmd
+	p.input, cmd = p.input.Update(msg)
+	return m, cmd
+}
+
+func (m *model) handleBackupDone(msg backupDoneMsg) (tea.Model, tea.Cmd) {
+	switch {
+	case msg.err != nil:
+		m.addSystemMsg("backup failed: " + msg.err.Error())
+	case msg.encrypted:
+		m.addSystemMsg(fmt.Sprintf("backed up %d files, including your key, to %s (encrypted)", msg.files, msg.path))
+	default:
+		m.addSystemMsg(fmt.Sprintf("backed up %d files to %s (unencrypted: your key and secret config values were left out)", msg.files, msg.path))
+	}
+	return m, nil
+}
+
+// handleRestoreRead asks to confirm the restore, unless it would overwrite
+// files without --force.
+func (m *model) handleRestoreRead(msg restoreReadMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.addSystemMsg("restore failed: " + msg.err.Error())
+		return m, nil
+	}
+	conflicts := restoreConflicts(m.configDir(), m.keyPath(), msg.files)
+	if len(conflicts) > 0 && !msg.force {
+		m.addSystemMsg(fmt.Sprintf("restore: refusing to overwrite %s in %s (use /restore --force %s)", strings.Join(conflicts, ", "), m.configDir(), msg.path))
+		return m, nil
+	}
+	m.pendingRestore = &pendingRestore{path: msg.path, files: msg.files, conflicts: conflicts}
+	return m, nil
+}
+
+// handleRestoreConfirmKey writes the backup on y and drops it on n or esc.
+func (m *model) handleRestoreConfirmKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y":
+		files := m.pendingRestore.files
+		m.pendingRestore = nil
+		return m, restoreCmd(m.configDir(), m.keyPath(), files)
+	case "n", "N", "esc":
+		m.pendingRestore = nil
+		m.addSystemMsg("restore cancelled")
+	}
+	return m, nil
+}
+
+func (m *model) handleRestoreDone(msg restoreDoneMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.addSystemMsg(fmt.Sprintf("restore failed after %d files: %v", msg.files, msg.err))
+		return m, nil
+	}
+	// Quit right away: the running session would otherwise write its own
+	// contacts, drafts, and other state over the restored files.
+	m.exitNote = fmt.Sprintf("restored %d files into %s; start nitrous again to use them", msg.files, m.configDir())
+	m.cancelAllRoomSubs()
+	if m.dmCancel != nil {
+		m.dmCancel()
+	}
+	return m, tea.Quit
+}
+
+// viewPassPrompt renders the passphrase prompt.
+func (m *model) viewPassPrompt() string {
+	p := m.passPrompt
+	title := "Passphrase for " + p.path
+	hint := "enter continues · esc cancels"
+	switch p.action {
+	case "nsec-rotate":
+		title = "Passphrase for the new key"
+		if p.first != "" {
+			title = "Repeat the passphrase"
+		}
+		hint = "your key file is encrypted, so the new key will be too (NIP-49) · esc cancels"
+	case "backup":
+		switch {
+		case p.first != "":
+			title = "Repeat the passphrase"
+		default:
+			title = "Passphrase for the backup"
+			hint = "leave empty for an unencrypted backup without your key · esc cancels"
+		}
+	}
+	return qrTitleStyle.Render(title) + "\n\n" + p.input.View() + "\n\n" + chatSystemStyle.Render(hint)
+}
+
+// viewRestoreConfirm renders the restore confirmation.
+func (m *model) viewRestoreConfirm() string {
+	r := m.pendingRestore
+	var b strings.Builder
+	b.WriteString(qrTitleStyle.Render(fmt.Sprintf("Restore %d files into %s?", len(r.files), m.configDir())) + "\n\n")
+	for _, f := range r.files {
+		line := "  " + f.Name
+		if slices.Contains(r.conflicts, f.Name) {
+			line += chatFailedStyle.Render("  (overwrites)")
+		}
+		b.WriteString(line + "\n")
+	}
+	if slices.ContainsFunc(r.files, func(f backupFile) bool { return f.Name == backupKeyName }) {
+		b.WriteString("\nThe key is restored to " + m.keyPath() + ".\n")
+	}
+	b.WriteString("\nnitrous quits after restoring · y restore · n/esc cancel")
+	return b.String()
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func backupNames(files []backupFile) map[string]string {
+	names := make(map[string]string)
+	for _, f := range files {
+		names[f.Name] = string(f.Data)
+	}
+	return names
+}
+
+func TestCollectBackup(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFiles(t, dir, map[string]string{
+		"config.toml":              "relays = []\ndigest_api_key = \"hunter2\"\n",
+		"nsec":                     "nsec1secret",
+		"nsec.old-20260101-120000": "nsec1old",
+		"last_dm_seen":             "123",
+	})
+	if err := os.Mkdir(filepath.Join(dir, "logs"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(dir, "nsec")
+
+	plain, err := collectBackup(dir, "config.toml", keyPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := backupNames(plain)
+	if _, ok := got[backupKeyName]; ok || len(got) != 2 {
+		t.Errorf("unencrypted backup has %v, want config.toml and last_dm_seen", got)
+	}
+	if strings.Contains(got["config.toml"], "hunter2") || !strings.Contains(got["config.toml"], "relays = []") {
+		t.Errorf("config not redacted:\n%s", got["config.toml"])
+	}
+
+	full, err := collectBackup(dir, "config.toml", keyPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = backupNames(full)
+	if got[backupKeyName] != "nsec1secret" || got["nsec.old-20260101-120000"] != "nsec1old" || !strings.Contains(got["config.toml"], "hunter2") {
+		t.Errorf("encrypted backup misses secrets: %v", got)
+	}
+}
+
+func TestRedactConfigSecrets(t *testing.T) {
+	configs := []string{
+		`relays = ["wss://a"]
+digest_api_key = "sk-1"
+relay = [{ url = "wss://b", token = "tok-inline" },
+  { url = "wss://c", token = 'tok-next' }]
+`,
+		`[[relay]]
+url = "wss://d"
+  token = "tok-table"
+
+[history]
+token = "not a secret here"
+`,
+	}
+	for _, config := range configs {
+		got := string(redactConfigSecrets([]byte(config)))
+		for _, secret := range []string{"sk-1", "tok-inline", "tok-next", "tok-table"} {
+			if strings.Contains(got, secret) {
+				t.Errorf("%s not redacted:\n%s", secret, got)
+			}
+		}
+		for _, kept := range []string{`url = "wss://`, "relay"} {
+			if !strings.Contains(got, kept) {
+				t.Errorf("%s lost:\n%s", kept, got)
+			}
+		}
+		var cfg Config
+		if _, err := toml.Decode(got, &cfg); err != nil {
+			t.Errorf("redacted config doesn't parse: %v\n%s", err, got)
+		}
+		if len(cfg.RelayOptions) == 0 || cfg.RelayOptions[0].Token != "" {
+			t.Errorf("relay options = %+v", cfg.RelayOptions)
+		}
+	}
+	if got := string(redactConfigSecrets([]byte(configs[1]))); !strings.Contains(got, "not a secret here") {
+		t.Errorf("token outside [[relay]] redacted:\n%s", got)
+	}
+}
+
+func TestCollectBackupKeyElsewhere(t *testing.T) {
+	dir, other := t.TempDir(), t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"config.toml": "", "nsec": "not the key"})
+	writeTestFiles(t, other, map[string]string{"nsec": "nsec1secret"})
+
+	files, err := collectBackup(dir, "config.toml", filepath.Join(other, "nsec"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := backupNames(files)
+	if got["nsec"] != "not the key" || got[backupKeyName] != "nsec1secret" {
+		t.Errorf("files = %v", got)
+	}
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	files := []backupFile{
+		{Name: "config.toml", Mode: 0644, Data: []byte("relays = []\n")},
+		{Name: "nsec", Mode: 0600, Data: []byte("nsec1secret")},
+	}
+	archive, err := packBackup(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := sealBackup(archive, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncryptedBackup(sealed) || bytes.Contains(sealed, []byte("nsec1secret")) {
+		t.Fatal("sealed backup isn't encrypted")
+	}
+	if _, err := openBackup(sealed, "wrong"); err == nil {
+		t.Error("openBackup accepted a wrong passphrase")
+	}
+	opened, err := openBackup(sealed, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := unpackBackup(opened)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[1].Name != "nsec" || got[1].Mode != 0600 || string(got[1].Data) != "nsec1secret" {
+		t.Errorf("unpacked %+v", got)
+	}
+}
+
+func TestUnpackBackupRejectsPaths(t *testing.T) {
+	for _, name := range []string{"../evil", "logs/x.log", "/etc/passwd"} {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		_ = tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 1, Typeflag: tar.TypeReg})
+		_, _ = tw.Write([]byte("x"))
+		_ = tw.Close()
+		_ = gz.Close()
+		if _, err := unpackBackup(buf.Bytes()); err == nil {
+			t.Errorf("unpackBackup accepted %q", name)
+		}
+	}
+}
+
+func TestUnpackBackupModeZero(t *testing.T) {
+	archive, err := packBackup([]backupFile{{Name: "contacts", Mode: 0, Data: []byte("x")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := unpackBackup(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Mode != 0600 {
+		t.Errorf("unpacked %+v, want mode 0600", got)
+	}
+}
+
+func TestRestoreConflicts(t *testing.T) {
+	dir, other := t.TempDir(), t.TempDir()
+	writeTestFiles(t, dir, map[string]string{"config.toml": ""})
+	writeTestFiles(t, other, map[string]string{"nsec": ""})
+	files := []backupFile{{Name: "config.toml"}, {Name: "contacts"}, {Name: backupKeyName}}
+	got := restoreConflicts(dir, filepath.Join(other, "nsec"), files)
+	if len(got) != 2 || got[0] != "config.toml" || got[1] != backupKeyName {
+		t.Errorf("restoreConflicts = %v, want [config.toml %s]", got, backupKeyName)
+	}
+}
+
+func TestRestoreCmd(t *testing.T) {
+	dir, other := t.TempDir(), t.TempDir()
+	keyPath := filepath.Join(other, "nsec")
+	if err := os.WriteFile(filepath.Join(dir, "contacts"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files := []backupFile{
+		{Name: "contacts", Mode: 0600, Data: []byte("alice")},
+		{Name: backupKeyName, Mode: 0600, Data: []byte("nsec1secret")},
+	}
+	msg := restoreCmd(dir, keyPath, files)().(restoreDoneMsg)
+	if msg.err != nil || msg.files != 2 {
+		t.Fatalf("restore = %+v", msg)
+	}
+	if data, _ := os.ReadFile(keyPath); string(data) != "nsec1secret" {
+		t.Errorf("key at private_key_file = %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, backupKeyName)); err == nil {
+		t.Error("key restored into the config directory too")
+	}
+	info, err := os.Stat(filepath.Join(dir, "contacts"))
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("overwritten file mode = %v, %v; want 0600", info.Mode().Perm(), err)
+	}
+
+	if msg := restoreCmd(dir, "", files)().(restoreDoneMsg); msg.err == nil {
+		t.Error("restoring a key without private_key_file should fail")
+	}
+}
+
+func TestHandleRestoreDoneQuits(t *testing.T) {
+	m := newTestModel(1, 0, 0)
+	_, cmd := m.handleRestoreDone(restoreDoneMsg{files: 3})
+	if cmd == nil || m.exitNote == "" {
+		t.Fatal("nitrous should quit after restoring")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Error("cmd doesn't quit")
+	}
+}
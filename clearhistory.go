@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// historyRoom returns the history store's roomType and roomKey for a
+// sidebar item.
+func historyRoom(item SidebarItem) (roomType, roomKey string) {
+	switch item.Kind() {
+	case SidebarChannel:
+		return "channel", item.ItemID()
+	case SidebarGroup:
+		return "group", item.ItemID()
+	}
+	return "dm", item.ItemID()
+}
+
+// formatSize renders a byte count as B, KiB or MiB.
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// describeCleared summarizes a historyCleared for the chat.
+func describeCleared(c historyCleared) string {
+	s := fmt.Sprintf("%d messages", c.Messages)
+	if c.Rooms != 1 {
+		s += fmt.Sprintf(" in %d rooms", c.Rooms)
+	}
+	if c.Bytes > 0 {
+		s += " (" + formatSize(c.Bytes) + ")"
+	}
+	return s
+}
+
+// findSidebarByName returns the sidebar item whose display name matches
+// name, ignoring case and an optional "#", "~" or "@" prefix, or nil.
+func (m *model) findSidebarByName(name string) SidebarItem {
+	for _, item := range m.sidebar {
+		n := strings.TrimPrefix(name, item.Prefix())
+		if strings.EqualFold(n, item.DisplayName()) {
+			return item
+		}
+	}
+	return nil
+}
+
+// clearHistory runs /clear-history [room|all [code]]: it deletes on-disk
+// history but leaves the messages on screen alone.
+func (m *model) clearHistory(args []string) (tea.Model, tea.Cmd) {
+	if m.logDir == "" {
+		m.addSystemMsg("clear-history: logging is disabled, there is no on-disk history")
+		return m, nil
+	}
+	if len(args) > 0 && args[0] == "all" {
+		return m.clearAllHistory(args[1:])
+	}
+
+	item := m.activeSidebarItem()
+	if len(args) > 0 {
+		item = m.findSidebarByName(strings.Join(args, " "))
+		if item == nil {
+			m.addSystemMsg(fmt.Sprintf("clear-history: no room named %q", strings.Join(args, " ")))
+			return m, nil
+		}
+	}
+	if item == nil {
+		m.addSystemMsg("clear-history: no room selected")
+		return m, nil
+	}
+	roomType, roomKey := historyRoom(item)
+	cleared, err := m.history.Clear(roomType, roomKey)
+	if err != nil {
+		m.addSystemMsg("clear-history: " + err.Error())
+		return m, nil
+	}
+	m.markHistoryCleared(roomKey)
+	threads := 0
+	if roomType == "group" {
+		for _, key := range m.groupThreadKeys(roomKey) {
+			c, err := m.history.Clear("thread", key)
+			if err != nil {
+				m.addSystemMsg("clear-history: " + err.Error())
+				return m, nil
+			}
+			m.markHistoryCleared(key)
+			if c.Messages > 0 || c.Bytes > 0 {
+				threads++
+			}
+			cleared.Messages += c.Messages
+			cleared.Bytes += c.Bytes
+		}
+	}
+	if cleared.Messages == 0 && cleared.Bytes == 0 {
+		m.addSystemMsg(fmt.Sprintf("no on-disk history for %s%s", item.Prefix(), item.DisplayName()))
+		return m, nil
+	}
+	cleared.Rooms = 1
+	msg := fmt.Sprintf("deleted %s of on-disk history for %s%s", describeCleared(cleared), item.Prefix(), item.DisplayName())
+	if threads > 0 {
+		msg += fmt.Sprintf(", including %d threads", threads)
+	}
+	m.addSystemMsg(msg)
+	return m, nil
+}
+
+// groupThreadKeys returns the thread keys of the group gk's threads: those
+// of its roots and of any thread a reply filed under gk belongs to.
+func (m *model) groupThreadKeys(gk string) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	add := func(rootID string) {
+		if key := threadKey(rootID); !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	for _, r := range threadRoots(m.msgs[gk]) {
+		add(r.ThreadID)
+	}
+	for key, msgs := range m.msgs {
+		if !strings.HasPrefix(key, threadKey("")) {
+			continue
+		}
+		for _, msg := range msgs {
+			if msg.GroupKey == gk {
+				add(strings.TrimPrefix(key, threadKey("")))
+				break
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// clearAllHistory deletes every room's on-disk history once the
+// confirmation code is given.
+func (m *model) clearAllHistory(args []string) (tea.Model, tea.Cmd) {
+	code := confirmCode(m.keys.NPub)
+	if len(args) == 0 || args[0] != code {
+		if len(args) > 0 {
+			m.addSystemMsg("clear-history: wrong confirmation code, nothing deleted")
+		}
+		m.addSystemMsg("⚠ /clear-history all deletes the on-disk history of every room in " + m.logDir)
+		m.addSystemMsg(fmt.Sprintf("  to proceed: /clear-history all %s", code))
+		return m, nil
+	}
+	cleared, err := m.history.Clear("", "")
+	if err != nil {
+		m.addSystemMsg("clear-history: " + err.Error())
+		return m, nil
+	}
+	for _, item := range m.sidebar {
+		m.markHistoryCleared(item.ItemID())
+	}
+	for key := range m.msgs {
+		if strings.HasPrefix(key, threadKey("")) {
+			m.markHistoryCleared(key)
+		}
+	}
+	m.addSystemMsg(fmt.Sprintf("deleted %s of on-disk history", describeCleared(cleared)))
+	return m, nil
+}
+
+// markHistoryCleared stops loadHistory from reading roomKey's history back
+// for the rest of the session.
+func (m *model) markHistoryCleared(roomKey string) {
+	if m.historyCleared == nil {
+		m.historyCleared = make(map[string]bool)
+	}
+	m.historyCleared[roomKey] = true
+}
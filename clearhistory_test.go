@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDescribeCleared(t *testing.T) {
+	tests := []struct {
+		in   historyCleared
+		want string
+	}{
+		{historyCleared{Rooms: 1, Messages: 3}, "3 messages"},
+		{historyCleared{Rooms: 1, Messages: 12, Bytes: 2048}, "12 messages (2.0 KiB)"},
+		{historyCleared{Rooms: 4, Messages: 900, Bytes: 3 << 20}, "900 messages in 4 rooms (3.0 MiB)"},
+		{historyCleared{Rooms: 2, Messages: 5, Bytes: 100}, "5 messages in 2 rooms (100 B)"},
+	}
+	for _, tt := range tests {
+		if got := describeCleared(tt.in); got != tt.want {
+			t.Errorf("describeCleared(%+v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestClearHistorySkipsReload(t *testing.T) {
+	m := newTestModel(1, 0, 0)
+	dir := t.TempDir()
+	m.logDir = dir
+	m.history = fileHistoryStore{dir: dir}
+	m.activeItem = 0
+	m.history.Append("channel", "ch0", ChatMessage{Timestamp: 1, EventID: "a", Content: "hi"}, "alice")
+
+	m.clearHistory(nil)
+	if !m.historyCleared["ch0"] {
+		t.Fatal("room not marked as cleared")
+	}
+	m.history.Append("channel", "ch0", ChatMessage{Timestamp: 2, EventID: "b", Content: "after"}, "alice")
+	m.msgs["ch0"] = nil
+	m.loadHistory("channel", "ch0")
+	if len(m.msgs["ch0"]) != 0 {
+		t.Errorf("loadHistory reloaded %d messages of a cleared room", len(m.msgs["ch0"]))
+	}
+}
+
+func TestClearHistoryGroupClearsThreads(t *testing.T) {
+	m := newTestModel(0, 1, 0)
+	dir := t.TempDir()
+	m.logDir = dir
+	m.history = fileHistoryStore{dir: dir}
+	m.activeItem = 0
+	gk := groupKey("wss://r", "g0")
+	root := ChatMessage{Timestamp: 1, EventID: "root", Content: "topic", GroupKey: gk, ThreadID: "root", ThreadTitle: "topic"}
+	m.msgs[gk] = []ChatMessage{root}
+	m.history.Append("group", gk, root, "alice")
+	reply := ChatMessage{Timestamp: 2, EventID: "r1", Content: "re", GroupKey: gk, ThreadID: "root"}
+	m.msgs[threadKey("root")] = []ChatMessage{reply}
+	m.history.Append("thread", threadKey("root"), reply, "bob")
+	// A thread whose root is no longer in memory still counts through its replies.
+	orphan := ChatMessage{Timestamp: 3, EventID: "r2", Content: "old", GroupKey: gk, ThreadID: "gone"}
+	m.msgs[threadKey("gone")] = []ChatMessage{orphan}
+	m.history.Append("thread", threadKey("gone"), orphan, "bob")
+	other := ChatMessage{Timestamp: 4, EventID: "r3", Content: "elsewhere", GroupKey: "wss://x\tg9", ThreadID: "other"}
+	m.history.Append("thread", threadKey("other"), other, "carol")
+	m.msgs[threadKey("other")] = []ChatMessage{other}
+
+	m.clearHistory(nil)
+	for _, root := range []string{"root", "gone"} {
+		if msgs, _ := m.history.Load("thread", threadKey(root), 10); len(msgs) != 0 {
+			t.Errorf("thread %s still has %d messages on disk", root, len(msgs))
+		}
+		if !m.historyCleared[threadKey(root)] {
+			t.Errorf("thread %s not marked as cleared", root)
+		}
+	}
+	if msgs, _ := m.history.Load("thread", threadKey("other"), 10); len(msgs) != 1 {
+		t.Errorf("another group's thread lost its history: %d messages left", len(msgs))
+	}
+	last := m.msgs[gk][len(m.msgs[gk])-1].Content
+	if want := "deleted 3 messages"; !strings.Contains(last, want) || !strings.Contains(last, "including 2 threads") {
+		t.Errorf("report = %q, want %q and the thread count", last, want)
+	}
+}
@@ -4,13 +4,118 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"fiatjaf.com/nostr"
 	"fiatjaf.com/nostr/nip19"
 )
 
+// commandHelp describes one usage of a slash command. The list drives /help,
+// top-level Tab completion, and the ctrl+p command palette.
+type commandHelp struct {
+	Name  string // command name, e.g. "/join"
+	Usage string // full usage line, e.g. "/join #name"
+	Desc  string // short description
+}
+
+var commandHelps = []commandHelp{
+	{"/channel", "/channel create #name", "create a NIP-28 channel"},
+	{"/join", "/join #name", "join a channel from your rooms file"},
+	{"/join", "/join <event-id>", "join a channel by ID"},
+	{"/join", "/join nevent1...", "join a channel, also using the relays in the nevent"},
+	{"/join", "/join naddr1... [code]", "join a NIP-29 group (with optional invite code)"},
+	{"/join", "/join host'groupid [code]", "join a NIP-29 group"},
+	{"/dm", "/dm <npub|user@domain>", "open a DM conversation"},
+	{"/dm", "/dm <user> <user> ...", "open a NIP-17 group DM with several people"},
+	{"/group", "/group create <name> <relay>", "create a closed NIP-29 group"},
+	{"/group", "/group set open|closed", "set group open or closed"},
+	{"/group", "/group user add <pubkey>", "add a user to the group"},
+	{"/group", "/group user remove <pubkey>", "remove a user from the group"},
+	{"/group", "/group name <new-name>", "edit group name"},
+	{"/group", "/group about <text>", "edit group description"},
+	{"/group", "/group picture <url>", "edit group picture"},
+	{"/group", "/group mirror [add|remove <relay>]", "list or change the further relays a group is read from and sent to"},
+	{"/group", "/group invite", "create an invite code for the group (NIP-29 kind 9009)"},
+	{"/invite", "/invite <name>", "add a contact to the group and DM them the link"},
+	{"/list-invites", "/list-invites [all]", "list the invite codes you created for this group (or all groups)"},
+	{"/revoke-invite", "/revoke-invite <code>", "ask the relay to revoke an invite code you created"},
+	{"/delete", "/delete", "delete your last message in the current group"},
+	{"/delete", "/delete <event-id>", "delete a message by ID (admin)"},
+	{"/leave", "/leave", "leave the current channel, group, or DM"},
+	{"/move", "/move up|down", "move the current channel, group, or DM within its sidebar section (saved)"},
+	{"/follow", "/follow [npub|name]", "follow someone (NIP-02 kind 3); no arg lists follows"},
+	{"/unfollow", "/unfollow <npub|name>", "remove someone from your follow list"},
+	{"/mute-word", "/mute-word [word]", "hide messages containing a word (no arg lists muted words)"},
+	{"/unmute-word", "/unmute-word <word>", "stop hiding messages containing a word"},
+	{"/boost", "/boost [n]", "repost the nth most recent message into this room (NIP-18)"},
+	{"/embed", "/embed <nevent>", "post a note from anywhere on nostr into this room, quoted as a card"},
+	{"/backup", "/backup <path>", "save config, key, and state files to one archive (encrypted with a passphrase)"},
+	{"/restore", "/restore [--force] <path>", "unpack a /backup archive into the config directory, then quit"},
+	{"/cw", "/cw <reason> <text>", "send a message behind a content warning (NIP-36); enter on an empty input reveals one"},
+	{"/timed", "/timed <duration> <text>", "send a DM that expires (NIP-40); best-effort: only relays that honor expiration drop it, and the recipient may keep a copy"},
+	{"/react", "/react [n] [emoji]", "react to the nth most recent message (NIP-25); without an emoji, pick one from a grid"},
+	{"/import", "/import contacts <path|list>", "bulk-add DM contacts from a file or pasted list of npubs (or name,npub)"},
+	{"/import", "/import rooms <path|list>", "bulk-add channels from a file or pasted list of channel IDs"},
+	{"/recent", "/recent [n]", "list the most recently active conversations, or jump to the nth"},
+	{"/peek-unread", "/peek-unread", "browse unread messages of all rooms without marking them read"},
+	{"/info", "/info [n]", "show details and relay delivery of the nth most recent message"},
+	{"/me", "/me", "show QR code of your npub"},
+	{"/room", "/room", "show QR code of the current channel or group"},
+	{"/invoice", "/invoice [n]", "show QR code of the nth most recent lightning invoice or cashu token"},
+	{"/dm-search", "/dm-search [--logs] <term>", "search DMs in memory (--logs also searches DM log files)"},
+	{"/dm-search", "/dm-search #<n>", "open the nth search result"},
+	{"/filter", "/filter [contacts|all|since <dur>|limit <n>|reset]", "adjust the current room's subscription filter"},
+	{"/nip05", "/nip05 [name]", "show the .well-known/nostr.json snippet for your pubkey"},
+	{"/nip05", "/nip05 <name@domain>", "check that a NIP-05 address points to you"},
+	{"/away", "/away [--all] [message]", "auto-reply once per person to new DMs from contacts (--all: from anyone) until /back"},
+	{"/back", "/back", "stop auto-replying to DMs"},
+	{"/whois", "/whois [npub|name]", "show profile, NIP-05, relays, shared rooms, and recent messages of a user"},
+	{"/ping", "/ping", "measure round-trip latency to each relay"},
+	{"/relay-test", "/relay-test <wss://...>", "check which NIPs nitrous uses a relay supports"},
+	{"/read-receipts", "/read-receipts [on|off]", "send and show DM read receipts this session (default: dm_read_receipts); on in a DM opts that peer in"},
+	{"/save-draft", "/save-draft <name> <text>", "save a reusable named draft"},
+	{"/drafts", "/drafts", "list saved drafts"},
+	{"/draft", "/draft <name>", "load a saved draft into the input"},
+	{"/read", "/read <naddr>", "read a NIP-23 long-form article full-screen"},
+	{"/nsec-rotate", "/nsec-rotate [code [migrate]]", "replace your key with a new one (shows warnings and the confirmation code)"},
+	{"/toggle-markdown", "/toggle-markdown", "switch this room between markdown and plain text (saved)"},
+	{"/whitelist", "/whitelist [show]", "show only messages from your contacts and follows in this room (saved); show expands the hidden ones"},
+	{"/display", "/display", "toggle display options (timestamps, avatars, markdown, ...) in a menu (saved)"},
+	{"/toggle-timestamps", "/toggle-timestamps", "show or hide message times (saved)"},
+	{"/toggle-avatars", "/toggle-avatars", "show or hide avatars (saved)"},
+	{"/stats-relay", "/stats-relay", "show which relays delivered events for each channel and group"},
+	{"/thread", "/thread [list]", "list the threads of the current group (NIP-29)"},
+	{"/thread", "/thread new <title>", "start a thread in the current group"},
+	{"/thread", "/thread open <n>", "read and reply to the nth thread; /thread close to go back"},
+	{"/reload", "/reload", "re-read the config and state files and apply them without restarting"},
+	{"/test-dm", "/test-dm", "send yourself a DM and check that it comes back (DM self-test)"},
+	{"/digest", "/digest [n]", "summarize the messages that were unread here, or the last n, via digest_endpoint"},
+	{"/mergerelays", "/mergerelays", "collapse equivalent relay URLs (case, trailing slash, default port) in the relay list"},
+	{"/dedup-stats", "/dedup-stats", "show the size and age of the duplicate-event caches"},
+	{"/dedup-clear", "/dedup-clear", "empty the duplicate-event caches (for troubleshooting)"},
+	{"/clear-history", "/clear-history [room|all]", "delete the on-disk history of this room, a named room, or all rooms"},
+	{"/retry-failed", "/retry-failed", "send all failed messages in this conversation again"},
+	{"/retry", "/retry <n>", "send the nth most recent message again if it failed"},
+	{"/help", "/help [page|command]", "show this help, one page at a time, or the help for matching commands"},
+}
+
+// commandNames returns the distinct command names from commandHelps, in order.
+func commandNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, h := range commandHelps {
+		if !seen[h.Name] {
+			seen[h.Name] = true
+			names = append(names, h.Name)
+		}
+	}
+	return names
+}
+
 func (m *model) handleCommand(text string) (tea.Model, tea.Cmd) {
 	parts := strings.SplitN(text, " ", 2)
 	cmd := strings.ToLower(parts[0])
@@ -25,7 +130,7 @@ func (m *model) handleCommand(text string) (tea.Model, tea.Cmd) {
 
 	case "/join":
 		if arg == "" {
-			m.addSystemMsg("usage: /join #name | <event-id> | naddr1... | host'groupid")
+			m.addSystemMsg("usage: /join #name | <event-id> | nevent1... | naddr1... | host'groupid")
 			return m, nil
 		}
 		// NIP-29 group: naddr or host'groupid
@@ -36,9 +141,12 @@ func (m *model) handleCommand(text string) (tea.Model, tea.Cmd) {
 
 	case "/dm":
 		if arg == "" {
-			m.addSystemMsg("usage: /dm <npub, hex pubkey, or NIP-05 address>")
+			m.addSystemMsg("usage: /dm <npub, hex pubkey, or NIP-05 address> [more ...]")
 			return m, nil
 		}
+		if fields := strings.Fields(arg); len(fields) > 1 {
+			return m.openGroupDM(fields)
+		}
 		return m.openDM(arg)
 
 	case "/me":
@@ -54,7 +162,7 @@ func (m *model) handleCommand(text string) (tea.Model, tea.Cmd) {
 					m.addSystemMsg(fmt.Sprintf("invalid channel ID: %v", err))
 					return m, nil
 				}
-				nevent := nip19.EncodeNevent(id, m.relays, nostr.PubKey{})
+				nevent := nip19.EncodeNevent(id, it.Channel.RelaysWith(m.relays), nostr.PubKey{})
 				m.qrOverlay = renderQR("#"+it.Channel.Name, "nostr:"+nevent)
 				return m, nil
 			case GroupItem:
@@ -70,6 +178,127 @@ func (m *model) handleCommand(text string) (tea.Model, tea.Cmd) {
 		m.addSystemMsg("no active channel or group — switch to one first")
 		return m, nil
 
+	case "/invoice":
+		return m.showPaymentQR(arg)
+
+	case "/info":
+		return m.showMessageInfo(arg)
+
+	case "/toggle-markdown":
+		return m.toggleMarkdown()
+
+	case "/whitelist":
+		return m.whitelistCommand(arg)
+
+	case "/display":
+		m.displayMenu = &displayMenu{}
+		return m, nil
+
+	case "/toggle-timestamps":
+		return m.toggleDisplayOption("timestamps")
+
+	case "/toggle-avatars":
+		return m.toggleDisplayOption("avatars")
+
+	case "/stats-relay":
+		return m.showRelayStats()
+
+	case "/thread":
+		return m.handleThreadCommand(arg)
+
+	case "/react":
+		return m.react(arg)
+
+	case "/test-dm":
+		return m.testDM()
+
+	case "/digest":
+		return m.digest(arg)
+
+	case "/mergerelays":
+		return m.mergeRelays()
+
+	case "/dedup-stats":
+		return m.showDedupStats()
+
+	case "/dedup-clear":
+		return m.clearDedup()
+
+	case "/clear-history":
+		return m.clearHistory(strings.Fields(arg))
+
+	case "/retry-failed":
+		return m.retryFailed()
+
+	case "/retry":
+		return m.retryMessage(arg)
+
+	case "/recent":
+		return m.showRecent(arg)
+
+	case "/import":
+		return m.handleImport(arg)
+
+	case "/nip05":
+		return m.showNIP05(arg)
+
+	case "/away":
+		return m.setAway(arg)
+
+	case "/back":
+		return m.setBack()
+
+	case "/whois":
+		return m.showWhois(arg)
+
+	case "/save-draft":
+		return m.saveDraft(arg)
+
+	case "/drafts":
+		return m.listDrafts()
+
+	case "/draft":
+		return m.loadDraft(arg)
+
+	case "/read":
+		return m.openReader(arg)
+
+	case "/nsec-rotate":
+		return m.rotateKey(strings.Fields(arg))
+
+	case "/ping":
+		m.addSystemMsg(fmt.Sprintf("pinging %d relays ...", len(m.relays)))
+		return m, pingRelaysCmd(m.pool, m.relays)
+	case "/relay-test":
+		return m.relayTest(arg)
+
+	case "/read-receipts":
+		return m.toggleReadReceipts(arg)
+
+	case "/filter":
+		return m.handleFilterCommand(arg)
+
+	case "/boost":
+		return m.boostMessage(arg)
+
+	case "/embed":
+		return m.embedNote(arg)
+
+	case "/cw":
+		return m.sendContentWarning(arg)
+
+	case "/backup":
+		return m.backupCommand(arg)
+
+	case "/restore":
+		return m.restoreCommand(arg)
+
+	case "/dm-search":
+		return m.handleDMSearch(arg)
+
+	case "/peek-unread":
+		return m.peekUnread()
+
 	case "/delete":
 		if !m.isGroupSelected() {
 			m.addSystemMsg("/delete only works in a NIP-29 group")
@@ -83,7 +312,17 @@ func (m *model) handleCommand(text string) (tea.Model, tea.Cmd) {
 		g := gi.Group
 		gk := groupKey(g.RelayURL, g.GroupID)
 		if arg != "" {
-			// Delete by explicit event ID (admin use).
+			// Delete by explicit event ID (admin use, unless it's our own message).
+			own := false
+			for _, cm := range m.msgs[gk] {
+				if cm.EventID == arg && cm.IsMine {
+					own = true
+					break
+				}
+			}
+			if !own && !m.requireGroupAdmin(g) {
+				return m, nil
+			}
 			// Remove from local messages.
 			msgs := m.msgs[gk]
 			for i, cm := range msgs {
@@ -117,6 +356,12 @@ func (m *model) handleCommand(text string) (tea.Model, tea.Cmd) {
 	case "/group":
 		return m.handleGroupCommand(arg)
 
+	case "/list-invites":
+		return m.listInvites(arg)
+
+	case "/revoke-invite":
+		return m.revokeInvite(arg)
+
 	case "/invite":
 		if !m.isGroupSelected() {
 			m.addSystemMsg("/invite requires a group to be selected")
@@ -130,35 +375,113 @@ func (m *model) handleCommand(text string) (tea.Model, tea.Cmd) {
 
 	case "/leave":
 		return m.leaveCurrentItem()
+	case "/move":
+		return m.moveActiveItem(arg)
+	case "/timed":
+		return m.sendTimed(arg)
+	case "/reload":
+		return m.reload()
+
+	case "/follow":
+		if arg == "" {
+			if len(m.follows) == 0 {
+				m.addSystemMsg("not following anyone — usage: /follow <npub|hex|name>")
+				return m, nil
+			}
+			names := make([]string, 0, len(m.follows))
+			for _, f := range m.follows {
+				names = append(names, m.resolveAuthor(f.PubKey))
+			}
+			m.addSystemMsg(fmt.Sprintf("following %d: %s", len(names), strings.Join(names, ", ")))
+			return m, nil
+		}
+		return m.followPubKey(arg)
 
-	case "/help":
-		m.addSystemMsg("/channel create #name — create a NIP-28 channel")
-		m.addSystemMsg("/join #name — join a channel from your rooms file")
-		m.addSystemMsg("/join <event-id> — join a channel by ID")
-		m.addSystemMsg("/join naddr1... [code] — join a NIP-29 group (with optional invite code)")
-		m.addSystemMsg("/join host'groupid [code] — join a NIP-29 group")
-		m.addSystemMsg("/dm <npub|user@domain> — open a DM conversation")
-		m.addSystemMsg("/group create <name> <relay> — create a closed NIP-29 group")
-		m.addSystemMsg("/group set open|closed — set group open or closed")
-		m.addSystemMsg("/group user add <pubkey> — add a user to the group")
-		m.addSystemMsg("/group name <new-name> — edit group name")
-		m.addSystemMsg("/group about <text> — edit group description")
-		m.addSystemMsg("/group picture <url> — edit group picture")
-		m.addSystemMsg("/invite <name> — add a contact to the group and DM them the link")
-		m.addSystemMsg("/delete — delete your last message in the current group")
-		m.addSystemMsg("/delete <event-id> — delete a message by ID (admin)")
-		m.addSystemMsg("/leave — leave the current channel, group, or DM")
-		m.addSystemMsg("/me — show QR code of your npub")
-		m.addSystemMsg("/room — show QR code of the current channel or group")
-		m.addSystemMsg("/help — show this help")
+	case "/unfollow":
+		if arg == "" {
+			m.addSystemMsg("usage: /unfollow <npub|hex|name>")
+			return m, nil
+		}
+		return m.unfollowPubKey(arg)
+
+	case "/mute-word":
+		if arg == "" {
+			if len(m.mutedWords) == 0 {
+				m.addSystemMsg("no muted words — usage: /mute-word <word>")
+				return m, nil
+			}
+			m.addSystemMsg("muted words: " + strings.Join(m.mutedWordsList(), ", "))
+			return m, nil
+		}
+		word := strings.ToLower(arg)
+		m.mutedWords[word] = true
+		m.addSystemMsg(fmt.Sprintf("muted %q for this session", word))
 		return m, nil
 
+	case "/unmute-word":
+		if arg == "" {
+			m.addSystemMsg("usage: /unmute-word <word>")
+			return m, nil
+		}
+		word := strings.ToLower(arg)
+		if !m.mutedWords[word] {
+			m.addSystemMsg(fmt.Sprintf("%q is not muted", word))
+			return m, nil
+		}
+		delete(m.mutedWords, word)
+		m.addSystemMsg(fmt.Sprintf("unmuted %q", word))
+		return m, nil
+
+	case "/help":
+		return m.showHelp(arg)
+
 	default:
 		m.addSystemMsg("unknown command: " + cmd)
 		return m, nil
 	}
 }
 
+// helpPageSize is how many commands one /help page lists, so a page fits the
+// chat view without scrolling.
+const helpPageSize = 15
+
+// showHelp handles /help [page|command]: a page of the command list, or
+// the entries matching a command name or word.
+func (m *model) showHelp(arg string) (tea.Model, tea.Cmd) {
+	page := 1
+	if arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			matches := filterCommandHelps(arg)
+			if len(matches) == 0 {
+				m.addSystemMsg(fmt.Sprintf("no command matches %q", arg))
+			}
+			for _, h := range matches {
+				m.addSystemMsg(h.Usage + " — " + h.Desc)
+			}
+			return m, nil
+		}
+		page = n
+	}
+	pages := (len(commandHelps) + helpPageSize - 1) / helpPageSize
+	if page < 1 || page > pages {
+		m.addSystemMsg(fmt.Sprintf("usage: /help [1-%d|command]", pages))
+		return m, nil
+	}
+	if page == 1 {
+		newline := strings.Join(m.cfg.NewlineKeys(), " or ")
+		m.addSystemMsg(m.cfg.SendKeyBinding() + " sends, " + newline + " inserts a newline")
+	}
+	start := (page - 1) * helpPageSize
+	for _, h := range commandHelps[start:min(start+helpPageSize, len(commandHelps))] {
+		m.addSystemMsg(h.Usage + " — " + h.Desc)
+	}
+	if page < pages {
+		m.addSystemMsg(fmt.Sprintf("help page %d/%d — /help %d for more, /help <command> to look one up", page, pages, page+1))
+	}
+	return m, nil
+}
+
 // handleChannelCommand handles /channel subcommands.
 func (m *model) handleChannelCommand(arg string) (tea.Model, tea.Cmd) {
 	if arg == "" {
@@ -190,7 +513,7 @@ func (m *model) handleChannelCommand(arg string) (tea.Model, tea.Cmd) {
 
 func (m *model) handleGroupCommand(arg string) (tea.Model, tea.Cmd) {
 	if arg == "" {
-		m.addSystemMsg("usage: /group create <name> <relay> | set open|closed | user add <pubkey> | name <new-name> | about <text> | picture <url>")
+		m.addSystemMsg("usage: /group create <name> <relay> | set open|closed | user add <pubkey> | name <new-name> | about <text> | picture <url> | mirror | invite")
 		return m, nil
 	}
 
@@ -212,7 +535,7 @@ func (m *model) handleGroupCommand(arg string) (tea.Model, tea.Cmd) {
 		name := createParts[0]
 		relayURL := m.cfg.GroupRelay
 		if len(createParts) >= 2 && (strings.HasPrefix(createParts[1], "wss://") || strings.HasPrefix(createParts[1], "ws://")) {
-			relayURL = createParts[1]
+			relayURL = normalizeRelayURL(createParts[1])
 		}
 		if relayURL == "" {
 			m.addSystemMsg("no relay specified and group_relay not set in config")
@@ -228,6 +551,9 @@ func (m *model) handleGroupCommand(arg string) (tea.Model, tea.Cmd) {
 		}
 		gi := m.activeSidebarItem().(GroupItem)
 		g := gi.Group
+		if !m.requireGroupAdmin(g) {
+			return m, nil
+		}
 		gk := groupKey(g.RelayURL, g.GroupID)
 		switch strings.ToLower(subArg) {
 		case "open":
@@ -240,14 +566,18 @@ func (m *model) handleGroupCommand(arg string) (tea.Model, tea.Cmd) {
 		}
 
 	case "user":
-		// /group user add <pubkey>
+		// /group user add|remove <pubkey>
 		if !m.isGroupSelected() {
 			m.addSystemMsg("/group user requires a group to be selected")
 			return m, nil
 		}
 		userParts := strings.SplitN(subArg, " ", 2)
-		if len(userParts) < 2 || strings.ToLower(userParts[0]) != "add" {
-			m.addSystemMsg("usage: /group user add <npub-or-hex>")
+		action := ""
+		if len(userParts) == 2 {
+			action = strings.ToLower(userParts[0])
+		}
+		if action != "add" && action != "remove" {
+			m.addSystemMsg("usage: /group user add|remove <npub-or-hex>")
 			return m, nil
 		}
 		pk := strings.TrimSpace(userParts[1])
@@ -261,7 +591,14 @@ func (m *model) handleGroupCommand(arg string) (tea.Model, tea.Cmd) {
 		}
 		gi := m.activeSidebarItem().(GroupItem)
 		g := gi.Group
+		if !m.requireGroupAdmin(g) {
+			return m, nil
+		}
 		gk := groupKey(g.RelayURL, g.GroupID)
+		if action == "remove" {
+			m.addSystemMsg(fmt.Sprintf("removing user %s from ~%s", shortPK(pk), g.Name))
+			return m, removeUserCmd(m.pool, g.RelayURL, g.GroupID, pk, m.groupRecentIDs[gk], m.keys)
+		}
 		m.addSystemMsg(fmt.Sprintf("adding user %s to ~%s", shortPK(pk), g.Name))
 		return m, putUserCmd(m.pool, g.RelayURL, g.GroupID, pk, m.groupRecentIDs[gk], m.keys)
 
@@ -277,6 +614,9 @@ func (m *model) handleGroupCommand(arg string) (tea.Model, tea.Cmd) {
 		}
 		gi := m.activeSidebarItem().(GroupItem)
 		g := gi.Group
+		if !m.requireGroupAdmin(g) {
+			return m, nil
+		}
 		gk := groupKey(g.RelayURL, g.GroupID)
 		m.updateGroupName(g.RelayURL, g.GroupID, subArg)
 		m.updateViewport()
@@ -297,9 +637,18 @@ func (m *model) handleGroupCommand(arg string) (tea.Model, tea.Cmd) {
 		}
 		gi := m.activeSidebarItem().(GroupItem)
 		g := gi.Group
+		if !m.requireGroupAdmin(g) {
+			return m, nil
+		}
 		gk := groupKey(g.RelayURL, g.GroupID)
 		return m, editGroupMetadataCmd(m.pool, g.RelayURL, g.GroupID, map[string]string{"about": subArg}, m.groupRecentIDs[gk], m.keys)
 
+	case "mirror":
+		return m.handleGroupMirror(subArg)
+
+	case "invite":
+		return m.createInvite()
+
 	case "picture":
 		// /group picture <url>
 		if !m.isGroupSelected() {
@@ -312,56 +661,44 @@ func (m *model) handleGroupCommand(arg string) (tea.Model, tea.Cmd) {
 		}
 		gi := m.activeSidebarItem().(GroupItem)
 		g := gi.Group
+		if !m.requireGroupAdmin(g) {
+			return m, nil
+		}
 		gk := groupKey(g.RelayURL, g.GroupID)
 		return m, editGroupMetadataCmd(m.pool, g.RelayURL, g.GroupID, map[string]string{"picture": subArg}, m.groupRecentIDs[gk], m.keys)
 
 	default:
 		m.addSystemMsg("unknown group subcommand: " + sub)
-		m.addSystemMsg("usage: /group create|set|user|name|about|picture")
+		m.addSystemMsg("usage: /group create|set|user|name|about|picture|mirror|invite")
 		return m, nil
 	}
 }
 
+// requireGroupAdmin reports whether an admin command may run in g. Once the
+// group's admins list is known and we're not on it, it says so instead of
+// publishing an event the relay would reject.
+func (m *model) requireGroupAdmin(g Group) bool {
+	if g.AdminsKnown && !g.isAdmin() {
+		m.addSystemMsg(fmt.Sprintf("you're not an admin of ~%s", g.Name))
+		return false
+	}
+	return true
+}
+
 // inviteToGroup resolves a contact name, npub, or hex pubkey, adds them to
 // the current group via kind 9000, and sends a DM with the group naddr.
 func (m *model) inviteToGroup(input string) (tea.Model, tea.Cmd) {
 	gi := m.activeSidebarItem().(GroupItem)
 	g := gi.Group
+	if !m.requireGroupAdmin(g) {
+		return m, nil
+	}
 	gk := groupKey(g.RelayURL, g.GroupID)
 
-	// Resolve input to a pubkey: try contact name first, then npub, then raw hex.
-	pk := ""
-	if strings.HasPrefix(input, "npub") {
-		prefix, decoded, err := nip19.Decode(input)
-		if err != nil || prefix != "npub" {
-			m.addSystemMsg("invalid npub")
-			return m, nil
-		}
-		pk = decoded.(nostr.PubKey).Hex()
-	} else if len(input) == 64 {
-		if _, err := hex.DecodeString(input); err != nil {
-			m.addSystemMsg("invalid hex pubkey")
-			return m, nil
-		}
-		pk = input
-	} else {
-		// Look up by display name in profiles (case-insensitive).
-		var matches []string
-		for pubkey, name := range m.profiles {
-			if strings.EqualFold(name, input) {
-				matches = append(matches, pubkey)
-			}
-		}
-		switch len(matches) {
-		case 0:
-			m.addSystemMsg(fmt.Sprintf("unknown contact: %s (use npub or hex pubkey)", input))
-			return m, nil
-		case 1:
-			pk = matches[0]
-		default:
-			m.addSystemMsg(fmt.Sprintf("ambiguous name %q matches %d contacts — use npub or hex pubkey instead", input, len(matches)))
-			return m, nil
-		}
+	pk, err := m.resolvePubKey(input)
+	if err != nil {
+		m.addSystemMsg(err.Error())
+		return m, nil
 	}
 
 	naddr, err := m.groupNaddr(g)
@@ -379,8 +716,100 @@ func (m *model) inviteToGroup(input string) (tea.Model, tea.Cmd) {
 	)
 }
 
+// resolvePubKey resolves an npub, raw hex pubkey, or known display name
+// (case-insensitive) to a hex pubkey.
+func (m *model) resolvePubKey(input string) (string, error) {
+	if strings.HasPrefix(input, "npub") {
+		prefix, decoded, err := nip19.Decode(input)
+		if err != nil || prefix != "npub" {
+			return "", fmt.Errorf("invalid npub")
+		}
+		return decoded.(nostr.PubKey).Hex(), nil
+	}
+	if len(input) == 64 {
+		if _, err := hex.DecodeString(input); err != nil {
+			return "", fmt.Errorf("invalid hex pubkey")
+		}
+		return input, nil
+	}
+	// Look up by display name in profiles (case-insensitive).
+	var matches []string
+	for pubkey, name := range m.profiles {
+		if strings.EqualFold(name, input) {
+			matches = append(matches, pubkey)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("unknown contact: %s (use npub or hex pubkey)", input)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("ambiguous name %q matches %d contacts — use npub or hex pubkey instead", input, len(matches))
+	}
+}
+
+// errFollowsNotLoaded explains why /follow and /unfollow refuse to publish.
+const errFollowsNotLoaded = "no follow list (kind 3) received from your relays yet, so /follow and /unfollow are off: " +
+	"publishing now could overwrite your real list with an empty one. Try again once it has loaded (/reload refetches it)"
+
+// followPubKey adds a pubkey to the kind 3 follow list, adds it as a DM
+// peer with follows_in_sidebar, and republishes the list.
+func (m *model) followPubKey(input string) (tea.Model, tea.Cmd) {
+	if !m.followsLoaded {
+		m.addSystemMsg(errFollowsNotLoaded)
+		return m, nil
+	}
+	pk, err := m.resolvePubKey(input)
+	if err != nil {
+		m.addSystemMsg(err.Error())
+		return m, nil
+	}
+	name := m.resolveAuthor(pk)
+	if followIndex(m.follows, pk) >= 0 {
+		m.addSystemMsg(fmt.Sprintf("already following %s", name))
+		return m, nil
+	}
+	// The petname slot is for names the user assigns, so it stays empty.
+	m.follows = append(m.follows, Follow{PubKey: pk})
+	m.addSystemMsg(fmt.Sprintf("following %s", name))
+
+	cmds := []tea.Cmd{publishFollowListCmd(m.pool, m.relays, m.follows, m.followsContent, m.keys)}
+	if m.cfg.FollowsAsDMs && !m.containsDMPeer(pk) {
+		m.appendDMItem(pk, name)
+		cmds = append(cmds, m.syncContacts())
+		if cmd := m.maybeRequestProfile(pk); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// unfollowPubKey removes a pubkey from the kind 3 follow list and republishes it.
+// The DM conversation is kept; use /leave to remove it from the sidebar.
+func (m *model) unfollowPubKey(input string) (tea.Model, tea.Cmd) {
+	if !m.followsLoaded {
+		m.addSystemMsg(errFollowsNotLoaded)
+		return m, nil
+	}
+	pk, err := m.resolvePubKey(input)
+	if err != nil {
+		m.addSystemMsg(err.Error())
+		return m, nil
+	}
+	idx := followIndex(m.follows, pk)
+	if idx < 0 {
+		m.addSystemMsg(fmt.Sprintf("not following %s", m.resolveAuthor(pk)))
+		return m, nil
+	}
+	m.follows = append(m.follows[:idx], m.follows[idx+1:]...)
+	m.addSystemMsg(fmt.Sprintf("unfollowed %s", m.resolveAuthor(pk)))
+	return m, publishFollowListCmd(m.pool, m.relays, m.follows, m.followsContent, m.keys)
+}
+
 // joinChannel handles /join. #name looks up the rooms file, a raw hex ID
-// joins directly and appends to the rooms file.
+// joins directly and appends to the rooms file. A nevent also adds its relay
+// hints to the channel's relays.
 func (m *model) joinChannel(arg string) (tea.Model, tea.Cmd) {
 	if strings.HasPrefix(arg, "#") {
 		// Lookup by name
@@ -390,21 +819,34 @@ func (m *model) joinChannel(arg string) (tea.Model, tea.Cmd) {
 				log.Printf("joinChannel: found %q -> %s", name, ci.Channel.ID)
 				m.activeItem = i
 				m.updateViewport()
-				return m, subscribeChannelCmd(m.pool, m.relays, ci.Channel.ID)
+				return m, m.subscribeChannel(ci.Channel.ID)
 			}
 		}
 		m.addSystemMsg("unknown room: " + name + " (add it to your rooms file)")
 		return m, nil
 	}
 
-	// Raw hex event ID — check if already known
-	id := arg
+	// Raw hex event ID, or nevent/note carrying it — check if already known
+	id, hints, err := parseChannelRef(arg)
+	if err != nil {
+		m.addSystemMsg("join: " + err.Error())
+		return m, nil
+	}
 	if idx := m.findChannelIdx(id); idx >= 0 {
 		ci := m.sidebar[idx].(ChannelItem)
 		log.Printf("joinChannel: already have %s as %q", id, ci.Channel.Name)
 		m.activeItem = idx
 		m.updateViewport()
-		return m, subscribeChannelCmd(m.pool, m.relays, ci.Channel.ID)
+		if merged := mergeRelayHints(ci.Channel.Relays, hints); len(merged) > len(ci.Channel.Relays) {
+			ci.Channel.Relays = merged
+			m.sidebar[idx] = ci
+			m.cancelRoomSub(id) // resubscribe on the new relays too
+			return m, tea.Batch(
+				m.subscribeChannel(id),
+				publishPublicChatsListCmd(m.pool, m.relays, m.allChannels(), m.keys),
+			)
+		}
+		return m, m.subscribeChannel(ci.Channel.ID)
 	}
 
 	// New room — add with placeholder, fetch metadata to get the real name
@@ -412,12 +854,12 @@ func (m *model) joinChannel(arg string) (tea.Model, tea.Cmd) {
 	if len(placeholder) > 8 {
 		placeholder = placeholder[:8]
 	}
-	idx := m.appendChannelItem(Channel{Name: placeholder, ID: id})
+	idx := m.appendChannelItem(Channel{Name: placeholder, ID: id, Relays: hints})
 	m.activeItem = idx
 	m.updateViewport()
 	return m, tea.Batch(
-		subscribeChannelCmd(m.pool, m.relays, id),
-		fetchChannelMetaCmd(m.pool, m.relays, id),
+		m.subscribeChannel(id),
+		fetchChannelMetaCmd(m.pool, m.queries, m.channelRelays(id), id),
 	)
 }
 
@@ -445,13 +887,13 @@ func (m *model) joinGroup(arg string) (tea.Model, tea.Cmd) {
 		m.activeItem = idx
 		m.updateViewport()
 		gi := m.sidebar[idx].(GroupItem)
-		return m, subscribeGroupCmd(m.pool, gi.Group.RelayURL, gi.Group.GroupID)
+		return m, m.subscribeGroup(gi.Group.RelayURL, gi.Group.GroupID)
 	}
 
 	// New group — send join request, then handle groupJoinedMsg
 	return m, tea.Batch(
 		joinGroupCmd(m.pool, relayURL, groupID, m.groupRecentIDs[gk], inviteCode, m.keys),
-		fetchGroupMetaCmd(m.pool, relayURL, groupID),
+		fetchGroupMetaCmd(m.pool, m.queries, relayURL, groupID, m.cfg.ReplaceableWait()),
 	)
 }
 
@@ -488,7 +930,7 @@ func (m *model) openDM(input string) (tea.Model, tea.Cmd) {
 	}
 	m.updateViewport()
 	if newPeer {
-		return m, publishContactsListCmd(m.pool, m.relays, contactsFromModel(m.allDMPeers(), m.profiles), m.keys, m.kr)
+		return m, m.syncContacts()
 	}
 	return m, nil
 }
@@ -551,8 +993,14 @@ func (m *model) leaveCurrentItem() (tea.Model, tea.Cmd) {
 		m.removeSidebarItem(m.activeItem)
 		delete(m.msgs, peer)
 
-		leaveCmds = append(leaveCmds, publishContactsListCmd(m.pool, m.relays, contactsFromModel(m.allDMPeers(), m.profiles), m.keys, m.kr))
+		leaveCmds = append(leaveCmds, m.syncContacts())
 		log.Printf("leaveCurrentItem: left DM with %s", m.resolveAuthor(peer))
+
+	case GroupDMItem:
+		// Not in the contacts list; it comes back if someone writes again.
+		m.removeSidebarItem(m.activeItem)
+		delete(m.msgs, it.ItemID())
+		log.Printf("leaveCurrentItem: left group DM with %s", it.Name)
 	}
 
 	// Clamp activeItem to valid range.
@@ -569,9 +1017,9 @@ func (m *model) leaveCurrentItem() (tea.Model, tea.Cmd) {
 	if newItem := m.activeSidebarItem(); newItem != nil {
 		switch it := newItem.(type) {
 		case ChannelItem:
-			leaveCmds = append(leaveCmds, subscribeChannelCmd(m.pool, m.relays, it.Channel.ID))
+			leaveCmds = append(leaveCmds, m.subscribeChannel(it.Channel.ID))
 		case GroupItem:
-			leaveCmds = append(leaveCmds, subscribeGroupCmd(m.pool, it.Group.RelayURL, it.Group.GroupID))
+			leaveCmds = append(leaveCmds, m.subscribeGroup(it.Group.RelayURL, it.Group.GroupID))
 		}
 	}
 
@@ -581,6 +1029,240 @@ func (m *model) leaveCurrentItem() (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// handleFilterCommand handles /filter: it changes the active channel or
+// group's live subscription filter and re-subscribes with it.
+func (m *model) handleFilterCommand(arg string) (tea.Model, tea.Cmd) {
+	var roomID string
+	switch it := m.activeSidebarItem().(type) {
+	case ChannelItem:
+		roomID = it.Channel.ID
+	case GroupItem:
+		roomID = groupKey(it.Group.RelayURL, it.Group.GroupID)
+	default:
+		m.addSystemMsg("/filter only works in a channel or group")
+		return m, nil
+	}
+
+	sf := m.roomFilters[roomID]
+	parts := strings.Fields(arg)
+	if len(parts) == 0 {
+		m.addSystemMsg("filter: " + sf.String())
+		return m, nil
+	}
+	switch strings.ToLower(parts[0]) {
+	case "contacts":
+		authors := m.contactPubKeys()
+		if len(authors) == 1 { // only ourselves
+			m.addSystemMsg("filter: no contacts to filter by (no follows or DM peers), filter unchanged")
+			return m, nil
+		}
+		sf.Authors = authors
+	case "all":
+		sf.Authors = nil
+	case "since":
+		if len(parts) < 2 {
+			m.addSystemMsg("usage: /filter since <duration> (e.g. 2h, 30m)")
+			return m, nil
+		}
+		d, err := time.ParseDuration(parts[1])
+		if err != nil || d <= 0 {
+			m.addSystemMsg(fmt.Sprintf("invalid duration %q", parts[1]))
+			return m, nil
+		}
+		sf.Since = nostr.Timestamp(time.Now().Add(-d).Unix())
+	case "limit":
+		n := 0
+		if len(parts) >= 2 {
+			n, _ = strconv.Atoi(parts[1])
+		}
+		if n < 1 {
+			m.addSystemMsg("usage: /filter limit <n>")
+			return m, nil
+		}
+		sf.Limit = n
+	case "reset":
+		sf = subFilter{}
+	default:
+		m.addSystemMsg("usage: /filter [contacts|all|since <dur>|limit <n>|reset]")
+		return m, nil
+	}
+
+	if sf.Limit == 0 && sf.Since == 0 && len(sf.Authors) == 0 {
+		delete(m.roomFilters, roomID)
+	} else {
+		m.roomFilters[roomID] = sf
+	}
+	m.addSystemMsg("filter: " + sf.String())
+
+	if g, ok := m.activeSidebarItem().(GroupItem); ok {
+		return m, m.subscribeGroup(g.Group.RelayURL, g.Group.GroupID)
+	}
+	return m, m.subscribeChannel(roomID)
+}
+
+// showNIP05 handles /nip05: with name@domain it verifies the identifier
+// against our pubkey, otherwise it shows the nostr.json snippet for name
+// (default "_", the domain's root identifier) in an overlay.
+func (m *model) showNIP05(arg string) (tea.Model, tea.Cmd) {
+	if strings.Contains(arg, "@") {
+		m.addSystemMsg("checking NIP-05 " + arg + "...")
+		return m, verifyNIP05Cmd(arg)
+	}
+	name := strings.ToLower(arg)
+	if name == "" {
+		name = "_"
+	}
+	if !isValidNIP05Name(name) {
+		m.addSystemMsg("invalid NIP-05 name (allowed: a-z 0-9 - _ .)")
+		return m, nil
+	}
+	doc, err := nip05JSON(name, m.keys.PK.Hex(), m.relays)
+	if err != nil {
+		m.addSystemMsg(fmt.Sprintf("nip05: %v", err))
+		return m, nil
+	}
+	var b strings.Builder
+	b.WriteString(qrTitleStyle.Render("Serve at https://<your-domain>/.well-known/nostr.json"))
+	b.WriteString("\n\n")
+	b.WriteString(doc)
+	b.WriteString("\n\n")
+	b.WriteString(chatSystemStyle.Render("Send Access-Control-Allow-Origin: * with it. Then set nip05 = \"" + name + "@<your-domain>\" in [profile]."))
+	m.qrOverlay = b.String()
+	return m, nil
+}
+
+// showRecent handles /recent: with no argument it lists conversations by
+// latest activity; with n it switches to the nth entry of that list.
+func (m *model) showRecent(arg string) (tea.Model, tea.Cmd) {
+	const maxRecent = 10
+	recent := m.recentConversations()
+	if arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 1 {
+			m.addSystemMsg("usage: /recent [n]")
+			return m, nil
+		}
+		if n > len(recent) {
+			m.addSystemMsg(fmt.Sprintf("no recent conversation #%d", n))
+			return m, nil
+		}
+		m.activeItem = recent[n-1].Index
+		m.clearUnread()
+		m.updateViewport()
+		return m, nil
+	}
+
+	if len(recent) == 0 {
+		m.addSystemMsg("no recent conversations")
+		return m, nil
+	}
+	var lines []string
+	for i, rc := range recent {
+		if i == maxRecent {
+			break
+		}
+		it := m.sidebar[rc.Index]
+		author := m.resolveAuthor(rc.Last.PubKey)
+		if rc.Last.IsMine {
+			author = "you"
+		}
+		preview, _, _ := strings.Cut(rc.Last.Content, "\n")
+		preview = truncateRunes(preview, 40)
+		lines = append(lines, fmt.Sprintf("%d. %s%s — %s %s: %s", i+1, it.Prefix(), it.DisplayName(),
+			rc.Last.Timestamp.Time().Format("01-02 15:04"), author, preview))
+	}
+	for _, l := range lines {
+		m.addSystemMsg(l)
+	}
+	m.addSystemMsg("use /recent <n> to jump")
+	return m, nil
+}
+
+// showMessageInfo prints details of the nth most recent message (1 = newest),
+// including per-relay delivery outcomes for messages we sent.
+func (m *model) showMessageInfo(arg string) (tea.Model, tea.Cmd) {
+	n := 1
+	if arg != "" {
+		v, err := strconv.Atoi(arg)
+		if err != nil || v < 1 {
+			m.addSystemMsg("usage: /info [n]")
+			return m, nil
+		}
+		n = v
+	}
+	msg, _, ok := m.nthRecentMessage(n)
+	if !ok {
+		m.addSystemMsg(fmt.Sprintf("no message #%d in this conversation", n))
+		return m, nil
+	}
+
+	m.addSystemMsg(fmt.Sprintf("message #%d by %s at %s", n, m.resolveAuthor(msg.PubKey), msg.Timestamp.Time().Format("2006-01-02 15:04:05")))
+	m.addSystemMsg("event id: " + msg.EventID)
+	if !msg.IsMine {
+		if msg.Relay != "" {
+			m.addSystemMsg("received from: " + msg.Relay)
+		}
+		return m, nil
+	}
+	if len(msg.Deliveries) == 0 {
+		m.addSystemMsg("no delivery data (still sending, or loaded from history)")
+		return m, nil
+	}
+	urls := make([]string, 0, len(msg.Deliveries))
+	accepted := 0
+	for url, status := range msg.Deliveries {
+		urls = append(urls, url)
+		if status == "ok" {
+			accepted++
+		}
+	}
+	sort.Strings(urls)
+	m.addSystemMsg(fmt.Sprintf("accepted by %d/%d relays:", accepted, len(urls)))
+	for _, url := range urls {
+		if status := msg.Deliveries[url]; status == "ok" {
+			m.addSystemMsg("  ✓ " + url)
+		} else {
+			m.addSystemMsg("  ✗ " + url + ": " + status)
+		}
+	}
+	return m, nil
+}
+
+// showPaymentQR shows a QR overlay for the nth most recent lightning invoice
+// or cashu token in the current conversation (1 = newest).
+func (m *model) showPaymentQR(arg string) (tea.Model, tea.Cmd) {
+	n := 1
+	if arg != "" {
+		v, err := strconv.Atoi(arg)
+		if err != nil || v < 1 {
+			m.addSystemMsg("usage: /invoice [n]")
+			return m, nil
+		}
+		n = v
+	}
+	item := m.activeSidebarItem()
+	if item == nil {
+		m.addSystemMsg("no active conversation")
+		return m, nil
+	}
+	tokens := recentPaymentTokens(m.msgs[item.ItemID()])
+	if len(tokens) == 0 {
+		m.addSystemMsg("no lightning invoices or cashu tokens in this conversation")
+		return m, nil
+	}
+	if n > len(tokens) {
+		m.addSystemMsg(fmt.Sprintf("only %d invoice(s)/token(s) in this conversation", len(tokens)))
+		return m, nil
+	}
+	tok := tokens[n-1]
+	if tok.Kind == paymentCashu {
+		m.qrOverlay = renderQR("🥜 cashu token", tok.Raw)
+	} else {
+		m.qrOverlay = renderQR(strings.Trim(tok.badge(), "[]"), "lightning:"+tok.Raw)
+	}
+	return m, nil
+}
+
 // groupNaddr encodes a NIP-19 naddr for a group, using the relay's pubkey if known.
 func (m *model) groupNaddr(g Group) (string, error) {
 	author := g.RelayPubKey
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -16,6 +18,14 @@ type ProfileConfig struct {
 	DisplayName string `toml:"display_name"`
 	About       string `toml:"about"`
 	Picture     string `toml:"picture"`
+	NIP05       string `toml:"nip05"`
+}
+
+// HistoryConfig caps how much backlog room subscriptions request.
+type HistoryConfig struct {
+	Limit        int    `toml:"limit"`         // past messages per room per relay; 0 = default (50)
+	Since        string `toml:"since"`         // Go duration, e.g. "72h"; empty = no bound
+	ExcludeKinds []int  `toml:"exclude_kinds"` // event kinds never subscribed to
 }
 
 type Config struct {
@@ -26,6 +36,45 @@ type Config struct {
 	MaxMessages    int           `toml:"max_messages"`
 	Logging        *bool         `toml:"logging"`        // nil = default (true)
 	LogDir         string        `toml:"log_dir"`
+	HistoryBackend string        `toml:"history_backend"` // "file" (default) or "sqlite"
+	MutedWords     []string      `toml:"muted_words"`
+	HighlightWords []string      `toml:"highlight_words"`
+	EditorKey      string        `toml:"editor_key"` // empty = default (ctrl+e)
+	EnterSends     *bool         `toml:"enter_sends"` // nil = default (true); false = enter inserts a newline
+	SendKey        string        `toml:"send_key"`    // key that sends when enter_sends = false; empty = ctrl+enter
+	LargeMsgLines  int           `toml:"large_message_lines"` // 0 = default (10), negative = never confirm
+	InputMinLines  int           `toml:"input_min_height"`    // 0 = default (1)
+	InputMaxLines  int           `toml:"input_max_height"`    // 0 = default (8)
+	InputCollapse  bool          `toml:"input_collapse"`      // empty input shrinks to one line below input_min_height
+	ComposePreview bool          `toml:"compose_preview"`     // start with the markdown preview pane open (ctrl+g toggles)
+	PreviewLines   int           `toml:"compose_preview_height"`    // 0 = default (6)
+	PreviewMaxChars int          `toml:"compose_preview_max_chars"` // 0 = default (2000); longer input isn't previewed
+	DMReadReceipts bool          `toml:"dm_read_receipts"`    // send and show NIP-17 DM read receipts
+	DMGrouping     string        `toml:"dm_grouping"`         // "conversation" (default) or "peer"
+	DMLookbackWin  string        `toml:"dm_lookback"`         // Go duration; empty = default (72h)
+	SyncContacts   *bool         `toml:"sync_contacts"`       // nil = default (true); false = contacts file only, no NIP-51 list
+	FollowsAsDMs   bool          `toml:"follows_in_sidebar"`  // list every followed pubkey as a DM conversation
+	ReplaceableWt  string        `toml:"replaceable_wait"`    // Go duration; empty = first relay to answer wins
+	StaleSubAfter  string        `toml:"stale_subscription_after"` // Go duration; empty = default (5m), "0" = no watchdog
+	StatusPingEvery string       `toml:"status_ping_interval"` // Go duration; empty = default (2m), "0" = no background ping
+	MessageFormat  string        `toml:"message_format"`      // empty = default ("{time} {author}: {content}")
+	MetaRetries    int           `toml:"metadata_retries"`    // 0 = default (4), negative = never retry
+	MaxConcQueries int           `toml:"max_concurrent_queries"` // 0 = default (8), negative = unlimited
+	MaxSubsPerRelay int          `toml:"max_subs_per_relay"`  // 0 = unlimited (one subscription per room)
+	MissingKey     string        `toml:"missing_key"`         // "prompt" (default) or "error"
+	CompactSidebar bool          `toml:"compact_sidebar"`     // hide headers of empty sidebar sections
+	Avatars        bool          `toml:"avatars"`             // colored initials before authors and DM entries
+	Timestamps     *bool         `toml:"timestamps"`          // nil = default (true); false drops {time} from message_format
+	Markdown       *bool         `toml:"markdown"`            // nil = default (true); false shows every room as plain text
+	GroupByAuthor  bool          `toml:"group_by_author"`     // blank the author of consecutive messages by the same person
+	ShortURLsOver  int           `toml:"shorten_urls_over"`   // 0 = full URLs; longer URLs are shown as host/…
+	DigestEndpoint string        `toml:"digest_endpoint"`     // OpenAI-compatible chat completions URL; empty = /digest off
+	DigestAPIKey   string        `toml:"digest_api_key"`      // sent as a Bearer token to digest_endpoint
+	DigestModel    string        `toml:"digest_model"`        // empty = default (gpt-4o-mini)
+	DigestDMs      bool          `toml:"digest_dms"`          // allow /digest in DMs, sending decrypted messages to digest_endpoint
+	RelayOptions   []RelayConfig            `toml:"relay"` // [[relay]] per-relay auth options
+	History        HistoryConfig            `toml:"history"`
+	RelayHistory   map[string]HistoryConfig `toml:"relay_history"` // per-relay overrides of [history]
 	Profile        ProfileConfig `toml:"profile"`
 }
 
@@ -37,6 +86,194 @@ func (c Config) LoggingEnabled() bool {
 	return *c.Logging
 }
 
+// SyncContactsEnabled reports whether DM contacts are published as a NIP-51
+// list (the default) rather than kept in the local contacts file.
+func (c Config) SyncContactsEnabled() bool {
+	return c.SyncContacts == nil || *c.SyncContacts
+}
+
+// ShowTimestamps reports whether messages show their time (the default).
+func (c Config) ShowTimestamps() bool {
+	return c.Timestamps == nil || *c.Timestamps
+}
+
+// MarkdownEnabled reports whether messages are rendered as markdown (the
+// default), except in rooms switched with /toggle-markdown.
+func (c Config) MarkdownEnabled() bool {
+	return c.Markdown == nil || *c.Markdown
+}
+
+// EditorKeyBinding returns the key that opens the external editor.
+func (c Config) EditorKeyBinding() string {
+	if c.EditorKey == "" {
+		return "ctrl+e"
+	}
+	return c.EditorKey
+}
+
+// EnterSendsMessage reports whether Enter sends the message (the default)
+// rather than inserting a newline.
+func (c Config) EnterSendsMessage() bool {
+	return c.EnterSends == nil || *c.EnterSends
+}
+
+// SendKeyBinding returns the key that sends the message.
+func (c Config) SendKeyBinding() string {
+	switch {
+	case c.EnterSendsMessage():
+		return "enter"
+	case c.SendKey == "":
+		return "ctrl+enter"
+	}
+	return c.SendKey
+}
+
+// NewlineKeys returns the keys that insert a newline in the input.
+func (c Config) NewlineKeys() []string {
+	keys := []string{"alt+enter", "ctrl+j"}
+	if !c.EnterSendsMessage() {
+		keys = []string{"enter", "ctrl+j"}
+	}
+	send := c.SendKeyBinding()
+	return slices.DeleteFunc(keys, func(k string) bool { return k == send })
+}
+
+// InputHeights returns the smallest and largest height of the input box in
+// lines.
+func (c Config) InputHeights() (lo, hi int) {
+	lo, hi = inputMinHeight, inputMaxHeight
+	if c.InputMinLines > 0 {
+		lo = c.InputMinLines
+	}
+	if c.InputMaxLines > 0 {
+		hi = c.InputMaxLines
+	}
+	return lo, hi
+}
+
+// PreviewLimits returns the height of the compose preview pane in lines and
+// the input length above which it stops rendering.
+func (c Config) PreviewLimits() (lines, maxChars int) {
+	lines, maxChars = previewHeight, previewMaxChars
+	if c.PreviewLines > 0 {
+		lines = c.PreviewLines
+	}
+	if c.PreviewMaxChars > 0 {
+		maxChars = c.PreviewMaxChars
+	}
+	return lines, maxChars
+}
+
+// DigestModelName returns the model requested from digest_endpoint.
+func (c Config) DigestModelName() string {
+	if c.DigestModel == "" {
+		return "gpt-4o-mini"
+	}
+	return c.DigestModel
+}
+
+// LargeMessageLines returns the line count above which sending asks for
+// confirmation, or 0 if confirmation is disabled.
+func (c Config) LargeMessageLines() int {
+	switch {
+	case c.LargeMsgLines == 0:
+		return 10
+	case c.LargeMsgLines < 0:
+		return 0
+	}
+	return c.LargeMsgLines
+}
+
+// ReplaceableWait returns how long to keep collecting answers for profiles,
+// relay lists, and group metadata after the first relay responds, or 0 to
+// take the first answer.
+func (c Config) ReplaceableWait() time.Duration {
+	d, err := time.ParseDuration(c.ReplaceableWt)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// DMLookback returns how far before the last DM seen the gift wrap
+// subscription starts, to cover NIP-59's backdated wrap timestamps.
+func (c Config) DMLookback() time.Duration {
+	d, err := time.ParseDuration(c.DMLookbackWin)
+	if err != nil || d < 0 {
+		return 72 * time.Hour
+	}
+	return d
+}
+
+// StatusPingInterval returns how often the relays are pinged for the status
+// bar's latency, or 0 if they aren't.
+func (c Config) StatusPingInterval() time.Duration {
+	if c.StatusPingEvery == "" {
+		return 2 * time.Minute
+	}
+	d, err := time.ParseDuration(c.StatusPingEvery)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// StaleSubInterval returns how long a room subscription may receive nothing
+// before the watchdog probes its relays, or 0 if the watchdog is off.
+func (c Config) StaleSubInterval() time.Duration {
+	if c.StaleSubAfter == "" {
+		return 5 * time.Minute
+	}
+	d, err := time.ParseDuration(c.StaleSubAfter)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// MetadataRetries returns how many times to refetch a channel or group
+// whose name did not resolve, or 0 to never retry.
+func (c Config) MetadataRetries() int {
+	switch {
+	case c.MetaRetries == 0:
+		return 4
+	case c.MetaRetries < 0:
+		return 0
+	}
+	return c.MetaRetries
+}
+
+// MaxQueries returns how many profile and metadata fetches may run at once,
+// or 0 for no limit.
+func (c Config) MaxQueries() int {
+	switch {
+	case c.MaxConcQueries == 0:
+		return 8
+	case c.MaxConcQueries < 0:
+		return 0
+	}
+	return c.MaxConcQueries
+}
+
+// MessageFormatString returns the template used to lay out each message.
+func (c Config) MessageFormatString() string {
+	if c.MessageFormat == "" {
+		return defaultMessageFormat
+	}
+	return c.MessageFormat
+}
+
+// validate checks that Since parses as a duration.
+func (h HistoryConfig) validate() error {
+	if h.Since == "" {
+		return nil
+	}
+	if _, err := time.ParseDuration(h.Since); err != nil {
+		return fmt.Errorf("invalid since %q: %w", h.Since, err)
+	}
+	return nil
+}
+
 func defaultConfig() Config {
 	return Config{
 		Relays: []string{
@@ -84,9 +321,106 @@ func LoadConfig(flagPath string) (Config, error) {
 	if cfg.MaxMessages <= 0 {
 		cfg.MaxMessages = 500
 	}
+	switch cfg.HistoryBackend {
+	case "", "file", "sqlite":
+	default:
+		return cfg, fmt.Errorf("history_backend: unknown backend %q (want file or sqlite)", cfg.HistoryBackend)
+	}
+	switch cfg.DMGrouping {
+	case "", "conversation", "peer":
+	default:
+		return cfg, fmt.Errorf("dm_grouping: unknown mode %q (want conversation or peer)", cfg.DMGrouping)
+	}
+	switch cfg.MissingKey {
+	case "", "prompt", "error":
+	default:
+		return cfg, fmt.Errorf("missing_key: unknown mode %q (want prompt or error)", cfg.MissingKey)
+	}
+	for i, rc := range cfg.RelayOptions {
+		if err := rc.validate(); err != nil {
+			return cfg, fmt.Errorf("relay #%d: %w", i+1, err)
+		}
+	}
+	if !cfg.EnterSendsMessage() && cfg.SendKeyBinding() == "enter" {
+		return cfg, fmt.Errorf("send_key: must not be enter when enter_sends = false")
+	}
+	if cfg.DigestEndpoint != "" && !strings.HasPrefix(cfg.DigestEndpoint, "https://") && !strings.HasPrefix(cfg.DigestEndpoint, "http://") {
+		return cfg, fmt.Errorf("digest_endpoint: want an http(s) URL, got %q", cfg.DigestEndpoint)
+	}
+	if cfg.InputMinLines < 0 || cfg.InputMaxLines < 0 {
+		return cfg, fmt.Errorf("input_min_height, input_max_height: must not be negative")
+	}
+	if lo, hi := cfg.InputHeights(); lo > hi {
+		return cfg, fmt.Errorf("input_min_height: %d is larger than input_max_height (%d)", lo, hi)
+	}
+	if cfg.PreviewLines < 0 || cfg.PreviewMaxChars < 0 {
+		return cfg, fmt.Errorf("compose_preview_height, compose_preview_max_chars: must not be negative")
+	}
+	if cfg.ShortURLsOver < 0 {
+		return cfg, fmt.Errorf("shorten_urls_over: must not be negative (got %d)", cfg.ShortURLsOver)
+	}
+	if cfg.MaxSubsPerRelay < 0 {
+		return cfg, fmt.Errorf("max_subs_per_relay: must not be negative (got %d)", cfg.MaxSubsPerRelay)
+	}
+	if cfg.StaleSubAfter != "" {
+		d, err := time.ParseDuration(cfg.StaleSubAfter)
+		if err != nil {
+			return cfg, fmt.Errorf("stale_subscription_after: %w", err)
+		}
+		if d < 0 || (d > 0 && d < time.Minute) {
+			return cfg, fmt.Errorf("stale_subscription_after: must be 0 or at least 1m (got %s)", d)
+		}
+	}
+	if cfg.StatusPingEvery != "" {
+		d, err := time.ParseDuration(cfg.StatusPingEvery)
+		if err != nil {
+			return cfg, fmt.Errorf("status_ping_interval: %w", err)
+		}
+		if d < 0 || (d > 0 && d < 10*time.Second) {
+			return cfg, fmt.Errorf("status_ping_interval: must be 0 or at least 10s (got %s)", d)
+		}
+	}
+	if cfg.DMLookbackWin != "" {
+		d, err := time.ParseDuration(cfg.DMLookbackWin)
+		if err != nil {
+			return cfg, fmt.Errorf("dm_lookback: %w", err)
+		}
+		if d < 0 {
+			return cfg, fmt.Errorf("dm_lookback: must not be negative (got %s)", d)
+		}
+	}
+	if cfg.ReplaceableWt != "" {
+		if _, err := time.ParseDuration(cfg.ReplaceableWt); err != nil {
+			return cfg, fmt.Errorf("replaceable_wait: %w", err)
+		}
+	}
+	if err := validateMessageFormat(cfg.MessageFormatString()); err != nil {
+		return cfg, fmt.Errorf("message_format: %w", err)
+	}
+	if err := cfg.History.validate(); err != nil {
+		return cfg, fmt.Errorf("history: %w", err)
+	}
+	for relay, h := range cfg.RelayHistory {
+		if err := h.validate(); err != nil {
+			return cfg, fmt.Errorf("relay_history %q: %w", relay, err)
+		}
+	}
 	if len(cfg.Relays) == 0 {
 		cfg.Relays = defaultConfig().Relays
 	}
+	for i, r := range cfg.Relays {
+		cfg.Relays[i] = normalizeRelayURL(r)
+	}
+	if cfg.GroupRelay != "" {
+		cfg.GroupRelay = normalizeRelayURL(cfg.GroupRelay)
+	}
+	if len(cfg.RelayHistory) > 0 {
+		byURL := make(map[string]HistoryConfig, len(cfg.RelayHistory))
+		for relay, h := range cfg.RelayHistory {
+			byURL[normalizeRelayURL(relay)] = h
+		}
+		cfg.RelayHistory = byURL
+	}
 	if cfg.Profile.Name == "" {
 		cfg.Profile.Name = os.Getenv("USER")
 	}
@@ -138,6 +472,9 @@ type SavedGroup struct {
 	Name     string
 	RelayURL string
 	GroupID  string
+	Mirrors  []string // further relays carrying the group
+
+	listedRelayURL string // RelayURL as written in the list, before normalizing
 }
 
 
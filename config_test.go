@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
@@ -167,3 +168,116 @@ func TestLoadLastDMSeenAndSaveLastDMSeen(t *testing.T) {
 		t.Errorf("LoadLastDMSeen = %d, want %d", got, want)
 	}
 }
+
+func TestEditorKeyBinding(t *testing.T) {
+	if got := (Config{}).EditorKeyBinding(); got != "ctrl+e" {
+		t.Errorf("default EditorKeyBinding = %q, want %q", got, "ctrl+e")
+	}
+	if got := (Config{EditorKey: "ctrl+x"}).EditorKeyBinding(); got != "ctrl+x" {
+		t.Errorf("EditorKeyBinding = %q, want %q", got, "ctrl+x")
+	}
+}
+
+func TestSendAndNewlineKeys(t *testing.T) {
+	off := false
+	tests := []struct {
+		cfg     Config
+		send    string
+		newline []string
+	}{
+		{Config{}, "enter", []string{"alt+enter", "ctrl+j"}},
+		{Config{EnterSends: &off}, "ctrl+enter", []string{"enter", "ctrl+j"}},
+		{Config{EnterSends: &off, SendKey: "ctrl+j"}, "ctrl+j", []string{"enter"}},
+	}
+	for _, tt := range tests {
+		if got := tt.cfg.SendKeyBinding(); got != tt.send {
+			t.Errorf("SendKeyBinding = %q, want %q", got, tt.send)
+		}
+		if got := tt.cfg.NewlineKeys(); !reflect.DeepEqual(got, tt.newline) {
+			t.Errorf("NewlineKeys = %v, want %v", got, tt.newline)
+		}
+	}
+}
+
+func TestInputHeight(t *testing.T) {
+	tests := []struct {
+		cfg   Config
+		lines int
+		empty bool
+		want  int
+	}{
+		{Config{}, 1, true, 1},
+		{Config{}, 12, false, 8},
+		{Config{InputMinLines: 4, InputMaxLines: 20}, 1, true, 4},
+		{Config{InputMinLines: 4, InputMaxLines: 20}, 12, false, 12},
+		{Config{InputMinLines: 4, InputCollapse: true}, 1, true, 1},
+		{Config{InputMinLines: 4, InputCollapse: true}, 1, false, 4},
+	}
+	for _, tt := range tests {
+		if got := inputHeight(tt.cfg, tt.lines, tt.empty); got != tt.want {
+			t.Errorf("inputHeight(%+v, %d, %v) = %d, want %d", tt.cfg, tt.lines, tt.empty, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigInputHeights(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte("input_min_height = 10\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected error for input_min_height above the default input_max_height")
+	}
+}
+
+func TestPreviewLimits(t *testing.T) {
+	if lines, maxChars := (Config{}).PreviewLimits(); lines != 6 || maxChars != 2000 {
+		t.Errorf("defaults = %d, %d, want 6, 2000", lines, maxChars)
+	}
+	if lines, maxChars := (Config{PreviewLines: 3, PreviewMaxChars: 500}).PreviewLimits(); lines != 3 || maxChars != 500 {
+		t.Errorf("got %d, %d, want 3, 500", lines, maxChars)
+	}
+}
+
+func TestDMLookback(t *testing.T) {
+	for in, want := range map[string]time.Duration{"": 72 * time.Hour, "0": 0, "120h": 120 * time.Hour} {
+		if got := (Config{DMLookbackWin: in}).DMLookback(); got != want {
+			t.Errorf("DMLookback(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestStaleSubInterval(t *testing.T) {
+	for in, want := range map[string]time.Duration{"": 5 * time.Minute, "0": 0, "10m": 10 * time.Minute} {
+		if got := (Config{StaleSubAfter: in}).StaleSubInterval(); got != want {
+			t.Errorf("StaleSubInterval(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestStatusPingInterval(t *testing.T) {
+	for in, want := range map[string]time.Duration{"": 2 * time.Minute, "0": 0, "30s": 30 * time.Second} {
+		if got := (Config{StatusPingEvery: in}).StatusPingInterval(); got != want {
+			t.Errorf("StatusPingInterval(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestMaxQueries(t *testing.T) {
+	for in, want := range map[int]int{0: 8, -1: 0, 20: 20} {
+		if got := (Config{MaxConcQueries: in}).MaxQueries(); got != want {
+			t.Errorf("MaxQueries(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
+
+func TestLoadConfigInvalidHistorySince(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte("[history]\nsince = \"three days\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("expected error for invalid history.since duration")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// largeMessageChars is the character count above which sending asks for
+// confirmation regardless of the line count.
+const largeMessageChars = 2000
+
+// isLargeMessage reports whether text is big enough to require confirmation
+// before sending. Confirmation is off when large_message_lines is negative.
+func (m *model) isLargeMessage(text string) bool {
+	limit := m.cfg.LargeMessageLines()
+	if limit == 0 {
+		return false
+	}
+	return strings.Count(text, "\n")+1 > limit || len([]rune(text)) > largeMessageChars
+}
+
+// handleConfirmSendKey handles keys while the large-message confirmation is
+// shown. y/enter sends; n/esc cancels and leaves the text in the input.
+func (m *model) handleConfirmSendKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y", "enter":
+		text := m.pendingSend
+		m.pendingSend = ""
+		return m.submitInput(text)
+	case "n", "N", "esc", "ctrl+c":
+		m.pendingSend = ""
+		m.addSystemMsg("send cancelled")
+	}
+	return m, nil
+}
+
+// viewConfirmSend renders the large-message confirmation overlay.
+func (m *model) viewConfirmSend() string {
+	lines := strings.Count(m.pendingSend, "\n") + 1
+	chars := len([]rune(m.pendingSend))
+	var b strings.Builder
+	b.WriteString(qrTitleStyle.Render(fmt.Sprintf("Send %d-line message (%d characters)?", lines, chars)))
+	b.WriteString("\n\n")
+	preview := strings.SplitN(m.pendingSend, "\n", 4)
+	if len(preview) > 3 {
+		preview[3] = "…"
+	}
+	for _, l := range preview {
+		b.WriteString(chatSystemStyle.Render(truncateRunes(l, 60)) + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString("y/enter send · n/esc cancel")
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colorPrimary).
+		Padding(0, 1).
+		Render(b.String())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box)
+}
+
+// truncateRunes shortens s to at most n runes, adding an ellipsis if cut.
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
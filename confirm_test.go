@@ -0,0 +1,38 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsLargeMessage(t *testing.T) {
+	m := &model{}
+	if m.isLargeMessage(strings.Repeat("line\n", 9) + "last") {
+		t.Error("10 lines should not need confirmation with the default threshold")
+	}
+	if !m.isLargeMessage(strings.Repeat("line\n", 10) + "last") {
+		t.Error("11 lines should need confirmation with the default threshold")
+	}
+	if !m.isLargeMessage(strings.Repeat("x", largeMessageChars+1)) {
+		t.Error("a very long single line should need confirmation")
+	}
+
+	m.cfg.LargeMsgLines = 3
+	if !m.isLargeMessage("a\nb\nc\nd") {
+		t.Error("4 lines should need confirmation with threshold 3")
+	}
+
+	m.cfg.LargeMsgLines = -1
+	if m.isLargeMessage(strings.Repeat("line\n", 100)) {
+		t.Error("confirmation should be disabled with a negative threshold")
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	if got := truncateRunes("hello", 10); got != "hello" {
+		t.Errorf("truncateRunes short = %q", got)
+	}
+	if got := truncateRunes("héllo wörld", 5); got != "héllo…" {
+		t.Errorf("truncateRunes long = %q, want %q", got, "héllo…")
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// contactsPath returns the path of the local contacts file next to the
+// config, used instead of the NIP-51 list when sync_contacts = false.
+func contactsPath(cfgFlagPath string) string {
+	return filepath.Join(filepath.Dir(configPath(cfgFlagPath)), "contacts")
+}
+
+// loadContactsFile reads the contacts file: one "pubkey<TAB>name" line per
+// DM peer, in sidebar order. A missing file is empty.
+func loadContactsFile(path string) ([]Contact, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	var contacts []Contact
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		pk, name, _ := strings.Cut(sc.Text(), "\t")
+		if pk = strings.TrimSpace(pk); pk != "" {
+			contacts = append(contacts, Contact{PubKey: pk, Name: name})
+		}
+	}
+	return contacts, sc.Err()
+}
+
+// saveContactsFile rewrites the contacts file.
+func saveContactsFile(path string, contacts []Contact) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, c := range contacts {
+		fmt.Fprintf(&b, "%s\t%s\n", c.PubKey, c.Name)
+	}
+	return os.WriteFile(path, []byte(b.String()), 0600)
+}
+
+// syncContacts stores the DM peer list after a change: as the NIP-51
+// contacts list on relays, or only in the contacts file when
+// sync_contacts = false.
+func (m *model) syncContacts() tea.Cmd {
+	contacts := contactsFromModel(m.allDMPeers(), m.profiles)
+	if m.cfg.SyncContactsEnabled() {
+		return publishContactsListCmd(m.pool, m.relays, contacts, m.keys, m.kr)
+	}
+	if err := saveContactsFile(contactsPath(m.cfgFlagPath), contacts); err != nil {
+		log.Printf("syncContacts: %v", err)
+		m.addSystemMsg("saving contacts: " + err.Error())
+	}
+	return nil
+}
+
+// loadLocalContacts adds the DM peers from the contacts file to the
+// sidebar, for sync_contacts = false.
+func (m *model) loadLocalContacts() []tea.Cmd {
+	contacts, err := loadContactsFile(contactsPath(m.cfgFlagPath))
+	if err != nil {
+		m.addSystemMsg("loading contacts: " + err.Error())
+		return nil
+	}
+	var cmds []tea.Cmd
+	for _, c := range contacts {
+		if m.containsDMPeer(c.PubKey) {
+			continue
+		}
+		if _, ok := m.profiles[c.PubKey]; !ok && c.Name != "" {
+			m.profiles[c.PubKey] = c.Name
+		}
+		m.appendDMItem(c.PubKey, m.resolveAuthor(c.PubKey))
+		if cmd := m.maybeRequestProfile(c.PubKey); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	}
+	m.addSystemMsg(fmt.Sprintf("%d contacts loaded from the contacts file (sync_contacts = false)", len(contacts)))
+	return cmds
+}
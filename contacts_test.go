@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestContactsFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "contacts")
+	if got, err := loadContactsFile(path); err != nil || got != nil {
+		t.Fatalf("missing file: %v, %v", got, err)
+	}
+	want := []Contact{{PubKey: "pk1", Name: "alice"}, {PubKey: "pk2", Name: ""}}
+	if err := saveContactsFile(path, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadContactsFile(path)
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("loadContactsFile = %+v, %v; want %+v", got, err, want)
+	}
+}
+
+func TestSyncContactsLocal(t *testing.T) {
+	dir := t.TempDir()
+	off := false
+	m := newTestModel(0, 0, 2)
+	m.cfg.SyncContacts = &off
+	m.cfgFlagPath = filepath.Join(dir, "config.toml")
+	m.profiles = map[string]string{"pk0": "alice"}
+
+	if cmd := m.syncContacts(); cmd != nil {
+		t.Error("syncContacts published with sync_contacts = false")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "contacts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "pk0\talice\npk1\tpk1\n"; got != want {
+		t.Errorf("contacts file = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"strings"
+
+	"fiatjaf.com/nostr"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// contentWarningTag returns the NIP-36 tag flagging a message as sensitive.
+func contentWarningTag(reason string) nostr.Tag {
+	if reason == "" {
+		return nostr.Tag{"content-warning"}
+	}
+	return nostr.Tag{"content-warning", reason}
+}
+
+// applyContentWarning marks cm as sensitive if evt carries a NIP-36
+// content-warning tag.
+func applyContentWarning(cm *ChatMessage, evt nostr.Event) {
+	for _, tag := range evt.Tags {
+		if len(tag) >= 1 && tag[0] == "content-warning" {
+			cm.Sensitive = true
+			if len(tag) > 1 {
+				cm.ContentWarning = tag[1]
+			}
+			return
+		}
+	}
+}
+
+// parseCWArgs splits the /cw argument into the reason and the message. The
+// reason is the first word, or a quoted phrase.
+func parseCWArgs(arg string) (reason, text string, ok bool) {
+	arg = strings.TrimSpace(arg)
+	if rest, found := strings.CutPrefix(arg, `"`); found {
+		reason, text, ok = strings.Cut(rest, `"`)
+	} else {
+		reason, text, ok = strings.Cut(arg, " ")
+	}
+	text = strings.TrimSpace(text)
+	return strings.TrimSpace(reason), text, ok && text != ""
+}
+
+// cwNotice is shown instead of a concealed message.
+func cwNotice(reason string) string {
+	if reason == "" {
+		return "⚠ content warning (enter to reveal)"
+	}
+	return "⚠ content warning: " + reason + " (enter to reveal)"
+}
+
+// isConcealed reports whether msg is shown as its content warning: it is
+// sensitive, not ours, and not revealed yet.
+func (m *model) isConcealed(msg ChatMessage) bool {
+	return msg.Sensitive && !msg.IsMine && !m.revealed[msg.EventID]
+}
+
+// revealLatest reveals the newest concealed message in the active room. It
+// is what enter does on an empty input; it reports whether there was one.
+func (m *model) revealLatest() bool {
+	msgs := m.msgs[m.activeRoomKey()]
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if m.isConcealed(msgs[i]) && !m.isFilteredMessage(msgs[i]) {
+			m.revealed[msgs[i].EventID] = true
+			m.updateViewport()
+			return true
+		}
+	}
+	return false
+}
+
+// sendContentWarning handles /cw <reason> <text>: the message is published
+// with a NIP-36 content-warning tag.
+func (m *model) sendContentWarning(arg string) (tea.Model, tea.Cmd) {
+	reason, text, ok := parseCWArgs(arg)
+	if !ok {
+		m.addSystemMsg(`usage: /cw <reason> <text> (quote a reason of several words: /cw "movie spoilers" ...)`)
+		return m, nil
+	}
+	tags := nostr.Tags{contentWarningTag(reason)}
+	switch it := m.activeSidebarItem().(type) {
+	case ChannelItem:
+		return m, publishChannelMessage(m.pool, it.Channel.RelaysWith(m.relays), it.Channel.ID, text, tags, m.keys)
+	case GroupItem:
+		if _, ok := m.activeThread(); ok {
+			m.addSystemMsg("/cw can't post into a thread")
+			return m, nil
+		}
+		gk := groupKey(it.Group.RelayURL, it.Group.GroupID)
+		return m, publishGroupMessage(m.pool, it.Group.Relays(), it.Group.GroupID, text, tags, m.groupRecentIDs[gk], m.keys)
+	}
+	m.addSystemMsg("/cw only works in a channel or group")
+	return m, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestParseCWArgs(t *testing.T) {
+	tests := []struct {
+		arg, reason, text string
+		ok                bool
+	}{
+		{"spoilers the butler did it", "spoilers", "the butler did it", true},
+		{`"movie spoilers" the butler did it`, "movie spoilers", "the butler did it", true},
+		{"spoilers", "", "", false},
+		{`"unterminated reason`, "", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		reason, text, ok := parseCWArgs(tt.arg)
+		if ok != tt.ok || (ok && (reason != tt.reason || text != tt.text)) {
+			t.Errorf("parseCWArgs(%q) = %q, %q, %v; want %q, %q, %v", tt.arg, reason, text, ok, tt.reason, tt.text, tt.ok)
+		}
+	}
+}
+
+func TestContentWarningRoundTrip(t *testing.T) {
+	keys := testKeys(t)
+	evt, err := buildGroupMessageEvent("g", "the butler did it", nostr.Tags{contentWarningTag("spoilers")}, nil, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := groupChatMessage(evt, groupKey("wss://r", "g"), keys)
+	if !cm.Sensitive || cm.ContentWarning != "spoilers" {
+		t.Errorf("group message: Sensitive=%v ContentWarning=%q", cm.Sensitive, cm.ContentWarning)
+	}
+
+	evt, err = buildChannelMessageEvent("ch", "hi", nostr.Tags{contentWarningTag("")}, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evt.Tags[0][0] != "e" {
+		t.Errorf("channel root tag moved: %v", evt.Tags)
+	}
+	var plain ChatMessage
+	applyContentWarning(&plain, evt)
+	if !plain.Sensitive || plain.ContentWarning != "" {
+		t.Errorf("reasonless warning: Sensitive=%v ContentWarning=%q", plain.Sensitive, plain.ContentWarning)
+	}
+}
+
+func TestRevealLatest(t *testing.T) {
+	m := newTestModel(1, 0, 0)
+	m.revealed = map[string]bool{}
+	m.msgs = map[string][]ChatMessage{"ch0": {
+		{EventID: "old", Content: "x", Sensitive: true, ChannelID: "ch0"},
+		{EventID: "mine", Content: "x", Sensitive: true, IsMine: true, ChannelID: "ch0"},
+		{EventID: "plain", Content: "x", ChannelID: "ch0"},
+	}}
+	if m.isConcealed(m.msgs["ch0"][1]) {
+		t.Error("own sensitive message concealed")
+	}
+	if !m.revealLatest() || !m.revealed["old"] {
+		t.Fatalf("revealLatest didn't reveal the only concealed message: %v", m.revealed)
+	}
+	if m.revealLatest() {
+		t.Error("revealLatest found another concealed message")
+	}
+}
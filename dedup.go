@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// dedupStats summarizes the dedup caches for /dedup-stats.
+type dedupStats struct {
+	seen       int           // entries in seenEvents
+	stale      int           // seenEvents entries past seenEventsTTL, evicted at the next sweep
+	oldest     time.Duration // age of the oldest seenEvents entry
+	evicted    int           // entries evicted by TTL so far
+	sinceSweep time.Duration // time since the last eviction sweep
+	dmEchoes   int           // entries in localDMEchoes
+	profiles   int           // entries in profilePending
+}
+
+// dedupStats collects the sizes and ages of the dedup caches as of now.
+func (m *model) dedupStats(now time.Time) dedupStats {
+	s := dedupStats{
+		seen:       len(m.seenEvents),
+		evicted:    m.seenEvicted,
+		sinceSweep: now.Sub(m.seenEventsClean),
+		dmEchoes:   len(m.localDMEchoes),
+		profiles:   len(m.profilePending),
+	}
+	for _, ts := range m.seenEvents {
+		age := now.Sub(ts)
+		if age > s.oldest {
+			s.oldest = age
+		}
+		if age > seenEventsTTL {
+			s.stale++
+		}
+	}
+	return s
+}
+
+// lines renders the stats, one cache per line.
+func (s dedupStats) lines() []string {
+	next := max(seenEventsCleanInterval-s.sinceSweep, 0)
+	return []string{
+		fmt.Sprintf("seenEvents: %d ids, oldest %s, %d past the %s TTL", s.seen, s.oldest.Round(time.Second), s.stale, seenEventsTTL),
+		fmt.Sprintf("  last sweep %s ago (next due in %s), %d evicted this session", s.sinceSweep.Round(time.Second), next.Round(time.Second), s.evicted),
+		fmt.Sprintf("localDMEchoes: %d sent DMs awaiting their relay copy", s.dmEchoes),
+		fmt.Sprintf("profilePending: %d profile fetches in flight", s.profiles),
+	}
+}
+
+// showDedupStats runs /dedup-stats.
+func (m *model) showDedupStats() (tea.Model, tea.Cmd) {
+	for _, line := range m.dedupStats(time.Now()).lines() {
+		m.addSystemMsg(line)
+	}
+	return m, nil
+}
+
+// clearDedup runs /dedup-clear: it empties the dedup caches so events
+// already seen this session are shown again when relays resend them.
+func (m *model) clearDedup() (tea.Model, tea.Cmd) {
+	s := m.dedupStats(time.Now())
+	m.seenEvents = make(map[string]time.Time)
+	m.seenEventsClean = time.Now()
+	m.localDMEchoes = make(map[string]time.Time)
+	m.profilePending = make(map[string]bool)
+	m.addSystemMsg(fmt.Sprintf("cleared %d seen event ids, %d DM echoes, %d pending profile fetches", s.seen, s.dmEchoes, s.profiles))
+	return m, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDedupStats(t *testing.T) {
+	now := time.Now()
+	m := &model{
+		seenEvents: map[string]time.Time{
+			"a": now.Add(-time.Minute),
+			"b": now.Add(-seenEventsTTL - time.Minute),
+			"c": now.Add(-seenEventsTTL - 2*time.Minute),
+		},
+		seenEventsClean: now.Add(-2 * time.Minute),
+		seenEvicted:     7,
+		localDMEchoes:   map[string]time.Time{"peer:hi": now},
+		profilePending:  map[string]bool{"pk1": true, "pk2": true},
+	}
+	s := m.dedupStats(now)
+	if s.seen != 3 || s.stale != 2 || s.evicted != 7 || s.dmEchoes != 1 || s.profiles != 2 {
+		t.Errorf("stats = %+v", s)
+	}
+	if s.oldest != seenEventsTTL+2*time.Minute {
+		t.Errorf("oldest = %s", s.oldest)
+	}
+	if s.sinceSweep != 2*time.Minute {
+		t.Errorf("sinceSweep = %s", s.sinceSweep)
+	}
+}
+
+func TestClearDedup(t *testing.T) {
+	m := newTestModel(1, 0, 0)
+	m.seenEvents = map[string]time.Time{"a": time.Now()}
+	m.localDMEchoes = map[string]time.Time{"peer:hi": time.Now()}
+	m.profilePending = map[string]bool{"pk": true}
+
+	m.clearDedup()
+	if m.isSeenEvent("a") || len(m.localDMEchoes) != 0 || len(m.profilePending) != 0 {
+		t.Error("caches not emptied")
+	}
+	m.markSeenEvent("b") // the maps must still be usable
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// digestMaxChars caps the transcript sent to the digest endpoint (roughly
+// 6k tokens); older messages are dropped first.
+const digestMaxChars = 24000
+
+const digestPrompt = "You summarize chat conversations. Reply with a short digest " +
+	"(at most 8 bullet points) of the topics, decisions and open questions in " +
+	"the following messages. Mention who said what where it matters. Reply in " +
+	"the language of the conversation."
+
+// digestMsg carries the summary (or error) for a /digest request.
+type digestMsg struct {
+	statusKey string
+	room      string // display name, for the overlay title
+	count     int    // messages summarized
+	summary   string
+	err       error
+}
+
+// digestTranscript formats msgs as "15:04 name: text" lines for the digest
+// prompt, keeping the newest lines that fit in maxChars. It returns the
+// transcript and how many older messages were dropped.
+func digestTranscript(msgs []ChatMessage, name func(ChatMessage) string, maxChars int) (string, int) {
+	lines := make([]string, len(msgs))
+	for i, msg := range msgs {
+		text := strings.Join(strings.Fields(msg.Content), " ")
+		lines[i] = msg.Timestamp.Time().Format("15:04") + " " + name(msg) + ": " + text
+	}
+	total := 0
+	start := len(lines)
+	for start > 0 && total+len(lines[start-1])+1 <= maxChars {
+		start--
+		total += len(lines[start]) + 1
+	}
+	return strings.Join(lines[start:], "\n"), start
+}
+
+// summarizeCmd asks an OpenAI-compatible chat completions endpoint to
+// summarize transcript.
+func summarizeCmd(endpoint, apiKey, model, transcript string, done digestMsg) tea.Cmd {
+	return func() tea.Msg {
+		done.summary, done.err = requestDigest(endpoint, apiKey, model, transcript)
+		return done
+	}
+}
+
+// requestDigest posts the transcript and returns the first choice's text.
+func requestDigest(endpoint, apiKey, model, transcript string) (string, error) {
+	type message struct {
+		Role    string `json:"role"`
+		Content string `json:"content"`
+	}
+	body, err := json.Marshal(struct {
+		Model    string    `json:"model"`
+		Messages []message `json:"messages"`
+	}{
+		Model: model,
+		Messages: []message{
+			{Role: "system", Content: digestPrompt},
+			{Role: "user", Content: transcript},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("read response: %w", err)
+	}
+
+	var out struct {
+		Choices []struct {
+			Message message `json:"message"`
+		} `json:"choices"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	jsonErr := json.Unmarshal(raw, &out)
+	switch {
+	case out.Error != nil && out.Error.Message != "":
+		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, out.Error.Message)
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("HTTP %d: %s", resp.StatusCode, truncateRunes(strings.TrimSpace(string(raw)), 200))
+	case jsonErr != nil:
+		return "", fmt.Errorf("decode response: %w", jsonErr)
+	case len(out.Choices) == 0 || strings.TrimSpace(out.Choices[0].Message.Content) == "":
+		return "", fmt.Errorf("empty response")
+	}
+	return strings.TrimSpace(out.Choices[0].Message.Content), nil
+}
+
+// digest runs /digest [n]: it summarizes the messages that were unread
+// when the room was opened, or the last n messages.
+func (m *model) digest(arg string) (tea.Model, tea.Cmd) {
+	if m.cfg.DigestEndpoint == "" {
+		m.addSystemMsg("digest: set digest_endpoint in the config to enable summaries")
+		return m, nil
+	}
+	item := m.activeSidebarItem()
+	if item == nil {
+		m.addSystemMsg("digest: no active conversation")
+		return m, nil
+	}
+	if item.Kind() == SidebarDM && !m.cfg.DigestDMs {
+		m.addSystemMsg("digest: DMs are end-to-end encrypted; set digest_dms = true to send them to digest_endpoint")
+		return m, nil
+	}
+	roomKey := m.activeRoomKey()
+	var msgs []ChatMessage
+	for _, msg := range m.msgs[roomKey] {
+		if msg.Author != "system" {
+			msgs = append(msgs, msg)
+		}
+	}
+
+	if arg = strings.TrimSpace(arg); arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n <= 0 {
+			m.addSystemMsg("usage: /digest [n]")
+			return m, nil
+		}
+		msgs = msgs[max(len(msgs)-n, 0):]
+	} else {
+		from := m.openedUnreadFrom
+		if m.openedRoom != item.ItemID() || from == 0 {
+			m.addSystemMsg("digest: nothing was unread here; /digest <n> summarizes the last n messages")
+			return m, nil
+		}
+		i := 0
+		for i < len(msgs) && msgs[i].Timestamp < from {
+			i++
+		}
+		msgs = msgs[i:]
+	}
+	if len(msgs) == 0 {
+		m.addSystemMsg("digest: no messages to summarize")
+		return m, nil
+	}
+
+	transcript, dropped := digestTranscript(msgs, func(msg ChatMessage) string {
+		if msg.PubKey == "" {
+			return msg.Author
+		}
+		return m.resolveAuthor(msg.PubKey)
+	}, digestMaxChars)
+	count := len(msgs) - dropped
+	m.digestSeq++
+	key := fmt.Sprintf("digest:%d", m.digestSeq)
+	status := fmt.Sprintf("summarizing %d messages…", count)
+	if dropped > 0 {
+		status = fmt.Sprintf("summarizing the newest %d of %d messages (size limit)…", count, len(msgs))
+	}
+	m.addStatusMsg(key, status)
+	done := digestMsg{statusKey: key, room: item.Prefix() + item.DisplayName(), count: count}
+	return m, summarizeCmd(m.cfg.DigestEndpoint, m.cfg.DigestAPIKey, m.cfg.DigestModelName(), transcript, done)
+}
+
+func (m *model) handleDigest(msg digestMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.updateStatusMsg(msg.statusKey, "digest failed: "+msg.err.Error())
+		return m, nil
+	}
+	m.updateStatusMsg(msg.statusKey, fmt.Sprintf("summarized %d messages", msg.count))
+	m.qrOverlay = m.renderDigest(msg)
+	return m, nil
+}
+
+// renderDigest formats a summary for the overlay.
+func (m *model) renderDigest(msg digestMsg) string {
+	width := min(max(m.width-8, 20), 80)
+	var b strings.Builder
+	b.WriteString(qrTitleStyle.Render(fmt.Sprintf("Digest of %s (%d messages)", msg.room, msg.count)))
+	b.WriteString("\n\n")
+	b.WriteString(lipgloss.NewStyle().Width(width).Render(msg.summary))
+	b.WriteString("\n\n")
+	b.WriteString(chatSystemStyle.Render("press any key to close"))
+	return b.String()
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestDigestTranscriptTruncates(t *testing.T) {
+	var msgs []ChatMessage
+	for i := range 5 {
+		msgs = append(msgs, ChatMessage{Author: "a", Content: strings.Repeat("x", 10) + "\nmore", Timestamp: nostr.Timestamp(i)})
+	}
+	name := func(msg ChatMessage) string { return msg.Author }
+
+	full, dropped := digestTranscript(msgs, name, 1000)
+	if dropped != 0 || strings.Count(full, "\n") != 4 {
+		t.Fatalf("untruncated: dropped=%d transcript=%q", dropped, full)
+	}
+	line := len(strings.Split(full, "\n")[0]) + 1
+	got, dropped := digestTranscript(msgs, name, 2*line)
+	if dropped != 3 || strings.Count(got, "\n") != 1 {
+		t.Errorf("truncated: dropped=%d transcript=%q", dropped, got)
+	}
+}
+
+func TestRequestDigest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer k" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":{"message":"bad key"}}`))
+			return
+		}
+		var req struct {
+			Model    string                     `json:"model"`
+			Messages []struct{ Content string } `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":" - ` + req.Model + `: ` + req.Messages[1].Content + ` "}}]}`))
+	}))
+	defer srv.Close()
+
+	got, err := requestDigest(srv.URL, "k", "m1", "hello")
+	if err != nil || got != "- m1: hello" {
+		t.Errorf("requestDigest = %q, %v", got, err)
+	}
+	if _, err := requestDigest(srv.URL, "wrong", "m1", "hello"); err == nil || !strings.Contains(err.Error(), "bad key") {
+		t.Errorf("want the endpoint's error message, got %v", err)
+	}
+}
+
+func TestOpenedUnreadFrom(t *testing.T) {
+	m := newTestModel(2, 0, 0)
+	m.unread = make(map[string]bool)
+	m.highlights = make(map[string]bool)
+	m.activeItem = 0
+	m.clearUnread()
+
+	m.markUnread("ch1", 20)
+	m.markUnread("ch1", 10)
+	m.markUnread("ch1", 30)
+	if m.unreadFrom["ch1"] != 10 {
+		t.Errorf("unreadFrom = %d, want the earliest (10)", m.unreadFrom["ch1"])
+	}
+
+	m.activeItem = 1
+	m.clearUnread()
+	m.clearUnread() // refreshing the open room keeps the boundary
+	if m.openedRoom != "ch1" || m.openedUnreadFrom != 10 {
+		t.Errorf("opened %q from %d, want ch1 from 10", m.openedRoom, m.openedUnreadFrom)
+	}
+	if _, ok := m.unreadFrom["ch1"]; ok {
+		t.Error("unreadFrom not cleared for the opened room")
+	}
+
+	m.activeItem = 0
+	m.clearUnread()
+	if m.openedUnreadFrom != 0 {
+		t.Errorf("room without unread messages opened with boundary %d", m.openedUnreadFrom)
+	}
+}
+
+func TestDigestSkipsDMsByDefault(t *testing.T) {
+	m := newTestModel(0, 0, 1)
+	m.cfg.DigestEndpoint = "http://127.0.0.1:1/unused"
+	m.activeItem = 0
+	m.msgs = map[string][]ChatMessage{"pk0": {{Author: "bob", PubKey: "pk0", Content: "secret", Timestamp: 1}}}
+
+	if _, cmd := m.digest("5"); cmd != nil {
+		t.Fatal("/digest in a DM should not send anything without digest_dms")
+	}
+	if msgs := m.msgs["pk0"]; !strings.Contains(msgs[len(msgs)-1].Content, "digest_dms") {
+		t.Errorf("want a hint about digest_dms, got %q", msgs[len(msgs)-1].Content)
+	}
+
+	m.cfg.DigestDMs = true
+	if _, cmd := m.digest("5"); cmd == nil {
+		t.Error("digest_dms = true should allow /digest in DMs")
+	}
+}
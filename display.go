@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// displayToggle is a display option that /display can switch at runtime.
+type displayToggle struct {
+	key   string // name in the display file
+	label string
+	get   func(Config) bool
+	set   func(*Config, bool)
+}
+
+// displayToggles are the options listed in the /display menu.
+var displayToggles = []displayToggle{
+	{"timestamps", "Timestamps", Config.ShowTimestamps, func(c *Config, v bool) { c.Timestamps = &v }},
+	{"avatars", "Avatars", func(c Config) bool { return c.Avatars }, func(c *Config, v bool) { c.Avatars = v }},
+	{"markdown", "Markdown rendering", Config.MarkdownEnabled, func(c *Config, v bool) { c.Markdown = &v }},
+	{"compact_sidebar", "Compact sidebar", func(c Config) bool { return c.CompactSidebar }, func(c *Config, v bool) { c.CompactSidebar = v }},
+	{"group_by_author", "Group messages by author", func(c Config) bool { return c.GroupByAuthor }, func(c *Config, v bool) { c.GroupByAuthor = v }},
+}
+
+// displayMenu is the /display overlay.
+type displayMenu struct {
+	sel int
+}
+
+// displayPath returns the path of the saved display options, next to the
+// config.
+func displayPath(cfgFlagPath string) string {
+	return filepath.Join(filepath.Dir(configPath(cfgFlagPath)), "display")
+}
+
+// loadDisplayPrefs reads the display file: one "key on|off" line per option
+// changed in /display. A missing file is empty.
+func loadDisplayPrefs(path string) (map[string]bool, error) {
+	prefs := make(map[string]bool)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return prefs, nil
+	}
+	if err != nil {
+		return prefs, err
+	}
+	defer func() { _ = f.Close() }()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		key, val, ok := strings.Cut(strings.TrimSpace(sc.Text()), " ")
+		if ok && (val == "on" || val == "off") {
+			prefs[key] = val == "on"
+		}
+	}
+	return prefs, sc.Err()
+}
+
+// saveDisplayPref records key's new value in the display file. Only options
+// switched at runtime are written, so the others keep following the config.
+func saveDisplayPref(path, key string, v bool) error {
+	prefs, err := loadDisplayPrefs(path)
+	if err != nil {
+		return err
+	}
+	prefs[key] = v
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, t := range displayToggles {
+		val, ok := prefs[t.key]
+		if !ok {
+			continue
+		}
+		state := "off"
+		if val {
+			state = "on"
+		}
+		fmt.Fprintf(&b, "%s %s\n", t.key, state)
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+// applyDisplayPrefs overrides the config's display options with the saved
+// ones.
+func applyDisplayPrefs(cfg *Config, prefs map[string]bool) {
+	for _, t := range displayToggles {
+		if v, ok := prefs[t.key]; ok {
+			t.set(cfg, v)
+		}
+	}
+}
+
+// setDisplayOption switches toggle t, redraws, and saves the choice.
+func (m *model) setDisplayOption(t displayToggle, v bool) {
+	t.set(&m.cfg, v)
+	m.updateLayout() // avatars and compact_sidebar change the sidebar
+	if err := saveDisplayPref(displayPath(m.cfgFlagPath), t.key, v); err != nil {
+		m.addSystemMsg("saving display options: " + err.Error())
+	}
+}
+
+// toggleDisplayOption handles /toggle-timestamps and /toggle-avatars.
+func (m *model) toggleDisplayOption(key string) (tea.Model, tea.Cmd) {
+	for _, t := range displayToggles {
+		if t.key == key {
+			v := !t.get(m.cfg)
+			m.setDisplayOption(t, v)
+			state := "off"
+			if v {
+				state = "on"
+			}
+			m.addSystemMsg(strings.ToLower(t.label) + " " + state)
+		}
+	}
+	return m, nil
+}
+
+// handleDisplayMenuKey moves the selection; space, enter, or x switches the
+// selected option and esc or q closes the menu.
+func (m *model) handleDisplayMenuKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	d := m.displayMenu
+	switch msg.String() {
+	case "esc", "q":
+		m.displayMenu = nil
+	case "up", "k":
+		d.sel = max(d.sel-1, 0)
+	case "down", "j":
+		d.sel = min(d.sel+1, len(displayToggles)-1)
+	case " ", "space", "enter", "x":
+		t := displayToggles[d.sel]
+		m.setDisplayOption(t, !t.get(m.cfg))
+	}
+	return m, nil
+}
+
+// viewDisplayMenu renders the options as a checklist.
+func (m *model) viewDisplayMenu() string {
+	var b strings.Builder
+	b.WriteString(qrTitleStyle.Render("Display") + "\n\n")
+	for i, t := range displayToggles {
+		box := "[ ]"
+		if t.get(m.cfg) {
+			box = "[x]"
+		}
+		line := fmt.Sprintf(" %s %s ", box, t.label)
+		if i == m.displayMenu.sel {
+			b.WriteString(acSelectedStyle.Render(line) + "\n")
+		} else {
+			b.WriteString(acSuggestionStyle.Render(line) + "\n")
+		}
+	}
+	b.WriteString("\n" + chatSystemStyle.Render("↑/↓ select · space toggles · esc closes (saved)"))
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDisplayPrefsRoundtrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "display")
+	prefs, err := loadDisplayPrefs(path)
+	if err != nil || len(prefs) != 0 {
+		t.Fatalf("missing file: got %v, %v", prefs, err)
+	}
+
+	if err := saveDisplayPref(path, "timestamps", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := saveDisplayPref(path, "avatars", true); err != nil {
+		t.Fatal(err)
+	}
+	prefs, err = loadDisplayPrefs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prefs) != 2 {
+		t.Errorf("saved %v, want only the two switched options", prefs)
+	}
+
+	var loaded Config
+	loaded.CompactSidebar = true // not switched at runtime, so the config wins
+	applyDisplayPrefs(&loaded, prefs)
+	if loaded.ShowTimestamps() || !loaded.Avatars || !loaded.MarkdownEnabled() || !loaded.CompactSidebar || loaded.GroupByAuthor {
+		t.Errorf("applied config = %+v", loaded)
+	}
+}
+
+func TestSameAuthorRun(t *testing.T) {
+	a := ChatMessage{PubKey: "pk1", Timestamp: 1000}
+	tests := []struct {
+		name string
+		cur  ChatMessage
+		want bool
+	}{
+		{"same author soon after", ChatMessage{PubKey: "pk1", Timestamp: 1060}, true},
+		{"other author", ChatMessage{PubKey: "pk2", Timestamp: 1060}, false},
+		{"long pause", ChatMessage{PubKey: "pk1", Timestamp: 1000 + authorRunGap + 1}, false},
+		{"mine", ChatMessage{PubKey: "pk1", Timestamp: 1060, IsMine: true}, false},
+		{"repost", ChatMessage{PubKey: "pk1", Timestamp: 1060, RepostOf: "pk3"}, false},
+	}
+	for _, tt := range tests {
+		if got := sameAuthorRun(a, tt.cur); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	// Our own messages group regardless of the peer pubkey they carry.
+	if !sameAuthorRun(ChatMessage{IsMine: true, PubKey: "peer", Timestamp: 1}, ChatMessage{IsMine: true, PubKey: "peer", Timestamp: 2}) {
+		t.Error("own consecutive messages should group")
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/nip17"
+	"fiatjaf.com/nostr/nip59"
+)
+
+// kindDMReadReceipt is the rumor kind of a read receipt inside a NIP-17 gift
+// wrap. No NIP defines DM receipts yet; an ephemeral-range kind keeps other
+// clients from showing it as a message.
+const kindDMReadReceipt nostr.Kind = 20014
+
+// dmReceiptMsg carries a peer's read receipt: they have seen our messages up
+// to and including seen.
+type dmReceiptMsg struct {
+	peer string
+	seen nostr.Timestamp
+}
+
+// buildReadReceiptRumor builds the unsigned receipt rumor telling recipient
+// that sender has read their messages up to seen.
+func buildReadReceiptRumor(sender, recipient nostr.PubKey, seen nostr.Timestamp) nostr.Event {
+	rumor := nostr.Event{
+		Kind:      kindDMReadReceipt,
+		CreatedAt: nostr.Now(),
+		PubKey:    sender,
+		Tags: nostr.Tags{
+			{"p", recipient.Hex()},
+			{"seen", strconv.FormatInt(int64(seen), 10)},
+		},
+	}
+	rumor.ID = rumor.GetID()
+	return rumor
+}
+
+// parseReadReceipt returns the "seen" timestamp of a receipt rumor.
+func parseReadReceipt(rumor nostr.Event) (nostr.Timestamp, bool) {
+	if rumor.Kind != kindDMReadReceipt {
+		return 0, false
+	}
+	tag := rumor.Tags.Find("seen")
+	if tag == nil {
+		return 0, false
+	}
+	v, err := strconv.ParseInt(tag[1], 10, 64)
+	if err != nil || v <= 0 {
+		return 0, false
+	}
+	return nostr.Timestamp(v), true
+}
+
+// sendReadReceiptCmd gift-wraps a read receipt to peer's DM relays. Failures
+// are only logged: a missing receipt is not worth interrupting the user.
+func sendReadReceiptCmd(pool *nostr.Pool, relays []string, peerPK string, seen nostr.Timestamp, keys Keys, kr nostr.Keyer) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		recipient, err := nostr.PubKeyFromHex(peerPK)
+		if err != nil {
+			log.Printf("sendReadReceipt: invalid peer pubkey: %v", err)
+			return nil
+		}
+		theirRelays := nip17.GetDMRelays(ctx, recipient, pool, relays)
+		if len(theirRelays) == 0 {
+			theirRelays = relays
+		}
+		wrap, err := nip59.GiftWrap(
+			buildReadReceiptRumor(keys.PK, recipient, seen),
+			recipient,
+			func(s string) (string, error) { return kr.Encrypt(ctx, s, recipient) },
+			func(e *nostr.Event) error { return kr.SignEvent(ctx, e) },
+			nil,
+		)
+		if err != nil {
+			log.Printf("sendReadReceipt: gift wrap failed: %v", err)
+			return nil
+		}
+		logEventOut(peerPK, wrap)
+		drainPublish(ctx, pool.PublishMany(ctx, theirRelays, wrap))
+		log.Printf("sendReadReceipt: sent receipt to %s seen=%d", shortPK(peerPK), seen)
+		return nil
+	}
+}
+
+// readReceiptCmd sends a read receipt when dm_read_receipts is enabled and
+// the open DM shows a peer message newer than the last receipt we sent.
+// Messages from earlier sessions are not acknowledged, and only peers in
+// receiptPeers get receipts: other clients would show the unknown rumor
+// kind as a message.
+func (m *model) readReceiptCmd() tea.Cmd {
+	if !m.cfg.DMReadReceipts {
+		return nil
+	}
+	peer := m.activeDMPeerPK()
+	if peer == "" || !m.receiptPeers[peer] {
+		return nil
+	}
+	var newest nostr.Timestamp
+	for _, msg := range m.msgs[peer] {
+		if !msg.IsMine && msg.Author != "system" && msg.Timestamp > newest {
+			newest = msg.Timestamp
+		}
+	}
+	if newest <= m.dmSeenAtStart || newest <= m.receiptsSent[peer] {
+		return nil
+	}
+	m.receiptsSent[peer] = newest
+	return sendReadReceiptCmd(m.pool, m.relays, peer, newest, m.keys, m.kr)
+}
+
+// seenMarkerID returns the event ID of our newest message in a DM that the
+// peer has acknowledged with a read receipt, or "" if none.
+func (m *model) seenMarkerID(peer string, msgs []ChatMessage) string {
+	seen := m.seenByPeer[peer]
+	if !m.cfg.DMReadReceipts || seen == 0 {
+		return ""
+	}
+	id := ""
+	for _, msg := range msgs {
+		if msg.IsMine && msg.Timestamp <= seen {
+			id = msg.EventID
+		}
+	}
+	return id
+}
+
+func (m *model) handleDMReceipt(msg dmReceiptMsg) (tea.Model, tea.Cmd) {
+	// The peer's client understands receipts, so it may get ours.
+	if m.receiptPeers == nil {
+		m.receiptPeers = make(map[string]bool)
+	}
+	m.receiptPeers[msg.peer] = true
+	// Receipts are mutual: ignore them unless we send ours too.
+	if m.cfg.DMReadReceipts && msg.seen > m.seenByPeer[msg.peer] {
+		log.Printf("dmReceiptMsg: %s has seen up to %d", shortPK(msg.peer), msg.seen)
+		m.seenByPeer[msg.peer] = msg.seen
+		if m.activeDMPeerPK() == msg.peer {
+			m.updateViewport()
+		}
+	}
+	if m.dmEvents != nil {
+		return m, waitForDMEvent(m.dmEvents, m.keys)
+	}
+	return m, nil
+}
+
+// toggleReadReceipts handles /read-receipts [on|off] for this session. In a
+// DM, "on" also starts sending receipts to that peer before their client has
+// sent one.
+func (m *model) toggleReadReceipts(arg string) (tea.Model, tea.Cmd) {
+	switch arg {
+	case "on":
+		m.cfg.DMReadReceipts = true
+		if peer := m.activeDMPeerPK(); peer != "" {
+			if m.receiptPeers == nil {
+				m.receiptPeers = make(map[string]bool)
+			}
+			m.receiptPeers[peer] = true
+			m.addSystemMsg("sending read receipts to " + m.resolveAuthor(peer) + " (their client should support them)")
+			return m, m.readReceiptCmd()
+		}
+	case "off":
+		m.cfg.DMReadReceipts = false
+	case "":
+	default:
+		m.addSystemMsg("usage: /read-receipts [on|off]")
+		return m, nil
+	}
+	if m.cfg.DMReadReceipts {
+		m.addSystemMsg("DM read receipts: on (sent when you view a DM of a peer whose client sent one, shown as ✓ seen)")
+	} else {
+		m.addSystemMsg("DM read receipts: off")
+	}
+	return m, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestReadReceiptRoundTrip(t *testing.T) {
+	keys := testKeys(t)
+	peer := nostr.Generate().Public()
+
+	rumor := buildReadReceiptRumor(keys.PK, peer, 1700000000)
+	if rumor.Kind != kindDMReadReceipt {
+		t.Errorf("Kind = %d, want %d", rumor.Kind, kindDMReadReceipt)
+	}
+	if !hasTag(rumor, "p", peer.Hex()) {
+		t.Error("missing p tag for the recipient")
+	}
+	seen, ok := parseReadReceipt(rumor)
+	if !ok || seen != 1700000000 {
+		t.Errorf("parseReadReceipt = %d, %v", seen, ok)
+	}
+
+	if _, ok := parseReadReceipt(nostr.Event{Kind: nostr.KindDirectMessage, Tags: nostr.Tags{{"seen", "1"}}}); ok {
+		t.Error("non-receipt kinds should not parse")
+	}
+	if _, ok := parseReadReceipt(nostr.Event{Kind: kindDMReadReceipt, Tags: nostr.Tags{{"seen", "soon"}}}); ok {
+		t.Error("invalid seen tag should not parse")
+	}
+}
+
+func TestSeenMarkerID(t *testing.T) {
+	m := newTestModel(0, 0, 1)
+	m.seenByPeer = map[string]nostr.Timestamp{"pk0": 200}
+	msgs := []ChatMessage{
+		{EventID: "a", IsMine: true, Timestamp: 100},
+		{EventID: "b", IsMine: false, Timestamp: 150},
+		{EventID: "c", IsMine: true, Timestamp: 200},
+		{EventID: "d", IsMine: true, Timestamp: 300},
+	}
+
+	if got := m.seenMarkerID("pk0", msgs); got != "" {
+		t.Errorf("receipts disabled: got %q, want none", got)
+	}
+	m.cfg.DMReadReceipts = true
+	if got := m.seenMarkerID("pk0", msgs); got != "c" {
+		t.Errorf("seenMarkerID = %q, want newest acknowledged message c", got)
+	}
+	if got := m.seenMarkerID("pk1", msgs); got != "" {
+		t.Errorf("peer without receipts: got %q", got)
+	}
+}
+
+func TestReadReceiptOnlyToReceiptPeers(t *testing.T) {
+	m := newTestModel(0, 0, 1)
+	m.cfg.DMReadReceipts = true
+	m.receiptsSent = map[string]nostr.Timestamp{}
+	m.seenByPeer = map[string]nostr.Timestamp{}
+	m.msgs = map[string][]ChatMessage{"pk0": {{Author: "bob", PubKey: "pk0", Timestamp: 100}}}
+	if m.activeDMPeerPK() != "pk0" {
+		t.Fatalf("active DM = %q, want pk0", m.activeDMPeerPK())
+	}
+
+	if cmd := m.readReceiptCmd(); cmd != nil {
+		t.Error("receipt sent to a peer whose client never sent one")
+	}
+	m.handleDMReceipt(dmReceiptMsg{peer: "pk0", seen: 50})
+	if cmd := m.readReceiptCmd(); cmd == nil {
+		t.Error("no receipt for a peer that sends receipts")
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// dmSearchHit is one /dm-search result.
+type dmSearchHit struct {
+	PeerPK  string
+	Msg     ChatMessage
+	FromLog bool // found only in the on-disk log, not in memory
+}
+
+// matchingMessages returns the non-system messages whose content contains
+// term, case-insensitively.
+func matchingMessages(msgs []ChatMessage, term string) []ChatMessage {
+	term = strings.ToLower(term)
+	var out []ChatMessage
+	for _, msg := range msgs {
+		if msg.Author != "system" && strings.Contains(strings.ToLower(msg.Content), term) {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+// snippetAround returns up to width runes of content centered on the first
+// case-insensitive occurrence of term, on a single line.
+func snippetAround(content, term string, width int) string {
+	flat := []rune(strings.Join(strings.Fields(content), " "))
+	lower := []rune(strings.ToLower(string(flat)))
+	idx := strings.Index(string(lower), strings.ToLower(term))
+	if idx < 0 || len(flat) <= width {
+		return truncateRunes(string(flat), width)
+	}
+	pos := len([]rune(string(lower)[:idx]))
+	start := max(pos-width/3, 0)
+	end := min(start+width, len(flat))
+	start = max(end-width, 0)
+	s := string(flat[start:end])
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(flat) {
+		s += "…"
+	}
+	return s
+}
+
+// searchDMs searches the in-memory DM buffers for term and, if includeLogs
+// is set, the on-disk DM logs as well. Hits are grouped by peer in sidebar
+// order, oldest first within a peer.
+func (m *model) searchDMs(term string, includeLogs bool) []dmSearchHit {
+	var hits []dmSearchHit
+	for _, peer := range m.allDMPeers() {
+		seen := make(map[string]bool)
+		var peerHits []dmSearchHit
+		for _, msg := range matchingMessages(m.msgs[peer], term) {
+			seen[msg.Content+"\x00"+strconv.FormatInt(int64(msg.Timestamp), 10)] = true
+			peerHits = append(peerHits, dmSearchHit{PeerPK: peer, Msg: msg})
+		}
+		if includeLogs {
+			logged, err := m.history.Search("dm", peer, term)
+			if err == nil {
+				for _, msg := range logged {
+					if seen[msg.Content+"\x00"+strconv.FormatInt(int64(msg.Timestamp), 10)] {
+						continue
+					}
+					peerHits = append(peerHits, dmSearchHit{PeerPK: peer, Msg: msg, FromLog: true})
+				}
+			}
+		}
+		sort.SliceStable(peerHits, func(a, b int) bool {
+			return peerHits[a].Msg.Timestamp < peerHits[b].Msg.Timestamp
+		})
+		hits = append(hits, peerHits...)
+	}
+	return hits
+}
+
+// handleDMSearch handles /dm-search [--logs] <term> and /dm-search #<n>.
+func (m *model) handleDMSearch(arg string) (tea.Model, tea.Cmd) {
+	if strings.HasPrefix(arg, "#") {
+		n, err := strconv.Atoi(arg[1:])
+		if err != nil || n < 1 || n > len(m.dmSearchHits) {
+			m.addSystemMsg(fmt.Sprintf("no search result %s", arg))
+			return m, nil
+		}
+		return m.openDMSearchHit(m.dmSearchHits[n-1])
+	}
+
+	includeLogs := false
+	if rest, ok := strings.CutPrefix(arg, "--logs"); ok {
+		includeLogs = true
+		arg = strings.TrimSpace(rest)
+	}
+	if arg == "" {
+		m.addSystemMsg("usage: /dm-search [--logs] <term>")
+		return m, nil
+	}
+	if includeLogs && m.logDir == "" {
+		m.addSystemMsg("logging is disabled; searching in-memory DMs only")
+		includeLogs = false
+	}
+
+	hits := m.searchDMs(arg, includeLogs)
+	m.dmSearchHits = hits
+	if len(hits) == 0 {
+		m.addSystemMsg(fmt.Sprintf("no DMs matching %q", arg))
+		return m, nil
+	}
+
+	var lines []string
+	lastPeer := ""
+	for i, h := range hits {
+		if h.PeerPK != lastPeer {
+			lines = append(lines, m.resolveAuthor(h.PeerPK)+":")
+			lastPeer = h.PeerPK
+		}
+		author := h.Msg.Author
+		if h.Msg.IsMine {
+			author = "you"
+		} else if !h.FromLog {
+			author = m.resolveAuthor(h.Msg.PubKey)
+		}
+		src := ""
+		if h.FromLog {
+			src = " (log)"
+		}
+		lines = append(lines, fmt.Sprintf("  #%d %s %s: %s%s", i+1,
+			h.Msg.Timestamp.Time().Format("2006-01-02 15:04"), author, snippetAround(h.Msg.Content, arg, 50), src))
+	}
+	for _, l := range lines {
+		m.addSystemMsg(l)
+	}
+	m.addSystemMsg(fmt.Sprintf("%d matches — /dm-search #<n> opens one", len(hits)))
+	return m, nil
+}
+
+// openDMSearchHit switches to the hit's DM conversation and scrolls to the
+// message if it is loaded.
+func (m *model) openDMSearchHit(h dmSearchHit) (tea.Model, tea.Cmd) {
+	idx := m.findDMPeerIdx(h.PeerPK)
+	if idx < 0 {
+		m.addSystemMsg("that conversation is no longer in the sidebar")
+		return m, nil
+	}
+	m.activeItem = idx
+	m.clearUnread()
+	m.updateViewport()
+	switch {
+	case h.FromLog:
+		m.addSystemMsg("that message is only in the on-disk log, not loaded here")
+	case !m.scrollToMessage(h.Msg.EventID):
+		m.addSystemMsg("that message is no longer loaded here")
+	}
+	return m, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import "testing"
+
+func TestMatchingMessages(t *testing.T) {
+	msgs := []ChatMessage{
+		{Author: "alice", Content: "Meet at the Cafe"},
+		{Author: "system", Content: "cafe notice"},
+		{Author: "bob", Content: "no match here"},
+	}
+	got := matchingMessages(msgs, "cafe")
+	if len(got) != 1 || got[0].Author != "alice" {
+		t.Errorf("matchingMessages = %v, want only alice's message", got)
+	}
+}
+
+func TestSnippetAround(t *testing.T) {
+	if got := snippetAround("short text", "text", 40); got != "short text" {
+		t.Errorf("short content: got %q", got)
+	}
+	long := "aaaa bbbb cccc dddd eeee ffff gggg NEEDLE hhhh iiii jjjj kkkk llll"
+	got := snippetAround(long, "needle", 20)
+	if !containsWholeWord(got, "needle") {
+		t.Errorf("snippet %q should contain the match", got)
+	}
+	if got[:3] != "…" {
+		t.Errorf("snippet %q should start with an ellipsis", got)
+	}
+	if got := snippetAround("line one\nline two", "two", 40); got != "line one line two" {
+		t.Errorf("newlines should be flattened, got %q", got)
+	}
+}
+
+func TestSearchDMs(t *testing.T) {
+	m := newTestModel(0, 0, 2) // DM peers pk0, pk1
+	m.msgs = map[string][]ChatMessage{
+		"pk0": {{Author: "x", PubKey: "pk0", Content: "the password is swordfish", Timestamp: 20}},
+		"pk1": {
+			{Author: "y", PubKey: "pk1", Content: "Swordfish again", Timestamp: 30},
+			{Author: "y", PubKey: "pk1", Content: "first swordfish", Timestamp: 10},
+		},
+	}
+	hits := m.searchDMs("swordfish", false)
+	if len(hits) != 3 {
+		t.Fatalf("expected 3 hits, got %d", len(hits))
+	}
+	if hits[0].PeerPK != "pk0" || hits[1].Msg.Content != "first swordfish" {
+		t.Errorf("hits should be grouped by peer, oldest first: %+v", hits)
+	}
+}
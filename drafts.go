@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// draftsPath returns the path to the named-drafts file next to the config.
+func draftsPath(cfgFlagPath string) string {
+	return filepath.Join(filepath.Dir(configPath(cfgFlagPath)), "drafts")
+}
+
+// loadDrafts reads the drafts file: one "name<TAB>text" line per draft, with
+// newlines in text escaped like the message logs. A missing file is empty.
+func loadDrafts(path string) (map[string]string, error) {
+	drafts := make(map[string]string)
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return drafts, nil
+	}
+	if err != nil {
+		return drafts, err
+	}
+	defer func() { _ = f.Close() }()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		name, text, ok := strings.Cut(sc.Text(), "\t")
+		if !ok || name == "" {
+			continue
+		}
+		drafts[name] = unescapeContent(text)
+	}
+	return drafts, sc.Err()
+}
+
+// saveDrafts rewrites the drafts file, sorted by name.
+func saveDrafts(path string, drafts map[string]string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, name := range sortedDraftNames(drafts) {
+		fmt.Fprintf(&b, "%s\t%s\n", name, escapeContent(drafts[name]))
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+func sortedDraftNames(drafts map[string]string) []string {
+	names := make([]string, 0, len(drafts))
+	for name := range drafts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// saveDraft handles /save-draft <name> <text>.
+func (m *model) saveDraft(arg string) (tea.Model, tea.Cmd) {
+	name, text, _ := strings.Cut(arg, " ")
+	text = strings.TrimSpace(text)
+	if name == "" || text == "" {
+		m.addSystemMsg("usage: /save-draft <name> <text>")
+		return m, nil
+	}
+	_, existed := m.drafts[name]
+	m.drafts[name] = text
+	if err := saveDrafts(draftsPath(m.cfgFlagPath), m.drafts); err != nil {
+		m.addSystemMsg("save-draft: " + err.Error())
+		return m, nil
+	}
+	if existed {
+		m.addSystemMsg(fmt.Sprintf("draft %q updated", name))
+	} else {
+		m.addSystemMsg(fmt.Sprintf("draft %q saved — load it with /draft %s", name, name))
+	}
+	return m, nil
+}
+
+// listDrafts handles /drafts.
+func (m *model) listDrafts() (tea.Model, tea.Cmd) {
+	if len(m.drafts) == 0 {
+		m.addSystemMsg("no drafts — save one with /save-draft <name> <text>")
+		return m, nil
+	}
+	m.addSystemMsg(fmt.Sprintf("%d drafts:", len(m.drafts)))
+	for _, name := range sortedDraftNames(m.drafts) {
+		preview := truncateRunes(strings.Join(strings.Fields(m.drafts[name]), " "), 60)
+		m.addSystemMsg(fmt.Sprintf("  %s — %s", name, preview))
+	}
+	return m, nil
+}
+
+// loadDraft handles /draft <name>: it puts the draft into the input for
+// editing before sending.
+func (m *model) loadDraft(name string) (tea.Model, tea.Cmd) {
+	if name == "" {
+		return m.listDrafts()
+	}
+	text, ok := m.drafts[name]
+	if !ok {
+		m.addSystemMsg(fmt.Sprintf("no draft named %q (see /drafts)", name))
+		return m, nil
+	}
+	m.input.SetValue(text)
+	m.syncInputHeight()
+	return m, nil
+}
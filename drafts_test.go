@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDraftsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "drafts")
+
+	got, err := loadDrafts(path)
+	if err != nil || len(got) != 0 {
+		t.Fatalf("missing file: got %v, %v; want empty, nil", got, err)
+	}
+
+	want := map[string]string{
+		"invite": "join us at ~relay'group\nsee you there",
+		"link":   `C:\path with\ttab`,
+	}
+	if err := saveDrafts(path, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err = loadDrafts(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %q, want %q", got, want)
+	}
+}
+
+func TestLoadDraftsSkipsMalformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "drafts")
+	data := "good\thello\nno tab here\n\tnameless\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadDrafts(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"good": "hello"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
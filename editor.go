@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// editorFinishedMsg is returned after the external editor exits.
+type editorFinishedMsg struct {
+	content string
+	err     error
+}
+
+// editorCommand returns the user's preferred editor command ($VISUAL, then $EDITOR).
+func editorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(env)); len(fields) > 0 {
+			return fields
+		}
+	}
+	return nil
+}
+
+// openExternalEditor suspends the TUI and opens $EDITOR on a temp file
+// seeded with the current input. The edited text is loaded back into the
+// input when the editor exits.
+func (m *model) openExternalEditor() (tea.Model, tea.Cmd) {
+	editor := editorCommand()
+	if editor == nil {
+		m.addSystemMsg("no editor configured — set $VISUAL or $EDITOR")
+		return m, nil
+	}
+
+	f, err := os.CreateTemp("", "nitrous-*.md")
+	if err != nil {
+		m.addSystemMsg(fmt.Sprintf("editor: create temp file: %v", err))
+		return m, nil
+	}
+	path := f.Name()
+	if _, err := f.WriteString(m.input.Value()); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		m.addSystemMsg(fmt.Sprintf("editor: write temp file: %v", err))
+		return m, nil
+	}
+	_ = f.Close()
+
+	log.Printf("openExternalEditor: %v %s", editor, path)
+	c := exec.Command(editor[0], append(editor[1:], path)...)
+	return m, tea.ExecProcess(c, func(err error) tea.Msg {
+		defer func() { _ = os.Remove(path) }()
+		if err != nil {
+			return editorFinishedMsg{err: err}
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return editorFinishedMsg{err: err}
+		}
+		return editorFinishedMsg{content: strings.TrimRight(string(data), "\n")}
+	})
+}
+
+func (m *model) handleEditorFinished(msg editorFinishedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		// Leave the prior input untouched.
+		log.Printf("editorFinishedMsg: %v", msg.err)
+		m.addSystemMsg(fmt.Sprintf("editor error: %v", msg.err))
+		return m, nil
+	}
+	m.input.SetValue(msg.content)
+	m.syncInputHeight()
+	return m, nil
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/nip19"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// embedCardLines is how many lines of a quoted note an embed card shows.
+const embedCardLines = 6
+
+// noteRefRe matches nostr: references to a single event (NIP-21).
+var noteRefRe = regexp.MustCompile(`nostr:(?:nevent1|note1)[02-9ac-hj-np-z]+`)
+
+// embedMsg is returned after fetching a referenced note. room is set when
+// the note was fetched by /embed, to post it there.
+type embedMsg struct {
+	id    string
+	evt   *nostr.Event
+	relay string // where the note was found
+	room  string
+	err   error
+}
+
+// parseEventRef decodes a nevent or note, optionally nostr:-prefixed.
+func parseEventRef(s string) (nostr.EventPointer, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "nostr:")
+	_, data, err := nip19.Decode(s)
+	if err != nil {
+		return nostr.EventPointer{}, fmt.Errorf("not a nevent or note: %w", err)
+	}
+	ptr, ok := data.(nostr.EventPointer)
+	if !ok {
+		return nostr.EventPointer{}, fmt.Errorf("not a nevent or note")
+	}
+	return ptr, nil
+}
+
+// noteRefs returns the events referenced in content, in order, once each.
+func noteRefs(content string) []nostr.EventPointer {
+	var ptrs []nostr.EventPointer
+	seen := make(map[nostr.ID]bool)
+	for _, ref := range noteRefRe.FindAllString(content, -1) {
+		ptr, err := parseEventRef(ref)
+		if err != nil || seen[ptr.ID] {
+			continue
+		}
+		seen[ptr.ID] = true
+		ptrs = append(ptrs, ptr)
+	}
+	return ptrs
+}
+
+// embedCard renders evt as a markdown quote headed by its author's name and
+// date, cut to embedCardLines lines.
+func embedCard(author string, evt nostr.Event) string {
+	lines := strings.Split(strings.TrimSpace(evt.Content), "\n")
+	if len(lines) > embedCardLines {
+		lines = append(lines[:embedCardLines], "…")
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "> **%s** · %s", author, evt.CreatedAt.Time().Format("2006-01-02 15:04"))
+	b.WriteString("\n>")
+	for _, l := range lines {
+		b.WriteString("\n> " + l)
+	}
+	return b.String()
+}
+
+// embedText is the message /embed posts: the card, readable anywhere, and
+// the reference, which other clients render natively.
+func embedText(author string, evt nostr.Event, relay string) string {
+	var hints []string
+	if relay != "" {
+		hints = []string{relay}
+	}
+	return embedCard(author, evt) + "\n\nnostr:" + nip19.EncodeNevent(evt.ID, hints, evt.PubKey)
+}
+
+// expandNoteRefs replaces the references in content whose note card returns
+// with that card. References right after a quote are kept as they are: that
+// is how /embed posts them, the card already above.
+func expandNoteRefs(content string, card func(nostr.ID) (string, bool)) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range noteRefRe.FindAllStringIndex(content, -1) {
+		before := strings.TrimRight(content[:loc[0]], " \t\n")
+		if i := strings.LastIndexByte(before, '\n'); strings.HasPrefix(strings.TrimSpace(before[i+1:]), ">") {
+			continue
+		}
+		ptr, err := parseEventRef(content[loc[0]:loc[1]])
+		if err != nil {
+			continue
+		}
+		c, ok := card(ptr.ID)
+		if !ok {
+			continue
+		}
+		b.WriteString(content[last:loc[0]])
+		b.WriteString("\n\n" + c + "\n\n")
+		last = loc[1]
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+// fetchEmbedCmd fetches the note at ptr from its relay hints and relays.
+func fetchEmbedCmd(pool *nostr.Pool, relays []string, ptr nostr.EventPointer, room string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		urls := mergeRelayHints(ptr.Relays, relays)
+		re := pool.QuerySingle(ctx, urls, nostr.Filter{IDs: []nostr.ID{ptr.ID}}, nostr.SubscriptionOptions{Label: "embed"})
+		if re == nil {
+			return embedMsg{id: ptr.ID.Hex(), room: room, err: fmt.Errorf("note not found on %d relays", len(urls))}
+		}
+		log.Printf("fetchEmbed: %s by %s from %s", shortPK(ptr.ID.Hex()), shortPK(re.PubKey.Hex()), re.Relay.URL)
+		return embedMsg{id: ptr.ID.Hex(), evt: &re.Event, relay: re.Relay.URL, room: room}
+	}
+}
+
+// noteCard returns the card of a fetched note.
+func (m *model) noteCard(id nostr.ID) (string, bool) {
+	evt, ok := m.embeds[id.Hex()]
+	if !ok {
+		return "", false
+	}
+	return embedCard(m.resolveAuthor(evt.PubKey.Hex()), evt), true
+}
+
+// maybeFetchEmbeds fetches the notes referenced in content that aren't
+// known yet, so their cards can be shown.
+func (m *model) maybeFetchEmbeds(content string, relays []string) tea.Cmd {
+	var cmds []tea.Cmd
+	for _, ptr := range noteRefs(content) {
+		id := ptr.ID.Hex()
+		if _, ok := m.embeds[id]; ok || m.embedPending[id] {
+			continue
+		}
+		m.embedPending[id] = true
+		cmds = append(cmds, fetchEmbedCmd(m.pool, relays, ptr, ""))
+	}
+	return tea.Batch(cmds...)
+}
+
+// embedNote handles /embed <nevent|note>.
+func (m *model) embedNote(arg string) (tea.Model, tea.Cmd) {
+	ptr, err := parseEventRef(arg)
+	if arg == "" || err != nil {
+		m.addSystemMsg("usage: /embed <nevent1...|note1...>")
+		return m, nil
+	}
+	item := m.activeSidebarItem()
+	if item == nil {
+		m.addSystemMsg("/embed needs a conversation to post to")
+		return m, nil
+	}
+	m.addSystemMsg("fetching the note to embed …")
+	return m, fetchEmbedCmd(m.pool, m.itemRelays(item), ptr, item.ItemID())
+}
+
+// itemRelays returns the relays the room of item is read from.
+func (m *model) itemRelays(item SidebarItem) []string {
+	switch it := item.(type) {
+	case ChannelItem:
+		return it.Channel.RelaysWith(m.relays)
+	case GroupItem:
+		return mergeRelayHints(m.relays, it.Group.Relays())
+	}
+	return m.relays
+}
+
+func (m *model) handleEmbed(msg embedMsg) (tea.Model, tea.Cmd) {
+	delete(m.embedPending, msg.id)
+	if msg.err != nil {
+		log.Printf("embedMsg: %s: %v", shortPK(msg.id), msg.err)
+		if msg.room != "" {
+			m.addRoomSystemMsg(msg.room, "embed: "+msg.err.Error())
+		}
+		return m, nil
+	}
+	m.embeds[msg.id] = *msg.evt
+	cmds := []tea.Cmd{m.maybeRequestProfile(msg.evt.PubKey.Hex())}
+	if msg.room != "" {
+		if idx := slices.IndexFunc(m.sidebar, func(it SidebarItem) bool { return it.ItemID() == msg.room }); idx >= 0 {
+			text := embedText(m.resolveAuthor(msg.evt.PubKey.Hex()), *msg.evt, msg.relay)
+			cmds = append(cmds, m.sendToItem(m.sidebar[idx], text))
+		} else {
+			m.addSystemMsg("embed: that conversation is no longer in the sidebar")
+		}
+	}
+	m.updateViewport()
+	return m, tea.Batch(cmds...)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/nip19"
+)
+
+func testNote(t *testing.T, content string) nostr.Event {
+	t.Helper()
+	evt := nostr.Event{Kind: nostr.KindTextNote, CreatedAt: nostr.Now(), Content: content}
+	if err := evt.Sign(nostr.Generate()); err != nil {
+		t.Fatal(err)
+	}
+	return evt
+}
+
+func TestParseEventRef(t *testing.T) {
+	evt := testNote(t, "hi")
+	nevent := nip19.EncodeNevent(evt.ID, []string{"wss://r.example"}, evt.PubKey)
+	for _, s := range []string{nevent, "nostr:" + nevent} {
+		ptr, err := parseEventRef(s)
+		if err != nil || ptr.ID != evt.ID {
+			t.Errorf("parseEventRef(%q) = %v, %v", s, ptr.ID, err)
+		}
+	}
+	if _, err := parseEventRef(nip19.EncodeNpub(evt.PubKey)); err == nil {
+		t.Error("parseEventRef accepted an npub")
+	}
+}
+
+func TestNoteRefs(t *testing.T) {
+	a, b := testNote(t, "a"), testNote(t, "b")
+	refA := "nostr:" + nip19.EncodeNevent(a.ID, nil, a.PubKey)
+	content := "look " + refA + " and nostr:" + nip19.EncodeNevent(b.ID, nil, b.PubKey) + " again " + refA
+	ptrs := noteRefs(content)
+	if len(ptrs) != 2 || ptrs[0].ID != a.ID || ptrs[1].ID != b.ID {
+		t.Errorf("noteRefs = %v, want a then b once each", ptrs)
+	}
+}
+
+func TestEmbedCard(t *testing.T) {
+	evt := testNote(t, strings.Repeat("line\n", embedCardLines+3))
+	card := embedCard("alice", evt)
+	lines := strings.Split(card, "\n")
+	if !strings.HasPrefix(lines[0], "> **alice** · ") {
+		t.Errorf("card header = %q", lines[0])
+	}
+	if len(lines) != embedCardLines+3 || lines[len(lines)-1] != "> …" {
+		t.Errorf("card not cut to %d lines:\n%s", embedCardLines, card)
+	}
+}
+
+func TestEmbedTextRoundTrip(t *testing.T) {
+	evt := testNote(t, "quoted")
+	text := embedText("alice", evt, "wss://r.example")
+	ptrs := noteRefs(text)
+	if len(ptrs) != 1 || ptrs[0].ID != evt.ID || ptrs[0].Author != evt.PubKey {
+		t.Fatalf("embed text references %v, want the note", ptrs)
+	}
+	if len(ptrs[0].Relays) != 1 || ptrs[0].Relays[0] != "wss://r.example" {
+		t.Errorf("relay hints = %v", ptrs[0].Relays)
+	}
+	// The card is already above the reference, so rendering keeps it as is.
+	card := func(nostr.ID) (string, bool) { return "CARD", true }
+	if got := expandNoteRefs(text, card); got != text {
+		t.Errorf("expandNoteRefs changed an /embed message:\n%s", got)
+	}
+}
+
+func TestExpandNoteRefs(t *testing.T) {
+	known, unknown := testNote(t, "k"), testNote(t, "u")
+	refK := "nostr:" + nip19.EncodeNevent(known.ID, nil, known.PubKey)
+	refU := "nostr:" + nip19.EncodeNevent(unknown.ID, nil, unknown.PubKey)
+	card := func(id nostr.ID) (string, bool) { return "CARD", id == known.ID }
+
+	got := expandNoteRefs("see "+refK+" and "+refU, card)
+	want := "see \n\nCARD\n\n and " + refU
+	if got != want {
+		t.Errorf("expandNoteRefs = %q, want %q", got, want)
+	}
+}
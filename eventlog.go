@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"fiatjaf.com/nostr"
+)
+
+// --event-log appends every event nitrous sends, and those received on room
+// subscriptions and as DMs, to a file, one JSON object per line, for
+// scripting and interop testing. Unlike the chat logs it is a raw protocol
+// trace. One-shot lookups (profiles, metadata, embeds) aren't logged.
+// Received DMs are logged as the NIP-17 rumor the gift wrap carried (marked
+// "rumor"), since the wraps are only seen by the nip17 listener; sent DMs
+// are logged as the gift wraps.
+
+// eventLog is the open --event-log, nil when the flag isn't given; the
+// logEvent* helpers then return right away.
+var eventLog *eventLogger
+
+// eventLogEntry is one line of the event log.
+type eventLogEntry struct {
+	Time  time.Time   `json:"time"`
+	Dir   string      `json:"dir"`             // "in" or "out"
+	Room  string      `json:"room,omitempty"`  // channel ID, group key, or DM peer
+	Relay string      `json:"relay,omitempty"` // where a received event came from
+	Rumor bool        `json:"rumor,omitempty"` // an unwrapped NIP-17 rumor
+	Event nostr.Event `json:"event"`
+}
+
+// eventLogger serializes writes to the event log; events are logged from
+// the concurrently running commands.
+type eventLogger struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// openEventLog opens path for appending, creating it if needed.
+func openEventLog(path string) (*eventLogger, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("opening event log: %w", err)
+	}
+	return &eventLogger{f: f}, nil
+}
+
+// write appends e as a JSON line. Errors are logged, not returned: tracing
+// must never interrupt the chat.
+func (l *eventLogger) write(e eventLogEntry) {
+	line, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("eventLog: %v", err)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.f.Write(append(line, '\n')); err != nil {
+		log.Printf("eventLog: %v", err)
+	}
+}
+
+func (l *eventLogger) Close() error {
+	return l.f.Close()
+}
+
+// logEventIn records an event received from relay for room.
+func logEventIn(room string, re nostr.RelayEvent) {
+	if eventLog == nil {
+		return
+	}
+	eventLog.write(eventLogEntry{Time: time.Now(), Dir: "in", Room: room, Relay: relayURLOf(re), Event: re.Event})
+}
+
+// logRumorIn records the rumor of a received DM. Rumors with a NIP-40
+// expiration (/timed DMs) are left out, like they are from the history.
+func logRumorIn(room string, rumor nostr.Event) {
+	if eventLog == nil || expirationOf(rumor.Tags) != 0 {
+		return
+	}
+	eventLog.write(eventLogEntry{Time: time.Now(), Dir: "in", Room: room, Rumor: true, Event: rumor})
+}
+
+// logEventOut records an event about to be published for room (empty for
+// lists, profiles, and other events outside a room).
+func logEventOut(room string, evt nostr.Event) {
+	if eventLog == nil {
+		return
+	}
+	eventLog.write(eventLogEntry{Time: time.Now(), Dir: "out", Room: room, Event: evt})
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestEventLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	l, err := openEventLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventLog = l
+	defer func() { eventLog = nil }()
+
+	keys := testKeys(t)
+	evt := nostr.Event{Kind: nostr.KindChannelMessage, Content: "hi", CreatedAt: nostr.Now()}
+	if err := evt.Sign(keys.SK); err != nil {
+		t.Fatal(err)
+	}
+	logEventOut("ch0", evt)
+	logEventIn("ch0", nostr.RelayEvent{Event: evt})
+	logRumorIn("pk0", nostr.Event{Kind: nostr.KindDirectMessage, Content: "psst"})
+	logRumorIn("pk0", nostr.Event{Kind: nostr.KindDirectMessage, Content: "timed", Tags: nostr.Tags{expirationTag(nostr.Now() + 600)}})
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	var entries []eventLogEntry
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e eventLogEntry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("%d entries, want 3 (no /timed rumor)", len(entries))
+	}
+	if e := entries[0]; e.Dir != "out" || e.Room != "ch0" || e.Event.ID != evt.ID || !e.Event.VerifySignature() {
+		t.Errorf("out entry = %+v", e)
+	}
+	if e := entries[1]; e.Dir != "in" || e.Rumor || e.Event.Content != "hi" {
+		t.Errorf("in entry = %+v", e)
+	}
+	if e := entries[2]; !e.Rumor || e.Room != "pk0" || e.Event.Content != "psst" {
+		t.Errorf("rumor entry = %+v", e)
+	}
+}
+
+func TestEventLogOff(t *testing.T) {
+	// Without --event-log the helpers must not touch anything.
+	logEventOut("ch0", nostr.Event{})
+	logEventIn("ch0", nostr.RelayEvent{})
+	logRumorIn("pk0", nostr.Event{})
+}
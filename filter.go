@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// containsWholeWord reports whether text contains word as a whole word,
+// case-insensitively. Word boundaries are any non-letter, non-digit runes.
+func containsWholeWord(text, word string) bool {
+	word = strings.ToLower(strings.TrimSpace(word))
+	if word == "" {
+		return false
+	}
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, f := range fields {
+		if f == word {
+			return true
+		}
+	}
+	return false
+}
+
+// mutedWordsList returns the muted words sorted alphabetically.
+func (m *model) mutedWordsList() []string {
+	words := make([]string, 0, len(m.mutedWords))
+	for w := range m.mutedWords {
+		words = append(words, w)
+	}
+	sort.Strings(words)
+	return words
+}
+
+// isMutedMessage reports whether a message contains any muted word.
+// System messages and our own messages are never muted.
+func (m *model) isMutedMessage(msg ChatMessage) bool {
+	if msg.Author == "system" || msg.IsMine {
+		return false
+	}
+	for w := range m.mutedWords {
+		if containsWholeWord(msg.Content, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// isMentionMessage reports whether a message @mentions our profile name or
+// contains our npub.
+func (m *model) isMentionMessage(msg ChatMessage) bool {
+	content := strings.ToLower(msg.Content)
+	if name := m.profiles[m.keys.PK.Hex()]; name != "" {
+		if strings.Contains(content, "@"+strings.ToLower(name)) {
+			return true
+		}
+	}
+	return m.keys.NPub != "" && strings.Contains(content, m.keys.NPub)
+}
+
+// isHighlightedMessage reports whether a message mentions us or contains a
+// highlight word. It is the single render/notify decision shared by the
+// viewport and the incoming-event handlers. System and own messages are
+// never highlighted.
+func (m *model) isHighlightedMessage(msg ChatMessage) bool {
+	if msg.Author == "system" || msg.IsMine {
+		return false
+	}
+	if m.isMentionMessage(msg) {
+		return true
+	}
+	for _, w := range m.cfg.HighlightWords {
+		if containsWholeWord(msg.Content, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// isFilteredMessage reports whether a message should be collapsed in the
+// viewport instead of rendered: muted words, the room's /filter, or its
+// /whitelist. Highlighted messages are never collapsed.
+func (m *model) isFilteredMessage(msg ChatMessage) bool {
+	if m.isHighlightedMessage(msg) {
+		return false
+	}
+	return m.isMutedMessage(msg) || m.isRoomFilteredOut(msg) || m.isWhitelistedOut(msg)
+}
+
+// notifyHighlight marks roomKey as having a highlight and rings the terminal
+// bell if msg is a highlighted message that arrived after startup.
+func (m *model) notifyHighlight(roomKey string, msg ChatMessage, active bool) tea.Cmd {
+	if !m.isHighlightedMessage(msg) || msg.Timestamp < m.startedAt {
+		return nil
+	}
+	if !active {
+		m.highlights[roomKey] = true
+	}
+	return m.ringBell()
+}
+
+// bellDoneMsg ends the frames that ring the bell.
+type bellDoneMsg struct{}
+
+// ringBell rings the terminal bell. Writing to the terminal outside the
+// renderer races with it, so the BEL goes out as part of the frames drawn
+// until bellDoneMsg, which span at least one repaint.
+func (m *model) ringBell() tea.Cmd {
+	m.bell = true
+	return tea.Tick(100*time.Millisecond, func(time.Time) tea.Msg { return bellDoneMsg{} })
+}
+
+// renderHiddenSummary renders the placeholder line for a run of collapsed messages.
+func renderHiddenSummary(n int) string {
+	noun := "messages"
+	if n == 1 {
+		noun = "message"
+	}
+	return chatSystemStyle.Render(fmt.Sprintf("  [%d %s hidden]", n, noun))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestContainsWholeWord(t *testing.T) {
+	tests := []struct {
+		text string
+		word string
+		want bool
+	}{
+		{"hello world", "world", true},
+		{"Hello World", "world", true},
+		{"hello WORLD!", "World", true},
+		{"worldwide news", "world", false},
+		{"underworld", "world", false},
+		{"crypto, again?", "crypto", true},
+		{"", "world", false},
+		{"hello world", "", false},
+		{"line one\nspam here", "spam", true},
+	}
+	for _, tt := range tests {
+		if got := containsWholeWord(tt.text, tt.word); got != tt.want {
+			t.Errorf("containsWholeWord(%q, %q) = %v, want %v", tt.text, tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestIsMutedMessage(t *testing.T) {
+	m := &model{mutedWords: map[string]bool{"spam": true}}
+
+	if !m.isMutedMessage(ChatMessage{Author: "alice", Content: "buy SPAM now"}) {
+		t.Error("expected message containing muted word to be muted")
+	}
+	if m.isMutedMessage(ChatMessage{Author: "alice", Content: "spammy but fine"}) {
+		t.Error("expected partial word match not to be muted")
+	}
+	if m.isMutedMessage(ChatMessage{Author: "alice", Content: "spam", IsMine: true}) {
+		t.Error("own messages should never be muted")
+	}
+	if m.isMutedMessage(ChatMessage{Author: "system", Content: "spam"}) {
+		t.Error("system messages should never be muted")
+	}
+}
+
+func TestIsHighlightedMessage(t *testing.T) {
+	m := &model{
+		cfg:        Config{HighlightWords: []string{"nitrous"}},
+		mutedWords: map[string]bool{"spam": true},
+		profiles:   map[string]string{},
+	}
+	m.profiles[m.keys.PK.Hex()] = "Alice"
+
+	if !m.isHighlightedMessage(ChatMessage{Author: "bob", Content: "anyone tried Nitrous?"}) {
+		t.Error("expected highlight word to highlight")
+	}
+	if !m.isHighlightedMessage(ChatMessage{Author: "bob", Content: "hey @alice look"}) {
+		t.Error("expected @mention to highlight")
+	}
+	if m.isHighlightedMessage(ChatMessage{Author: "bob", Content: "nitrousoxide"}) {
+		t.Error("expected partial word not to highlight")
+	}
+	if m.isHighlightedMessage(ChatMessage{Author: "bob", Content: "nitrous", IsMine: true}) {
+		t.Error("own messages should never be highlighted")
+	}
+
+	// Highlights override mutes.
+	if m.isFilteredMessage(ChatMessage{Author: "bob", Content: "spam about nitrous"}) {
+		t.Error("highlighted message should not be collapsed by a muted word")
+	}
+	if !m.isFilteredMessage(ChatMessage{Author: "bob", Content: "just spam"}) {
+		t.Error("muted message without highlight should be collapsed")
+	}
+}
+
+func TestNotifyHighlightRingsBellInView(t *testing.T) {
+	m := &model{
+		cfg:        Config{HighlightWords: []string{"nitrous"}},
+		profiles:   map[string]string{},
+		highlights: map[string]bool{},
+	}
+	if cmd := m.notifyHighlight("ch0", ChatMessage{Author: "bob", Content: "nitrous!", Timestamp: 1}, false); cmd == nil {
+		t.Fatal("no bell for a highlight")
+	}
+	if !m.highlights["ch0"] || !strings.HasSuffix(m.View(), "\a") {
+		t.Error("highlight not marked or BEL not in the frame")
+	}
+	m.Update(bellDoneMsg{})
+	if strings.HasSuffix(m.View(), "\a") {
+		t.Error("BEL still in the frame after bellDoneMsg")
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/nip17"
+	"fiatjaf.com/nostr/nip59"
+)
+
+// GroupDMItem is a NIP-17 conversation with more than one other participant.
+// It lives in the DMs section; code that works on a single peer (contacts
+// list, read receipts, /whois) only handles DMItem and skips these.
+type GroupDMItem struct {
+	Members []string // other participants' hex pubkeys, sorted
+	Name    string   // participant names, comma-separated
+}
+
+func (g GroupDMItem) Kind() SidebarKind  { return SidebarDM }
+func (g GroupDMItem) ItemID() string     { return dmGroupKey(g.Members) }
+func (g GroupDMItem) DisplayName() string { return g.Name }
+func (g GroupDMItem) Prefix() string     { return "@" }
+
+// dmGroupKey identifies a group DM by its participant set (excluding us).
+// It is hashed so it stays short enough for a log file name.
+func dmGroupKey(members []string) string {
+	h := sha256.Sum256([]byte(strings.Join(members, ",")))
+	return "grp:" + hex.EncodeToString(h[:8])
+}
+
+// dmParticipants returns the sorted, de-duplicated pubkeys of everyone in a
+// rumor's conversation (the sender and all p tags), except self.
+func dmParticipants(rumor nostr.Event, self nostr.PubKey) []string {
+	seen := map[string]bool{self.Hex(): true}
+	var out []string
+	add := func(pk string) {
+		if !seen[pk] {
+			seen[pk] = true
+			out = append(out, pk)
+		}
+	}
+	add(rumor.PubKey.Hex())
+	for _, tag := range rumor.Tags {
+		if len(tag) >= 2 && tag[0] == "p" {
+			add(tag[1])
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// dmRoomKey returns the m.msgs key of a DM: the group key for group DMs,
+// otherwise the peer pubkey.
+func dmRoomKey(cm ChatMessage) string {
+	if len(cm.DMMembers) > 0 {
+		return dmGroupKey(cm.DMMembers)
+	}
+	return cm.PubKey
+}
+
+// collapseGroupDM turns a group DM into a 1:1 DM with its sender (or, for
+// our own messages, the first participant), for dm_grouping = "peer".
+func collapseGroupDM(cm ChatMessage) ChatMessage {
+	if len(cm.DMMembers) == 0 {
+		return cm
+	}
+	if cm.IsMine {
+		cm.PubKey = cm.DMMembers[0]
+	}
+	cm.DMMembers = nil
+	return cm
+}
+
+// groupDMName joins the participants' display names.
+func (m *model) groupDMName(members []string) string {
+	names := make([]string, len(members))
+	for i, pk := range members {
+		names[i] = m.resolveAuthor(pk)
+	}
+	return strings.Join(names, ", ")
+}
+
+// findGroupDMIdx returns the sidebar index of the group DM with key, or -1.
+func (m *model) findGroupDMIdx(key string) int {
+	for i, it := range m.sidebar {
+		if g, ok := it.(GroupDMItem); ok && g.ItemID() == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// appendGroupDMItem appends a group DM at the end of the sidebar and
+// returns its index.
+func (m *model) appendGroupDMItem(members []string) int {
+	m.sidebar = append(m.sidebar, GroupDMItem{Members: members, Name: m.groupDMName(members)})
+	return len(m.sidebar) - 1
+}
+
+// refreshGroupDMNames recomputes group DM titles after a profile resolves.
+func (m *model) refreshGroupDMNames(pubkey string) {
+	for i, it := range m.sidebar {
+		g, ok := it.(GroupDMItem)
+		if !ok {
+			continue
+		}
+		for _, pk := range g.Members {
+			if pk == pubkey {
+				g.Name = m.groupDMName(g.Members)
+				m.sidebar[i] = g
+				break
+			}
+		}
+	}
+}
+
+// openGroupDM handles /dm with several recipients: it opens (creating if
+// needed) the conversation with all of them.
+func (m *model) openGroupDM(inputs []string) (tea.Model, tea.Cmd) {
+	seen := map[string]bool{m.keys.PK.Hex(): true}
+	var members []string
+	for _, in := range inputs {
+		pk, err := m.resolvePubKey(in)
+		if err != nil {
+			m.addSystemMsg(err.Error())
+			return m, nil
+		}
+		if !seen[pk] {
+			seen[pk] = true
+			members = append(members, pk)
+		}
+	}
+	if len(members) < 2 {
+		if len(members) == 1 {
+			return m.openDM(members[0])
+		}
+		m.addSystemMsg("usage: /dm <user> [user ...]")
+		return m, nil
+	}
+	sort.Strings(members)
+	key := dmGroupKey(members)
+	idx := m.findGroupDMIdx(key)
+	if idx < 0 {
+		idx = m.appendGroupDMItem(members)
+	}
+	m.activeItem = idx
+	if len(m.msgs[key]) == 0 {
+		m.loadHistory("dm", key)
+	}
+	m.updateViewport()
+	var cmds []tea.Cmd
+	for _, pk := range members {
+		cmds = append(cmds, m.maybeRequestProfile(pk))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// buildGroupDMRumor builds the unsigned kind-14 rumor of a group DM,
+// addressed to every member.
+func buildGroupDMRumor(members []string, content string, extra nostr.Tags, self nostr.PubKey) nostr.Event {
+	tags := slices.Clone(extra)
+	for _, pk := range members {
+		tags = append(tags, nostr.Tag{"p", pk})
+	}
+	rumor := nostr.Event{
+		Kind:      nostr.KindDirectMessage,
+		Content:   content,
+		Tags:      tags,
+		CreatedAt: nostr.Now(),
+		PubKey:    self,
+	}
+	rumor.ID = rumor.GetID()
+	return rumor
+}
+
+// sendGroupDM sends a NIP-17 message to every member of a group DM. One
+// rumor with p tags for all members is sealed and gift-wrapped separately
+// for each member and for us, so everyone holds the same message (the same
+// rumor ID, which reactions and receipts refer to). The members are sent to
+// in parallel. extra tags are added to the rumor.
+func sendGroupDM(pool *nostr.Pool, relays []string, members []string, content string, extra nostr.Tags, keys Keys, kr nostr.Keyer) tea.Cmd {
+	key := dmGroupKey(members)
+	expires := expirationOf(extra)
+	fail := func(err error) tea.Msg {
+		return dmSendErrMsg{peerPK: key, content: content, members: members, expires: expires, err: fmt.Errorf("send group DM: %w", err)}
+	}
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		recipients := make([]nostr.PubKey, len(members))
+		for i, pk := range members {
+			recipient, err := nostr.PubKeyFromHex(pk)
+			if err != nil {
+				return fail(fmt.Errorf("invalid member pubkey: %w", err))
+			}
+			recipients[i] = recipient
+		}
+		rumor := buildGroupDMRumor(members, content, extra, keys.PK)
+		var modify func(*nostr.Event)
+		if exp := extra.Find("expiration"); exp != nil {
+			modify = func(gw *nostr.Event) { gw.Tags = append(gw.Tags, exp) }
+		}
+		wrapFor := func(pk nostr.PubKey) (nostr.Event, error) {
+			return nip59.GiftWrap(rumor, pk,
+				func(s string) (string, error) { return kr.Encrypt(ctx, s, pk) },
+				func(e *nostr.Event) error { return kr.SignEvent(ctx, e) },
+				modify)
+		}
+
+		// Our own copy, so the message shows up on our other devices.
+		toUs, err := wrapFor(keys.PK)
+		if err != nil {
+			return fail(err)
+		}
+		logEventOut(key, toUs)
+		sentToUs := false
+		for _, url := range relays {
+			if err := publishWithAuth(ctx, pool, kr, url, toUs); err != nil {
+				log.Printf("sendGroupDM: self copy to %s failed: %v", url, err)
+				continue
+			}
+			sentToUs = true
+		}
+		if !sentToUs {
+			return fail(fmt.Errorf("failed to send event to ourselves in any of %v", relays))
+		}
+
+		type memberResult struct {
+			deliveries map[string]string
+			ok         bool
+		}
+		results := make(chan memberResult, len(recipients))
+		for _, recipient := range recipients {
+			go func() {
+				res := memberResult{deliveries: make(map[string]string)}
+				toThem, err := wrapFor(recipient)
+				if err != nil {
+					log.Printf("sendGroupDM: wrap for %s: %v", shortPK(recipient.Hex()), err)
+					results <- res
+					return
+				}
+				logEventOut(key, toThem)
+				theirRelays := nip17.GetDMRelays(ctx, recipient, pool, relays)
+				if len(theirRelays) == 0 {
+					theirRelays = relays
+				}
+				for _, url := range theirRelays {
+					if err := publishWithAuth(ctx, pool, kr, url, toThem); err != nil {
+						res.deliveries[url] = err.Error()
+						continue
+					}
+					res.deliveries[url] = "ok"
+					res.ok = true
+				}
+				if !res.ok {
+					log.Printf("sendGroupDM: to %s: failed in any of %v", shortPK(recipient.Hex()), theirRelays)
+				}
+				results <- res
+			}()
+		}
+		deliveries := make(map[string]string)
+		sent := 0
+		for range recipients {
+			res := <-results
+			for url, status := range res.deliveries {
+				if deliveries[url] != "ok" {
+					deliveries[url] = status
+				}
+			}
+			if res.ok {
+				sent++
+			}
+		}
+		if sent == 0 {
+			return fail(fmt.Errorf("no member could be reached"))
+		}
+
+		ts := rumor.CreatedAt
+		h := sha256.Sum256([]byte(fmt.Sprintf("local:%s:%s:%d:%s", keys.PK.Hex(), key, ts, content)))
+		return dmEventMsg(ChatMessage{
+			Author:     shortPK(keys.PK.Hex()),
+			PubKey:     keys.PK.Hex(),
+			Content:    content,
+			Timestamp:  ts,
+			EventID:    hex.EncodeToString(h[:]),
+			IsMine:     true,
+			Deliveries: deliveries,
+			DMMembers:  members,
+			Expires:    expires,
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestDMParticipants(t *testing.T) {
+	self := nostr.GetPublicKey(nostr.Generate())
+	alice := nostr.GetPublicKey(nostr.Generate())
+	bob := nostr.GetPublicKey(nostr.Generate())
+
+	// Incoming 1:1 DM: only the sender remains.
+	rumor := nostr.Event{PubKey: alice, Tags: nostr.Tags{{"p", self.Hex()}}}
+	if got := dmParticipants(rumor, self); !reflect.DeepEqual(got, []string{alice.Hex()}) {
+		t.Errorf("1:1: got %v", got)
+	}
+
+	// Group DM from alice to us and bob, with a duplicate p tag.
+	rumor = nostr.Event{PubKey: alice, Tags: nostr.Tags{{"p", self.Hex()}, {"p", bob.Hex()}, {"p", bob.Hex()}}}
+	got := dmParticipants(rumor, self)
+	if len(got) != 2 || got[0] > got[1] {
+		t.Fatalf("group: got %v, want 2 sorted members", got)
+	}
+
+	// Our own message to the same group yields the same participant set.
+	mine := nostr.Event{PubKey: self, Tags: nostr.Tags{{"p", bob.Hex()}, {"p", alice.Hex()}}}
+	if other := dmParticipants(mine, self); !reflect.DeepEqual(other, got) {
+		t.Errorf("own message: got %v, want %v", other, got)
+	}
+	if dmGroupKey(got) != dmGroupKey(dmParticipants(mine, self)) {
+		t.Error("group key differs between directions")
+	}
+}
+
+func TestDMRoomKeyAndCollapse(t *testing.T) {
+	members := []string{"aa", "bb"}
+	in := ChatMessage{PubKey: "bb", DMMembers: members}
+	if got := dmRoomKey(in); got != dmGroupKey(members) {
+		t.Errorf("dmRoomKey(group) = %q", got)
+	}
+	if got := dmRoomKey(ChatMessage{PubKey: "cc"}); got != "cc" {
+		t.Errorf("dmRoomKey(1:1) = %q, want cc", got)
+	}
+
+	if c := collapseGroupDM(in); c.PubKey != "bb" || c.DMMembers != nil {
+		t.Errorf("collapse incoming = %+v, want sender bb", c)
+	}
+	mine := ChatMessage{PubKey: "self", IsMine: true, DMMembers: members}
+	if c := collapseGroupDM(mine); c.PubKey != "aa" || c.DMMembers != nil {
+		t.Errorf("collapse own = %+v, want first member aa", c)
+	}
+}
+
+func TestHandleDMEventGroupDM(t *testing.T) {
+	m := newTestModel(0, 0, 0)
+	m.unread = make(map[string]bool)
+	m.highlights = make(map[string]bool)
+	m.profiles = map[string]string{"aa": "alice", "bb": "bob"}
+	m.profilePending = make(map[string]bool)
+	m.seenEvents = make(map[string]time.Time)
+	m.localDMEchoes = make(map[string]time.Time)
+	m.history = fileHistoryStore{}
+
+	cm := ChatMessage{PubKey: "aa", Content: "hi all", EventID: "e1", Timestamp: 100, DMMembers: []string{"aa", "bb"}}
+	m.handleDMEvent(dmEventMsg(cm))
+
+	idx := m.findGroupDMIdx(dmGroupKey(cm.DMMembers))
+	if idx < 0 {
+		t.Fatal("group DM not added to sidebar")
+	}
+	if name := m.sidebar[idx].DisplayName(); name != "alice, bob" {
+		t.Errorf("title = %q, want %q", name, "alice, bob")
+	}
+	if m.containsDMPeer("aa") {
+		t.Error("group DM also added a 1:1 item for the sender")
+	}
+	if len(m.msgs[dmGroupKey(cm.DMMembers)]) != 1 {
+		t.Error("message not stored under the group key")
+	}
+}
+
+func TestBuildGroupDMRumor(t *testing.T) {
+	self := nostr.GetPublicKey(nostr.Generate())
+	members := []string{nostr.GetPublicKey(nostr.Generate()).Hex(), nostr.GetPublicKey(nostr.Generate()).Hex()}
+	slices.Sort(members)
+	rumor := buildGroupDMRumor(members, "hi all", nostr.Tags{{"expiration", "123"}}, self)
+
+	// Every member sees us and the other member in the conversation.
+	for _, pk := range members {
+		them, _ := nostr.PubKeyFromHex(pk)
+		if got := dmParticipants(rumor, them); len(got) != 2 || !slices.Contains(got, self.Hex()) {
+			t.Errorf("member %s sees participants %v", shortPK(pk), got)
+		}
+	}
+	if got := dmParticipants(rumor, self); !reflect.DeepEqual(got, members) {
+		t.Errorf("own participants = %v, want %v", got, members)
+	}
+	if rumor.ID != rumor.GetID() || rumor.Tags.Find("expiration") == nil {
+		t.Errorf("rumor = %+v, want its ID set and the extra tags kept", rumor)
+	}
+}
+
+func TestReplaceDMPeersKeepsGroupDMs(t *testing.T) {
+	m := newTestModel(1, 0, 2)
+	m.sidebar = append(m.sidebar, GroupDMItem{Members: []string{"aa", "bb"}, Name: "a, b"})
+
+	m.replaceDMPeers([]Contact{{PubKey: "pk9", Name: "nine"}})
+
+	var dms, groups int
+	for _, it := range m.sidebar {
+		switch it.(type) {
+		case DMItem:
+			dms++
+		case GroupDMItem:
+			groups++
+		}
+	}
+	if dms != 1 || groups != 1 {
+		t.Errorf("got %d DMs and %d group DMs, want the new contact and the kept group DM", dms, groups)
+	}
+}
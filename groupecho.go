@@ -0,0 +1,100 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// echoState tracks whether a group message we sent came back through the
+// group subscription.
+type echoState int
+
+const (
+	echoNone        echoState = iota // not ours, or not a group message
+	echoPending                      // shown locally, relay copy not seen yet
+	echoConfirmed                    // the relay sent it back
+	echoUnconfirmed                  // no relay copy within groupEchoTimeout
+)
+
+// groupEchoTimeout is how long a sent group message may stay pending before
+// it is marked unconfirmed.
+const groupEchoTimeout = 15 * time.Second
+
+// groupEchoTimeoutMsg fires groupEchoTimeout after a group message was sent.
+type groupEchoTimeoutMsg struct {
+	eventID string
+}
+
+// groupEchoTimeoutCmd schedules the unconfirmed check for a sent message.
+func groupEchoTimeoutCmd(eventID string) tea.Cmd {
+	return tea.Tick(groupEchoTimeout, func(time.Time) tea.Msg {
+		return groupEchoTimeoutMsg{eventID: eventID}
+	})
+}
+
+// groupRoomKey returns the m.msgs key a group message is shown under.
+func groupRoomKey(cm ChatMessage) string {
+	if isThreadReply(cm) {
+		return threadKey(cm.ThreadID)
+	}
+	return cm.GroupKey
+}
+
+// setEchoState updates the state of the message with eventID in msgs and
+// reports whether it was found.
+func setEchoState(msgs []ChatMessage, eventID string, state echoState) bool {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].EventID == eventID {
+			msgs[i].Echo = state
+			return true
+		}
+	}
+	return false
+}
+
+// trackGroupEcho marks a just-shown group message of ours as pending.
+func (m *model) trackGroupEcho(cm ChatMessage) {
+	if m.pendingEchoes == nil {
+		m.pendingEchoes = make(map[string]string)
+	}
+	m.pendingEchoes[cm.EventID] = groupRoomKey(cm)
+}
+
+// confirmGroupEcho flips a pending message to confirmed when its event
+// arrives from the relay.
+func (m *model) confirmGroupEcho(eventID string) {
+	roomKey, ok := m.pendingEchoes[eventID]
+	if !ok {
+		return
+	}
+	delete(m.pendingEchoes, eventID)
+	if setEchoState(m.msgs[roomKey], eventID, echoConfirmed) && m.activeRoomKey() == roomKey {
+		m.updateViewport()
+	}
+}
+
+func (m *model) handleGroupEchoTimeout(msg groupEchoTimeoutMsg) (tea.Model, tea.Cmd) {
+	roomKey, ok := m.pendingEchoes[msg.eventID]
+	if !ok {
+		return m, nil
+	}
+	delete(m.pendingEchoes, msg.eventID)
+	if setEchoState(m.msgs[roomKey], msg.eventID, echoUnconfirmed) && m.activeRoomKey() == roomKey {
+		m.updateViewport()
+	}
+	return m, nil
+}
+
+// echoLabel is the status shown after a sent group message.
+func echoLabel(s echoState) string {
+	switch s {
+	case echoPending:
+		return chatSystemStyle.Render("… pending")
+	case echoConfirmed:
+		return chatSystemStyle.Render("✓")
+	case echoUnconfirmed:
+		return chatUnconfirmedStyle.Render("? unconfirmed")
+	}
+	return ""
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGroupEchoReconciliation(t *testing.T) {
+	m := newTestModel(0, 1, 0)
+	m.unread = make(map[string]bool)
+	m.groupRecentIDs = make(map[string][]string)
+	m.roomSubs = make(map[string]*roomSub)
+	m.seenEvents = make(map[string]time.Time)
+	m.history = fileHistoryStore{}
+	gk := groupKey("wss://r", "g0")
+
+	sent := func(id string) ChatMessage {
+		return ChatMessage{EventID: id, GroupKey: gk, IsMine: true, Content: "hi", Echo: echoPending}
+	}
+	m.handleGroupEvent(groupEventMsg(sent("a")))
+	m.handleGroupEvent(groupEventMsg(sent("b")))
+
+	// The relay sends "a" back; "b" never comes.
+	m.handleGroupEvent(groupEventMsg(ChatMessage{EventID: "a", GroupKey: gk, Content: "hi", Relay: "wss://r"}))
+	m.handleGroupEchoTimeout(groupEchoTimeoutMsg{eventID: "a"})
+	m.handleGroupEchoTimeout(groupEchoTimeoutMsg{eventID: "b"})
+
+	msgs := m.msgs[gk]
+	if len(msgs) != 2 {
+		t.Fatalf("got %d messages, want 2 (relay copy deduplicated)", len(msgs))
+	}
+	if msgs[0].Echo != echoConfirmed {
+		t.Errorf("echoed message state = %d, want confirmed", msgs[0].Echo)
+	}
+	if msgs[1].Echo != echoUnconfirmed {
+		t.Errorf("dropped message state = %d, want unconfirmed", msgs[1].Echo)
+	}
+	if len(m.pendingEchoes) != 0 {
+		t.Errorf("pendingEchoes not drained: %v", m.pendingEchoes)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// editMirrors returns mirrors with relay added or removed. home is the
+// group's own relay, which can't be a mirror of itself.
+func editMirrors(mirrors []string, home, action, relay string) ([]string, error) {
+	relay = normalizeRelayURL(relay)
+	if !strings.HasPrefix(relay, "wss://") && !strings.HasPrefix(relay, "ws://") {
+		return mirrors, fmt.Errorf("not a relay URL: %q", relay)
+	}
+	switch action {
+	case "add":
+		if relay == home || slices.Contains(mirrors, relay) {
+			return mirrors, fmt.Errorf("%s already carries this group", relay)
+		}
+		return append(slices.Clone(mirrors), relay), nil
+	case "remove":
+		i := slices.Index(mirrors, relay)
+		if i < 0 {
+			return mirrors, fmt.Errorf("%s is not a mirror of this group", relay)
+		}
+		return slices.Delete(slices.Clone(mirrors), i, i+1), nil
+	}
+	return mirrors, fmt.Errorf("unknown action %q", action)
+}
+
+// handleGroupMirror runs /group mirror [add|remove <relay>] for the
+// selected group: it lists or edits the relays the group is mirrored on,
+// then resubscribes and saves the group list.
+func (m *model) handleGroupMirror(arg string) (tea.Model, tea.Cmd) {
+	if !m.isGroupSelected() {
+		m.addSystemMsg("/group mirror requires a group to be selected")
+		return m, nil
+	}
+	g := m.activeSidebarItem().(GroupItem).Group
+	fields := strings.Fields(arg)
+	if len(fields) == 0 {
+		m.addSystemMsg(fmt.Sprintf("~%s is on %s", g.Name, strings.Join(g.Relays(), ", ")))
+		return m, nil
+	}
+	if len(fields) != 2 {
+		m.addSystemMsg("usage: /group mirror [add|remove <relay>]")
+		return m, nil
+	}
+	mirrors, err := editMirrors(g.Mirrors, g.RelayURL, strings.ToLower(fields[0]), fields[1])
+	if err != nil {
+		m.addSystemMsg("group mirror: " + err.Error())
+		return m, nil
+	}
+	g.Mirrors = mirrors
+	m.sidebar[m.activeItem] = GroupItem{Group: g}
+
+	gk := groupKey(g.RelayURL, g.GroupID)
+	m.cancelRoomSub(gk)
+	m.addSystemMsg(fmt.Sprintf("~%s now reads from and sends to %s", g.Name, strings.Join(g.Relays(), ", ")))
+	return m, tea.Batch(
+		m.subscribeGroup(g.RelayURL, g.GroupID),
+		publishSimpleGroupsListCmd(m.pool, m.relays, m.allGroups(), m.keys),
+	)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestEditMirrors(t *testing.T) {
+	home := "wss://home.example"
+	got, err := editMirrors(nil, home, "add", "wss://Mirror.example/")
+	if err != nil || !reflect.DeepEqual(got, []string{"wss://mirror.example"}) {
+		t.Fatalf("add = %v, %v", got, err)
+	}
+	if _, err := editMirrors(got, home, "add", "wss://mirror.example"); err == nil {
+		t.Error("adding a mirror twice should fail")
+	}
+	if _, err := editMirrors(got, home, "add", home+"/"); err == nil {
+		t.Error("the home relay can't be its own mirror")
+	}
+	if _, err := editMirrors(got, home, "add", "not a url"); err == nil {
+		t.Error("non-URL accepted")
+	}
+	got, err = editMirrors(got, home, "remove", "wss://mirror.example")
+	if err != nil || len(got) != 0 {
+		t.Errorf("remove = %v, %v", got, err)
+	}
+}
+
+func TestSimpleGroupsListMirrorsRoundTrip(t *testing.T) {
+	sk := nostr.Generate()
+	keys := Keys{SK: sk, PK: sk.Public()}
+	groups := []Group{
+		{RelayURL: "wss://a.example", GroupID: "g1", Name: "one", Mirrors: []string{"wss://b.example", "wss://c.example"}},
+		{RelayURL: "wss://a.example", GroupID: "g2"},
+	}
+	evt, err := buildSimpleGroupsListEvent(groups, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tag := range evt.Tags {
+		if tag[0] == "group" && len(tag) > 4 {
+			t.Errorf("group tag %v carries extra elements", tag)
+		}
+	}
+	saved := parseSimpleGroupsListEvent(&evt)
+	if len(saved) != 2 {
+		t.Fatalf("got %d groups, want 2", len(saved))
+	}
+	if !reflect.DeepEqual(saved[0].Mirrors, groups[0].Mirrors) || saved[0].Name != "one" {
+		t.Errorf("group 1 = %+v", saved[0])
+	}
+	if saved[1].Mirrors != nil {
+		t.Errorf("group 2 mirrors = %v, want none", saved[1].Mirrors)
+	}
+	if got := (Group{RelayURL: "wss://a", Mirrors: []string{"wss://b"}}).Relays(); !reflect.DeepEqual(got, []string{"wss://a", "wss://b"}) {
+		t.Errorf("Relays = %v", got)
+	}
+}
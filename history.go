@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"path/filepath"
+)
+
+// historyStore persists chat messages per room. roomType is "channel",
+// "group", "dm" or "thread"; roomKey is the channel ID, groupKey, peer
+// pubkey or threadKey.
+type historyStore interface {
+	// Append stores one message. Errors are logged, not returned: a failed
+	// write must never interrupt the chat.
+	Append(roomType, roomKey string, msg ChatMessage, displayName string)
+	// Load returns up to max of the room's most recent messages, oldest first.
+	Load(roomType, roomKey string, max int) ([]ChatMessage, error)
+	// Search returns the room's newest historySearchLimit messages
+	// containing term (case-insensitive), oldest first.
+	Search(roomType, roomKey, term string) ([]ChatMessage, error)
+	// Clear deletes the room's stored messages, or every room's when
+	// roomType is empty.
+	Clear(roomType, roomKey string) (historyCleared, error)
+	// Rename moves the room's stored messages from oldKey to newKey, merging
+	// them with any already stored under newKey.
+	Rename(roomType, oldKey, newKey string) error
+	Close() error
+}
+
+// historySearchLimit caps the matches historyStore.Search returns.
+const historySearchLimit = 500
+
+// historyCleared reports what historyStore.Clear removed. Bytes is 0 for
+// backends that don't free space per room.
+type historyCleared struct {
+	Rooms    int
+	Messages int
+	Bytes    int64
+}
+
+// newHistoryStore opens the history backend selected by history_backend.
+// An empty dir (logging disabled) yields a file store that stores nothing.
+// The SQLite backend is only compiled in with the sqlite build tag.
+func newHistoryStore(backend, dir string) (historyStore, error) {
+	if backend == "sqlite" && dir != "" {
+		return openSQLiteHistory(filepath.Join(dir, "history.db"))
+	}
+	return fileHistoryStore{dir: dir}, nil
+}
+
+// fileHistoryStore keeps the plain-text logs: one append-only file per room.
+type fileHistoryStore struct {
+	dir string
+}
+
+func (s fileHistoryStore) Append(roomType, roomKey string, msg ChatMessage, displayName string) {
+	appendLogEntry(s.dir, roomType, roomKey, msg, displayName)
+}
+
+func (s fileHistoryStore) Load(roomType, roomKey string, max int) ([]ChatMessage, error) {
+	return loadLogHistory(s.dir, roomType, roomKey, max)
+}
+
+// Search scans the whole log file; fine for DMs, slow for busy rooms.
+func (s fileHistoryStore) Search(roomType, roomKey, term string) ([]ChatMessage, error) {
+	msgs, err := loadLogHistory(s.dir, roomType, roomKey, math.MaxInt)
+	if err != nil {
+		return nil, err
+	}
+	msgs = matchingMessages(msgs, term)
+	return msgs[max(len(msgs)-historySearchLimit, 0):], nil
+}
+
+func (s fileHistoryStore) Clear(roomType, roomKey string) (historyCleared, error) {
+	return clearLogs(s.dir, roomType, roomKey)
+}
+
+func (s fileHistoryStore) Rename(roomType, oldKey, newKey string) error {
+	return renameLog(s.dir, roomType, oldKey, newKey)
+}
+
+func (s fileHistoryStore) Close() error { return nil }
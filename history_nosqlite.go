@@ -0,0 +1,16 @@
+//go:build !sqlite
+
+package main
+
+import "errors"
+
+// sqliteSupported reports whether this build includes the SQLite backend.
+const sqliteSupported = false
+
+// errNoSQLite is returned for history_backend = "sqlite" in builds without
+// the sqlite tag.
+var errNoSQLite = errors.New(`history_backend = "sqlite" needs a build with -tags sqlite`)
+
+func openSQLiteHistory(string) (historyStore, error) {
+	return nil, errNoSQLite
+}
@@ -0,0 +1,178 @@
+//go:build sqlite
+
+package main
+
+// The SQLite history backend. modernc.org/sqlite is a pure-Go port that adds
+// several MB to the binary, so it is only built with -tags sqlite.
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"fiatjaf.com/nostr"
+	"modernc.org/sqlite"
+)
+
+// sqliteSupported reports whether this build includes the SQLite backend.
+const sqliteSupported = true
+
+// sqliteHistoryStore keeps all rooms in one SQLite database indexed by room
+// and timestamp.
+type sqliteHistoryStore struct {
+	db *sql.DB
+}
+
+const sqliteHistorySchema = `
+CREATE TABLE IF NOT EXISTS messages (
+	room_type TEXT NOT NULL,
+	room_key  TEXT NOT NULL,
+	ts        INTEGER NOT NULL,
+	event_id  TEXT NOT NULL,
+	pubkey    TEXT NOT NULL,
+	author    TEXT NOT NULL,
+	content   TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS messages_room_ts ON messages(room_type, room_key, ts);
+CREATE INDEX IF NOT EXISTS messages_pubkey ON messages(pubkey);
+CREATE UNIQUE INDEX IF NOT EXISTS messages_event ON messages(room_type, room_key, event_id) WHERE event_id != '';
+`
+
+// openSQLiteHistory opens (creating if needed) the SQLite history database.
+func openSQLiteHistory(path string) (historyStore, error) {
+	if err := ensureLogDir(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("history: create dir: %w", err)
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("history: open %s: %w", path, err)
+	}
+	// One connection: writes come from the UI goroutine anyway, and SQLite
+	// serializes writers.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteHistorySchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("history: init %s: %w", path, err)
+	}
+	return &sqliteHistoryStore{db: db}, nil
+}
+
+func (s *sqliteHistoryStore) Append(roomType, roomKey string, msg ChatMessage, displayName string) {
+	_, err := s.db.Exec(
+		`INSERT OR IGNORE INTO messages (room_type, room_key, ts, event_id, pubkey, author, content) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		roomType, roomKey, int64(msg.Timestamp), msg.EventID, msg.PubKey, displayName, msg.Content,
+	)
+	if err != nil {
+		log.Printf("history: insert into %s/%s: %v", roomType, roomKey, err)
+	}
+}
+
+func (s *sqliteHistoryStore) Load(roomType, roomKey string, max int) ([]ChatMessage, error) {
+	msgs, err := s.query(
+		`SELECT ts, event_id, pubkey, author, content FROM messages
+		 WHERE room_type = ? AND room_key = ? ORDER BY ts DESC, rowid DESC LIMIT ?`,
+		roomType, roomKey, max,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
+		msgs[i], msgs[j] = msgs[j], msgs[i]
+	}
+	return msgs, nil
+}
+
+// Search matches in SQL. LIKE folds ASCII case only, so the content is
+// lowered with go_lower, as matchingMessages does for the file backend.
+func (s *sqliteHistoryStore) Search(roomType, roomKey, term string) ([]ChatMessage, error) {
+	msgs, err := s.query(
+		`SELECT ts, event_id, pubkey, author, content FROM messages
+		 WHERE room_type = ? AND room_key = ? AND author != 'system' AND go_lower(content) LIKE ? ESCAPE '\'
+		 ORDER BY ts DESC, rowid DESC LIMIT ?`,
+		roomType, roomKey, "%"+likeEscaper.Replace(strings.ToLower(term))+"%", historySearchLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(msgs)
+	return msgs, nil
+}
+
+// likeEscaper escapes the LIKE wildcards in a search term.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func init() {
+	sqlite.MustRegisterDeterministicScalarFunction("go_lower", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		switch v := args[0].(type) {
+		case string:
+			return strings.ToLower(v), nil
+		case []byte:
+			return strings.ToLower(string(v)), nil
+		}
+		return args[0], nil
+	})
+}
+
+func (s *sqliteHistoryStore) query(q string, args ...any) ([]ChatMessage, error) {
+	rows, err := s.db.Query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("history: query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var msgs []ChatMessage
+	for rows.Next() {
+		var ts int64
+		var msg ChatMessage
+		if err := rows.Scan(&ts, &msg.EventID, &msg.PubKey, &msg.Author, &msg.Content); err != nil {
+			return nil, fmt.Errorf("history: scan: %w", err)
+		}
+		msg.Timestamp = nostr.Timestamp(ts)
+		msgs = append(msgs, msg)
+	}
+	return msgs, rows.Err()
+}
+
+// Clear also vacuums the database so the deleted text doesn't linger in
+// free pages.
+func (s *sqliteHistoryStore) Clear(roomType, roomKey string) (historyCleared, error) {
+	var cleared historyCleared
+	where, args := "WHERE room_type = ? AND room_key = ?", []any{roomType, roomKey}
+	if roomType == "" {
+		where, args = "", nil
+	}
+	if err := s.db.QueryRow(`SELECT COUNT(DISTINCT room_type || '/' || room_key) FROM messages `+where, args...).Scan(&cleared.Rooms); err != nil {
+		return cleared, fmt.Errorf("history: count: %w", err)
+	}
+	res, err := s.db.Exec(`DELETE FROM messages `+where, args...)
+	if err != nil {
+		return cleared, fmt.Errorf("history: delete: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	cleared.Messages = int(n)
+	if _, err := s.db.Exec(`VACUUM`); err != nil {
+		log.Printf("history: vacuum: %v", err)
+	}
+	return cleared, nil
+}
+
+func (s *sqliteHistoryStore) Rename(roomType, oldKey, newKey string) error {
+	if oldKey == newKey {
+		return nil
+	}
+	// Rows already stored under newKey win; the leftover duplicates go.
+	if _, err := s.db.Exec(`UPDATE OR IGNORE messages SET room_key = ? WHERE room_type = ? AND room_key = ?`, newKey, roomType, oldKey); err != nil {
+		return fmt.Errorf("history: rename: %w", err)
+	}
+	if _, err := s.db.Exec(`DELETE FROM messages WHERE room_type = ? AND room_key = ?`, roomType, oldKey); err != nil {
+		return fmt.Errorf("history: rename: %w", err)
+	}
+	return nil
+}
+
+func (s *sqliteHistoryStore) Close() error {
+	return s.db.Close()
+}
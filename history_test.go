@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+// testHistoryStores returns a file store and, in builds with the sqlite tag,
+// a SQLite store, each in its own temp dir.
+func testHistoryStores(t *testing.T) map[string]historyStore {
+	t.Helper()
+	backends := []string{"file"}
+	if sqliteSupported {
+		backends = append(backends, "sqlite")
+	}
+	stores := make(map[string]historyStore)
+	for _, backend := range backends {
+		s, err := newHistoryStore(backend, t.TempDir())
+		if err != nil {
+			t.Fatalf("newHistoryStore(%s): %v", backend, err)
+		}
+		t.Cleanup(func() { _ = s.Close() })
+		stores[backend] = s
+	}
+	return stores
+}
+
+func TestHistoryStoreAppendLoad(t *testing.T) {
+	for backend, s := range testHistoryStores(t) {
+		t.Run(backend, func(t *testing.T) {
+			for i := range 5 {
+				msg := ChatMessage{
+					Timestamp: nostr.Timestamp(1700000000 + i),
+					EventID:   fmt.Sprintf("ev%d", i),
+					PubKey:    "pk",
+					Content:   fmt.Sprintf("line %d\nsecond", i),
+				}
+				s.Append("channel", "room", msg, "alice")
+			}
+			s.Append("channel", "other", ChatMessage{Timestamp: 1700000000, EventID: "x", Content: "elsewhere"}, "bob")
+
+			got, err := s.Load("channel", "room", 3)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if len(got) != 3 {
+				t.Fatalf("got %d messages, want 3", len(got))
+			}
+			if got[0].EventID != "ev2" || got[2].EventID != "ev4" {
+				t.Errorf("want the 3 newest oldest-first, got %s..%s", got[0].EventID, got[2].EventID)
+			}
+			if got[2].Author != "alice" || got[2].Content != "line 4\nsecond" {
+				t.Errorf("round trip: author=%q content=%q", got[2].Author, got[2].Content)
+			}
+		})
+	}
+}
+
+func TestHistoryStoreSearch(t *testing.T) {
+	for backend, s := range testHistoryStores(t) {
+		t.Run(backend, func(t *testing.T) {
+			s.Append("dm", "peer", ChatMessage{Timestamp: 1, EventID: "a", Content: "Meet at noon"}, "alice")
+			s.Append("dm", "peer", ChatMessage{Timestamp: 2, EventID: "b", Content: "lunch?"}, "bob")
+			s.Append("dm", "peer", ChatMessage{Timestamp: 3, EventID: "c", Content: "100% sure, noon it is"}, "alice")
+
+			got, err := s.Search("dm", "peer", "NOON")
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			if len(got) != 2 || got[0].EventID != "a" || got[1].EventID != "c" {
+				t.Errorf("Search(NOON) = %+v, want a, c", got)
+			}
+			got, _ = s.Search("dm", "peer", "0%")
+			if len(got) != 1 || got[0].EventID != "c" {
+				t.Errorf("Search(0%%) = %+v, want only c (%% is literal)", got)
+			}
+
+			s.Append("dm", "peer", ChatMessage{Timestamp: 4, EventID: "d", Content: "Grüße aus MÜNCHEN"}, "bob")
+			got, _ = s.Search("dm", "peer", "münchen")
+			if len(got) != 1 || got[0].EventID != "d" {
+				t.Errorf("Search(münchen) = %+v, want d (non-ASCII case folded)", got)
+			}
+
+			for i := range historySearchLimit + 1 {
+				s.Append("dm", "many", ChatMessage{Timestamp: nostr.Timestamp(i + 1), EventID: strconv.Itoa(i), Content: "hit"}, "bob")
+			}
+			got, _ = s.Search("dm", "many", "hit")
+			if len(got) != historySearchLimit || got[0].EventID != "1" {
+				t.Errorf("Search returned %d matches starting at %+v, want the newest %d", len(got), got[0], historySearchLimit)
+			}
+		})
+	}
+}
+
+func TestSQLiteHistoryIgnoresDuplicates(t *testing.T) {
+	if !sqliteSupported {
+		t.Skip("built without -tags sqlite")
+	}
+	s, err := newHistoryStore("sqlite", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = s.Close() }()
+	msg := ChatMessage{Timestamp: 1, EventID: "same", Content: "hi"}
+	s.Append("group", "g", msg, "alice")
+	s.Append("group", "g", msg, "alice")
+	got, _ := s.Load("group", "g", 10)
+	if len(got) != 1 {
+		t.Errorf("got %d messages, want duplicate event ignored", len(got))
+	}
+}
+
+func TestNewHistoryStoreDisabled(t *testing.T) {
+	s, err := newHistoryStore("sqlite", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.(fileHistoryStore); !ok {
+		t.Errorf("disabled logging should yield a no-op file store, got %T", s)
+	}
+}
+
+func TestNewHistoryStoreWithoutSQLite(t *testing.T) {
+	if sqliteSupported {
+		t.Skip("built with -tags sqlite")
+	}
+	if _, err := newHistoryStore("sqlite", t.TempDir()); err == nil {
+		t.Error("sqlite backend should fail to open without the sqlite tag")
+	}
+}
+
+func TestHistoryStoreClear(t *testing.T) {
+	for backend, s := range testHistoryStores(t) {
+		t.Run(backend, func(t *testing.T) {
+			for i := range 3 {
+				s.Append("channel", "room", ChatMessage{Timestamp: nostr.Timestamp(i + 1), EventID: fmt.Sprintf("ev%d", i), Content: "hi"}, "alice")
+			}
+			s.Append("dm", "peer", ChatMessage{Timestamp: 1, EventID: "d", Content: "secret"}, "bob")
+
+			cleared, err := s.Clear("channel", "room")
+			if err != nil {
+				t.Fatalf("Clear: %v", err)
+			}
+			if cleared.Messages != 3 {
+				t.Errorf("cleared %d messages, want 3", cleared.Messages)
+			}
+			if got, _ := s.Load("channel", "room", 10); len(got) != 0 {
+				t.Errorf("room still has %d messages after Clear", len(got))
+			}
+			if got, _ := s.Load("dm", "peer", 10); len(got) != 1 {
+				t.Errorf("other room lost its history: %d messages", len(got))
+			}
+
+			// Appending after a clear starts a fresh history.
+			s.Append("channel", "room", ChatMessage{Timestamp: 9, EventID: "new", Content: "again"}, "alice")
+			cleared, err = s.Clear("", "")
+			if err != nil {
+				t.Fatalf("Clear all: %v", err)
+			}
+			if cleared.Messages != 2 || cleared.Rooms != 2 {
+				t.Errorf("Clear all = %+v, want 2 messages in 2 rooms", cleared)
+			}
+		})
+	}
+}
+
+func TestHistoryStoreRename(t *testing.T) {
+	for backend, s := range testHistoryStores(t) {
+		t.Run(backend, func(t *testing.T) {
+			s.Append("group", "old", ChatMessage{Timestamp: 1, EventID: "a", Content: "first"}, "alice")
+			s.Append("group", "old", ChatMessage{Timestamp: 2, EventID: "b", Content: "second"}, "alice")
+			s.Append("group", "new", ChatMessage{Timestamp: 3, EventID: "c", Content: "third"}, "bob")
+
+			if err := s.Rename("group", "old", "new"); err != nil {
+				t.Fatalf("Rename: %v", err)
+			}
+			got, err := s.Load("group", "new", 10)
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			var contents []string
+			for _, m := range got {
+				contents = append(contents, m.Content)
+			}
+			if strings.Join(contents, ",") != "first,second,third" {
+				t.Errorf("renamed room = %v, want first,second,third", contents)
+			}
+			if got, _ := s.Load("group", "old", 10); len(got) != 0 {
+				t.Errorf("old key still has %d messages", len(got))
+			}
+			if err := s.Rename("group", "missing", "new"); err != nil {
+				t.Errorf("Rename of an empty room: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/nip19"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// importEntry is one line of an /import list: a value (npub, hex pubkey, or
+// channel ID) with an optional name from "name,value" CSV lines.
+type importEntry struct {
+	Name  string
+	Value string
+}
+
+// parseImportLines parses an /import list. Each non-empty line is either
+// "name,value" or one or more whitespace-separated bare values. Blank lines
+// and lines starting with # are skipped.
+func parseImportLines(text string) []importEntry {
+	var out []importEntry
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if name, value, ok := strings.Cut(line, ","); ok {
+			out = append(out, importEntry{Name: strings.TrimSpace(name), Value: strings.TrimSpace(value)})
+			continue
+		}
+		for _, v := range strings.Fields(line) {
+			out = append(out, importEntry{Value: v})
+		}
+	}
+	return out
+}
+
+// parseImportPubKey decodes an npub or hex pubkey from an import line.
+func parseImportPubKey(s string) (string, bool) {
+	if strings.HasPrefix(s, "npub") {
+		prefix, val, err := nip19.Decode(s)
+		if err != nil || prefix != "npub" {
+			return "", false
+		}
+		return val.(nostr.PubKey).Hex(), true
+	}
+	pk, err := nostr.PubKeyFromHex(s)
+	if err != nil {
+		return "", false
+	}
+	return pk.Hex(), true
+}
+
+// parseImportChannelID validates a hex channel ID (kind 40 event ID) from an
+// import line.
+func parseImportChannelID(s string) (string, bool) {
+	id, err := nostr.IDFromHex(s)
+	if err != nil {
+		return "", false
+	}
+	return id.Hex(), true
+}
+
+// readImportSource returns the list text for /import: the contents of the
+// file at src, or src itself when it is a pasted block (several lines or
+// not an existing file).
+func readImportSource(src string) (string, error) {
+	if strings.Contains(src, "\n") {
+		return src, nil
+	}
+	path := expandKeyPath(src)
+	if _, err := os.Stat(path); err != nil {
+		if strings.ContainsAny(src, "/~") {
+			return "", fmt.Errorf("cannot read %s: %w", src, err)
+		}
+		return src, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("cannot read %s: %w", src, err)
+	}
+	return string(data), nil
+}
+
+// handleImport handles /import contacts|rooms <path or pasted list>.
+func (m *model) handleImport(arg string) (tea.Model, tea.Cmd) {
+	what, src, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	if w, rest, ok := strings.Cut(what, "\n"); ok {
+		what, src = w, rest+" "+src
+	}
+	src = strings.TrimSpace(src)
+	if (what != "contacts" && what != "rooms") || src == "" {
+		m.addSystemMsg("usage: /import contacts|rooms <path or pasted list>")
+		return m, nil
+	}
+	text, err := readImportSource(src)
+	if err != nil {
+		m.addSystemMsg("import: " + err.Error())
+		return m, nil
+	}
+	entries := parseImportLines(text)
+	if len(entries) == 0 {
+		m.addSystemMsg("import: nothing to import")
+		return m, nil
+	}
+	if what == "contacts" {
+		return m.importContacts(entries)
+	}
+	return m.importRooms(entries)
+}
+
+// importContacts adds DM peers in bulk and republishes the contacts list once.
+func (m *model) importContacts(entries []importEntry) (tea.Model, tea.Cmd) {
+	added, dupes, invalid := 0, 0, 0
+	var cmds []tea.Cmd
+	for _, e := range entries {
+		pk, ok := parseImportPubKey(e.Value)
+		if !ok {
+			invalid++
+			continue
+		}
+		if m.containsDMPeer(pk) {
+			dupes++
+			continue
+		}
+		name := e.Name
+		if _, known := m.profiles[pk]; known || name == "" {
+			name = m.resolveAuthor(pk)
+		}
+		m.appendDMItem(pk, name)
+		added++
+		if cmd := m.maybeRequestProfile(pk); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	}
+	m.addSystemMsg(fmt.Sprintf("imported %d contacts (%d duplicates skipped, %d invalid)", added, dupes, invalid))
+	if added > 0 {
+		cmds = append(cmds, m.syncContacts())
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// importRooms adds channels in bulk, subscribes to them, and republishes the
+// public chats list once.
+func (m *model) importRooms(entries []importEntry) (tea.Model, tea.Cmd) {
+	added, dupes, invalid := 0, 0, 0
+	var cmds []tea.Cmd
+	for _, e := range entries {
+		id, ok := parseImportChannelID(e.Value)
+		if !ok {
+			invalid++
+			continue
+		}
+		if m.findChannelIdx(id) >= 0 {
+			dupes++
+			continue
+		}
+		name := e.Name
+		if name == "" {
+			name = id[:8]
+		}
+		// Keep the current selection when inserting above it.
+		if idx := m.appendChannelItem(Channel{Name: name, ID: id}); idx <= m.activeItem {
+			m.activeItem++
+		}
+		added++
+		cmds = append(cmds, m.subscribeChannel(id), fetchChannelMetaCmd(m.pool, m.queries, m.relays, id))
+	}
+	m.addSystemMsg(fmt.Sprintf("imported %d rooms (%d duplicates skipped, %d invalid)", added, dupes, invalid))
+	if added > 0 {
+		cmds = append(cmds, publishPublicChatsListCmd(m.pool, m.relays, m.allChannels(), m.keys))
+	}
+	return m, tea.Batch(cmds...)
+}
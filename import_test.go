@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseImportLines(t *testing.T) {
+	text := "# my contacts\n\nalice, npub1aaa\nnpub1bbb npub1ccc\n  bob,npub1ddd  \n"
+	got := parseImportLines(text)
+	want := []importEntry{
+		{Name: "alice", Value: "npub1aaa"},
+		{Value: "npub1bbb"},
+		{Value: "npub1ccc"},
+		{Name: "bob", Value: "npub1ddd"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseImportPubKey(t *testing.T) {
+	keys := testKeys(t)
+	hex := keys.PK.Hex()
+	for _, in := range []string{hex, keys.NPub} {
+		pk, ok := parseImportPubKey(in)
+		if !ok || pk != hex {
+			t.Errorf("parseImportPubKey(%q) = %q, %v", in, pk, ok)
+		}
+	}
+	for _, in := range []string{"npub1invalid", "nsec1xyz", "name", ""} {
+		if _, ok := parseImportPubKey(in); ok {
+			t.Errorf("parseImportPubKey(%q) should fail", in)
+		}
+	}
+}
+
+func TestReadImportSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "contacts.csv")
+	if err := os.WriteFile(path, []byte("alice,npub1aaa\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := readImportSource(path); err != nil || got != "alice,npub1aaa\n" {
+		t.Errorf("file: got %q, %v", got, err)
+	}
+	if got, err := readImportSource("npub1aaa\nnpub1bbb"); err != nil || got != "npub1aaa\nnpub1bbb" {
+		t.Errorf("pasted block: got %q, %v", got, err)
+	}
+	if got, err := readImportSource("npub1aaa"); err != nil || got != "npub1aaa" {
+		t.Errorf("single value: got %q, %v", got, err)
+	}
+	if _, err := readImportSource(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("expected error for a missing file path")
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"fiatjaf.com/nostr"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// groupInvite is an invite code we created with /group invite.
+type groupInvite struct {
+	Code     string
+	RelayURL string
+	GroupID  string
+	EventID  string // kind 9009 event that created it
+	Created  time.Time
+}
+
+// inviteRevokedMsg is returned after trying to revoke an invite.
+type inviteRevokedMsg struct {
+	code string
+	err  error
+}
+
+// invitesPath returns the path of the created-invites file next to the
+// config.
+func invitesPath(cfgFlagPath string) string {
+	return filepath.Join(filepath.Dir(configPath(cfgFlagPath)), "invites")
+}
+
+// loadInvites reads the invites file: one JSON-encoded groupInvite per
+// line, oldest first. A missing file is empty; unreadable lines are skipped.
+func loadInvites(path string) ([]groupInvite, error) {
+	lines, err := readStateLines(path)
+	var invites []groupInvite
+	for _, line := range lines {
+		var inv groupInvite
+		if json.Unmarshal([]byte(line), &inv) == nil {
+			invites = append(invites, inv)
+		}
+	}
+	return invites, err
+}
+
+// saveInvites rewrites the invites file. It is only readable by us, as
+// anyone holding a code can join the group.
+func saveInvites(path string, invites []groupInvite) error {
+	lines := make([]string, 0, len(invites))
+	for _, inv := range invites {
+		line, err := json.Marshal(inv)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, string(line))
+	}
+	return writeStateLines(path, lines, 0600)
+}
+
+// newInviteCode returns a random invite code.
+func newInviteCode() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// inviteJoinCommand is the /join line that redeems inv.
+func inviteJoinCommand(inv groupInvite) string {
+	return fmt.Sprintf("/join %s'%s %s", strings.TrimPrefix(inv.RelayURL, "wss://"), inv.GroupID, inv.Code)
+}
+
+// revokeInviteCmd asks the group relay to delete the kind 9009 event that
+// created an invite (kind 9005). Relays that don't tie invites to that event
+// may accept the deletion and still honor the code.
+func revokeInviteCmd(pool *nostr.Pool, inv groupInvite, previousIDs []string, keys Keys) tea.Cmd {
+	return func() tea.Msg {
+		evt, err := buildDeleteGroupEventEvent(inv.GroupID, inv.EventID, previousIDs, keys)
+		if err != nil {
+			return inviteRevokedMsg{code: inv.Code, err: err}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		r, err := pool.EnsureRelay(inv.RelayURL)
+		if err != nil {
+			return inviteRevokedMsg{code: inv.Code, err: fmt.Errorf("connect %s: %w", inv.RelayURL, err)}
+		}
+		logEventOut(groupKey(inv.RelayURL, inv.GroupID), evt)
+		if err := r.Publish(ctx, evt); err != nil {
+			return inviteRevokedMsg{code: inv.Code, err: err}
+		}
+		log.Printf("revokeInviteCmd: deleted invite event %s in group %s on %s", inv.EventID, inv.GroupID, inv.RelayURL)
+		return inviteRevokedMsg{code: inv.Code}
+	}
+}
+
+// createInvite handles /group invite: it creates an invite code for the
+// selected group.
+func (m *model) createInvite() (tea.Model, tea.Cmd) {
+	if !m.isGroupSelected() {
+		m.addSystemMsg("/group invite requires a group to be selected")
+		return m, nil
+	}
+	g := m.activeSidebarItem().(GroupItem).Group
+	if !m.requireGroupAdmin(g) {
+		return m, nil
+	}
+	gk := groupKey(g.RelayURL, g.GroupID)
+	return m, createGroupInviteCmd(m.pool, g.RelayURL, g.GroupID, newInviteCode(), m.groupRecentIDs[gk], m.keys)
+}
+
+func (m *model) handleGroupInviteCreated(msg groupInviteCreatedMsg) (tea.Model, tea.Cmd) {
+	log.Printf("groupInviteCreatedMsg: relay=%s group=%s code=%s", msg.RelayURL, msg.GroupID, msg.Code)
+	inv := groupInvite{Code: msg.Code, RelayURL: msg.RelayURL, GroupID: msg.GroupID, EventID: msg.EventID, Created: time.Now()}
+	m.invites = append(m.invites, inv)
+	if err := saveInvites(invitesPath(m.cfgFlagPath), m.invites); err != nil {
+		m.addSystemMsg("saving invite: " + err.Error())
+	}
+	m.addSystemMsg(fmt.Sprintf("invite code: %s  join with: %s", msg.Code, inviteJoinCommand(inv)))
+	return m, nil
+}
+
+// listInvites handles /list-invites [all]: the invites created for the
+// selected group, or for every group.
+func (m *model) listInvites(arg string) (tea.Model, tea.Cmd) {
+	gk := ""
+	if arg != "all" {
+		if !m.isGroupSelected() {
+			m.addSystemMsg("usage: /list-invites in a group, or /list-invites all")
+			return m, nil
+		}
+		g := m.activeSidebarItem().(GroupItem).Group
+		gk = groupKey(g.RelayURL, g.GroupID)
+	}
+	var shown []groupInvite
+	for _, inv := range m.invites {
+		if gk == "" || groupKey(inv.RelayURL, inv.GroupID) == gk {
+			shown = append(shown, inv)
+		}
+	}
+	if len(shown) == 0 {
+		m.addSystemMsg("no invites — create one with /group invite")
+		return m, nil
+	}
+	m.addSystemMsg(fmt.Sprintf("%d invites:", len(shown)))
+	for _, inv := range shown {
+		name := inv.GroupID
+		if idx := m.findGroupIdx(inv.RelayURL, inv.GroupID); idx >= 0 {
+			name = m.sidebar[idx].(GroupItem).Group.Name
+		}
+		m.addSystemMsg(fmt.Sprintf("  %s  ~%s, created %s  %s", inv.Code, name, inv.Created.Format("2006-01-02 15:04"), inviteJoinCommand(inv)))
+	}
+	return m, nil
+}
+
+// revokeInvite handles /revoke-invite <code>.
+func (m *model) revokeInvite(code string) (tea.Model, tea.Cmd) {
+	if code == "" {
+		m.addSystemMsg("usage: /revoke-invite <code>")
+		return m, nil
+	}
+	for _, inv := range m.invites {
+		if inv.Code != code {
+			continue
+		}
+		if inv.EventID == "" {
+			m.addSystemMsg("revoke-invite: the event that created this invite is unknown")
+			return m, nil
+		}
+		gk := groupKey(inv.RelayURL, inv.GroupID)
+		m.addSystemMsg(fmt.Sprintf("revoking invite %s on %s", code, inv.RelayURL))
+		return m, revokeInviteCmd(m.pool, inv, m.groupRecentIDs[gk], m.keys)
+	}
+	m.addSystemMsg(fmt.Sprintf("no invite %q (see /list-invites all)", code))
+	return m, nil
+}
+
+func (m *model) handleInviteRevoked(msg inviteRevokedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.addSystemMsg(fmt.Sprintf("revoke-invite %s: the relay refused (%v); it may not support revoking invites", msg.code, msg.err))
+		return m, nil
+	}
+	for i, inv := range m.invites {
+		if inv.Code == msg.code {
+			m.invites = append(m.invites[:i:i], m.invites[i+1:]...)
+			break
+		}
+	}
+	if err := saveInvites(invitesPath(m.cfgFlagPath), m.invites); err != nil {
+		m.addSystemMsg("saving invites: " + err.Error())
+	}
+	m.addSystemMsg(fmt.Sprintf("invite %s revoked", msg.code))
+	return m, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInvitesRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "invites")
+	if got, err := loadInvites(path); err != nil || got != nil {
+		t.Fatalf("missing file: %v, %v", got, err)
+	}
+	want := []groupInvite{
+		{Code: "abc", RelayURL: "wss://r", GroupID: "g0", EventID: "e1", Created: time.Unix(1700000000, 0)},
+		{Code: "def", RelayURL: "wss://s", GroupID: "g1", EventID: "e2", Created: time.Unix(1700000100, 0)},
+	}
+	if err := saveInvites(path, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadInvites(path)
+	for i := range got {
+		if i < len(want) && got[i].Created.Equal(want[i].Created) {
+			got[i].Created = want[i].Created
+		}
+	}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("loadInvites = %+v, %v; want %+v", got, err, want)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm != 0600 {
+		t.Errorf("invites file mode = %v, want 0600", perm)
+	}
+}
+
+func TestInviteJoinCommand(t *testing.T) {
+	inv := groupInvite{Code: "abc", RelayURL: "wss://groups.example.com", GroupID: "g0"}
+	if got, want := inviteJoinCommand(inv), "/join groups.example.com'g0 abc"; got != want {
+		t.Errorf("inviteJoinCommand = %q, want %q", got, want)
+	}
+}
+
+func TestListInvitesFiltersByGroup(t *testing.T) {
+	m := newTestModel(0, 2, 0)
+	m.invites = []groupInvite{
+		{Code: "abc", RelayURL: "wss://r", GroupID: "g0"},
+		{Code: "def", RelayURL: "wss://r", GroupID: "g1"},
+	}
+	m.listInvites("")
+	msgs := m.msgs[groupKey("wss://r", "g0")]
+	if len(msgs) != 2 || msgs[0].Content != "1 invites:" {
+		t.Fatalf("messages = %+v", msgs)
+	}
+	m.listInvites("all")
+	msgs = m.msgs[groupKey("wss://r", "g0")]
+	if len(msgs) != 5 || msgs[2].Content != "2 invites:" {
+		t.Errorf("messages with all = %+v", msgs)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/nip19"
+	"fiatjaf.com/nostr/nip49"
+	"golang.org/x/term"
+)
+
+// keyEncryptionLogN is the scrypt cost (log2 N) used when encrypting the key
+// file. 16 is the NIP-49 recommended minimum and takes well under a second.
+const keyEncryptionLogN = 16
+
+// readPassphrase prompts for a passphrase on the terminal. It is a variable
+// so tests can stub it out.
+var readPassphrase = func(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	pass, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	return string(pass), nil
+}
+
+// isEncryptedKey reports whether raw is a NIP-49 encrypted secret key.
+func isEncryptedKey(raw string) bool {
+	return strings.HasPrefix(raw, "ncryptsec1")
+}
+
+// expandKeyPath expands a leading ~/ in the private_key_file path.
+func expandKeyPath(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
+
+// parseSecretKey parses an nsec, hex, or NIP-49 ncryptsec secret key. For
+// ncryptsec it prompts for the passphrase, so it must run before the TUI
+// takes over the terminal.
+func parseSecretKey(raw string) (nostr.SecretKey, error) {
+	switch {
+	case isEncryptedKey(raw):
+		pass, err := readPassphrase("Passphrase for nostr key: ")
+		if err != nil {
+			return nostr.SecretKey{}, err
+		}
+		sk, err := nip49.Decrypt(raw, pass)
+		if err != nil {
+			return nostr.SecretKey{}, fmt.Errorf("failed to decrypt key (wrong passphrase?): %w", err)
+		}
+		return sk, nil
+	case strings.HasPrefix(raw, "nsec"):
+		prefix, val, err := nip19.Decode(raw)
+		if err != nil {
+			return nostr.SecretKey{}, fmt.Errorf("failed to decode nsec: %w", err)
+		}
+		if prefix != "nsec" {
+			return nostr.SecretKey{}, fmt.Errorf("expected nsec prefix, got %s", prefix)
+		}
+		return val.(nostr.SecretKey), nil
+	default:
+		sk, err := nostr.SecretKeyFromHex(raw)
+		if err != nil {
+			return nostr.SecretKey{}, fmt.Errorf("failed to parse hex secret key: %w", err)
+		}
+		return sk, nil
+	}
+}
+
+// readKeyFile returns the resolved path and trimmed contents of private_key_file.
+func readKeyFile(cfg Config) (string, string, error) {
+	if cfg.PrivateKeyFile == "" {
+		return "", "", fmt.Errorf("private_key_file not set in config")
+	}
+	path := expandKeyPath(cfg.PrivateKeyFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read private_key_file %q: %w", path, err)
+	}
+	return path, strings.TrimSpace(string(data)), nil
+}
+
+// encryptKeyFile rewrites the key file at path as a NIP-49 ncryptsec
+// encrypted with pass.
+func encryptKeyFile(path, raw, pass string) error {
+	if isEncryptedKey(raw) {
+		return fmt.Errorf("%s is already encrypted", path)
+	}
+	sk, err := parseSecretKey(raw)
+	if err != nil {
+		return err
+	}
+	enc, err := nip49.Encrypt(sk, pass, keyEncryptionLogN, nip49.ClientDoesNotTrackThisData)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt key: %w", err)
+	}
+	return writeKeyFile(path, enc)
+}
+
+// decryptKeyFile rewrites an encrypted key file at path as a plaintext nsec.
+// parseSecretKey prompts for the passphrase.
+func decryptKeyFile(path, raw string) error {
+	if !isEncryptedKey(raw) {
+		return fmt.Errorf("%s is not encrypted", path)
+	}
+	sk, err := parseSecretKey(raw)
+	if err != nil {
+		return err
+	}
+	return writeKeyFile(path, nip19.EncodeNsec(sk))
+}
+
+// writeKeyFile replaces the key file at path with key. It writes a
+// temporary file next to it and renames it over the original, so a crash
+// mid-write leaves the old key intact rather than a truncated file.
+func writeKeyFile(path, key string) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer func() { _ = os.Remove(tmp) }() // no-op after the rename
+	if err := f.Chmod(0600); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if _, err := f.WriteString(key + "\n"); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// runEncryptKey implements `nitrous encrypt-key`.
+func runEncryptKey(cfg Config) {
+	path, raw, err := readKeyFile(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if isEncryptedKey(raw) {
+		fmt.Fprintf(os.Stderr, "error: %s is already encrypted\n", path)
+		os.Exit(1)
+	}
+	pass, err := readPassphrase("New passphrase: ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if pass == "" {
+		fmt.Fprintf(os.Stderr, "error: empty passphrase\n")
+		os.Exit(1)
+	}
+	confirm, err := readPassphrase("Repeat passphrase: ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if pass != confirm {
+		fmt.Fprintf(os.Stderr, "error: passphrases do not match\n")
+		os.Exit(1)
+	}
+	if err := encryptKeyFile(path, raw, pass); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Encrypted %s (NIP-49). You will be asked for the passphrase on startup.\n", path)
+}
+
+// runDecryptKey implements `nitrous decrypt-key`.
+func runDecryptKey(cfg Config) {
+	path, raw, err := readKeyFile(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := decryptKeyFile(path, raw); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Decrypted %s back to a plaintext nsec.\n", path)
+}
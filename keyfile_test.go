@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/nip19"
+)
+
+func stubPassphrase(t *testing.T, pass string) {
+	t.Helper()
+	orig := readPassphrase
+	readPassphrase = func(string) (string, error) { return pass, nil }
+	t.Cleanup(func() { readPassphrase = orig })
+}
+
+func TestEncryptDecryptKeyFile(t *testing.T) {
+	sk := nostr.Generate()
+	nsec := nip19.EncodeNsec(sk)
+	path := filepath.Join(t.TempDir(), "nsec")
+	if err := os.WriteFile(path, []byte(nsec+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{PrivateKeyFile: path}
+
+	if err := encryptKeyFile(path, nsec, "hunter2"); err != nil {
+		t.Fatalf("encryptKeyFile: %v", err)
+	}
+	_, raw, err := readKeyFile(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isEncryptedKey(raw) {
+		t.Fatalf("expected ncryptsec in key file, got %q", raw[:10])
+	}
+	if err := encryptKeyFile(path, raw, "hunter2"); err == nil {
+		t.Error("expected error encrypting an already encrypted key")
+	}
+
+	// loadKeys prompts for the passphrase and decrypts into memory.
+	stubPassphrase(t, "hunter2")
+	keys, err := loadKeys(cfg)
+	if err != nil {
+		t.Fatalf("loadKeys: %v", err)
+	}
+	if keys.SK != sk {
+		t.Error("decrypted key does not match original")
+	}
+
+	stubPassphrase(t, "wrong")
+	if _, err := loadKeys(cfg); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("expected wrong passphrase error, got %v", err)
+	}
+
+	// decrypt-key reverts to a plaintext nsec.
+	stubPassphrase(t, "hunter2")
+	if err := decryptKeyFile(path, raw); err != nil {
+		t.Fatalf("decryptKeyFile: %v", err)
+	}
+	_, raw, _ = readKeyFile(cfg)
+	if raw != nsec {
+		t.Errorf("expected plaintext nsec after decrypt, got %q", raw)
+	}
+	if err := decryptKeyFile(path, raw); err == nil {
+		t.Error("expected error decrypting a plaintext key")
+	}
+}
+
+func TestWriteKeyFileReplacesAtomically(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "nsec")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeKeyFile(path, "new"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new\n" {
+		t.Fatalf("key file = %q, %v", data, err)
+	}
+	if fi, _ := os.Stat(path); fi.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", fi.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary file left behind: %v", entries)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/nip19"
+)
+
+// errKeyFileMissing is returned by loadKeys when private_key_file does not
+// exist and NOSTR_PRIVATE_KEY is unset, e.g. after copying the config to a
+// new machine without the key.
+var errKeyFileMissing = errors.New("private_key_file does not exist")
+
+// writeNewKeyFile writes raw as the key file at path, creating its
+// directory. It never overwrites an existing file.
+func writeNewKeyFile(path, raw string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("creating key directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(raw + "\n"); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// promptForKey asks what to do about a missing key file: generate a new
+// key, or paste an existing one (nsec, hex, or ncryptsec, read without echo
+// via readPassphrase). The key is written to path as entered, so an
+// ncryptsec stays encrypted.
+func promptForKey(path string, in *bufio.Reader, out io.Writer) error {
+	fmt.Fprintf(out, "No private key found at %s.\n", path)
+	fmt.Fprintln(out, "  [g] generate a new key (a new identity)")
+	fmt.Fprintln(out, "  [p] paste an existing nsec, hex key, or ncryptsec")
+	fmt.Fprintln(out, "  [q] quit")
+	for {
+		fmt.Fprint(out, "Choice: ")
+		line, err := in.ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("no key: %w", err)
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "g":
+			sk := nostr.Generate()
+			if err := writeNewKeyFile(path, nip19.EncodeNsec(sk)); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Generated a new key for %s\n", nip19.EncodeNpub(nostr.GetPublicKey(sk)))
+			return nil
+		case "p":
+			raw, err := readPassphrase("Key: ")
+			if err != nil {
+				return err
+			}
+			raw = strings.TrimSpace(raw)
+			sk, err := parseSecretKey(raw)
+			if err != nil {
+				fmt.Fprintf(out, "Invalid key: %v\n", err)
+				continue
+			}
+			if err := writeNewKeyFile(path, raw); err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "Saved the key for %s\n", nip19.EncodeNpub(nostr.GetPublicKey(sk)))
+			return nil
+		case "q":
+			return errors.New("no private key")
+		}
+	}
+}
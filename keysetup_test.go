@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/nip19"
+)
+
+func TestLoadKeysMissingFile(t *testing.T) {
+	t.Setenv("NOSTR_PRIVATE_KEY", "")
+	cfg := Config{PrivateKeyFile: filepath.Join(t.TempDir(), "nsec")}
+	if _, err := loadKeys(cfg); !errors.Is(err, errKeyFileMissing) {
+		t.Errorf("loadKeys error = %v, want errKeyFileMissing", err)
+	}
+
+	sk := nostr.Generate()
+	t.Setenv("NOSTR_PRIVATE_KEY", nip19.EncodeNsec(sk))
+	keys, err := loadKeys(cfg)
+	if err != nil || keys.SK != sk {
+		t.Errorf("with NOSTR_PRIVATE_KEY set: %v", err)
+	}
+}
+
+func TestPromptForKeyGenerate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "nsec")
+	if err := promptForKey(path, bufio.NewReader(strings.NewReader("x\ng\n")), io.Discard); err != nil {
+		t.Fatalf("promptForKey: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.HasPrefix(string(data), "nsec1") {
+		t.Fatalf("key file = %q, %v", data, err)
+	}
+	// An existing file is never overwritten.
+	if err := promptForKey(path, bufio.NewReader(strings.NewReader("g\n")), io.Discard); err == nil {
+		t.Error("second generate overwrote the key file")
+	}
+}
+
+func TestPromptForKeyPaste(t *testing.T) {
+	sk := nostr.Generate()
+	nsec := nip19.EncodeNsec(sk)
+	stubPassphrase(t, " "+nsec+" ")
+	path := filepath.Join(t.TempDir(), "nsec")
+	if err := promptForKey(path, bufio.NewReader(strings.NewReader("p\n")), io.Discard); err != nil {
+		t.Fatalf("promptForKey: %v", err)
+	}
+	keys, err := loadKeys(Config{PrivateKeyFile: path})
+	if err != nil || keys.SK != sk {
+		t.Errorf("pasted key not saved: %v", err)
+	}
+}
+
+func TestPromptForKeyQuit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nsec")
+	if err := promptForKey(path, bufio.NewReader(strings.NewReader("q\n")), io.Discard); err == nil {
+		t.Error("quit returned no error")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("quit wrote a key file")
+	}
+}
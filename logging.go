@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"fiatjaf.com/nostr"
@@ -46,7 +47,7 @@ func unescapeContent(s string) string {
 }
 
 // logFilePath returns the log file path for a given room.
-// roomType is "channel", "group", or "dm". roomKey is the room identifier.
+// roomType is "channel", "group", "dm" or "thread". roomKey is the room identifier.
 func logFilePath(logDir, roomType, roomKey string) string {
 	// Sanitize roomKey for filesystem safety (replace path separators and special chars).
 	safe := strings.NewReplacer(
@@ -64,11 +65,17 @@ func ensureLogDir(logDir string) error {
 	return os.MkdirAll(logDir, 0755)
 }
 
+// logMu serializes log writes with clearLogs, so a file is never removed
+// halfway through an append.
+var logMu sync.Mutex
+
 // appendLogEntry appends a single message to the room's log file.
 func appendLogEntry(logDir, roomType, roomKey string, msg ChatMessage, displayName string) {
 	if logDir == "" {
 		return
 	}
+	logMu.Lock()
+	defer logMu.Unlock()
 	if err := ensureLogDir(logDir); err != nil {
 		log.Printf("logging: failed to create log dir: %v", err)
 		return
@@ -90,6 +97,82 @@ func appendLogEntry(logDir, roomType, roomKey string, msg ChatMessage, displayNa
 	}
 }
 
+// clearLogs deletes the room's log file, or every room log in logDir when
+// roomType is empty, and reports what was removed. A missing file is not an
+// error.
+func clearLogs(logDir, roomType, roomKey string) (historyCleared, error) {
+	var cleared historyCleared
+	if logDir == "" {
+		return cleared, nil
+	}
+	logMu.Lock()
+	defer logMu.Unlock()
+
+	var paths []string
+	if roomType == "" {
+		for _, t := range []string{"channel", "group", "dm", "thread"} {
+			matches, err := filepath.Glob(filepath.Join(logDir, t+"_*.log"))
+			if err != nil {
+				return cleared, err
+			}
+			paths = append(paths, matches...)
+		}
+	} else {
+		paths = []string{logFilePath(logDir, roomType, roomKey)}
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return cleared, fmt.Errorf("logging: read %s: %w", path, err)
+		}
+		if err := os.Remove(path); err != nil {
+			return cleared, fmt.Errorf("logging: remove %s: %w", path, err)
+		}
+		cleared.Rooms++
+		cleared.Messages += strings.Count(string(data), "\n")
+		cleared.Bytes += int64(len(data))
+	}
+	return cleared, nil
+}
+
+// renameLog moves a room's log file from oldKey to newKey. If both exist the
+// old entries, which are the older ones, are put in front of the new file's.
+// A missing old file is not an error.
+func renameLog(logDir, roomType, oldKey, newKey string) error {
+	if logDir == "" || oldKey == newKey {
+		return nil
+	}
+	logMu.Lock()
+	defer logMu.Unlock()
+
+	oldPath := logFilePath(logDir, roomType, oldKey)
+	newPath := logFilePath(logDir, roomType, newKey)
+	if oldPath == newPath {
+		return nil
+	}
+	old, err := os.ReadFile(oldPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("logging: read %s: %w", oldPath, err)
+	}
+	cur, err := os.ReadFile(newPath)
+	if os.IsNotExist(err) {
+		return os.Rename(oldPath, newPath)
+	}
+	if err != nil {
+		return fmt.Errorf("logging: read %s: %w", newPath, err)
+	}
+	if err := os.WriteFile(newPath, append(old, cur...), 0644); err != nil {
+		return fmt.Errorf("logging: write %s: %w", newPath, err)
+	}
+	return os.Remove(oldPath)
+}
+
 // loadLogHistory loads the last maxMessages entries from a room's log file
 // using backward seeking for efficiency on large files.
 func loadLogHistory(logDir, roomType, roomKey string, maxMessages int) ([]ChatMessage, error) {
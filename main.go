@@ -1,8 +1,10 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	_ "embed"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -15,6 +17,7 @@ import (
 	"fiatjaf.com/nostr"
 	"fiatjaf.com/nostr/keyer"
 	"fiatjaf.com/nostr/nip19"
+	"golang.org/x/term"
 )
 
 //go:embed config.example.toml
@@ -23,6 +26,7 @@ var defaultConfigContent string
 func main() {
 	configFlag := flag.String("config", "", "path to config file")
 	debugFlag := flag.Bool("debug", false, "enable debug logging to debug.log")
+	eventLogFlag := flag.String("event-log", "", "append sent events and received room and DM events as JSON lines to this file")
 	flag.Parse()
 
 	if *debugFlag {
@@ -41,6 +45,16 @@ func main() {
 		nostr.InfoLogger.SetOutput(io.Discard)
 		nostr.DebugLogger.SetOutput(io.Discard)
 	}
+	if *eventLogFlag != "" {
+		l, err := openEventLog(*eventLogFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		defer func() { _ = l.Close() }()
+		eventLog = l
+		log.Printf("event log: %s", *eventLogFlag)
+	}
 
 	cfgPath := configPath(*configFlag)
 	isKeygen := len(flag.Args()) > 0 && flag.Args()[0] == "keygen"
@@ -63,8 +77,27 @@ func main() {
 		runKeygen(cfg)
 		return
 	}
+	if len(flag.Args()) > 0 {
+		switch flag.Args()[0] {
+		case "encrypt-key":
+			runEncryptKey(cfg)
+			return
+		case "decrypt-key":
+			runDecryptKey(cfg)
+			return
+		}
+	}
 
+	// loadKeys may prompt for the key passphrase, so it must run before the
+	// TUI takes over the terminal.
 	keys, err := loadKeys(cfg)
+	if errors.Is(err, errKeyFileMissing) && cfg.MissingKey != "error" && term.IsTerminal(int(os.Stdin.Fd())) {
+		if perr := promptForKey(expandKeyPath(cfg.PrivateKeyFile), bufio.NewReader(os.Stdin), os.Stdout); perr != nil {
+			fmt.Fprintf(os.Stderr, "key error: %v\n", perr)
+			os.Exit(1)
+		}
+		keys, err = loadKeys(cfg)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "key error: %v\n", err)
 		os.Exit(1)
@@ -80,21 +113,34 @@ func main() {
 
 	kr := keyer.NewPlainKeySigner(keys.SK)
 
+	dups := &relayDuplicates{}
 	pool := nostr.NewPool(nostr.PoolOptions{
 		AuthRequiredHandler: func(ctx context.Context, evt *nostr.Event) error {
 			log.Printf("NIP-42 auth requested")
 			return kr.SignEvent(ctx, evt)
 		},
+		DuplicateMiddleware: dups.record,
 	})
 
 	m := newModel(cfg, *configFlag, keys, pool, &kr, mdRender, mdStyle)
+	m.relayDups = dups
+	m.access = newRelayAccess(pool, cfg.RelayOptions, func(ctx context.Context, evt *nostr.Event) error {
+		return kr.SignEvent(ctx, evt)
+	})
 
 	log.Println("starting TUI")
 	p := tea.NewProgram(&m, tea.WithAltScreen(), tea.WithMouseCellMotion())
-	if _, err := p.Run(); err != nil {
+	_, err = p.Run()
+	if cerr := m.history.Close(); cerr != nil {
+		log.Printf("closing history: %v", cerr)
+	}
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
+	if m.exitNote != "" {
+		fmt.Println(m.exitNote)
+	}
 
 	pool.Close("shutdown")
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// plainRoomsPath returns the path of the file listing rooms shown without
+// markdown rendering, next to the config.
+func plainRoomsPath(cfgFlagPath string) string {
+	return filepath.Join(filepath.Dir(configPath(cfgFlagPath)), "plain_rooms")
+}
+
+// loadRoomSet reads a room-set file such as plain_rooms: one room key
+// (channel ID, groupKey, or DM key) per line. A missing file is empty.
+func loadRoomSet(path string) (map[string]bool, error) {
+	rooms := make(map[string]bool)
+	lines, err := readStateLines(path)
+	for _, key := range lines {
+		rooms[key] = true
+	}
+	return rooms, err
+}
+
+// saveRoomSet rewrites a room-set file, sorted.
+func saveRoomSet(path string, rooms map[string]bool) error {
+	keys := make([]string, 0, len(rooms))
+	for key := range rooms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return writeStateLines(path, keys, 0644)
+}
+
+// readStateLines reads a line-per-entry state file, skipping blank lines.
+// A missing file is empty.
+func readStateLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	var lines []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, sc.Err()
+}
+
+// writeStateLines rewrites a line-per-entry state file with mode perm,
+// creating its directory if needed. An existing file gets perm as well.
+func writeStateLines(path string, lines []string, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line + "\n")
+	}
+	if err := os.WriteFile(path, []byte(b.String()), perm); err != nil {
+		return err
+	}
+	return os.Chmod(path, perm)
+}
+
+// toggleMarkdown handles /toggle-markdown: switches the active room between
+// glamour rendering and plain wrapped text, and saves the choice.
+func (m *model) toggleMarkdown() (tea.Model, tea.Cmd) {
+	item := m.activeSidebarItem()
+	if item == nil {
+		m.addSystemMsg("no active conversation")
+		return m, nil
+	}
+	key := item.ItemID()
+	if m.plainRooms[key] {
+		delete(m.plainRooms, key)
+	} else {
+		m.plainRooms[key] = true
+	}
+	if err := saveRoomSet(plainRoomsPath(m.cfgFlagPath), m.plainRooms); err != nil {
+		m.addSystemMsg("toggle-markdown: " + err.Error())
+	}
+	if m.plainRooms[key] {
+		m.addSystemMsg("markdown off in this room: messages are shown as plain text")
+	} else {
+		m.addSystemMsg("markdown on in this room")
+	}
+	return m, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPlainRoomsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plain_rooms")
+	rooms, err := loadRoomSet(path)
+	if err != nil || len(rooms) != 0 {
+		t.Fatalf("missing file: %v, %v", rooms, err)
+	}
+	want := map[string]bool{"chan": true, groupKey("wss://r", "g"): true}
+	if err := saveRoomSet(path, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadRoomSet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !got["chan"] || !got[groupKey("wss://r", "g")] {
+		t.Errorf("loaded %v, want %v", got, want)
+	}
+}
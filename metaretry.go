@@ -0,0 +1,75 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// metaRetryBase is the delay before the first metadata retry; each further
+// retry waits twice as long.
+const metaRetryBase = 5 * time.Second
+
+// metaRetryMsg asks to fetch a room's metadata again. roomKey is a channel
+// ID or a groupKey.
+type metaRetryMsg struct{ roomKey string }
+
+// groupMetaMissingMsg reports that a group's kind-39000 metadata could not
+// be fetched.
+type groupMetaMissingMsg struct {
+	RelayURL string
+	GroupID  string
+}
+
+// metaRetryDelay returns the backoff before retry number attempt (1-based).
+func metaRetryDelay(attempt int) time.Duration {
+	return metaRetryBase << (attempt - 1)
+}
+
+// isPlaceholderName reports whether name is the stand-in shown for a room
+// whose metadata has not resolved.
+func isPlaceholderName(name, id string) bool {
+	return name == "" || name == id || name == shortPK(id)
+}
+
+// scheduleMetaRetry schedules another metadata fetch for roomKey with
+// exponential backoff, or returns nil once metadata_retries is used up,
+// leaving the placeholder name.
+func (m *model) scheduleMetaRetry(roomKey string) tea.Cmd {
+	attempt := m.metaAttempts[roomKey] + 1
+	if attempt > m.cfg.MetadataRetries() {
+		log.Printf("metaRetry: giving up on %s after %d retries", roomKey, attempt-1)
+		return nil
+	}
+	m.metaAttempts[roomKey] = attempt
+	d := metaRetryDelay(attempt)
+	log.Printf("metaRetry: retry %d for %s in %s", attempt, roomKey, d)
+	return tea.Tick(d, func(time.Time) tea.Msg { return metaRetryMsg{roomKey: roomKey} })
+}
+
+func (m *model) handleGroupMetaMissing(msg groupMetaMissingMsg) (tea.Model, tea.Cmd) {
+	idx := m.findGroupIdx(msg.RelayURL, msg.GroupID)
+	// The fetch may race the join; retry unless the group is known to be named.
+	if idx >= 0 && !isPlaceholderName(m.sidebar[idx].(GroupItem).Group.Name, msg.GroupID) {
+		return m, nil
+	}
+	return m, m.scheduleMetaRetry(groupKey(msg.RelayURL, msg.GroupID))
+}
+
+// handleMetaRetry refetches metadata for a room that still shows its
+// placeholder name. Rooms that were left or resolved meanwhile are dropped.
+func (m *model) handleMetaRetry(msg metaRetryMsg) (tea.Model, tea.Cmd) {
+	if idx := m.findChannelIdx(msg.roomKey); idx >= 0 {
+		if isPlaceholderName(m.sidebar[idx].(ChannelItem).Channel.Name, msg.roomKey) {
+			return m, fetchChannelMetaCmd(m.pool, m.queries, m.channelRelays(msg.roomKey), msg.roomKey)
+		}
+	} else if relayURL, groupID := splitGroupKey(msg.roomKey); relayURL != "" {
+		if idx := m.findGroupIdx(relayURL, groupID); idx >= 0 &&
+			isPlaceholderName(m.sidebar[idx].(GroupItem).Group.Name, groupID) {
+			return m, fetchGroupMetaCmd(m.pool, m.queries, relayURL, groupID, m.cfg.ReplaceableWait())
+		}
+	}
+	delete(m.metaAttempts, msg.roomKey)
+	return m, nil
+}
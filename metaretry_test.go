@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMetaRetryDelay(t *testing.T) {
+	want := []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 40 * time.Second}
+	for i, w := range want {
+		if got := metaRetryDelay(i + 1); got != w {
+			t.Errorf("metaRetryDelay(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+}
+
+func TestIsPlaceholderName(t *testing.T) {
+	id := "0123456789abcdef"
+	for _, tt := range []struct {
+		name string
+		want bool
+	}{
+		{"", true},
+		{"01234567", true},
+		{id, true},
+		{"general", false},
+	} {
+		if got := isPlaceholderName(tt.name, id); got != tt.want {
+			t.Errorf("isPlaceholderName(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScheduleMetaRetryGivesUp(t *testing.T) {
+	m := newTestModel(1, 0, 0)
+	m.metaAttempts = make(map[string]int)
+	m.cfg.MetaRetries = 2
+	for i := 0; i < 2; i++ {
+		if m.scheduleMetaRetry("ch0") == nil {
+			t.Fatalf("retry %d not scheduled", i+1)
+		}
+	}
+	if m.scheduleMetaRetry("ch0") != nil {
+		t.Error("retry scheduled past metadata_retries")
+	}
+
+	m.cfg.MetaRetries = -1
+	if m.scheduleMetaRetry("ch1") != nil {
+		t.Error("retry scheduled with retries disabled")
+	}
+}
+
+func TestHandleMetaRetryDropsResolved(t *testing.T) {
+	m := newTestModel(1, 0, 0) // channel "ch0" named "chan0"
+	m.metaAttempts = map[string]int{"ch0": 1}
+	if _, cmd := m.handleMetaRetry(metaRetryMsg{roomKey: "ch0"}); cmd != nil {
+		t.Error("refetched a channel that already has a name")
+	}
+	if _, ok := m.metaAttempts["ch0"]; ok {
+		t.Error("attempts not cleared for resolved channel")
+	}
+}
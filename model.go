@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -24,6 +25,27 @@ type Group struct {
 	GroupID     string
 	Name        string
 	RelayPubKey string // pubkey of the relay (author of kind 39000 metadata)
+
+	// Mirrors are further relays carrying the same group. Messages are
+	// read from and published to all of them; metadata and the group's
+	// identity (groupKey) stay with RelayURL.
+	Mirrors []string
+
+	// Our roles in the group from the kind 39001 admins list. AdminsKnown is
+	// false until that list has been seen, so commands aren't gated on
+	// relays that never publish it.
+	Roles       []string
+	AdminsKnown bool
+}
+
+// Relays returns the group's relay set: RelayURL first, then its mirrors.
+func (g Group) Relays() []string {
+	return append([]string{g.RelayURL}, g.Mirrors...)
+}
+
+// isAdmin reports whether we hold any role in the group's admins list.
+func (g Group) isAdmin() bool {
+	return len(g.Roles) > 0
 }
 
 type model struct {
@@ -34,6 +56,8 @@ type model struct {
 	pool        *nostr.Pool
 	kr          nostr.Keyer
 	relays      []string
+	access      *relayAccess  // connects relays with [[relay]] options; nil in tests
+	queries     *queryLimiter // bounds concurrent profile and metadata fetches
 
 
 	// TUI dimensions
@@ -45,8 +69,21 @@ type model struct {
 	activeItem int
 	sidebar    []SidebarItem
 
+	// NIP-29 thread open in each group (groupKey → root event ID).
+	groupThread map[string]string
+
+	// Sidebar sections folded with alt+- (or a click on their header).
+	foldedSections map[SidebarKind]bool
+
 	// Per-room subscriptions — all channels and groups are subscribed simultaneously.
 	roomSubs map[string]*roomSub
+	// Live /filter overrides per room (channel ID or groupKey).
+	roomFilters map[string]subFilter
+
+	// Rooms waiting to be opened as one combined subscription
+	// (max_subs_per_relay), and whether a flush is scheduled.
+	subQueue        map[string]bool
+	subFlushPending bool
 
 	// NIP-29 Group recent event IDs (per-group ring buffer, max 50)
 	groupRecentIDs map[string][]string
@@ -71,19 +108,34 @@ type model struct {
 	// Dedup
 	seenEvents      map[string]time.Time
 	seenEventsClean time.Time // last time stale entries were evicted
+	seenEvicted     int       // entries evicted by TTL this session (/dedup-stats)
 	localDMEchoes map[string]time.Time // "peer:content" keys for sent DMs awaiting relay echo
 
 	// Unread indicators (keyed by channel ID, group key, or DM peer pubkey)
 	unread        map[string]bool
 	dmSeenAtStart nostr.Timestamp // lastDMSeen at startup, to suppress unread for replayed messages
 
+	// Timestamp of the first unread message per unread room, and of the
+	// active room's first unread message when it was opened (0 = none).
+	unreadFrom       map[string]nostr.Timestamp
+	openedRoom       string
+	openedUnreadFrom nostr.Timestamp
+
 	// Profile resolution (NIP-01 kind 0)
 	profiles       map[string]string // pubkey -> display name
 	profilePending map[string]bool   // pubkeys with in-flight fetches
+	embeds         map[string]nostr.Event // notes fetched for /embed and nostr: references, by ID
+	embedPending   map[string]bool        // note IDs with in-flight fetches
 
 	// Input tracking
 	lastInputHeight int
 
+	// Compose preview (ctrl+g)
+	preview      bool
+	previewSeq   int      // debounce generation, see previewTickMsg
+	previewSrc   string   // input the pane was last rendered from
+	previewLines []string // rendered pane content
+
 	// Autocomplete
 	acSuggestions []string
 	acIndex       int
@@ -94,12 +146,118 @@ type model struct {
 	historyIndex int      // -1 = current input, 0..len-1 = history position from end
 	historySaved string   // unsent input saved when entering history
 
+	// Muted words (lowercase) — messages containing any of them are collapsed.
+	mutedWords map[string]bool
+
+	// Highlights: rooms with an unread highlighted message (mention or
+	// highlight word), and the start time used to avoid ringing the bell
+	// for replayed history.
+	highlights map[string]bool
+	startedAt  nostr.Timestamp
+	bell       bool // the next frames end in a BEL (see ringBell)
+
 	// Status
 	statusMsg string
 
 	// QR overlay (non-empty = show full-screen QR)
 	qrOverlay string
 
+	// Away auto-reply: the message (empty = not away), when /away was set,
+	// whether strangers get it too (/away --all), and the peers already
+	// auto-replied to.
+	awayMsg     string
+	awaySince   nostr.Timestamp
+	awayAll     bool
+	awayReplied map[string]bool
+
+	// Counter for /digest status lines, so each request updates its own.
+	digestSeq int
+
+	// Whether the expiry labels of /timed DMs are being redrawn.
+	timedRefreshing bool
+
+	// The latest /test-dm run (nil before the first).
+	dmTest *dmSelfTest
+
+	// Report shown in the overlay by /whois while its fetches complete.
+	whois *whoisReport
+
+	// NIP-25 reaction counts by target event ID and emoji, and the reaction
+	// event IDs already counted.
+	reactions     map[string]map[string]int
+	seenReactions map[string]bool
+
+	// Emoji picker opened by /react (nil when closed), and the reactions we
+	// used most recently, newest first (saved).
+	reactPicker     *reactPicker
+	recentReactions []string
+
+	// DM read receipts: the newest peer message we acknowledged, and the
+	// newest of our messages each peer acknowledged, by peer pubkey.
+	// receiptPeers are the peers receipts are sent to: those whose client
+	// sent us one, or opted in with /read-receipts on in their DM.
+	receiptsSent map[string]nostr.Timestamp
+	seenByPeer   map[string]nostr.Timestamp
+	receiptPeers map[string]bool
+
+	// Full-screen NIP-23 article reader opened by /read (nil when closed).
+	reader *articleReader
+
+	// Named drafts from the drafts file (/save-draft, /draft), by name.
+	drafts map[string]string
+
+	// Rooms shown as plain text instead of markdown (/toggle-markdown).
+	plainRooms map[string]bool
+
+	// Rooms showing only contacts' messages (/whitelist, saved), and those
+	// expanded this session to show everyone (/whitelist show).
+	whitelistRooms map[string]bool
+	whitelistShown map[string]bool
+
+	// Event IDs of content-warned messages revealed with enter.
+	revealed map[string]bool
+
+	// Group invite codes we created, oldest first (saved; /list-invites).
+	invites []groupInvite
+
+	// Rooms whose on-disk history was deleted this session (/clear-history);
+	// loadHistory leaves them alone.
+	historyCleared map[string]bool
+
+	// Metadata retries made so far per room (channel ID or groupKey) whose
+	// name has not resolved.
+	metaAttempts map[string]int
+
+	// Room key (groupKey or threadKey) of each sent group message still
+	// waiting for its relay copy, by event ID.
+	pendingEchoes map[string]string
+
+	// Delivery reports that arrived before their message's local echo, by
+	// event ID; applied when the echo is appended.
+	earlyReports map[string]deliveryReportMsg
+
+	// Events delivered per room and relay URL this session (/stats-relay),
+	// and the copies the pool dropped as duplicates, per relay (nil in tests).
+	relayStats map[string]map[string]int
+	relayDups  *relayDuplicates
+
+	// Last /ping round-trip time per relay URL (absent = unknown or unreachable).
+	relayLatency map[string]time.Duration
+
+	// Viewport line index of each rendered message's first line, by event ID.
+	msgLines map[string]int
+
+	// Results of the last /dm-search, numbered from 1.
+	dmSearchHits []dmSearchHit
+
+	// Message awaiting "send large message?" confirmation; empty when none.
+	pendingSend string
+
+	// Command palette (ctrl+p)
+	paletteOpen  bool
+	paletteQuery string
+	paletteIndex int
+
 	// Mouse selection state
 	selecting  bool
 	selectFrom [2]int // [x, y] screen coordinates at press
@@ -110,8 +268,38 @@ type model struct {
 	channelsListTS nostr.Timestamp
 	groupsListTS   nostr.Timestamp
 
+	// NIP-02 kind 3 follow list. followsLoaded guards against publishing
+	// (and thereby wiping) the list before the relay copy has been fetched.
+	follows        []Follow
+	followsListTS  nostr.Timestamp
+	followsContent string
+	followsLoaded  bool
+
 	// Logging
-	logDir string // empty = logging disabled
+	logDir     string       // empty = logging disabled
+	history    historyStore // message history backend (file logs or SQLite)
+	historyErr error        // why the configured backend failed to open; shown at startup
+
+	// Overlay of all rooms' unread messages opened by /peek-unread (nil
+	// when closed).
+	unreadPeek *unreadPeek
+
+	// Overlay of display toggles opened by /display (nil when closed).
+	displayMenu *displayMenu
+
+	// /backup and /restore: the passphrase prompt, a read backup waiting
+	// for confirmation, and the note printed once nitrous quits after
+	// restoring.
+	passPrompt     *passPrompt
+	pendingRestore *pendingRestore
+	exitNote       string
+
+	// CLOSED notices of room subscriptions, and NIP-42 auth for reading;
+	// keys are room + "\t" + relay URL.
+	subClosed   chan subClosedMsg
+	authReads   map[string]bool // auth-required already reported
+	authRetries map[string]int  // resubscriptions after our own AUTH
+	authPending map[string]bool // AUTH in flight
 }
 
 // roomSub holds a per-room subscription (channel or group).
@@ -120,6 +308,10 @@ type roomSub struct {
 	roomID string // channel ID or groupKey
 	events <-chan nostr.RelayEvent
 	cancel context.CancelFunc
+	batch  string // combined subscription shared with other rooms; empty if dedicated
+
+	lastActivity time.Time // last event delivered (or keepalive answered), for the watchdog
+	probing      bool      // a keepalive query is in flight
 }
 
 // waitForRoomSub returns a Cmd that waits for the next event on a specific room subscription.
@@ -216,17 +408,27 @@ func (m *model) activeSidebarItem() SidebarItem {
 
 
 
+// configureInput applies the input settings of cfg: the send and newline
+// keys and the maximum height.
+func configureInput(ta *textarea.Model, cfg Config) {
+	ta.Placeholder = "Type a message... (/help for commands)"
+	if !cfg.EnterSendsMessage() {
+		ta.Placeholder = "Type a message... (" + cfg.SendKeyBinding() + " sends, /help for commands)"
+	}
+	_, maxLines := cfg.InputHeights()
+	ta.MaxHeight = maxLines
+	ta.KeyMap.InsertNewline = key.NewBinding(key.WithKeys(cfg.NewlineKeys()...))
+}
+
 func newModel(cfg Config, cfgFlagPath string, keys Keys, pool *nostr.Pool, kr nostr.Keyer, mdRender *glamour.TermRenderer, mdStyle string) model {
 	ta := textarea.New()
-	ta.Placeholder = "Type a message... (/help for commands)"
 	ta.Prompt = "> "
 	ta.CharLimit = 2000
-	ta.SetHeight(inputMinHeight)
-	ta.MaxHeight = inputMaxHeight
+	configureInput(&ta, cfg)
+	ta.SetHeight(inputHeight(cfg, 1, true))
 	ta.ShowLineNumbers = false
 	ta.FocusedStyle.CursorLine = lipgloss.NewStyle()
 	ta.BlurredStyle.CursorLine = lipgloss.NewStyle()
-	ta.KeyMap.InsertNewline = key.NewBinding(key.WithKeys("alt+enter", "ctrl+j"))
 	ta.Focus()
 
 	vp := viewport.New(80, 20)
@@ -245,6 +447,10 @@ func newModel(cfg Config, cfgFlagPath string, keys Keys, pool *nostr.Pool, kr no
 
 	lastSeen := LoadLastDMSeen(cfgFlagPath)
 
+	state := loadLocalState(cfgFlagPath)
+	applyDisplayPrefs(&cfg, state.displayPrefs)
+	mutedWords := mutedWordSet(cfg.MutedWords)
+
 	// Resolve log directory.
 	var logDir string
 	if cfg.LoggingEnabled() {
@@ -254,12 +460,18 @@ func newModel(cfg Config, cfgFlagPath string, keys Keys, pool *nostr.Pool, kr no
 			logDir = filepath.Join(filepath.Dir(configPath(cfgFlagPath)), "logs")
 		}
 	}
+	history, historyErr := newHistoryStore(cfg.HistoryBackend, logDir)
+	if historyErr != nil {
+		log.Printf("newModel: %v — falling back to file logs", historyErr)
+		history = fileHistoryStore{dir: logDir}
+	}
 
 	return model{
 		cfg:         cfg,
 		cfgFlagPath: cfgFlagPath,
 		keys:        keys,
 		pool:        pool,
+		queries:     newQueryLimiter(cfg.MaxQueries()),
 		kr:          kr,
 		relays:      cfg.Relays,
 		width:       80,
@@ -276,14 +488,42 @@ func newModel(cfg Config, cfgFlagPath string, keys Keys, pool *nostr.Pool, kr no
 		localDMEchoes:  make(map[string]time.Time),
 		profiles:       profiles,
 		profilePending: make(map[string]bool),
-		lastInputHeight: inputMinHeight,
+		embeds:         make(map[string]nostr.Event),
+		embedPending:   make(map[string]bool),
+		lastInputHeight: inputHeight(cfg, 1, true),
+		preview:         cfg.ComposePreview,
+		subClosed:       make(chan subClosedMsg, 16),
+		authReads:       make(map[string]bool),
+		authRetries:     make(map[string]int),
+		authPending:     make(map[string]bool),
 		historyIndex:    -1,
+		mutedWords:      mutedWords,
+		highlights:      make(map[string]bool),
+		reactions:       make(map[string]map[string]int),
+		seenReactions:   make(map[string]bool),
+		receiptsSent:    make(map[string]nostr.Timestamp),
+		seenByPeer:      make(map[string]nostr.Timestamp),
+		relayLatency:    make(map[string]time.Duration),
+		relayStats:      make(map[string]map[string]int),
+		metaAttempts:    make(map[string]int),
+		drafts:          state.drafts,
+		plainRooms:      state.plainRooms,
+		whitelistRooms:  state.whitelistRooms,
+		whitelistShown:  make(map[string]bool),
+		revealed:        make(map[string]bool),
+		recentReactions: state.recentReactions,
+		invites:         state.invites,
+		roomFilters:     make(map[string]subFilter),
+		subQueue:        make(map[string]bool),
+		startedAt:       nostr.Now(),
 		viewport:       vp,
 		input:          ta,
 		mdRender:       mdRender,
 		mdStyle:        mdStyle,
 		statusMsg:      fmt.Sprintf("connected to %d relays", len(cfg.Relays)),
 		logDir:         logDir,
+		history:        history,
+		historyErr:     historyErr,
 	}
 }
 
@@ -291,32 +531,69 @@ func (m *model) Init() tea.Cmd {
 	log.Println("Init() called")
 	m.addSystemMsg("nitrous — nostr chat")
 	m.addSystemMsg(fmt.Sprintf("npub: %s", m.keys.NPub))
+	if m.historyErr != nil {
+		m.addSystemMsg(fmt.Sprintf("history: %v — using file logs", m.historyErr))
+	}
 	for _, r := range m.relays {
-		m.addSystemMsg(fmt.Sprintf("connecting to %s ...", r))
+		m.addStatusMsg(relayStatusKey(r), fmt.Sprintf("connecting to %s ...", r))
 	}
 	m.addSystemMsg("fetching lists from relays ...")
 
 	cmds := []tea.Cmd{
 		textarea.Blink,
-		subscribeDMCmd(m.pool, m.relays, m.kr, m.lastDMSeen),
+		m.afterRelayAccess(m.relays, subscribeDMCmd(m.pool, m.relays, m.kr, m.lastDMSeen, m.cfg.DMLookback())),
 		publishDMRelaysCmd(m.pool, m.relays, m.keys),
-		fetchNIP51ListsCmd(m.pool, m.relays, m.keys, m.kr),
+		m.afterRelayAccess(m.relays, fetchNIP51ListsCmd(m.pool, m.relays, m.keys, m.kr, m.cfg.SyncContactsEnabled(), m.cfg.ReplaceableWait())),
+		waitForSubClosed(m.subClosed),
+	}
+	if !m.cfg.SyncContactsEnabled() {
+		cmds = append(cmds, m.loadLocalContacts()...)
+	}
+	if after := m.cfg.StaleSubInterval(); after > 0 {
+		cmds = append(cmds, staleCheckCmd(after))
+	}
+	if m.cfg.StatusPingInterval() > 0 {
+		cmds = append(cmds, statusPingCmd(m.pool, m.relays))
 	}
 	if m.cfg.Profile.Name != "" || m.cfg.Profile.DisplayName != "" || m.cfg.Profile.About != "" || m.cfg.Profile.Picture != "" {
 		cmds = append(cmds, publishProfileCmd(m.pool, m.relays, m.cfg.Profile, m.keys))
 	}
+	for _, r := range m.relays {
+		cmds = append(cmds, probeRelayCmd(m.pool, r))
+	}
 	return tea.Batch(cmds...)
 }
 
 // addSystemMsg appends a local-only notice into the current chat view.
 func (m *model) addSystemMsg(text string) {
+	m.addStatusMsg("", text)
+}
+
+// addRoomSystemMsg is addSystemMsg for room roomKey, which need not be
+// the active one (e.g. for results of async sends).
+func (m *model) addRoomSystemMsg(roomKey, text string) {
+	msg := ChatMessage{
+		Author:    "system",
+		Content:   text,
+		Timestamp: nostr.Now(),
+	}
+	m.msgs[roomKey] = appendMessage(m.msgs[roomKey], msg, m.cfg.MaxMessages)
+	if m.activeRoomKey() == roomKey {
+		m.updateViewport()
+	}
+}
+
+// addStatusMsg is addSystemMsg for a notice that updateStatusMsg can later
+// rewrite in place, identified by key.
+func (m *model) addStatusMsg(key, text string) {
 	msg := ChatMessage{
 		Author:    "system",
 		Content:   text,
 		Timestamp: nostr.Now(),
+		StatusKey: key,
 	}
 	if item := m.activeSidebarItem(); item != nil {
-		key := item.ItemID()
+		key := m.activeRoomKey()
 		m.msgs[key] = appendMessage(m.msgs[key], msg, m.cfg.MaxMessages)
 	} else {
 		m.globalMsgs = appendMessage(m.globalMsgs, msg, m.cfg.MaxMessages)
@@ -324,6 +601,30 @@ func (m *model) addSystemMsg(text string) {
 	m.updateViewport()
 }
 
+// updateStatusMsg replaces the text of the system message added with key,
+// wherever it is stored. It reports false if no such message remains.
+func (m *model) updateStatusMsg(key, text string) bool {
+	replace := func(msgs []ChatMessage) bool {
+		for i := len(msgs) - 1; i >= 0; i-- {
+			if msgs[i].StatusKey == key {
+				msgs[i].Content = text
+				return true
+			}
+		}
+		return false
+	}
+	found := replace(m.globalMsgs)
+	for _, msgs := range m.msgs {
+		if replace(msgs) {
+			found = true
+		}
+	}
+	if found {
+		m.updateViewport()
+	}
+	return found
+}
+
 // resolveAuthor returns the cached display name for a pubkey, or shortPK as fallback.
 func (m *model) resolveAuthor(pubkey string) string {
 	if name, ok := m.profiles[pubkey]; ok {
@@ -344,19 +645,24 @@ func (m *model) maybeRequestProfile(pubkey string) tea.Cmd {
 		return nil
 	}
 	m.profilePending[pubkey] = true
-	return fetchProfileCmd(m.pool, m.relays, pubkey)
+	return fetchProfileCmd(m.pool, m.queries, m.relays, pubkey, m.cfg.ReplaceableWait())
+}
+
+// inputHeight returns the input box height for lines of content, within
+// input_min_height and input_max_height. With input_collapse an empty
+// input takes a single line.
+func inputHeight(cfg Config, lines int, empty bool) int {
+	if cfg.InputCollapse && empty {
+		return 1
+	}
+	lo, hi := cfg.InputHeights()
+	return min(max(lines, lo), hi)
 }
 
 // syncInputHeight resizes the textarea to match its content and re-layouts if needed.
 // Handles shrinking (e.g. backspace joining lines) and any growth not caught by pre-grow.
 func (m *model) syncInputHeight() {
-	lines := m.input.LineCount()
-	if lines < inputMinHeight {
-		lines = inputMinHeight
-	}
-	if lines > inputMaxHeight {
-		lines = inputMaxHeight
-	}
+	lines := inputHeight(m.cfg, m.input.LineCount(), m.input.Value() == "")
 	if lines != m.lastInputHeight {
 		m.input.SetHeight(lines)
 		m.lastInputHeight = lines
@@ -364,10 +670,31 @@ func (m *model) syncInputHeight() {
 	}
 }
 
+// markUnread flags roomKey as unread and records ts as its first unread
+// message unless an earlier one is already recorded.
+func (m *model) markUnread(roomKey string, ts nostr.Timestamp) {
+	m.unread[roomKey] = true
+	if m.unreadFrom == nil {
+		m.unreadFrom = make(map[string]nostr.Timestamp)
+	}
+	if from, ok := m.unreadFrom[roomKey]; !ok || ts < from {
+		m.unreadFrom[roomKey] = ts
+	}
+}
+
 // clearUnread removes the unread indicator for the currently active item.
+// When the item was just opened, its first-unread time moves to
+// openedUnreadFrom, so /digest can still tell which messages were new.
 func (m *model) clearUnread() {
 	if item := m.activeSidebarItem(); item != nil {
-		delete(m.unread, item.ItemID())
+		id := item.ItemID()
+		delete(m.unread, id)
+		delete(m.highlights, id)
+		if id != m.openedRoom {
+			m.openedRoom = id
+			m.openedUnreadFrom = m.unreadFrom[id]
+			delete(m.unreadFrom, id)
+		}
 	}
 }
 
@@ -387,9 +714,57 @@ func appendMessage(msgs []ChatMessage, msg ChatMessage, maxMessages int) []ChatM
 	return msgs
 }
 
-// loadHistory loads message history from a log file and marks event IDs as seen.
+// nthRecentMessage returns the nth most recent non-system message in the
+// active conversation (1 = newest) and its index in m.msgs.
+func (m *model) nthRecentMessage(n int) (ChatMessage, int, bool) {
+	item := m.activeSidebarItem()
+	if item == nil || n < 1 {
+		return ChatMessage{}, -1, false
+	}
+	msgs := m.msgs[m.activeRoomKey()]
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Author == "system" {
+			continue
+		}
+		n--
+		if n == 0 {
+			return msgs[i], i, true
+		}
+	}
+	return ChatMessage{}, -1, false
+}
+
+// recentConversation is a sidebar item paired with its latest message.
+type recentConversation struct {
+	Index int         // index into m.sidebar
+	Last  ChatMessage // newest non-system message
+}
+
+// recentConversations returns sidebar items that have at least one
+// non-system message, most recently active first.
+func (m *model) recentConversations() []recentConversation {
+	var out []recentConversation
+	for i, it := range m.sidebar {
+		msgs := m.msgs[it.ItemID()]
+		for j := len(msgs) - 1; j >= 0; j-- {
+			if msgs[j].Author != "system" {
+				out = append(out, recentConversation{Index: i, Last: msgs[j]})
+				break
+			}
+		}
+	}
+	sort.SliceStable(out, func(a, b int) bool {
+		return out[a].Last.Timestamp > out[b].Last.Timestamp
+	})
+	return out
+}
+
+// loadHistory loads message history from the history store and marks event IDs as seen.
 func (m *model) loadHistory(roomType, roomKey string) {
-	msgs, err := loadLogHistory(m.logDir, roomType, roomKey, m.cfg.MaxMessages)
+	if m.historyCleared[roomKey] {
+		return
+	}
+	msgs, err := m.history.Load(roomType, roomKey, m.cfg.MaxMessages)
 	if err != nil {
 		log.Printf("loadHistory: %v", err)
 		return
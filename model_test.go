@@ -81,6 +81,8 @@ func TestAppendMessage(t *testing.T) {
 func newTestModel(channels int, groups int, dmPeers int) *model {
 	m := &model{
 		activeItem: 0,
+		msgs:       make(map[string][]ChatMessage),
+		cfg:        Config{MaxMessages: 100},
 	}
 	for i := 0; i < channels; i++ {
 		m.sidebar = append(m.sidebar, ChannelItem{Channel: Channel{ID: "ch" + string(rune('0'+i)), Name: "chan" + string(rune('0'+i))}})
@@ -512,3 +514,131 @@ func TestSidebarHelpers(t *testing.T) {
 		}
 	})
 }
+
+func TestNthRecentMessage(t *testing.T) {
+	m := newTestModel(1, 0, 0)
+	m.msgs = map[string][]ChatMessage{
+		"ch0": {
+			{Author: "alice", Content: "first", Timestamp: 100},
+			{Author: "bob", Content: "second", Timestamp: 200},
+			{Author: "system", Content: "notice", Timestamp: 250},
+			{Author: "alice", Content: "third", Timestamp: 300},
+		},
+	}
+
+	msg, idx, ok := m.nthRecentMessage(1)
+	if !ok || msg.Content != "third" || idx != 3 {
+		t.Errorf("nthRecentMessage(1) = %q, %d, %v; want third, 3, true", msg.Content, idx, ok)
+	}
+	// System messages are skipped when counting.
+	msg, idx, ok = m.nthRecentMessage(2)
+	if !ok || msg.Content != "second" || idx != 1 {
+		t.Errorf("nthRecentMessage(2) = %q, %d, %v; want second, 1, true", msg.Content, idx, ok)
+	}
+	if _, _, ok := m.nthRecentMessage(4); ok {
+		t.Error("nthRecentMessage(4) should be out of range")
+	}
+	if _, _, ok := m.nthRecentMessage(0); ok {
+		t.Error("nthRecentMessage(0) should be invalid")
+	}
+}
+
+func TestRecentConversations(t *testing.T) {
+	m := newTestModel(2, 1, 1) // ch0, ch1, g0, pk0
+	m.msgs = map[string][]ChatMessage{
+		"ch0":         {{Author: "alice", Content: "old", Timestamp: 100}},
+		"ch1":         {{Author: "system", Content: "only system", Timestamp: 900}},
+		"wss://r\tg0": {{Author: "bob", Content: "newest", Timestamp: 500}, {Author: "system", Content: "notice", Timestamp: 999}},
+		"pk0":         {{Author: "carol", Content: "middle", Timestamp: 300}},
+	}
+
+	got := m.recentConversations()
+	if len(got) != 3 {
+		t.Fatalf("expected 3 conversations (system-only room skipped), got %d", len(got))
+	}
+	wantIdx := []int{2, 3, 0}
+	for i, rc := range got {
+		if rc.Index != wantIdx[i] {
+			t.Errorf("recent[%d].Index = %d, want %d", i, rc.Index, wantIdx[i])
+		}
+	}
+	if got[0].Last.Content != "newest" {
+		t.Errorf("expected latest non-system message, got %q", got[0].Last.Content)
+	}
+}
+
+func TestUpdateStatusMsg(t *testing.T) {
+	m := newTestModel(1, 0, 0)
+	m.msgs = map[string][]ChatMessage{
+		"ch0": {
+			{Author: "system", Content: "connecting to wss://a ...", StatusKey: relayStatusKey("wss://a")},
+			{Author: "system", Content: "connecting to wss://b ...", StatusKey: relayStatusKey("wss://b")},
+		},
+	}
+
+	if !m.updateStatusMsg(relayStatusKey("wss://b"), "connected to wss://b") {
+		t.Fatal("expected status message to be found")
+	}
+	if got := m.msgs["ch0"][1].Content; got != "connected to wss://b" {
+		t.Errorf("updated content = %q", got)
+	}
+	if got := m.msgs["ch0"][0].Content; got != "connecting to wss://a ..." {
+		t.Errorf("other status line changed to %q", got)
+	}
+	if m.updateStatusMsg(relayStatusKey("wss://c"), "connected") {
+		t.Error("unknown key should not be found")
+	}
+}
+
+func TestSidebarRowsCompactAndFolded(t *testing.T) {
+	m := newTestModel(2, 0, 2) // channels: 0-1, DMs: 2-3
+
+	// Without compact_sidebar the empty GROUPS header still takes a row.
+	if idx, ok := m.sidebarItemAt(5); !ok || idx != 2 {
+		t.Errorf("row 5 = (%d, %v), want first DM", idx, ok)
+	}
+	if kind, ok := m.sidebarHeaderAt(3); !ok || kind != SidebarGroup {
+		t.Errorf("row 3 = (%v, %v), want GROUPS header", kind, ok)
+	}
+
+	m.cfg.CompactSidebar = true
+	if kind, ok := m.sidebarHeaderAt(3); !ok || kind != SidebarDM {
+		t.Errorf("compact row 3 = (%v, %v), want DMS header", kind, ok)
+	}
+	if idx, ok := m.sidebarItemAt(4); !ok || idx != 2 {
+		t.Errorf("compact row 4 = (%d, %v), want first DM", idx, ok)
+	}
+
+	// Folding channels keeps only the active channel under its header.
+	m.activeItem = 1
+	m.setSectionFolded(SidebarChannel, true)
+	if idx, ok := m.sidebarItemAt(1); !ok || idx != 1 {
+		t.Errorf("folded row 1 = (%d, %v), want active channel", idx, ok)
+	}
+	if kind, ok := m.sidebarHeaderAt(2); !ok || kind != SidebarDM {
+		t.Errorf("folded row 2 = (%v, %v), want DMS header", kind, ok)
+	}
+	if _, ok := m.sidebarItemAt(5); ok {
+		t.Error("row past the end should not map to an item")
+	}
+}
+
+func TestDeliveryReportBeforeEcho(t *testing.T) {
+	m := newTestModel(1, 0, 0)
+	m.msgs = map[string][]ChatMessage{}
+	report := deliveryReportMsg{roomKey: "other", eventID: "e1", deliveries: map[string]string{"wss://a": "ok"}}
+
+	// The publish can finish before the batched echo is handled.
+	m.handleDeliveryReport(report)
+	if _, ok := m.earlyReports["e1"]; !ok {
+		t.Fatal("report for an unknown message was dropped")
+	}
+	m.msgs["other"] = []ChatMessage{{EventID: "e1", IsMine: true}}
+	m.applyEarlyReport("e1")
+	if got := m.msgs["other"][0].Deliveries["wss://a"]; got != "ok" {
+		t.Errorf("delivery = %q, want ok", got)
+	}
+	if len(m.earlyReports) != 0 {
+		t.Errorf("earlyReports = %v, want empty", m.earlyReports)
+	}
+}
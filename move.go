@@ -0,0 +1,54 @@
+package main
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// moveSidebarItem moves the item at index i of items one place up (delta
+// -1) or down (delta 1) within its section and returns its new index, or
+// -1 when it is already at that end of the section.
+func moveSidebarItem(items []SidebarItem, i, delta int) int {
+	j := i + delta
+	if i < 0 || i >= len(items) || j < 0 || j >= len(items) || items[j].Kind() != items[i].Kind() {
+		return -1
+	}
+	items[i], items[j] = items[j], items[i]
+	return j
+}
+
+// moveActiveItem handles /move up|down: it moves the active item within its
+// section and stores the new order where the section is saved, the NIP-51
+// public chats or simple groups list, or the contacts.
+func (m *model) moveActiveItem(arg string) (tea.Model, tea.Cmd) {
+	var delta int
+	var end string
+	switch arg {
+	case "up":
+		delta, end = -1, "top"
+	case "down":
+		delta, end = 1, "bottom"
+	default:
+		m.addSystemMsg("usage: /move up|down")
+		return m, nil
+	}
+	item := m.activeSidebarItem()
+	if item == nil {
+		m.addSystemMsg("no active channel, group, or DM to move")
+		return m, nil
+	}
+	j := moveSidebarItem(m.sidebar, m.activeItem, delta)
+	if j < 0 {
+		m.addSystemMsg(item.Prefix() + item.DisplayName() + " is already at the " + end + " of its section")
+		return m, nil
+	}
+	m.activeItem = j
+	switch item.(type) {
+	case ChannelItem:
+		return m, publishPublicChatsListCmd(m.pool, m.relays, m.allChannels(), m.keys)
+	case GroupItem:
+		return m, publishSimpleGroupsListCmd(m.pool, m.relays, m.allGroups(), m.keys)
+	case DMItem:
+		return m, m.syncContacts()
+	}
+	return m, nil // group DMs aren't stored, their order lasts the session
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestMoveSidebarItem(t *testing.T) {
+	m := newTestModel(2, 2, 2)
+	// ch0 ch1 | g0 g1 | pk0 pk1
+	if j := moveSidebarItem(m.sidebar, 1, 1); j != -1 {
+		t.Errorf("moved ch1 into the groups: %d", j)
+	}
+	if j := moveSidebarItem(m.sidebar, 0, -1); j != -1 {
+		t.Errorf("moved ch0 above the top: %d", j)
+	}
+	if j := moveSidebarItem(m.sidebar, 5, 1); j != -1 {
+		t.Errorf("moved pk1 past the end: %d", j)
+	}
+	if j := moveSidebarItem(m.sidebar, 3, -1); j != 2 {
+		t.Fatalf("moving g1 up returned %d, want 2", j)
+	}
+	var ids []string
+	for _, it := range m.sidebar {
+		ids = append(ids, it.ItemID())
+	}
+	want := []string{"ch0", "ch1", groupKey("wss://r", "g1"), groupKey("wss://r", "g0"), "pk0", "pk1"}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("sidebar = %v, want %v", ids, want)
+		}
+	}
+}
+
+func TestMoveActiveItemTracksItem(t *testing.T) {
+	m := newTestModel(0, 0, 3)
+	m.activeItem = 0
+	m.moveActiveItem("down")
+	if m.activeItem != 1 || m.sidebar[1].ItemID() != "pk0" {
+		t.Errorf("activeItem = %d (%s), want 1 (pk0)", m.activeItem, m.sidebar[m.activeItem].ItemID())
+	}
+}
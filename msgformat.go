@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultMessageFormat is the weechat-style layout used when message_format
+// is not configured.
+const defaultMessageFormat = "{time} {author}: {content}"
+
+// messageFormatTokenRe matches {token} placeholders in message_format.
+var messageFormatTokenRe = regexp.MustCompile(`\{[a-z]+\}`)
+
+// messageFormatTokens are the placeholders message_format may use.
+var messageFormatTokens = map[string]bool{
+	"{time}":      true,
+	"{author}":    true,
+	"{id}":        true,
+	"{reactions}": true,
+	"{content}":   true,
+}
+
+// validateMessageFormat checks that format contains {content} exactly once
+// and no unknown tokens.
+func validateMessageFormat(format string) error {
+	if n := strings.Count(format, "{content}"); n != 1 {
+		return fmt.Errorf("must contain {content} exactly once, found %d", n)
+	}
+	for _, tok := range messageFormatTokenRe.FindAllString(format, -1) {
+		if !messageFormatTokens[tok] {
+			return fmt.Errorf("unknown token %s", tok)
+		}
+	}
+	return nil
+}
+
+// splitMessageFormat splits format around {content} into the prefix drawn
+// before the first line and the suffix appended after the last line.
+func splitMessageFormat(format string) (prefix, suffix string) {
+	prefix, suffix, _ = strings.Cut(format, "{content}")
+	return prefix, suffix
+}
+
+// dropFormatToken removes tok from a message_format template together with
+// the space after it (or before it, at the end), so hiding a value doesn't
+// leave a gap.
+func dropFormatToken(tmpl, tok string) string {
+	if strings.Contains(tmpl, tok+" ") {
+		return strings.Replace(tmpl, tok+" ", "", 1)
+	}
+	if strings.Contains(tmpl, " "+tok) {
+		return strings.Replace(tmpl, " "+tok, "", 1)
+	}
+	return strings.Replace(tmpl, tok, "", 1)
+}
+
+// expandMessageFormat replaces the tokens in a prefix or suffix template
+// with the already-styled values for one message.
+func expandMessageFormat(tmpl string, vals map[string]string) string {
+	return messageFormatTokenRe.ReplaceAllStringFunc(tmpl, func(tok string) string {
+		if v, ok := vals[tok]; ok {
+			return v
+		}
+		return tok
+	})
+}
+
+// formatReactions renders reaction counts as "👍2 ❤1", most frequent first.
+func formatReactions(counts map[string]int) string {
+	if len(counts) == 0 {
+		return ""
+	}
+	emojis := make([]string, 0, len(counts))
+	for e := range counts {
+		emojis = append(emojis, e)
+	}
+	sort.Slice(emojis, func(i, j int) bool {
+		if counts[emojis[i]] != counts[emojis[j]] {
+			return counts[emojis[i]] > counts[emojis[j]]
+		}
+		return emojis[i] < emojis[j]
+	})
+	parts := make([]string, len(emojis))
+	for i, e := range emojis {
+		parts[i] = e + strconv.Itoa(counts[e])
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestValidateMessageFormat(t *testing.T) {
+	tests := []struct {
+		format  string
+		wantErr bool
+	}{
+		{defaultMessageFormat, false},
+		{"[{id}] {time} <{author}> {content} {reactions}", false},
+		{"{time} {author}", true},          // no {content}
+		{"{content} {content}", true},      // twice
+		{"{time} {nick}: {content}", true}, // unknown token
+		{"{content} (literal braces {})", false},
+	}
+	for _, tt := range tests {
+		err := validateMessageFormat(tt.format)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateMessageFormat(%q) error = %v, wantErr %v", tt.format, err, tt.wantErr)
+		}
+	}
+}
+
+func TestExpandMessageFormat(t *testing.T) {
+	prefix, suffix := splitMessageFormat("[{id}] {time} {author}: {content} {reactions}")
+	vals := map[string]string{
+		"{time}":      "12:34",
+		"{author}":    "alice",
+		"{id}":        "deadbeef",
+		"{reactions}": "👍2",
+	}
+	if got := expandMessageFormat(prefix, vals); got != "[deadbeef] 12:34 alice: " {
+		t.Errorf("prefix = %q", got)
+	}
+	if got := expandMessageFormat(suffix, vals); got != " 👍2" {
+		t.Errorf("suffix = %q", got)
+	}
+}
+
+func TestDropFormatToken(t *testing.T) {
+	tests := map[string]string{
+		"{time} {author}: {content}":   "{author}: {content}",
+		"{author}: {content} {time}":   "{author}: {content}",
+		"[{time}] {author}: {content}": "[] {author}: {content}",
+		"{author} ({time}): {content}": "{author} (): {content}",
+		"{author}: {content}":          "{author}: {content}",
+	}
+	for format, want := range tests {
+		if got := dropFormatToken(format, "{time}"); got != want {
+			t.Errorf("dropFormatToken(%q) = %q, want %q", format, got, want)
+		}
+	}
+}
+
+func TestFormatReactions(t *testing.T) {
+	if got := formatReactions(nil); got != "" {
+		t.Errorf("formatReactions(nil) = %q, want empty", got)
+	}
+	got := formatReactions(map[string]int{"❤": 1, "👍": 3, "🔥": 1})
+	if got != "👍3 ❤1 🔥1" {
+		t.Errorf("formatReactions = %q", got)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+
+	"fiatjaf.com/nostr"
+)
+
+// Follow is an entry in a NIP-02 kind-3 follow list.
+type Follow struct {
+	PubKey  string
+	Relay   string // relay hint, may be empty
+	Petname string // may be empty
+}
+
+// buildFollowListEvent builds a kind 3 (follow list) event with
+// ["p", pubkey, relay, petname] tags. content is carried over from the
+// previously fetched list (some clients store relay preferences there).
+func buildFollowListEvent(follows []Follow, content string, keys Keys) (nostr.Event, error) {
+	var tags nostr.Tags
+	for _, f := range follows {
+		tag := nostr.Tag{"p", f.PubKey, f.Relay}
+		if f.Petname != "" {
+			tag = append(tag, f.Petname)
+		}
+		tags = append(tags, tag)
+	}
+
+	evt := nostr.Event{
+		Kind:      nostr.KindFollowList, // 3
+		CreatedAt: nostr.Now(),
+		Tags:      tags,
+		Content:   content,
+	}
+	if err := evt.Sign(keys.SK); err != nil {
+		return evt, fmt.Errorf("buildFollowListEvent: sign: %w", err)
+	}
+	return evt, nil
+}
+
+// parseFollowListEvent extracts follows from a kind 3 event.
+func parseFollowListEvent(evt *nostr.Event) []Follow {
+	if evt == nil {
+		return nil
+	}
+	var follows []Follow
+	for _, tag := range evt.Tags {
+		if len(tag) < 2 || tag[0] != "p" {
+			continue
+		}
+		f := Follow{PubKey: tag[1]}
+		if len(tag) >= 3 {
+			f.Relay = tag[2]
+		}
+		if len(tag) >= 4 {
+			f.Petname = tag[3]
+		}
+		follows = append(follows, f)
+	}
+	return follows
+}
+
+// followIndex returns the index of pubkey in follows, or -1.
+func followIndex(follows []Follow, pubkey string) int {
+	for i, f := range follows {
+		if f.PubKey == pubkey {
+			return i
+		}
+	}
+	return -1
+}
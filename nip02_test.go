@@ -0,0 +1,76 @@
+package main
+
+import "testing"
+
+func TestBuildParseFollowListRoundtrip(t *testing.T) {
+	keys := testKeys(t)
+	follows := []Follow{
+		{PubKey: "aaaa", Relay: "wss://relay.example.com", Petname: "alice"},
+		{PubKey: "bbbb"},
+	}
+
+	evt, err := buildFollowListEvent(follows, `{"wss://relay.example.com":{"read":true}}`, keys)
+	if err != nil {
+		t.Fatalf("buildFollowListEvent: %v", err)
+	}
+	if evt.Kind != 3 {
+		t.Errorf("kind = %d, want 3", evt.Kind)
+	}
+	if evt.Content != `{"wss://relay.example.com":{"read":true}}` {
+		t.Errorf("content not preserved: %q", evt.Content)
+	}
+	if ok := evt.CheckID(); !ok {
+		t.Error("event ID mismatch")
+	}
+
+	got := parseFollowListEvent(&evt)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 follows, got %d", len(got))
+	}
+	if got[0] != follows[0] {
+		t.Errorf("follow[0] = %+v, want %+v", got[0], follows[0])
+	}
+	if got[1] != follows[1] {
+		t.Errorf("follow[1] = %+v, want %+v", got[1], follows[1])
+	}
+}
+
+func TestParseFollowListNil(t *testing.T) {
+	if got := parseFollowListEvent(nil); got != nil {
+		t.Errorf("expected nil, got %v", got)
+	}
+}
+
+func TestFollowIndex(t *testing.T) {
+	follows := []Follow{{PubKey: "a"}, {PubKey: "b"}}
+	if i := followIndex(follows, "b"); i != 1 {
+		t.Errorf("followIndex(b) = %d, want 1", i)
+	}
+	if i := followIndex(follows, "c"); i != -1 {
+		t.Errorf("followIndex(c) = %d, want -1", i)
+	}
+}
+
+func TestFollowsLoadedOnlyFromReceivedList(t *testing.T) {
+	m := newTestModel(0, 0, 0)
+	m.profiles = map[string]string{}
+
+	// The kind 3 query timed out or found nothing: /follow must stay off.
+	m.handleNIP51ListsFetched(nip51ListsFetchedMsg{})
+	if m.followsLoaded {
+		t.Fatal("followsLoaded set without a follow list")
+	}
+	m.followPubKey("pk0")
+	if len(m.follows) != 0 {
+		t.Errorf("followed %v before the list loaded", m.follows)
+	}
+
+	m.handleNIP51ListsFetched(nip51ListsFetchedMsg{follows: []Follow{{PubKey: "pk1"}}, followsTS: 100})
+	if !m.followsLoaded || len(m.follows) != 1 {
+		t.Fatalf("followsLoaded = %v, follows = %v", m.followsLoaded, m.follows)
+	}
+	// Follows only join the DM sidebar with follows_in_sidebar.
+	if m.findDMPeerIdx("pk1") >= 0 {
+		t.Error("follow added to the DM sidebar by default")
+	}
+}
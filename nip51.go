@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 
 	"fiatjaf.com/nostr"
 )
@@ -99,11 +100,12 @@ func parseContactsListEvent(ctx context.Context, evt *nostr.Event, kr nostr.Keye
 }
 
 // buildPublicChatsListEvent builds a kind 10005 (public chat list) event
-// with ["e", channelID] tags for each joined NIP-28 channel.
+// with ["e", channelID] tags for each joined NIP-28 channel. Relay hints
+// follow the ID; other clients read only the first.
 func buildPublicChatsListEvent(channels []Channel, keys Keys) (nostr.Event, error) {
 	var tags nostr.Tags
 	for _, ch := range channels {
-		tags = append(tags, nostr.Tag{"e", ch.ID})
+		tags = append(tags, append(nostr.Tag{"e", ch.ID}, ch.Relays...))
 	}
 
 	evt := nostr.Event{
@@ -128,14 +130,22 @@ func parsePublicChatsListEvent(evt *nostr.Event) []Channel {
 	var channels []Channel
 	for _, tag := range evt.Tags {
 		if len(tag) >= 2 && tag[0] == "e" {
-			channels = append(channels, Channel{ID: tag[1], Name: shortPK(tag[1])})
+			ch := Channel{ID: tag[1], Name: shortPK(tag[1])}
+			for _, r := range tag[2:] {
+				if r = normalizeRelayURL(r); r != "" && !slices.Contains(ch.Relays, r) {
+					ch.Relays = append(ch.Relays, r)
+				}
+			}
+			channels = append(channels, ch)
 		}
 	}
 	return channels
 }
 
 // buildSimpleGroupsListEvent builds a kind 10009 (simple group list) event
-// with ["group", groupID, relayURL] tags for each joined NIP-29 group.
+// with ["group", groupID, relayURL, name] tags for each joined NIP-29 group.
+// Each mirror relay gets its own ["r", mirrorURL, groupID, relayURL] tag, so
+// clients reading the group tags by position aren't confused by them.
 func buildSimpleGroupsListEvent(groups []Group, keys Keys) (nostr.Event, error) {
 	var tags nostr.Tags
 	for _, g := range groups {
@@ -144,6 +154,9 @@ func buildSimpleGroupsListEvent(groups []Group, keys Keys) (nostr.Event, error)
 			tag = append(tag, g.Name)
 		}
 		tags = append(tags, tag)
+		for _, r := range g.Mirrors {
+			tags = append(tags, nostr.Tag{"r", r, g.GroupID, g.RelayURL})
+		}
 	}
 
 	evt := nostr.Event{
@@ -169,7 +182,7 @@ func parseSimpleGroupsListEvent(evt *nostr.Event) []SavedGroup {
 			continue
 		}
 		groupID := tag[1]
-		relayURL := tag[2]
+		relayURL := normalizeRelayURL(tag[2])
 		name := ""
 		if len(tag) >= 4 {
 			name = tag[3]
@@ -177,11 +190,31 @@ func parseSimpleGroupsListEvent(evt *nostr.Event) []SavedGroup {
 		if name == "" {
 			name = shortPK(groupID)
 		}
-		groups = append(groups, SavedGroup{Name: name, RelayURL: relayURL, GroupID: groupID})
+		groups = append(groups, SavedGroup{Name: name, RelayURL: relayURL, GroupID: groupID, listedRelayURL: tag[2]})
+	}
+	for _, tag := range evt.Tags {
+		if len(tag) < 4 || tag[0] != "r" {
+			continue
+		}
+		home := normalizeRelayURL(tag[3])
+		for i := range groups {
+			if groups[i].GroupID == tag[2] && groups[i].RelayURL == home {
+				groups[i].Mirrors = addMirror(groups[i].Mirrors, home, tag[1])
+			}
+		}
 	}
 	return groups
 }
 
+// addMirror appends the normalized relay r to mirrors unless it is empty,
+// the home relay, or already listed.
+func addMirror(mirrors []string, home, r string) []string {
+	if r = normalizeRelayURL(r); r != "" && r != home && !slices.Contains(mirrors, r) {
+		mirrors = append(mirrors, r)
+	}
+	return mirrors
+}
+
 // contactsFromModel converts in-memory DM peer list + profile cache into a
 // []Contact suitable for building a kind 30000 event.
 func contactsFromModel(dmPeers []string, profiles map[string]string) []Contact {
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"slices"
 	"testing"
 
 	"fiatjaf.com/nostr"
@@ -221,6 +222,33 @@ func TestBuildParsePublicChatsListRoundtrip(t *testing.T) {
 	}
 }
 
+func TestBuildParsePublicChatsListRelayHints(t *testing.T) {
+	keys, _ := testKeysWithKeyer(t)
+
+	channels := []Channel{
+		{ID: "event1111111111111111111111111111111111111111111111111111111111", Relays: []string{"wss://a.example.com", "wss://b.example.com"}},
+		{ID: "event2222222222222222222222222222222222222222222222222222222222"},
+	}
+	evt, err := buildPublicChatsListEvent(channels, keys)
+	if err != nil {
+		t.Fatalf("buildPublicChatsListEvent: %v", err)
+	}
+	if len(evt.Tags[0]) != 4 || evt.Tags[0][2] != "wss://a.example.com" {
+		t.Errorf("tag = %v, want relay hints after the ID", evt.Tags[0])
+	}
+
+	got := parsePublicChatsListEvent(&evt)
+	if len(got) != 2 {
+		t.Fatalf("got %d channels, want 2", len(got))
+	}
+	if !slices.Equal(got[0].Relays, channels[0].Relays) {
+		t.Errorf("relays = %v, want %v", got[0].Relays, channels[0].Relays)
+	}
+	if got[1].Relays != nil {
+		t.Errorf("relays = %v, want none", got[1].Relays)
+	}
+}
+
 func TestBuildParsePublicChatsListEmpty(t *testing.T) {
 	keys, _ := testKeysWithKeyer(t)
 
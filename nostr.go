@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"path/filepath"
 	"slices"
 	"strings"
 	"time"
@@ -35,6 +34,69 @@ type ChatMessage struct {
 	ChannelID string // NIP-28 channel this message belongs to
 	GroupKey  string // NIP-29 group key "relay_url\tgroup_id" (empty for channels/DMs)
 	IsMine    bool
+
+	// Deliveries maps relay URL to publish outcome ("ok" or the rejection
+	// reason) for messages we sent. Nil for received or logged messages.
+	Deliveries map[string]string
+
+	// Failed is set on a message we sent that no relay accepted; it stays
+	// in the buffer until /retry-failed or /retry sends it again.
+	Failed bool
+
+	// SentEvent is the signed event behind a channel or group message we
+	// sent, kept so a failed publish can be retried unchanged. Nil otherwise.
+	SentEvent *nostr.Event
+
+	// Echo tracks whether a group message we sent came back from the relay.
+	Echo echoState
+
+	// Relay is the URL of the relay this message was first received from.
+	// Empty for messages we sent, DMs, and logged history.
+	Relay string
+
+	// StatusKey identifies a system message that is updated in place (e.g.
+	// "relay:<url>" for a relay's connection status). Empty otherwise.
+	StatusKey string
+
+	// DMMembers lists the other participants (sorted hex pubkeys) of a
+	// NIP-17 group DM; PubKey is then the sender. Nil for 1:1 DMs.
+	DMMembers []string
+
+	// RepostOf is the hex pubkey of the original author when this message is
+	// a NIP-18 repost; Content then holds the original's content.
+	RepostOf string
+
+	// ThreadID is the root event ID of the NIP-29 thread this message
+	// opens (kind 11, then ThreadTitle is set) or replies to (kind 12).
+	ThreadID    string
+	ThreadTitle string
+
+	// Sensitive is set when the message carries a NIP-36 content-warning
+	// tag; ContentWarning is its reason, which may be empty.
+	Sensitive      bool
+	ContentWarning string
+
+	// Expires is the NIP-40 expiration of a /timed DM, 0 for none.
+	Expires nostr.Timestamp
+}
+
+// deliveryReportMsg carries per-relay publish outcomes for a sent message.
+type deliveryReportMsg struct {
+	roomKey    string // channel ID, groupKey, or DM peer pubkey
+	eventID    string
+	deliveries map[string]string
+}
+
+// publishEventCmd publishes a signed event to relays and reports each
+// relay's outcome for the message roomKey shows it in.
+func publishEventCmd(pool *nostr.Pool, relays []string, roomKey string, evt nostr.Event) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		logEventOut(roomKey, evt)
+		deliveries := collectPublishResults(ctx, pool.PublishMany(ctx, relays, evt))
+		return deliveryReportMsg{roomKey: roomKey, eventID: evt.GetID().Hex(), deliveries: deliveries}
+	}
 }
 
 // nostrErrMsg wraps a nostr operation error as a Bubbletea message.
@@ -53,15 +115,12 @@ type profileResolvedMsg struct {
 func loadKeys(cfg Config) (Keys, error) {
 	var raw string
 	if cfg.PrivateKeyFile != "" {
-		path := cfg.PrivateKeyFile
-		// Expand ~ to home directory.
-		if strings.HasPrefix(path, "~/") {
-			if home, err := os.UserHomeDir(); err == nil {
-				path = filepath.Join(home, path[2:])
-			}
-		}
+		path := expandKeyPath(cfg.PrivateKeyFile)
 		data, err := os.ReadFile(path)
-		if err != nil {
+		switch {
+		case os.IsNotExist(err) && os.Getenv("NOSTR_PRIVATE_KEY") == "":
+			return Keys{}, fmt.Errorf("%w: %s", errKeyFileMissing, path)
+		case err != nil && !os.IsNotExist(err):
 			return Keys{}, fmt.Errorf("failed to read private_key_file %q: %w", path, err)
 		}
 		raw = strings.TrimSpace(string(data))
@@ -73,22 +132,9 @@ func loadKeys(cfg Config) (Keys, error) {
 		return Keys{}, fmt.Errorf("no private key: set private_key_file in config or NOSTR_PRIVATE_KEY env var")
 	}
 
-	var sk nostr.SecretKey
-	if strings.HasPrefix(raw, "nsec") {
-		prefix, val, err := nip19.Decode(raw)
-		if err != nil {
-			return Keys{}, fmt.Errorf("failed to decode nsec: %w", err)
-		}
-		if prefix != "nsec" {
-			return Keys{}, fmt.Errorf("expected nsec prefix, got %s", prefix)
-		}
-		sk = val.(nostr.SecretKey)
-	} else {
-		var err error
-		sk, err = nostr.SecretKeyFromHex(raw)
-		if err != nil {
-			return Keys{}, fmt.Errorf("failed to parse hex secret key: %w", err)
-		}
+	sk, err := parseSecretKey(raw)
+	if err != nil {
+		return Keys{}, err
 	}
 
 	pk := nostr.GetPublicKey(sk)
@@ -100,11 +146,9 @@ func loadKeys(cfg Config) (Keys, error) {
 // fetchProfileCmd fetches a kind-0 event (NIP-01 profile metadata) for a pubkey.
 // If not found on the user's relays, looks up the peer's NIP-65 relay list
 // and tries their write relays.
-func fetchProfileCmd(pool *nostr.Pool, relays []string, pubkey string) tea.Cmd {
+func fetchProfileCmd(pool *nostr.Pool, limit *queryLimiter, relays []string, pubkey string, wait time.Duration) tea.Cmd {
 	return func() tea.Msg {
 		log.Printf("fetchProfile: pubkey=%s", shortPK(pubkey))
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
 
 		pk, err := nostr.PubKeyFromHex(pubkey)
 		if err != nil {
@@ -112,20 +156,19 @@ func fetchProfileCmd(pool *nostr.Pool, relays []string, pubkey string) tea.Cmd {
 			return profileResolvedMsg{PubKey: pubkey, DisplayName: shortPK(pubkey)}
 		}
 
-		re := pool.QuerySingle(ctx, relays, nostr.Filter{
+		filter := nostr.Filter{
 			Kinds:   []nostr.Kind{nostr.KindProfileMetadata},
 			Authors: []nostr.PubKey{pk},
-		}, nostr.SubscriptionOptions{})
+		}
+		what := "profile " + shortPK(pubkey)
+		re := queryReplaceableLimited(limit, what, pool, relays, filter, wait, 10*time.Second)
 
 		// If not found locally, check the peer's NIP-65 relay list for their write relays.
 		if re == nil {
-			peerRelays := getPeerRelays(pool, relays, pk)
+			peerRelays := getPeerRelays(pool, limit, relays, pk, wait)
 			if len(peerRelays) > 0 {
 				log.Printf("fetchProfile: not on local relays, trying %d peer relays for %s", len(peerRelays), shortPK(pubkey))
-				re = pool.QuerySingle(ctx, peerRelays, nostr.Filter{
-					Kinds:   []nostr.Kind{nostr.KindProfileMetadata},
-					Authors: []nostr.PubKey{pk},
-				}, nostr.SubscriptionOptions{})
+				re = queryReplaceableLimited(limit, what, pool, peerRelays, filter, wait, 10*time.Second)
 			}
 		}
 
@@ -159,6 +202,9 @@ func buildProfileEvent(profile ProfileConfig, keys Keys) (nostr.Event, error) {
 	if profile.Picture != "" {
 		meta["picture"] = profile.Picture
 	}
+	if profile.NIP05 != "" {
+		meta["nip05"] = profile.NIP05
+	}
 
 	content, err := json.Marshal(meta)
 	if err != nil {
@@ -191,6 +237,7 @@ func publishProfileCmd(pool *nostr.Pool, relays []string, profile ProfileConfig,
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 		var successCount int
+		logEventOut("", evt)
 		for res := range pool.PublishMany(ctx, relays, evt) {
 			if res.Error == nil {
 				successCount++
@@ -211,6 +258,7 @@ type nip05ResolvedMsg struct {
 	Identifier string // original input e.g. "alice@example.com"
 	PubKey     string // resolved hex pubkey, empty on failure
 	Err        error
+	Verify     bool // true for /nip05 self-verification rather than opening a DM
 }
 
 // resolveNIP05Cmd resolves a NIP-05 internet identifier to a hex pubkey.
@@ -236,6 +284,48 @@ func resolveNIP05Cmd(identifier string) tea.Cmd {
 	}
 }
 
+// verifyNIP05Cmd resolves a NIP-05 identifier for /nip05 self-verification.
+func verifyNIP05Cmd(identifier string) tea.Cmd {
+	return func() tea.Msg {
+		msg := resolveNIP05Cmd(identifier)().(nip05ResolvedMsg)
+		msg.Verify = true
+		return msg
+	}
+}
+
+// nip05JSON returns the .well-known/nostr.json document mapping name to
+// pubkey, with relays advertised for that pubkey if any are given.
+func nip05JSON(name, pubkey string, relays []string) (string, error) {
+	doc := struct {
+		Names  map[string]string   `json:"names"`
+		Relays map[string][]string `json:"relays,omitempty"`
+	}{
+		Names: map[string]string{name: pubkey},
+	}
+	if len(relays) > 0 {
+		doc.Relays = map[string][]string{pubkey: relays}
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// isValidNIP05Name reports whether name is a valid NIP-05 local part
+// (a-z0-9-_. only).
+func isValidNIP05Name(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
 // parseProfileMeta extracts a display name from a kind-0 profile JSON content string.
 // Prefers display_name, falls back to name, then returns empty string.
 func parseProfileMeta(content string) string {
@@ -267,6 +357,27 @@ func drainPublish(ctx context.Context, ch <-chan nostr.PublishResult) {
 	}
 }
 
+// collectPublishResults drains the PublishMany result channel like
+// drainPublish, recording each relay's outcome ("ok" or the error text).
+func collectPublishResults(ctx context.Context, ch <-chan nostr.PublishResult) map[string]string {
+	deliveries := make(map[string]string)
+	for {
+		select {
+		case res, ok := <-ch:
+			if !ok {
+				return deliveries
+			}
+			if res.Error != nil {
+				deliveries[res.RelayURL] = res.Error.Error()
+			} else {
+				deliveries[res.RelayURL] = "ok"
+			}
+		case <-ctx.Done():
+			return deliveries
+		}
+	}
+}
+
 // shortPK returns the first 8 characters of a public key for display.
 func shortPK(pk string) string {
 	if len(pk) > 8 {
@@ -285,6 +396,9 @@ type nip51ListsFetchedMsg struct {
 	channelsTS nostr.Timestamp
 	groups     []SavedGroup
 	groupsTS   nostr.Timestamp
+	follows    []Follow
+	followsTS  nostr.Timestamp
+	followsRaw string // content of the kind 3 event, preserved on republish
 }
 
 // nip51PublishResultMsg is returned after publishing a NIP-51 list event.
@@ -293,20 +407,31 @@ type nip51PublishResultMsg struct {
 	err      error
 }
 
-// fetchNIP51ListsCmd queries relays for the user's kind 30000, 10005, and 10009 lists.
-func fetchNIP51ListsCmd(pool *nostr.Pool, relays []string, keys Keys, kr nostr.Keyer) tea.Cmd {
+// followListWait is the least time answers for the kind 3 follow list are
+// collected, even when replaceable_wait lets the first relay win: /follow
+// and /nsec-rotate republish the list in full, so a stale copy would drop
+// the follows made in other clients since.
+const followListWait = 2 * time.Second
+
+// fetchNIP51ListsCmd queries relays for the user's kind 30000, 10005, and 10009
+// lists, plus the NIP-02 kind 3 follow list, of which the newest copy wins.
+// withContacts = false skips the kind 30000 contacts list.
+func fetchNIP51ListsCmd(pool *nostr.Pool, relays []string, keys Keys, kr nostr.Keyer, withContacts bool, wait time.Duration) tea.Cmd {
 	return func() tea.Msg {
 		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 		defer cancel()
 
 		var result nip51ListsFetchedMsg
+		var re *nostr.RelayEvent
 
 		// Kind 30000 "Chat-Friends" (parameterized replaceable)
-		re := pool.QuerySingle(ctx, relays, nostr.Filter{
-			Kinds:   []nostr.Kind{nostr.KindCategorizedPeopleList},
-			Authors: []nostr.PubKey{keys.PK},
-			Tags:    nostr.TagMap{"d": {"Chat-Friends"}},
-		}, nostr.SubscriptionOptions{})
+		if withContacts {
+			re = pool.QuerySingle(ctx, relays, nostr.Filter{
+				Kinds:   []nostr.Kind{nostr.KindCategorizedPeopleList},
+				Authors: []nostr.PubKey{keys.PK},
+				Tags:    nostr.TagMap{"d": {"Chat-Friends"}},
+			}, nostr.SubscriptionOptions{})
+		}
 		if re != nil {
 			contacts, err := parseContactsListEvent(ctx, &re.Event, kr)
 			if err != nil {
@@ -342,6 +467,19 @@ func fetchNIP51ListsCmd(pool *nostr.Pool, relays []string, keys Keys, kr nostr.K
 			log.Printf("fetchNIP51Lists: got %d groups (ts=%d)", len(groups), re.CreatedAt)
 		}
 
+		// Kind 3 (NIP-02 follow list, standard replaceable)
+		re = queryReplaceable(ctx, pool, relays, nostr.Filter{
+			Kinds:   []nostr.Kind{nostr.KindFollowList},
+			Authors: []nostr.PubKey{keys.PK},
+		}, max(wait, followListWait))
+		if re != nil {
+			follows := parseFollowListEvent(&re.Event)
+			result.follows = follows
+			result.followsTS = re.CreatedAt
+			result.followsRaw = re.Content
+			log.Printf("fetchNIP51Lists: got %d follows (ts=%d)", len(follows), re.CreatedAt)
+		}
+
 		return result
 	}
 }
@@ -358,6 +496,7 @@ func publishContactsListCmd(pool *nostr.Pool, relays []string, contacts []Contac
 		}
 
 		defer cancel()
+		logEventOut("", evt)
 		drainPublish(ctx, pool.PublishMany(ctx, relays, evt))
 		log.Printf("publishContactsList: published kind %d with %d contacts", nostr.KindCategorizedPeopleList, len(contacts))
 		return nip51PublishResultMsg{listKind: nostr.KindCategorizedPeopleList}
@@ -376,6 +515,7 @@ func publishPublicChatsListCmd(pool *nostr.Pool, relays []string, channels []Cha
 		}
 
 		defer cancel()
+		logEventOut("", evt)
 		drainPublish(ctx, pool.PublishMany(ctx, relays, evt))
 		log.Printf("publishPublicChatsList: published kind %d with %d channels", nostr.KindPublicChatList, len(channels))
 		return nip51PublishResultMsg{listKind: nostr.KindPublicChatList}
@@ -394,22 +534,79 @@ func publishSimpleGroupsListCmd(pool *nostr.Pool, relays []string, groups []Grou
 		}
 
 		defer cancel()
+		logEventOut("", evt)
 		drainPublish(ctx, pool.PublishMany(ctx, relays, evt))
 		log.Printf("publishSimpleGroupsList: published kind %d with %d groups", nostr.KindSimpleGroupList, len(groups))
 		return nip51PublishResultMsg{listKind: nostr.KindSimpleGroupList}
 	}
 }
 
-// getPeerRelays fetches the NIP-65 relay list (kind 10002) for a pubkey
-// and returns the write relay URLs. Falls back to nil if not found.
-func getPeerRelays(pool *nostr.Pool, relays []string, pubkey nostr.PubKey) []string {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// publishFollowListCmd builds and publishes a kind 3 follow list event.
+func publishFollowListCmd(pool *nostr.Pool, relays []string, follows []Follow, content string, keys Keys) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+		evt, err := buildFollowListEvent(follows, content, keys)
+		if err != nil {
+			cancel()
+			return nip51PublishResultMsg{listKind: nostr.KindFollowList, err: err}
+		}
+
+		defer cancel()
+		logEventOut("", evt)
+		drainPublish(ctx, pool.PublishMany(ctx, relays, evt))
+		log.Printf("publishFollowList: published kind %d with %d follows", nostr.KindFollowList, len(follows))
+		return nip51PublishResultMsg{listKind: nostr.KindFollowList}
+	}
+}
+
+// queryReplaceable fetches a replaceable event (kind 0, 10002, 39000, ...).
+// With wait <= 0 the first relay to answer wins. Otherwise answers are
+// collected until every relay is done or wait has passed since the first
+// one, and the newest by created_at is returned, so a stale copy on a fast
+// relay can't shadow a newer one on a slow relay.
+func queryReplaceable(ctx context.Context, pool *nostr.Pool, relays []string, filter nostr.Filter, wait time.Duration) *nostr.RelayEvent {
+	if wait <= 0 {
+		return pool.QuerySingle(ctx, relays, filter, nostr.SubscriptionOptions{})
+	}
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
+	filter.Limit = 1
+	return collectNewest(ctx, pool.FetchMany(ctx, relays, filter, nostr.SubscriptionOptions{}), wait)
+}
+
+// collectNewest reads events until ch closes, ctx ends, or wait has passed
+// since the first event, and returns the one with the highest created_at.
+func collectNewest(ctx context.Context, ch <-chan nostr.RelayEvent, wait time.Duration) *nostr.RelayEvent {
+	var best *nostr.RelayEvent
+	var deadline <-chan time.Time
+	for {
+		select {
+		case re, ok := <-ch:
+			if !ok {
+				return best
+			}
+			if best == nil {
+				deadline = time.After(wait)
+			}
+			if best == nil || re.CreatedAt > best.CreatedAt {
+				best = &re
+			}
+		case <-deadline:
+			return best
+		case <-ctx.Done():
+			return best
+		}
+	}
+}
 
-	re := pool.QuerySingle(ctx, relays, nostr.Filter{
+// getPeerRelays fetches the NIP-65 relay list (kind 10002) for a pubkey
+// and returns the write relay URLs. Falls back to nil if not found.
+func getPeerRelays(pool *nostr.Pool, limit *queryLimiter, relays []string, pubkey nostr.PubKey, wait time.Duration) []string {
+	re := queryReplaceableLimited(limit, "relay list "+shortPK(pubkey.Hex()), pool, relays, nostr.Filter{
 		Kinds:   []nostr.Kind{nostr.KindRelayListMetadata},
 		Authors: []nostr.PubKey{pubkey},
-	}, nostr.SubscriptionOptions{})
+	}, wait, 5*time.Second)
 	if re == nil {
 		return nil
 	}
@@ -5,23 +5,73 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"slices"
+	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/nip19"
 )
 
 // Channel represents a NIP-28 channel (kind 40 creation event).
 type Channel struct {
-	ID   string
-	Name string
+	ID     string
+	Name   string
+	Relays []string // relay hints (e.g. from the nevent it was joined by), used besides the global relays
+}
+
+// RelaysWith returns global followed by the channel's relay hints that
+// aren't in it already.
+func (c Channel) RelaysWith(global []string) []string {
+	if len(c.Relays) == 0 {
+		return global
+	}
+	return mergeRelayHints(global, c.Relays)
+}
+
+// mergeRelayHints returns hints followed by the relays of more that it
+// doesn't contain yet.
+func mergeRelayHints(hints, more []string) []string {
+	merged := slices.Clone(hints)
+	for _, r := range more {
+		if !slices.Contains(merged, r) {
+			merged = append(merged, r)
+		}
+	}
+	return merged
+}
+
+// parseChannelRef parses the channel argument of /join: a hex event ID, or
+// a nevent (whose relay hints are returned) or note of the kind-40 event.
+func parseChannelRef(arg string) (id string, hints []string, err error) {
+	if !strings.HasPrefix(arg, "nevent1") && !strings.HasPrefix(arg, "note1") {
+		return arg, nil, nil
+	}
+	prefix, data, err := nip19.Decode(arg)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid %s: %w", strings.SplitN(arg, "1", 2)[0], err)
+	}
+	ptr, ok := data.(nostr.EventPointer)
+	if !ok {
+		return "", nil, fmt.Errorf("not an event reference: %s", prefix)
+	}
+	for _, r := range ptr.Relays {
+		if r = normalizeRelayURL(r); r != "" && !slices.Contains(hints, r) {
+			hints = append(hints, r)
+		}
+	}
+	return ptr.ID.Hex(), hints, nil
 }
 
 // Bubbletea message types for NIP-28 channel events.
 type channelEventMsg ChatMessage
 
 // Subscription-ended message — triggers reconnection.
-type channelSubEndedMsg struct{ channelID string }
+type channelSubEndedMsg struct {
+	channelID string
+	events    <-chan nostr.RelayEvent // the ended subscription, to detect stale messages
+}
 
 // Reconnection delay message — dispatched after a brief pause.
 type channelReconnectMsg struct{ channelID string }
@@ -32,12 +82,14 @@ type channelSubStartedMsg struct {
 	channelID string
 	events    <-chan nostr.RelayEvent
 	cancel    context.CancelFunc
+	batch     string // combined subscription this room is part of; empty if dedicated
 }
 
 // channelMetaMsg is returned after fetching a kind-40 event to resolve channel metadata.
 type channelMetaMsg struct {
-	ID   string
-	Name string
+	ID       string
+	Name     string
+	NotFound bool // no relay returned the kind-40 event; worth retrying
 }
 
 // channelCreatedMsg is returned after publishing a kind-40 channel creation event.
@@ -47,9 +99,10 @@ type channelCreatedMsg struct {
 }
 
 // fetchChannelMetaCmd fetches a kind-40 event by ID to resolve the channel name.
-func fetchChannelMetaCmd(pool *nostr.Pool, relays []string, eventID string) tea.Cmd {
+func fetchChannelMetaCmd(pool *nostr.Pool, limit *queryLimiter, relays []string, eventID string) tea.Cmd {
 	return func() tea.Msg {
 		log.Printf("fetchChannelMeta: id=%s", eventID)
+		defer limit.acquire("channel " + shortPK(eventID))()
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
@@ -65,7 +118,7 @@ func fetchChannelMetaCmd(pool *nostr.Pool, relays []string, eventID string) tea.
 		}, nostr.SubscriptionOptions{})
 		if re == nil {
 			log.Printf("fetchChannelMeta: not found for %s", eventID)
-			return channelMetaMsg{ID: eventID, Name: shortPK(eventID)}
+			return channelMetaMsg{ID: eventID, Name: shortPK(eventID), NotFound: true}
 		}
 
 		name := parseChannelMeta(re.Content)
@@ -112,6 +165,7 @@ func createChannelCmd(pool *nostr.Pool, relays []string, name string, keys Keys)
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		go func() {
 			defer cancel()
+			logEventOut("", evt)
 			drainPublish(ctx, pool.PublishMany(ctx, relays, evt))
 		}()
 
@@ -122,15 +176,26 @@ func createChannelCmd(pool *nostr.Pool, relays []string, name string, keys Keys)
 }
 
 // subscribeChannelCmd opens a channel subscription inside a tea.Cmd so it doesn't block Init/Update.
-func subscribeChannelCmd(pool *nostr.Pool, relays []string, channelID string) tea.Cmd {
+func subscribeChannelCmd(pool *nostr.Pool, relays []string, channelID string, cfg Config, sf subFilter, closed chan<- subClosedMsg) tea.Cmd {
 	return func() tea.Msg {
-		log.Printf("subscribeChannelCmd: channelID=%s", channelID)
+		log.Printf("subscribeChannelCmd: channelID=%s filter=%s", channelID, sf)
 		ctx, cancel := context.WithCancel(context.Background())
-		ch := pool.SubscribeMany(ctx, relays, nostr.Filter{
-			Kinds: []nostr.Kind{nostr.KindChannelMessage},
-			Tags:  nostr.TagMap{"e": {channelID}},
-			Limit: 50,
-		}, nostr.SubscriptionOptions{})
+		// One filter per relay so [relay_history] overrides apply per relay.
+		var dfs []nostr.DirectedFilter
+		for _, url := range relays {
+			rf := sf.forRelay(cfg, url)
+			tags := nostr.TagMap{"e": {channelID}}
+			for _, f := range []nostr.Filter{
+				rf.apply(nostr.Filter{Kinds: []nostr.Kind{nostr.KindChannelMessage, nostr.KindGenericRepost}, Tags: tags}),
+				reactionFilter(rf, tags),
+			} {
+				if len(f.Kinds) > 0 {
+					dfs = append(dfs, nostr.DirectedFilter{Relay: url, Filter: f})
+				}
+			}
+		}
+		ch, closedBy := pool.BatchedSubscribeManyNotifyClosed(ctx, dfs, nostr.SubscriptionOptions{})
+		go forwardClosed(ctx, closedBy, []string{channelID}, closed)
 		return channelSubStartedMsg{channelID: channelID, events: ch, cancel: cancel}
 	}
 }
@@ -146,30 +211,43 @@ func channelReconnectDelayCmd(channelID string) tea.Cmd {
 // waitForChannelEvent blocks on the subscription channel and returns the next event.
 func waitForChannelEvent(events <-chan nostr.RelayEvent, channelID string, keys Keys) tea.Cmd {
 	return func() tea.Msg {
-		re, ok := <-events
-		if !ok {
-			return channelSubEndedMsg{channelID: channelID}
+		for {
+			re, ok := <-events
+			if !ok {
+				return channelSubEndedMsg{channelID: channelID, events: events}
+			}
+			logEventIn(channelID, re)
+			if re.Kind == nostr.KindReaction {
+				if target, emoji, ok := parseReaction(re.Event, channelID); ok {
+					return reactionMsg{roomKey: channelID, reactionID: re.ID.Hex(), targetID: target, emoji: emoji}
+				}
+				continue
+			}
+			cm := ChatMessage{
+				Author:    shortPK(re.PubKey.Hex()),
+				PubKey:    re.PubKey.Hex(),
+				Content:   re.Content,
+				Timestamp: re.CreatedAt,
+				EventID:   re.ID.Hex(),
+				ChannelID: channelID,
+				IsMine:    re.PubKey == keys.PK,
+				Relay:     relayURLOf(re),
+			}
+			applyRepost(&cm, re.Event)
+			applyContentWarning(&cm, re.Event)
+			return channelEventMsg(cm)
 		}
-		return channelEventMsg(ChatMessage{
-			Author:    shortPK(re.PubKey.Hex()),
-			PubKey:    re.PubKey.Hex(),
-			Content:   re.Content,
-			Timestamp: re.CreatedAt,
-			EventID:   re.ID.Hex(),
-			ChannelID: channelID,
-			IsMine:    re.PubKey == keys.PK,
-		})
 	}
 }
 
 // buildChannelMessageEvent builds a kind-42 message event for a NIP-28 channel.
-func buildChannelMessageEvent(channelID, content string, keys Keys) (nostr.Event, error) {
+func buildChannelMessageEvent(channelID, content string, extra nostr.Tags, keys Keys) (nostr.Event, error) {
 	evt := nostr.Event{
 		Kind:      nostr.KindChannelMessage,
 		CreatedAt: nostr.Now(),
-		Tags: nostr.Tags{
+		Tags: append(nostr.Tags{
 			{"e", channelID, "", "root"},
-		},
+		}, extra...),
 		Content: content,
 	}
 	if err := evt.Sign(keys.SK); err != nil {
@@ -179,30 +257,31 @@ func buildChannelMessageEvent(channelID, content string, keys Keys) (nostr.Event
 }
 
 // publishChannelMessage signs and publishes a kind-42 message to a channel.
-// Returns a channelEventMsg with the local message so it appears immediately.
-func publishChannelMessage(pool *nostr.Pool, relays []string, channelID string, content string, keys Keys) tea.Cmd {
-	return func() tea.Msg {
-		evt, err := buildChannelMessageEvent(channelID, content, keys)
-		if err != nil {
-			return nostrErrMsg{err}
-		}
-
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		go func() {
-			defer cancel()
-			drainPublish(ctx, pool.PublishMany(ctx, relays, evt))
-		}()
+// The local echo is returned immediately as a channelEventMsg so it appears
+// without waiting for relays; per-relay outcomes follow as a deliveryReportMsg.
+func publishChannelMessage(pool *nostr.Pool, relays []string, channelID string, content string, extra nostr.Tags, keys Keys) tea.Cmd {
+	evt, err := buildChannelMessageEvent(channelID, content, extra, keys)
+	if err != nil {
+		return func() tea.Msg { return nostrErrMsg{err} }
+	}
+	eventID := evt.GetID().Hex()
 
-		return channelEventMsg(ChatMessage{
+	echo := func() tea.Msg {
+		cm := ChatMessage{
 			Author:    shortPK(keys.PK.Hex()),
 			PubKey:    keys.PK.Hex(),
 			Content:   content,
 			Timestamp: evt.CreatedAt,
-			EventID:   evt.GetID().Hex(),
+			EventID:   eventID,
 			ChannelID: channelID,
 			IsMine:    true,
-		})
+			SentEvent: &evt,
+		}
+		applyContentWarning(&cm, evt)
+		return channelEventMsg(cm)
 	}
+
+	return tea.Batch(echo, publishEventCmd(pool, relays, channelID, evt))
 }
 
 // parseChannelMeta extracts a channel name from a kind-40 channel JSON content string.
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/nip19"
+)
+
+func TestParseChannelRef(t *testing.T) {
+	id := nostr.Generate().Public().Hex() // any 32-byte hex
+	eid, _ := nostr.IDFromHex(id)
+
+	got, hints, err := parseChannelRef(id)
+	if err != nil || got != id || hints != nil {
+		t.Errorf("hex: got %q %v %v", got, hints, err)
+	}
+
+	nevent := nip19.EncodeNevent(eid, []string{"wss://a.example.com/", "WSS://a.example.com", "wss://b.example.com"}, nostr.PubKey{})
+	got, hints, err = parseChannelRef(nevent)
+	if err != nil || got != id {
+		t.Fatalf("nevent: got %q %v", got, err)
+	}
+	if want := []string{"wss://a.example.com", "wss://b.example.com"}; !slices.Equal(hints, want) {
+		t.Errorf("hints = %v, want %v", hints, want)
+	}
+
+	if _, _, err := parseChannelRef("nevent1garbage"); err == nil {
+		t.Error("expected an error for an invalid nevent")
+	}
+}
+
+func TestChannelRelaysWith(t *testing.T) {
+	global := []string{"wss://a", "wss://b"}
+	if got := (Channel{}).RelaysWith(global); !slices.Equal(got, global) {
+		t.Errorf("no hints: got %v", got)
+	}
+	ch := Channel{Relays: []string{"wss://b", "wss://c"}}
+	if got, want := ch.RelaysWith(global), []string{"wss://a", "wss://b", "wss://c"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if !slices.Equal(global, []string{"wss://a", "wss://b"}) {
+		t.Errorf("global modified: %v", global)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,12 +22,17 @@ type dmEventMsg ChatMessage
 // message is shown in the correct DM conversation, not whatever room
 // happens to be active when the async send fails.
 type dmSendErrMsg struct {
-	peerPK string
-	err    error
+	peerPK  string // peer pubkey, or the group DM key
+	err     error
+	content string   // the unsent message, kept as a failed message for /retry
+	members []string // group DM members; nil for 1:1 DMs
+	expires nostr.Timestamp
 }
 
 // Subscription-ended message — triggers reconnection.
-type dmSubEndedMsg struct{}
+type dmSubEndedMsg struct {
+	events <-chan nostr.Event // the ended subscription, to detect stale messages
+}
 
 // Reconnection delay message — dispatched after a brief pause.
 type dmReconnectMsg struct{}
@@ -49,7 +55,7 @@ func dmReconnectDelayCmd() tea.Cmd {
 // subscribeDMCmd opens a NIP-17 DM listener inside a tea.Cmd so it doesn't block Init/Update.
 // NIP-42 auth is handled by the pool's AuthRequiredHandler; we pre-connect to each relay
 // and wait briefly so the AUTH handshake completes before subscribing.
-func subscribeDMCmd(pool *nostr.Pool, relays []string, kr nostr.Keyer, since nostr.Timestamp) tea.Cmd {
+func subscribeDMCmd(pool *nostr.Pool, relays []string, kr nostr.Keyer, since nostr.Timestamp, lookback time.Duration) tea.Cmd {
 	return func() tea.Msg {
 		ctx, cancel := context.WithCancel(context.Background())
 		pk, err := kr.GetPublicKey(ctx)
@@ -58,13 +64,7 @@ func subscribeDMCmd(pool *nostr.Pool, relays []string, kr nostr.Keyer, since nos
 			cancel()
 			return nostrErrMsg{fmt.Errorf("subscribeDMCmd: %w", err)}
 		}
-		// NIP-59 gift wraps use randomized created_at timestamps (up to ±2 days)
-		// to thwart time-analysis attacks. Subtract 3 days from the since filter
-		// so we don't miss events whose outer timestamp is in the past.
-		adjustedSince := since - 259200 // 3 days
-		if adjustedSince < 0 {
-			adjustedSince = 0
-		}
+		adjustedSince := dmSubscribeSince(since, lookback)
 		log.Printf("subscribeDMCmd: listening for kind 1059 gift wraps to %s since %d (adjusted from %d)", shortPK(pk.Hex()), adjustedSince, since)
 
 		// Pre-authenticate with each relay via NIP-42 before subscribing.
@@ -97,18 +97,56 @@ func subscribeDMCmd(pool *nostr.Pool, relays []string, kr nostr.Keyer, since nos
 	}
 }
 
+// dmMaxFutureSkew is how far in the future a rumor's created_at may be
+// before it is treated as a skewed sender clock.
+const dmMaxFutureSkew = 10 * time.Minute
+
+// dmSubscribeSince returns the since filter for the gift wrap subscription.
+// NIP-59 gift wraps use randomized created_at timestamps (up to 2 days in
+// the past) to thwart time-analysis attacks, so the filter starts lookback
+// before the newest DM seen to not miss wraps dated before it.
+func dmSubscribeSince(lastSeen nostr.Timestamp, lookback time.Duration) nostr.Timestamp {
+	return max(lastSeen-nostr.Timestamp(lookback/time.Second), 0)
+}
+
+// dmTimestamp returns the time to order and show a DM by: the rumor's own
+// created_at (the wrap's is randomized), or now when the rumor claims to be
+// from the future, so a skewed sender clock can't pin it below newer
+// messages or move lastDMSeen ahead.
+func dmTimestamp(rumor nostr.Event, now nostr.Timestamp) nostr.Timestamp {
+	if rumor.CreatedAt > now+nostr.Timestamp(dmMaxFutureSkew/time.Second) {
+		return now
+	}
+	return rumor.CreatedAt
+}
+
 // waitForDMEvent blocks on the NIP-17 DM channel and returns the next decrypted rumor.
 func waitForDMEvent(events <-chan nostr.Event, keys Keys) tea.Cmd {
 	return func() tea.Msg {
 		rumor, ok := <-events
 		if !ok {
-			return dmSubEndedMsg{}
+			return dmSubEndedMsg{events: events}
+		}
+		for rumor.Kind == kindDMReadReceipt {
+			logRumorIn(rumor.PubKey.Hex(), rumor)
+			if seen, ok := parseReadReceipt(rumor); ok && rumor.PubKey != keys.PK {
+				return dmReceiptMsg{peer: rumor.PubKey.Hex(), seen: seen}
+			}
+			if rumor, ok = <-events; !ok {
+				return dmSubEndedMsg{events: events}
+			}
 		}
 
 		// rumor.PubKey = sender, rumor.Content = plaintext (already decrypted by nip17)
+		// With more than one other participant it is a group DM: keep the
+		// sender as PubKey and list the members.
+		members := dmParticipants(rumor, keys.PK)
+		if len(members) < 2 {
+			members = nil
+		}
 		// Determine peer: if sender is us, look at "p" tag for recipient
 		peer := rumor.PubKey.Hex()
-		if rumor.PubKey == keys.PK {
+		if rumor.PubKey == keys.PK && members == nil {
 			for _, tag := range rumor.Tags {
 				if len(tag) >= 2 && tag[0] == "p" {
 					peer = tag[1]
@@ -124,27 +162,34 @@ func waitForDMEvent(events <-chan nostr.Event, keys Keys) tea.Cmd {
 			eventID = hex.EncodeToString(h[:])
 		}
 
-		return dmEventMsg(ChatMessage{
+		cm := ChatMessage{
 			Author:    shortPK(rumor.PubKey.Hex()),
 			PubKey:    peer,
 			Content:   rumor.Content,
-			Timestamp: rumor.CreatedAt,
+			Timestamp: dmTimestamp(rumor, nostr.Now()),
 			EventID:   eventID,
 			IsMine:    rumor.PubKey == keys.PK,
-		})
+			DMMembers: members,
+		}
+		applyContentWarning(&cm, rumor)
+		cm.Expires = expirationOf(rumor.Tags)
+		logRumorIn(dmRoomKey(cm), rumor)
+		return dmEventMsg(cm)
 	}
 }
 
-// sendDM publishes a NIP-17 gift-wrapped DM to a recipient.
+// sendDM publishes a NIP-17 gift-wrapped DM to a recipient, with extra tags
+// (e.g. a NIP-40 expiration) added to the rumor.
 // Returns a dmEventMsg with the plaintext so it appears locally.
-func sendDM(pool *nostr.Pool, relays []string, recipientPK string, content string, keys Keys, kr nostr.Keyer) tea.Cmd {
+func sendDM(pool *nostr.Pool, relays []string, recipientPK string, content string, extra nostr.Tags, keys Keys, kr nostr.Keyer) tea.Cmd {
+	expires := expirationOf(extra)
 	return func() tea.Msg {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
 		recipient, err := nostr.PubKeyFromHex(recipientPK)
 		if err != nil {
-			return dmSendErrMsg{peerPK: recipientPK, err: fmt.Errorf("send DM: invalid recipient pubkey: %w", err)}
+			return dmSendErrMsg{peerPK: recipientPK, content: content, expires: expires, err: fmt.Errorf("send DM: invalid recipient pubkey: %w", err)}
 		}
 
 		theirRelays := nip17.GetDMRelays(ctx, recipient, pool, relays)
@@ -152,24 +197,90 @@ func sendDM(pool *nostr.Pool, relays []string, recipientPK string, content strin
 			theirRelays = relays // fallback to our relays
 		}
 
-		err = nip17.PublishMessage(ctx, content, nil, pool, relays, theirRelays, kr, recipient, nil)
+		// Taken before the rumor is built so the echo is never newer than
+		// the rumor the peer sees (read receipts compare timestamps).
+		ts := nostr.Now()
+		deliveries, err := publishDM(ctx, pool, content, extra, relays, theirRelays, kr, recipient)
 		if err != nil {
-			return dmSendErrMsg{peerPK: recipientPK, err: fmt.Errorf("send DM: %w", err)}
+			return dmSendErrMsg{peerPK: recipientPK, content: content, expires: expires, err: fmt.Errorf("send DM: %w", err)}
 		}
 
-		ts := nostr.Now()
 		h := sha256.Sum256([]byte(fmt.Sprintf("local:%s:%s:%d:%s", keys.PK.Hex(), recipientPK, ts, content)))
 		return dmEventMsg(ChatMessage{
-			Author:    shortPK(keys.PK.Hex()),
-			PubKey:    recipientPK,
-			Content:   content,
-			Timestamp: ts,
-			EventID:   hex.EncodeToString(h[:]),
-			IsMine:    true,
+			Author:     shortPK(keys.PK.Hex()),
+			PubKey:     recipientPK,
+			Content:    content,
+			Timestamp:  ts,
+			EventID:    hex.EncodeToString(h[:]),
+			IsMine:     true,
+			Deliveries: deliveries,
+			Expires:    expires,
 		})
 	}
 }
 
+// publishDM gift-wraps a NIP-17 message and publishes our copy to ourRelays
+// and the recipient's copy to theirRelays, like nip17.PublishMessage, but
+// records each recipient relay's outcome instead of discarding it. tags are
+// added to the rumor; a NIP-40 expiration among them is also put on the
+// gift wraps, the only part relays can read. A nil ourRelays skips our copy.
+func publishDM(ctx context.Context, pool *nostr.Pool, content string, tags nostr.Tags, ourRelays, theirRelays []string, kr nostr.Keyer, recipient nostr.PubKey) (map[string]string, error) {
+	var wrap func(*nostr.Event)
+	if exp := tags.Find("expiration"); exp != nil {
+		wrap = func(gw *nostr.Event) { gw.Tags = append(gw.Tags, exp) }
+	}
+	toUs, toThem, err := nip17.PrepareMessage(ctx, content, tags, kr, recipient, wrap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare message: %w", err)
+	}
+	logEventOut(recipient.Hex(), toUs)
+	logEventOut(recipient.Hex(), toThem)
+
+	// Our own copy, so the message shows up on our other devices.
+	sentToUs := false
+	for _, url := range ourRelays {
+		if err := publishWithAuth(ctx, pool, kr, url, toUs); err != nil {
+			log.Printf("publishDM: self copy to %s failed: %v", url, err)
+			continue
+		}
+		sentToUs = true
+	}
+	if !sentToUs && ourRelays != nil {
+		return nil, fmt.Errorf("failed to send event to ourselves in any of %v", ourRelays)
+	}
+
+	deliveries := make(map[string]string, len(theirRelays))
+	sentToThem := false
+	for _, url := range theirRelays {
+		if err := publishWithAuth(ctx, pool, kr, url, toThem); err != nil {
+			deliveries[url] = err.Error()
+			continue
+		}
+		deliveries[url] = "ok"
+		sentToThem = true
+	}
+	if !sentToThem {
+		return deliveries, fmt.Errorf("failed to send event to them in any of %v", theirRelays)
+	}
+	return deliveries, nil
+}
+
+// publishWithAuth publishes a gift wrap to url, authenticating (NIP-42) and
+// retrying once if the relay requires it, as DM relays often do.
+func publishWithAuth(ctx context.Context, pool *nostr.Pool, kr nostr.Keyer, url string, evt nostr.Event) error {
+	r, err := pool.EnsureRelay(url)
+	if err != nil {
+		return err
+	}
+	err = r.Publish(ctx, evt)
+	if err != nil && strings.HasPrefix(err.Error(), "auth-required:") {
+		if authErr := r.Auth(ctx, kr.SignEvent); authErr == nil {
+			err = r.Publish(ctx, evt)
+		}
+	}
+	return err
+}
+
 // buildDMRelaysEvent builds a kind-10050 event (NIP-17 DM relay list).
 func buildDMRelaysEvent(relays []string, keys Keys) (nostr.Event, error) {
 	var tags nostr.Tags
@@ -204,6 +315,7 @@ func publishDMRelaysCmd(pool *nostr.Pool, relays []string, keys Keys) tea.Cmd {
 
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
+		logEventOut("", evt)
 		drainPublish(ctx, pool.PublishMany(ctx, relays, evt))
 		log.Printf("publishDMRelays: published kind 10050 with %d relays", len(relays))
 		return dmRelaysPublishedMsg{}
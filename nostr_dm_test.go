@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/keyer"
+	"fiatjaf.com/nostr/nip59"
+)
+
+func TestDMSubscribeSince(t *testing.T) {
+	if got := dmSubscribeSince(1_000_000, 72*time.Hour); got != 1_000_000-259200 {
+		t.Errorf("got %d, want 3 days earlier", got)
+	}
+	if got := dmSubscribeSince(1000, 72*time.Hour); got != 0 {
+		t.Errorf("got %d, want 0", got)
+	}
+	if got := dmSubscribeSince(1000, 0); got != 1000 {
+		t.Errorf("got %d, want 1000", got)
+	}
+}
+
+// wrapAndDeliver gift-wraps rumor to recipient with the outer created_at set
+// to wrapTime, unwraps it, and returns what waitForDMEvent makes of it.
+func wrapAndDeliver(t *testing.T, rumor nostr.Event, sender nostr.Keyer, recipient Keys, wrapTime nostr.Timestamp) ChatMessage {
+	t.Helper()
+	ctx := context.Background()
+	rumor.ID = rumor.GetID()
+	wrap, err := nip59.GiftWrap(rumor, recipient.PK,
+		func(s string) (string, error) { return sender.Encrypt(ctx, s, recipient.PK) },
+		func(e *nostr.Event) error { return sender.SignEvent(ctx, e) },
+		func(e *nostr.Event) { e.CreatedAt = wrapTime },
+	)
+	if err != nil {
+		t.Fatalf("GiftWrap: %v", err)
+	}
+	if wrap.CreatedAt != wrapTime {
+		t.Fatalf("wrap created_at = %d, want %d", wrap.CreatedAt, wrapTime)
+	}
+	rkr := keyer.NewPlainKeySigner(recipient.SK)
+	unwrapped, err := nip59.GiftUnwrap(wrap, func(pk nostr.PubKey, c string) (string, error) { return rkr.Decrypt(ctx, c, pk) })
+	if err != nil {
+		t.Fatalf("GiftUnwrap: %v", err)
+	}
+	events := make(chan nostr.Event, 1)
+	events <- unwrapped
+	msg, ok := waitForDMEvent(events, recipient)().(dmEventMsg)
+	if !ok {
+		t.Fatal("expected a dmEventMsg")
+	}
+	return ChatMessage(msg)
+}
+
+func TestDMTimestampIgnoresWrapTime(t *testing.T) {
+	senderSK := nostr.Generate()
+	sender := keyer.NewPlainKeySigner(senderSK)
+	rsk := nostr.Generate()
+	recipient := Keys{SK: rsk, PK: rsk.Public()}
+
+	now := nostr.Now()
+	for name, tc := range map[string]struct {
+		rumorTime, wrapTime, want nostr.Timestamp
+	}{
+		"past-dated wrap":   {now - 60, now - 2*86400, now - 60},
+		"future-dated wrap": {now - 60, now + 86400, now - 60},
+		"old message":       {now - 10*86400, now - 11*86400, now - 10*86400},
+	} {
+		rumor := nostr.Event{
+			Kind:      14,
+			PubKey:    senderSK.Public(),
+			CreatedAt: tc.rumorTime,
+			Tags:      nostr.Tags{{"p", recipient.PK.Hex()}},
+			Content:   "hi",
+		}
+		if got := wrapAndDeliver(t, rumor, sender, recipient, tc.wrapTime); got.Timestamp != tc.want {
+			t.Errorf("%s: timestamp = %d, want the rumor's %d", name, got.Timestamp, tc.want)
+		}
+	}
+}
+
+func TestDMTimestampClampsFutureRumor(t *testing.T) {
+	now := nostr.Timestamp(1_000_000)
+	if got := dmTimestamp(nostr.Event{CreatedAt: now + 60}, now); got != now+60 {
+		t.Errorf("small skew: got %d, want the rumor time", got)
+	}
+	if got := dmTimestamp(nostr.Event{CreatedAt: now + 86400}, now); got != now {
+		t.Errorf("a day ahead: got %d, want now", got)
+	}
+	if got := dmTimestamp(nostr.Event{CreatedAt: now - 86400}, now); got != now-86400 {
+		t.Errorf("past: got %d, want the rumor time", got)
+	}
+}
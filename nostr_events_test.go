@@ -69,7 +69,7 @@ func TestBuildChannelMessageEvent(t *testing.T) {
 	channelID := "abc123def456abc123def456abc123def456abc123def456abc123def456abcd"
 	content := "hello world"
 
-	evt, err := buildChannelMessageEvent(channelID, content, keys)
+	evt, err := buildChannelMessageEvent(channelID, content, nil, keys)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -105,6 +105,7 @@ func TestBuildProfileEvent(t *testing.T) {
 		DisplayName: "Alice Wonderland",
 		About:       "Down the rabbit hole",
 		Picture:     "https://example.com/alice.png",
+		NIP05:       "alice@example.com",
 	}
 
 	evt, err := buildProfileEvent(profile, keys)
@@ -133,6 +134,9 @@ func TestBuildProfileEvent(t *testing.T) {
 	if meta["picture"] != "https://example.com/alice.png" {
 		t.Errorf("picture = %q, want %q", meta["picture"], "https://example.com/alice.png")
 	}
+	if meta["nip05"] != "alice@example.com" {
+		t.Errorf("nip05 = %q, want %q", meta["nip05"], "alice@example.com")
+	}
 
 	if !evt.VerifySignature() {
 		t.Error("invalid signature")
@@ -199,7 +203,7 @@ func TestBuildGroupMessageEvent(t *testing.T) {
 	content := "hello group"
 	previousIDs := []string{"aaa111", "bbb222"}
 
-	evt, err := buildGroupMessageEvent(groupID, content, previousIDs, keys)
+	evt, err := buildGroupMessageEvent(groupID, content, nil, previousIDs, keys)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -348,6 +352,53 @@ func TestBuildPutUserEvent(t *testing.T) {
 	}
 }
 
+func TestBuildRemoveUserEvent(t *testing.T) {
+	keys := testKeys(t)
+	userPK := "aaaa1111bbbb2222cccc3333dddd4444aaaa1111bbbb2222cccc3333dddd4444"
+	evt, err := buildRemoveUserEvent("grp1", userPK, nil, keys)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if evt.Kind != nostr.KindSimpleGroupRemoveUser {
+		t.Errorf("Kind = %d, want %d", evt.Kind, nostr.KindSimpleGroupRemoveUser)
+	}
+	if !hasTag(evt, "h", "grp1") {
+		t.Error("missing [\"h\", \"grp1\"] tag")
+	}
+	if !hasTag(evt, "p", userPK) {
+		t.Errorf("missing [\"p\", %q] tag", userPK)
+	}
+
+	if !evt.VerifySignature() {
+		t.Error("invalid signature")
+	}
+}
+
+func TestParseGroupAdminRoles(t *testing.T) {
+	me := "aaaa1111bbbb2222cccc3333dddd4444aaaa1111bbbb2222cccc3333dddd4444"
+	other := "bbbb1111bbbb2222cccc3333dddd4444aaaa1111bbbb2222cccc3333dddd4444"
+	evt := &nostr.Event{
+		Kind: nostr.KindSimpleGroupAdmins,
+		Tags: nostr.Tags{{"d", "grp1"}, {"p", other, "moderator"}, {"p", me, "admin", "owner"}},
+	}
+	roles := parseGroupAdminRoles(evt, me)
+	if len(roles) != 2 || roles[0] != "admin" || roles[1] != "owner" {
+		t.Errorf("roles = %v, want [admin owner]", roles)
+	}
+
+	evt.Tags = nostr.Tags{{"d", "grp1"}, {"p", other, "admin"}}
+	if roles := parseGroupAdminRoles(evt, me); roles != nil {
+		t.Errorf("expected nil roles when not listed, got %v", roles)
+	}
+
+	// A bare p tag without role names still marks us as an admin.
+	evt.Tags = nostr.Tags{{"p", me}}
+	if roles := parseGroupAdminRoles(evt, me); len(roles) != 1 {
+		t.Errorf("expected a default role for bare p tag, got %v", roles)
+	}
+}
+
 func TestBuildEditGroupMetadataEvent(t *testing.T) {
 	keys := testKeys(t)
 
@@ -396,7 +447,7 @@ func TestBuildEditGroupMetadataEvent(t *testing.T) {
 
 func TestBuildCreateGroupInviteEvent(t *testing.T) {
 	keys := testKeys(t)
-	evt, err := buildCreateGroupInviteEvent("grp1", []string{"prev1"}, keys)
+	evt, err := buildCreateGroupInviteEvent("grp1", "c0de", []string{"prev1"}, keys)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -407,6 +458,9 @@ func TestBuildCreateGroupInviteEvent(t *testing.T) {
 	if !hasTag(evt, "h", "grp1") {
 		t.Error("missing [\"h\", \"grp1\"] tag")
 	}
+	if !hasTag(evt, "code", "c0de") {
+		t.Error("missing [\"code\", \"c0de\"] tag")
+	}
 
 	if !evt.VerifySignature() {
 		t.Error("invalid signature")
@@ -450,12 +504,12 @@ func TestEventBuildersPubKeyConsistency(t *testing.T) {
 		build func() (nostr.Event, error)
 	}{
 		{"CreateChannel", func() (nostr.Event, error) { return buildCreateChannelEvent("ch", keys) }},
-		{"ChannelMessage", func() (nostr.Event, error) { return buildChannelMessageEvent("ch", "hi", keys) }},
+		{"ChannelMessage", func() (nostr.Event, error) { return buildChannelMessageEvent("ch", "hi", nil, keys) }},
 		{"Profile", func() (nostr.Event, error) {
 			return buildProfileEvent(ProfileConfig{Name: "test"}, keys)
 		}},
 		{"DMRelays", func() (nostr.Event, error) { return buildDMRelaysEvent([]string{"wss://r"}, keys) }},
-		{"GroupMessage", func() (nostr.Event, error) { return buildGroupMessageEvent("g", "hi", nil, keys) }},
+		{"GroupMessage", func() (nostr.Event, error) { return buildGroupMessageEvent("g", "hi", nil, nil, keys) }},
 		{"JoinGroup", func() (nostr.Event, error) { return buildJoinGroupEvent("g", nil, "", keys) }},
 		{"LeaveGroup", func() (nostr.Event, error) { return buildLeaveGroupEvent("g", nil, keys) }},
 		{"CreateGroup", func() (nostr.Event, error) { return buildCreateGroupEvent("gid", "name", keys) }},
@@ -464,7 +518,7 @@ func TestEventBuildersPubKeyConsistency(t *testing.T) {
 		{"EditGroupMetadata", func() (nostr.Event, error) {
 			return buildEditGroupMetadataEvent("g", map[string]string{"name": "n"}, nil, keys)
 		}},
-		{"CreateGroupInvite", func() (nostr.Event, error) { return buildCreateGroupInviteEvent("g", nil, keys) }},
+		{"CreateGroupInvite", func() (nostr.Event, error) { return buildCreateGroupInviteEvent("g", "c", nil, keys) }},
 		{"BlossomAuth", func() (nostr.Event, error) { return buildBlossomAuthEvent("hash", keys) }},
 	}
 
@@ -39,8 +39,12 @@ type groupSubStartedMsg struct {
 	groupKey string
 	events   <-chan nostr.RelayEvent
 	cancel   context.CancelFunc
+	batch    string // combined subscription this room is part of; empty if dedicated
+}
+type groupSubEndedMsg struct {
+	groupKey string
+	events   <-chan nostr.RelayEvent // the ended subscription, to detect stale messages
 }
-type groupSubEndedMsg struct{ groupKey string }
 type groupReconnectMsg struct{ groupKey string }
 type groupMetaMsg struct {
 	RelayURL    string
@@ -55,6 +59,13 @@ type groupJoinedMsg struct {
 	Name     string
 }
 
+// groupAdminsMsg carries our roles parsed from a group's kind 39001 admins list.
+type groupAdminsMsg struct {
+	RelayURL string
+	GroupID  string
+	Roles    []string // our roles; empty if we are not listed
+}
+
 // groupCreatedMsg is returned after publishing a kind 9007 group creation event.
 type groupCreatedMsg struct {
 	RelayURL string
@@ -67,45 +78,68 @@ type groupInviteCreatedMsg struct {
 	RelayURL string
 	GroupID  string
 	Code     string
+	EventID  string // the kind 9009 event, for revoking the invite
 }
 
-// subscribeGroupCmd opens a subscription on a single relay for a NIP-29 group.
-// Subscribes to both kind 9 (chat messages) and kind 39000 (metadata) using
-// two separate subscriptions merged into one channel (the new library takes
-// a single filter per SubscribeMany call).
-func subscribeGroupCmd(pool *nostr.Pool, relayURL, groupID string) tea.Cmd {
+// subscribeGroupCmd opens a subscription for a NIP-29 group on its relay
+// set (the home relay first, then mirrors).
+// Subscribes to kind 9 (chat messages), kind 39000 (metadata), and kind 39001
+// (admins) using separate subscriptions merged into one channel (the new
+// library takes a single filter per SubscribeMany call).
+func subscribeGroupCmd(pool *nostr.Pool, relays []string, groupID string, cfg Config, sf subFilter, closed chan<- subClosedMsg) tea.Cmd {
 	return func() tea.Msg {
+		relayURL := relays[0]
 		gk := groupKey(relayURL, groupID)
-		log.Printf("subscribeGroupCmd: relay=%s group=%s", relayURL, groupID)
+		rf := sf.forRelay(cfg, relayURL)
+		log.Printf("subscribeGroupCmd: relays=%v group=%s filter=%s", relays, groupID, sf)
 		ctx, cancel := context.WithCancel(context.Background())
 		merged := make(chan nostr.RelayEvent)
 
-		var wg sync.WaitGroup
-		wg.Add(2)
-
-		// Chat messages (kind 9)
-		go func() {
-			defer wg.Done()
-			for re := range pool.SubscribeMany(ctx, []string{relayURL}, nostr.Filter{
-				Kinds: []nostr.Kind{nostr.KindSimpleGroupChatMessage},
+		filters := []nostr.Filter{
+			// Chat messages (kind 9), threads, and reposts into the group
+			// (kind 16)
+			rf.apply(nostr.Filter{
+				Kinds: []nostr.Kind{nostr.KindSimpleGroupChatMessage, nostr.KindSimpleGroupThreadedReply, nostr.KindSimpleGroupThread, nostr.KindSimpleGroupReply, nostr.KindGenericRepost},
 				Tags:  nostr.TagMap{"h": {groupID}},
-				Limit: 50,
-			}, nostr.SubscriptionOptions{}) {
-				merged <- re
-			}
-		}()
-
-		// Metadata (kind 39000)
-		go func() {
-			defer wg.Done()
-			for re := range pool.SubscribeMany(ctx, []string{relayURL}, nostr.Filter{
+			}),
+			// Reactions (kind 7), with their own limit
+			reactionFilter(rf, nostr.TagMap{"h": {groupID}}),
+			// Metadata (kind 39000)
+			{
 				Kinds: []nostr.Kind{nostr.KindSimpleGroupMetadata},
 				Tags:  nostr.TagMap{"d": {groupID}},
 				Limit: 1,
-			}, nostr.SubscriptionOptions{}) {
-				merged <- re
+			},
+			// Admins and their roles (kind 39001)
+			{
+				Kinds: []nostr.Kind{nostr.KindSimpleGroupAdmins},
+				Tags:  nostr.TagMap{"d": {groupID}},
+				Limit: 1,
+			},
+		}
+
+		var wg sync.WaitGroup
+		for i, f := range filters {
+			if len(f.Kinds) == 0 || rf.excludes(f.Kinds[0]) {
+				continue
 			}
-		}()
+			// Messages and reactions come from every mirror (deduplicated
+			// by event ID downstream); metadata and admins only from the
+			// home relay, which signs them.
+			urls := relays
+			if i > 1 {
+				urls = relays[:1]
+			}
+			wg.Add(1)
+			go func(f nostr.Filter) {
+				defer wg.Done()
+				events, closedBy := pool.SubscribeManyNotifyClosed(ctx, urls, f, nostr.SubscriptionOptions{})
+				go forwardClosed(ctx, closedBy, []string{gk}, closed)
+				for re := range events {
+					merged <- re
+				}
+			}(f)
+		}
 
 		go func() {
 			wg.Wait()
@@ -123,8 +157,9 @@ func waitForGroupEvent(events <-chan nostr.RelayEvent, gk string, relayURL strin
 		for {
 			re, ok := <-events
 			if !ok {
-				return groupSubEndedMsg{groupKey: gk}
+				return groupSubEndedMsg{groupKey: gk, events: events}
 			}
+			logEventIn(gk, re)
 
 			// Handle metadata events (kind 39000) — extract group name from tags.
 			if re.Kind == nostr.KindSimpleGroupMetadata {
@@ -148,23 +183,69 @@ func waitForGroupEvent(events <-chan nostr.RelayEvent, gk string, relayURL strin
 				continue
 			}
 
-			return groupEventMsg(ChatMessage{
-				Author:    shortPK(re.PubKey.Hex()),
-				PubKey:    re.PubKey.Hex(),
-				Content:   re.Content,
-				Timestamp: re.CreatedAt,
-				EventID:   re.ID.Hex(),
-				GroupKey:  gk,
-				IsMine:    re.PubKey == keys.PK,
-			})
+			if re.Kind == nostr.KindSimpleGroupAdmins {
+				groupID := re.Tags.GetD()
+				if groupID == "" {
+					continue
+				}
+				roles := parseGroupAdminRoles(&re.Event, keys.PK.Hex())
+				log.Printf("waitForGroupEvent: got admins for group %s: our roles=%v", groupID, roles)
+				return groupAdminsMsg{RelayURL: relayURL, GroupID: groupID, Roles: roles}
+			}
+
+			if re.Kind == nostr.KindReaction {
+				if target, emoji, ok := parseReaction(re.Event, ""); ok {
+					return reactionMsg{roomKey: gk, reactionID: re.ID.Hex(), targetID: target, emoji: emoji}
+				}
+				continue
+			}
+
+			cm := groupChatMessage(re.Event, gk, keys)
+			cm.Relay = relayURLOf(re)
+			return groupEventMsg(cm)
+		}
+	}
+}
+
+// groupChatMessage converts a group chat event (kind 9, a kind 10/11/12
+// thread event, or a repost) to a ChatMessage.
+func groupChatMessage(evt nostr.Event, gk string, keys Keys) ChatMessage {
+	cm := ChatMessage{
+		Author:    shortPK(evt.PubKey.Hex()),
+		PubKey:    evt.PubKey.Hex(),
+		Content:   evt.Content,
+		Timestamp: evt.CreatedAt,
+		EventID:   evt.ID.Hex(),
+		GroupKey:  gk,
+		IsMine:    evt.PubKey == keys.PK,
+	}
+	applyRepost(&cm, evt)
+	applyThread(&cm, evt)
+	applyContentWarning(&cm, evt)
+	return cm
+}
+
+// parseGroupAdminRoles returns the roles assigned to pubkey in a kind 39001
+// admins event (["p", <pubkey>, <role>...] tags), or nil if not listed.
+func parseGroupAdminRoles(evt *nostr.Event, pubkey string) []string {
+	for _, tag := range evt.Tags {
+		if len(tag) >= 2 && tag[0] == "p" && tag[1] == pubkey {
+			roles := append([]string{}, tag[2:]...)
+			if len(roles) == 0 {
+				roles = []string{"admin"}
+			}
+			return roles
 		}
 	}
+	return nil
 }
 
 // buildGroupMessageEvent builds a kind-9 message event for a NIP-29 group.
-func buildGroupMessageEvent(groupID, content string, previousIDs []string, keys Keys) (nostr.Event, error) {
+// extra tags (e.g. a NIP-36 content warning) follow the group tags.
+func buildGroupMessageEvent(groupID, content string, extra nostr.Tags, previousIDs []string, keys Keys) (nostr.Event, error) {
 	tags := nostr.Tags{{"h", groupID}}
 	tags = append(tags, pickPreviousTags(previousIDs)...)
+	tags = append(tags, extra...)
 	evt := nostr.Event{
 		Kind:      nostr.KindSimpleGroupChatMessage,
 		CreatedAt: nostr.Now(),
@@ -177,35 +258,28 @@ func buildGroupMessageEvent(groupID, content string, previousIDs []string, keys
 	return evt, nil
 }
 
-// publishGroupMessage signs and publishes a kind-9 message to a NIP-29 group.
-func publishGroupMessage(pool *nostr.Pool, relayURL, groupID, content string, previousIDs []string, keys Keys) tea.Cmd {
-	return func() tea.Msg {
-		gk := groupKey(relayURL, groupID)
-		evt, err := buildGroupMessageEvent(groupID, content, previousIDs, keys)
-		if err != nil {
-			return nostrErrMsg{err}
-		}
-
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		r, err := pool.EnsureRelay(relayURL)
-		if err != nil {
-			return nostrErrMsg{fmt.Errorf("group publish: connect %s: %w", relayURL, err)}
-		}
-		if err := r.Publish(ctx, evt); err != nil {
-			return nostrErrMsg{fmt.Errorf("group publish: %w", err)}
-		}
-
-		return groupEventMsg(ChatMessage{
-			Author:    shortPK(keys.PK.Hex()),
-			PubKey:    keys.PK.Hex(),
-			Content:   content,
-			Timestamp: evt.CreatedAt,
-			EventID:   evt.GetID().Hex(),
-			GroupKey:  gk,
-			IsMine:    true,
-		})
+// publishGroupMessage signs and publishes a kind-9 message to a NIP-29 group
+// on its relay set (home relay first). Like publishChannelMessage, the local
+// echo comes first and the relays' outcome follows as a deliveryReportMsg.
+func publishGroupMessage(pool *nostr.Pool, relays []string, groupID, content string, extra nostr.Tags, previousIDs []string, keys Keys) tea.Cmd {
+	evt, err := buildGroupMessageEvent(groupID, content, extra, previousIDs, keys)
+	if err != nil {
+		return func() tea.Msg { return nostrErrMsg{err} }
 	}
+	return publishGroupEventCmd(pool, relays, groupKey(relays[0], groupID), evt, keys)
+}
+
+// publishGroupEventCmd echoes a signed group chat event locally and
+// publishes it to relays. Thread replies report delivery to their thread's
+// buffer.
+func publishGroupEventCmd(pool *nostr.Pool, relays []string, gk string, evt nostr.Event, keys Keys) tea.Cmd {
+	evt.ID = evt.GetID()
+	cm := groupChatMessage(evt, gk, keys)
+	cm.SentEvent = &evt
+	cm.Echo = echoPending
+	roomKey := groupRoomKey(cm)
+	echo := func() tea.Msg { return groupEventMsg(cm) }
+	return tea.Batch(echo, publishEventCmd(pool, relays, roomKey, evt), groupEchoTimeoutCmd(cm.EventID))
 }
 
 // buildJoinGroupEvent builds a kind-9021 join request event for a NIP-29 group.
@@ -240,6 +314,7 @@ func joinGroupCmd(pool *nostr.Pool, relayURL, groupID string, previousIDs []stri
 		if err != nil {
 			return nostrErrMsg{fmt.Errorf("group join: connect %s: %w", relayURL, err)}
 		}
+		logEventOut(groupKey(relayURL, groupID), evt)
 		if err := r.Publish(ctx, evt); err != nil {
 			// "already a member" is not a real error — treat it as success.
 			errStr := strings.ToLower(err.Error())
@@ -283,6 +358,7 @@ func leaveGroupCmd(pool *nostr.Pool, relayURL, groupID string, previousIDs []str
 		if err != nil {
 			return nostrErrMsg{fmt.Errorf("group leave: connect %s: %w", relayURL, err)}
 		}
+		logEventOut(groupKey(relayURL, groupID), evt)
 		if err := r.Publish(ctx, evt); err != nil {
 			return nostrErrMsg{fmt.Errorf("group leave: publish to %s group %s: %w", relayURL, groupID, err)}
 		}
@@ -292,19 +368,16 @@ func leaveGroupCmd(pool *nostr.Pool, relayURL, groupID string, previousIDs []str
 }
 
 // fetchGroupMetaCmd fetches a kind-39000 event to resolve the group name.
-func fetchGroupMetaCmd(pool *nostr.Pool, relayURL, groupID string) tea.Cmd {
+func fetchGroupMetaCmd(pool *nostr.Pool, limit *queryLimiter, relayURL, groupID string, wait time.Duration) tea.Cmd {
 	return func() tea.Msg {
 		log.Printf("fetchGroupMeta: relay=%s group=%s", relayURL, groupID)
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		re := pool.QuerySingle(ctx, []string{relayURL}, nostr.Filter{
+		re := queryReplaceableLimited(limit, "group "+groupID, pool, []string{relayURL}, nostr.Filter{
 			Kinds: []nostr.Kind{nostr.KindSimpleGroupMetadata},
 			Tags:  nostr.TagMap{"d": {groupID}},
-		}, nostr.SubscriptionOptions{})
+		}, wait, 10*time.Second)
 		if re == nil {
 			log.Printf("fetchGroupMeta: not found for %s on %s", groupID, relayURL)
-			return nil
+			return groupMetaMissingMsg{RelayURL: relayURL, GroupID: groupID}
 		}
 
 		g, err := nip29.NewGroupFromMetadataEvent(relayURL, &re.Event)
@@ -349,7 +422,7 @@ func parseGroupInput(input string) (string, string, error) {
 		if len(ep.Relays) == 0 {
 			return "", "", fmt.Errorf("naddr has no relay")
 		}
-		return ep.Relays[0], ep.Identifier, nil
+		return normalizeRelayURL(ep.Relays[0]), ep.Identifier, nil
 	}
 
 	// Try host'groupid format
@@ -357,7 +430,7 @@ func parseGroupInput(input string) (string, string, error) {
 	if err != nil {
 		return "", "", fmt.Errorf("invalid group address: %w", err)
 	}
-	return ga.Relay, ga.ID, nil
+	return normalizeRelayURL(ga.Relay), ga.ID, nil
 }
 
 // pickPreviousTags selects up to 3 random IDs from the recent event list
@@ -427,6 +500,7 @@ func createGroupCmd(pool *nostr.Pool, relayURL, name string, keys Keys) tea.Cmd
 		if err != nil {
 			return nostrErrMsg{fmt.Errorf("create group: connect %s: %w", relayURL, err)}
 		}
+		logEventOut("", evt)
 		if err := r.Publish(ctx, evt); err != nil {
 			return nostrErrMsg{fmt.Errorf("create group: publish: %w", err)}
 		}
@@ -466,6 +540,7 @@ func deleteGroupEventCmd(pool *nostr.Pool, relayURL, groupID, eventID string, pr
 		if err != nil {
 			return nostrErrMsg{fmt.Errorf("delete event: connect %s: %w", relayURL, err)}
 		}
+		logEventOut(groupKey(relayURL, groupID), evt)
 		if err := r.Publish(ctx, evt); err != nil {
 			return nostrErrMsg{fmt.Errorf("delete event: publish: %w", err)}
 		}
@@ -476,8 +551,8 @@ func deleteGroupEventCmd(pool *nostr.Pool, relayURL, groupID, eventID string, pr
 }
 
 // buildCreateGroupInviteEvent builds a kind-9009 invite event for a NIP-29 group.
-func buildCreateGroupInviteEvent(groupID string, previousIDs []string, keys Keys) (nostr.Event, error) {
-	tags := nostr.Tags{{"h", groupID}}
+func buildCreateGroupInviteEvent(groupID, code string, previousIDs []string, keys Keys) (nostr.Event, error) {
+	tags := nostr.Tags{{"h", groupID}, {"code", code}}
 	tags = append(tags, pickPreviousTags(previousIDs)...)
 
 	evt := nostr.Event{
@@ -492,10 +567,9 @@ func buildCreateGroupInviteEvent(groupID string, previousIDs []string, keys Keys
 }
 
 // createGroupInviteCmd publishes a kind 9009 event to create an invite for a NIP-29 group.
-//nolint:unused // will be wired to /group invite command
-func createGroupInviteCmd(pool *nostr.Pool, relayURL, groupID string, previousIDs []string, keys Keys) tea.Cmd {
+func createGroupInviteCmd(pool *nostr.Pool, relayURL, groupID, code string, previousIDs []string, keys Keys) tea.Cmd {
 	return func() tea.Msg {
-		evt, err := buildCreateGroupInviteEvent(groupID, previousIDs, keys)
+		evt, err := buildCreateGroupInviteEvent(groupID, code, previousIDs, keys)
 		if err != nil {
 			return nostrErrMsg{fmt.Errorf("create invite: sign: %w", err)}
 		}
@@ -506,17 +580,13 @@ func createGroupInviteCmd(pool *nostr.Pool, relayURL, groupID string, previousID
 		if err != nil {
 			return nostrErrMsg{fmt.Errorf("create invite: connect %s: %w", relayURL, err)}
 		}
+		logEventOut(groupKey(relayURL, groupID), evt)
 		if err := r.Publish(ctx, evt); err != nil {
 			return nostrErrMsg{fmt.Errorf("create invite: publish: %w", err)}
 		}
 
-		// Use the event ID as the invite code; r.Publish does not return
-		// relay-generated content, and buildCreateGroupInviteEvent leaves
-		// Content empty, so the event ID is the only stable identifier.
-		code := evt.GetID().Hex()
-
 		log.Printf("createGroupInviteCmd: invite for group %s on %s: %s", groupID, relayURL, code)
-		return groupInviteCreatedMsg{RelayURL: relayURL, GroupID: groupID, Code: code}
+		return groupInviteCreatedMsg{RelayURL: relayURL, GroupID: groupID, Code: code, EventID: evt.ID.Hex()}
 	}
 }
 
@@ -526,7 +596,7 @@ func createGroupInviteCmd(pool *nostr.Pool, relayURL, groupID string, previousID
 func inviteDMCmd(pool *nostr.Pool, relays []string, groupName, naddrStr, recipientPK string, keys Keys, kr nostr.Keyer) tea.Cmd {
 	return func() tea.Msg {
 		dmText := "nostr:" + naddrStr
-		return sendDM(pool, relays, recipientPK, dmText, keys, kr)()
+		return sendDM(pool, relays, recipientPK, dmText, nil, keys, kr)()
 	}
 }
 
@@ -560,6 +630,7 @@ func putUserCmd(pool *nostr.Pool, relayURL, groupID, pubkey string, previousIDs
 		if err != nil {
 			return nostrErrMsg{fmt.Errorf("put user: connect %s: %w", relayURL, err)}
 		}
+		logEventOut(groupKey(relayURL, groupID), evt)
 		if err := r.Publish(ctx, evt); err != nil {
 			return nostrErrMsg{fmt.Errorf("put user: publish: %w", err)}
 		}
@@ -569,6 +640,46 @@ func putUserCmd(pool *nostr.Pool, relayURL, groupID, pubkey string, previousIDs
 	}
 }
 
+// buildRemoveUserEvent builds a kind-9001 event to remove a user from a NIP-29 group.
+func buildRemoveUserEvent(groupID, pubkey string, previousIDs []string, keys Keys) (nostr.Event, error) {
+	tags := nostr.Tags{{"h", groupID}, {"p", pubkey}}
+	tags = append(tags, pickPreviousTags(previousIDs)...)
+
+	evt := nostr.Event{
+		Kind:      nostr.KindSimpleGroupRemoveUser,
+		CreatedAt: nostr.Now(),
+		Tags:      tags,
+	}
+	if err := evt.Sign(keys.SK); err != nil {
+		return evt, err
+	}
+	return evt, nil
+}
+
+// removeUserCmd publishes a kind 9001 event to remove a user from a NIP-29 group.
+func removeUserCmd(pool *nostr.Pool, relayURL, groupID, pubkey string, previousIDs []string, keys Keys) tea.Cmd {
+	return func() tea.Msg {
+		evt, err := buildRemoveUserEvent(groupID, pubkey, previousIDs, keys)
+		if err != nil {
+			return nostrErrMsg{fmt.Errorf("remove user: sign: %w", err)}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		r, err := pool.EnsureRelay(relayURL)
+		if err != nil {
+			return nostrErrMsg{fmt.Errorf("remove user: connect %s: %w", relayURL, err)}
+		}
+		logEventOut(groupKey(relayURL, groupID), evt)
+		if err := r.Publish(ctx, evt); err != nil {
+			return nostrErrMsg{fmt.Errorf("remove user: publish: %w", err)}
+		}
+
+		log.Printf("removeUserCmd: removed %s from group %s on %s", shortPK(pubkey), groupID, relayURL)
+		return nil
+	}
+}
+
 // buildEditGroupMetadataEvent builds a kind-9002 event to edit group metadata.
 func buildEditGroupMetadataEvent(groupID string, fields map[string]string, previousIDs []string, keys Keys) (nostr.Event, error) {
 	tags := nostr.Tags{{"h", groupID}}
@@ -606,6 +717,7 @@ func editGroupMetadataCmd(pool *nostr.Pool, relayURL, groupID string, fields map
 		if err != nil {
 			return nostrErrMsg{fmt.Errorf("edit metadata: connect %s: %w", relayURL, err)}
 		}
+		logEventOut(groupKey(relayURL, groupID), evt)
 		if err := r.Publish(ctx, evt); err != nil {
 			return nostrErrMsg{fmt.Errorf("edit metadata: publish: %w", err)}
 		}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"strings"
 	"testing"
+	"time"
 
 	"fiatjaf.com/nostr"
 	"fiatjaf.com/nostr/nip19"
@@ -251,3 +255,81 @@ func TestSlicesEqual(t *testing.T) {
 		})
 	}
 }
+
+func TestNIP05JSON(t *testing.T) {
+	pk := "aaaa1111bbbb2222cccc3333dddd4444aaaa1111bbbb2222cccc3333dddd4444"
+	doc, err := nip05JSON("alice", pk, []string{"wss://relay.example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var parsed struct {
+		Names  map[string]string   `json:"names"`
+		Relays map[string][]string `json:"relays"`
+	}
+	if err := json.Unmarshal([]byte(doc), &parsed); err != nil {
+		t.Fatalf("not valid JSON: %v", err)
+	}
+	if parsed.Names["alice"] != pk {
+		t.Errorf("names[alice] = %q, want %q", parsed.Names["alice"], pk)
+	}
+	if len(parsed.Relays[pk]) != 1 {
+		t.Errorf("relays[pk] = %v, want one relay", parsed.Relays[pk])
+	}
+
+	doc, _ = nip05JSON("_", pk, nil)
+	if strings.Contains(doc, "relays") {
+		t.Errorf("expected relays to be omitted when empty, got %s", doc)
+	}
+}
+
+func TestIsValidNIP05Name(t *testing.T) {
+	for _, name := range []string{"alice", "_", "bob.smith", "a-b_c9"} {
+		if !isValidNIP05Name(name) {
+			t.Errorf("isValidNIP05Name(%q) = false, want true", name)
+		}
+	}
+	for _, name := range []string{"", "Alice", "a b", "al@ce", "ü"} {
+		if isValidNIP05Name(name) {
+			t.Errorf("isValidNIP05Name(%q) = true, want false", name)
+		}
+	}
+}
+
+func TestCollectNewest(t *testing.T) {
+	ev := func(ts nostr.Timestamp, content string) nostr.RelayEvent {
+		return nostr.RelayEvent{Event: nostr.Event{CreatedAt: ts, Content: content}}
+	}
+
+	t.Run("newest wins once all relays finish", func(t *testing.T) {
+		ch := make(chan nostr.RelayEvent, 3)
+		ch <- ev(100, "stale")
+		ch <- ev(300, "newest")
+		ch <- ev(200, "older")
+		close(ch)
+		got := collectNewest(context.Background(), ch, time.Second)
+		if got == nil || got.Content != "newest" {
+			t.Errorf("got %+v, want newest", got)
+		}
+	})
+
+	t.Run("window closes after the first answer", func(t *testing.T) {
+		ch := make(chan nostr.RelayEvent, 1)
+		ch <- ev(100, "fast")
+		start := time.Now()
+		got := collectNewest(context.Background(), ch, 50*time.Millisecond)
+		if got == nil || got.Content != "fast" {
+			t.Errorf("got %+v, want fast", got)
+		}
+		if time.Since(start) > time.Second {
+			t.Error("should not wait for the slow relay past the window")
+		}
+	})
+
+	t.Run("nothing found", func(t *testing.T) {
+		ch := make(chan nostr.RelayEvent)
+		close(ch)
+		if got := collectNewest(context.Background(), ch, time.Second); got != nil {
+			t.Errorf("got %+v, want nil", got)
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// paletteMaxRows caps how many matches the command palette shows at once.
+const paletteMaxRows = 12
+
+// fuzzyMatch reports whether every rune of pattern appears in s in order,
+// case-insensitively (e.g. "mw" matches "/mute-word").
+func fuzzyMatch(pattern, s string) bool {
+	pattern = strings.ToLower(pattern)
+	s = strings.ToLower(s)
+	for _, r := range pattern {
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+utf8.RuneLen(r):]
+	}
+	return true
+}
+
+// filterCommandHelps returns the command help entries whose usage or
+// description matches query. An empty query matches everything.
+func filterCommandHelps(query string) []commandHelp {
+	query = strings.TrimSpace(query)
+	var out []commandHelp
+	for _, h := range commandHelps {
+		if query == "" || fuzzyMatch(query, h.Usage) || strings.Contains(strings.ToLower(h.Desc), strings.ToLower(query)) {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+// openPalette shows the command palette with an empty filter.
+func (m *model) openPalette() {
+	m.paletteOpen = true
+	m.paletteQuery = ""
+	m.paletteIndex = 0
+}
+
+// handlePaletteKey handles key presses while the command palette is open.
+func (m *model) handlePaletteKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	matches := filterCommandHelps(m.paletteQuery)
+	switch msg.String() {
+	case "esc", "ctrl+p":
+		m.paletteOpen = false
+	case "up", "shift+tab", "ctrl+k":
+		if m.paletteIndex > 0 {
+			m.paletteIndex--
+		}
+	case "down", "tab", "ctrl+j":
+		if m.paletteIndex < len(matches)-1 {
+			m.paletteIndex++
+		}
+	case "enter":
+		m.paletteOpen = false
+		if len(matches) == 0 {
+			return m, nil
+		}
+		m.input.SetValue(matches[m.paletteIndex].Name + " ")
+		m.input.CursorEnd()
+		m.syncInputHeight()
+		m.updateSuggestions()
+	case "backspace":
+		if m.paletteQuery != "" {
+			_, size := utf8.DecodeLastRuneInString(m.paletteQuery)
+			m.paletteQuery = m.paletteQuery[:len(m.paletteQuery)-size]
+			m.paletteIndex = 0
+		}
+	default:
+		if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+			m.paletteQuery += string(msg.Runes)
+			m.paletteIndex = 0
+		}
+	}
+	return m, nil
+}
+
+// viewPalette renders the command palette overlay.
+func (m *model) viewPalette() string {
+	matches := filterCommandHelps(m.paletteQuery)
+
+	var b strings.Builder
+	b.WriteString(qrTitleStyle.Render("Commands"))
+	b.WriteString("\n")
+	b.WriteString("> " + m.paletteQuery + "█")
+	b.WriteString("\n\n")
+
+	if len(matches) == 0 {
+		b.WriteString(chatSystemStyle.Render("no matching commands"))
+	}
+	// Scroll so the selected row stays visible.
+	start := 0
+	if m.paletteIndex >= paletteMaxRows {
+		start = m.paletteIndex - paletteMaxRows + 1
+	}
+	end := min(start+paletteMaxRows, len(matches))
+	usageW := 0
+	for _, h := range matches[start:end] {
+		usageW = max(usageW, lipgloss.Width(h.Usage))
+	}
+	for i := start; i < end; i++ {
+		h := matches[i]
+		usage := h.Usage + strings.Repeat(" ", usageW-lipgloss.Width(h.Usage))
+		if i == m.paletteIndex {
+			b.WriteString(acSelectedStyle.Render(usage) + "  " + h.Desc)
+		} else {
+			b.WriteString(acSuggestionStyle.Render(usage) + "  " + chatSystemStyle.Render(h.Desc))
+		}
+		if i < end-1 {
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString("\n\n")
+	b.WriteString(chatSystemStyle.Render("↑/↓ select · enter insert · esc close"))
+
+	box := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colorPrimary).
+		Padding(0, 1).
+		MaxWidth(m.width).
+		Render(b.String())
+	return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, box)
+}
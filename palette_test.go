@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		want    bool
+	}{
+		{"mw", "/mute-word", true},
+		{"JOIN", "/join #name", true},
+		{"", "/help", true},
+		{"wm", "/mute-word", false},
+		{"xyz", "/help", false},
+		{"helpp", "/help", false},
+	}
+	for _, tt := range tests {
+		if got := fuzzyMatch(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("fuzzyMatch(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestFilterCommandHelps(t *testing.T) {
+	if got := filterCommandHelps(""); len(got) != len(commandHelps) {
+		t.Errorf("empty query: got %d entries, want %d", len(got), len(commandHelps))
+	}
+	for _, h := range filterCommandHelps("qr code") {
+		if h.Name != "/me" && h.Name != "/room" && h.Name != "/invoice" {
+			t.Errorf("unexpected match for description query: %s", h.Usage)
+		}
+	}
+	if got := filterCommandHelps("zzzz"); len(got) != 0 {
+		t.Errorf("expected no matches, got %d", len(got))
+	}
+}
+
+func TestCommandNamesUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, n := range commandNames() {
+		if seen[n] {
+			t.Errorf("duplicate command name %s", n)
+		}
+		seen[n] = true
+	}
+	if !seen["/help"] || !seen["/join"] {
+		t.Error("expected /help and /join in command names")
+	}
+}
+
+func TestShowHelpPages(t *testing.T) {
+	m := newTestModel(1, 0, 0)
+	lines := func() []ChatMessage {
+		defer func() { m.msgs["ch0"] = nil }()
+		return m.msgs["ch0"]
+	}
+	pages := (len(commandHelps) + helpPageSize - 1) / helpPageSize
+
+	m.showHelp("")
+	first := lines()
+	if len(first) != helpPageSize+2 {
+		t.Fatalf("page 1 has %d lines, want key hint + %d commands + footer", len(first), helpPageSize)
+	}
+	if !strings.Contains(first[1].Content, commandHelps[0].Usage) {
+		t.Errorf("page 1 starts with %q, want %q", first[1].Content, commandHelps[0].Usage)
+	}
+
+	m.showHelp(strconv.Itoa(pages))
+	last := lines()
+	if got := last[len(last)-1].Content; !strings.Contains(got, "/help [page|command]") {
+		t.Errorf("last page ends with %q, want the /help entry and no footer", got)
+	}
+
+	m.showHelp("whois")
+	if got := lines(); len(got) != 1 || !strings.HasPrefix(got[0].Content, "/whois") {
+		t.Errorf("/help whois = %+v, want only the /whois entry", got)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+)
+
+// paymentKind identifies the type of a detected payment token.
+type paymentKind int
+
+const (
+	paymentInvoice paymentKind = iota // BOLT11 lightning invoice
+	paymentCashu                      // cashu ecash token
+)
+
+// paymentToken is a lightning invoice or cashu token found in message content.
+type paymentToken struct {
+	Kind        paymentKind
+	Raw         string // the token as it appeared in the message (without "lightning:")
+	AmountSats  int64  // 0 if the invoice has no amount
+	Description string // BOLT11 "d" field, if present
+}
+
+// bolt11Prefixes are the human-readable prefixes of mainnet, testnet,
+// signet, and regtest BOLT11 invoices.
+var bolt11Prefixes = []string{"lnbcrt", "lntbs", "lnbc", "lntb"}
+
+// findPaymentTokens scans content for lightning invoices and cashu tokens.
+func findPaymentTokens(content string) []paymentToken {
+	var tokens []paymentToken
+	for _, word := range strings.Fields(content) {
+		word = strings.Trim(word, "`*_()[]<>.,;!?\"'")
+		raw := word
+		if len(raw) > len("lightning:") && strings.EqualFold(raw[:len("lightning:")], "lightning:") {
+			raw = raw[len("lightning:"):]
+		}
+		if strings.HasPrefix(raw, "cashuA") || strings.HasPrefix(raw, "cashuB") {
+			tokens = append(tokens, paymentToken{Kind: paymentCashu, Raw: raw})
+			continue
+		}
+		if isBolt11(raw) {
+			tok := paymentToken{Kind: paymentInvoice, Raw: raw}
+			if sats, desc, err := decodeBolt11(raw); err == nil {
+				tok.AmountSats = sats
+				tok.Description = desc
+			}
+			tokens = append(tokens, tok)
+		}
+	}
+	return tokens
+}
+
+// isBolt11 reports whether s looks like a BOLT11 invoice.
+func isBolt11(s string) bool {
+	lower := strings.ToLower(s)
+	if len(lower) < 20 || !strings.Contains(lower, "1") {
+		return false
+	}
+	for _, p := range bolt11Prefixes {
+		if strings.HasPrefix(lower, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeBolt11 extracts the amount (in sats) and description from a BOLT11
+// invoice. The signature is not verified — this is for display only.
+func decodeBolt11(invoice string) (int64, string, error) {
+	hrp, data, err := bech32.DecodeNoLimit(strings.ToLower(invoice))
+	if err != nil {
+		return 0, "", fmt.Errorf("bolt11: %w", err)
+	}
+
+	sats, err := bolt11Amount(hrp)
+	if err != nil {
+		return 0, "", err
+	}
+
+	// Data layout: 7-word timestamp, tagged fields, 104-word signature.
+	const timestampWords = 7
+	const signatureWords = 104
+	if len(data) < timestampWords+signatureWords {
+		return sats, "", fmt.Errorf("bolt11: data too short")
+	}
+	fields := data[timestampWords : len(data)-signatureWords]
+
+	var desc string
+	for len(fields) >= 3 {
+		tag := fields[0]
+		length := int(fields[1])<<5 | int(fields[2])
+		fields = fields[3:]
+		if length > len(fields) {
+			break
+		}
+		// Tag 13 is 'd' (short description) in the bech32 charset.
+		if tag == 13 {
+			b, err := bech32.ConvertBits(fields[:length], 5, 8, false)
+			if err == nil {
+				desc = string(b)
+			}
+		}
+		fields = fields[length:]
+	}
+	return sats, desc, nil
+}
+
+// bolt11Amount parses the amount from a BOLT11 human-readable part
+// (e.g. "lnbc2500u" → 250000 sats). Returns 0 for amountless invoices.
+func bolt11Amount(hrp string) (int64, error) {
+	var rest string
+	for _, p := range bolt11Prefixes {
+		if strings.HasPrefix(hrp, p) {
+			rest = hrp[len(p):]
+			break
+		}
+	}
+	if rest == "" {
+		return 0, nil
+	}
+
+	// Multiplier → millisatoshis per unit.
+	msatPerUnit := int64(100_000_000_000) // 1 BTC
+	divisor := int64(1)
+	switch rest[len(rest)-1] {
+	case 'm':
+		msatPerUnit = 100_000_000
+	case 'u':
+		msatPerUnit = 100_000
+	case 'n':
+		msatPerUnit = 100
+	case 'p':
+		msatPerUnit = 1
+		divisor = 10
+	}
+	if msatPerUnit != 100_000_000_000 || divisor != 1 {
+		rest = rest[:len(rest)-1]
+	}
+
+	n, err := strconv.ParseInt(rest, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bolt11: invalid amount %q", rest)
+	}
+	return n * msatPerUnit / divisor / 1000, nil
+}
+
+// badge returns the compact inline replacement text for a payment token.
+func (t paymentToken) badge() string {
+	if t.Kind == paymentCashu {
+		return "[🥜 cashu token]"
+	}
+	label := "[⚡ invoice"
+	if t.AmountSats > 0 {
+		label += fmt.Sprintf(": %d sats", t.AmountSats)
+	}
+	if t.Description != "" {
+		label += " — " + t.Description
+	}
+	return label + "]"
+}
+
+// replacePaymentTokens swaps lightning invoices and cashu tokens in content
+// for compact badges so they don't flood the viewport.
+func replacePaymentTokens(content string) string {
+	for _, tok := range findPaymentTokens(content) {
+		i := strings.Index(content, tok.Raw)
+		if i < 0 {
+			continue
+		}
+		start := i
+		if p := i - len("lightning:"); p >= 0 && strings.EqualFold(content[p:i], "lightning:") {
+			start = p // the URI prefix is case-insensitive ("LIGHTNING:" in QR payloads)
+		}
+		content = content[:start] + tok.badge() + content[i+len(tok.Raw):]
+	}
+	return content
+}
+
+// recentPaymentTokens returns payment tokens in msgs, newest first.
+func recentPaymentTokens(msgs []ChatMessage) []paymentToken {
+	var out []paymentToken
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Author == "system" {
+			continue
+		}
+		toks := findPaymentTokens(msgs[i].Content)
+		for j := len(toks) - 1; j >= 0; j-- {
+			out = append(out, toks[j])
+		}
+	}
+	return out
+}
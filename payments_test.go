@@ -0,0 +1,85 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Example invoice from the BOLT11 specification.
+const specInvoice = "lnbc2500u1pvjluezpp5qqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqqqsyqcyq5rqwzqfqypqdq5xysxxatsyp3k7enxv4jsxqzpuaztrnwngzn3kdzw5hydlzf03qdgm2hdq27cqv3agm2awhz5se903vruatfhq77w3ls4evs3ch9zw97j25emudupq63nyw24cg27h2rspfj9srp"
+
+func TestBolt11Amount(t *testing.T) {
+	tests := []struct {
+		hrp  string
+		want int64
+	}{
+		{"lnbc", 0},
+		{"lnbc1", 100_000_000},
+		{"lnbc2500u", 250_000},
+		{"lnbc20m", 2_000_000},
+		{"lnbc10n", 1},
+		{"lnbc10000p", 1},
+		{"lntb100u", 10_000},
+		{"lnbcrt5m", 500_000},
+	}
+	for _, tt := range tests {
+		got, err := bolt11Amount(tt.hrp)
+		if err != nil {
+			t.Errorf("bolt11Amount(%q): unexpected error: %v", tt.hrp, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("bolt11Amount(%q) = %d, want %d", tt.hrp, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeBolt11(t *testing.T) {
+	sats, desc, err := decodeBolt11(specInvoice)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sats != 250_000 {
+		t.Errorf("sats = %d, want 250000", sats)
+	}
+	if desc != "1 cup coffee" {
+		t.Errorf("description = %q, want %q", desc, "1 cup coffee")
+	}
+}
+
+func TestFindPaymentTokens(t *testing.T) {
+	content := "pay me: lightning:" + specInvoice + " or use cashuAeyJ0b2tlbiI6W119 thanks"
+	toks := findPaymentTokens(content)
+	if len(toks) != 2 {
+		t.Fatalf("expected 2 tokens, got %d", len(toks))
+	}
+	if toks[0].Kind != paymentInvoice || toks[0].Raw != specInvoice {
+		t.Errorf("first token = %+v, want invoice", toks[0])
+	}
+	if toks[1].Kind != paymentCashu || toks[1].Raw != "cashuAeyJ0b2tlbiI6W119" {
+		t.Errorf("second token = %+v, want cashu token", toks[1])
+	}
+
+	if toks := findPaymentTokens("lnbc is a prefix, not an invoice"); len(toks) != 0 {
+		t.Errorf("expected no tokens, got %d", len(toks))
+	}
+}
+
+func TestReplacePaymentTokens(t *testing.T) {
+	got := replacePaymentTokens("invoice: lightning:" + specInvoice)
+	want := "invoice: [⚡ invoice: 250000 sats — 1 cup coffee]"
+	if got != want {
+		t.Errorf("replacePaymentTokens = %q, want %q", got, want)
+	}
+
+	// QR payloads are usually all upper case, URI scheme included.
+	got = replacePaymentTokens("invoice: LIGHTNING:" + strings.ToUpper(specInvoice))
+	if got != want {
+		t.Errorf("replacePaymentTokens(upper case) = %q, want %q", got, want)
+	}
+
+	got = replacePaymentTokens("here cashuBo2Fteexample")
+	if !strings.Contains(got, "[🥜 cashu token]") {
+		t.Errorf("expected cashu badge, got %q", got)
+	}
+}
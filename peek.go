@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// peekEntry is one unread message in the /peek-unread overlay.
+type peekEntry struct {
+	room string // sidebar item ID
+	msg  ChatMessage
+}
+
+// unreadPeek is the /peek-unread overlay: the unread messages of all rooms,
+// which stay unread until one is opened.
+type unreadPeek struct {
+	entries []peekEntry
+	sel     int
+}
+
+// unreadPeekEntries collects the unread messages of each room in items, in
+// sidebar order: every message from the room's first unread one on, except
+// our own and system lines.
+func (m *model) unreadPeekEntries(items []SidebarItem) []peekEntry {
+	var entries []peekEntry
+	for _, it := range items {
+		id := it.ItemID()
+		from, ok := m.unreadFrom[id]
+		if !m.unread[id] || !ok {
+			continue
+		}
+		for _, msg := range m.msgs[id] {
+			if msg.Timestamp < from || msg.IsMine || msg.Author == "system" || m.isFilteredMessage(msg) {
+				continue
+			}
+			entries = append(entries, peekEntry{room: id, msg: msg})
+		}
+	}
+	return entries
+}
+
+// peekScroll returns the first line to show of total lines so that line sel
+// is visible in a window of height lines, keeping it centered when possible.
+func peekScroll(total, sel, height int) int {
+	if total <= height {
+		return 0
+	}
+	return min(max(sel-height/2, 0), total-height)
+}
+
+// peekUnread handles /peek-unread.
+func (m *model) peekUnread() (tea.Model, tea.Cmd) {
+	entries := m.unreadPeekEntries(m.sidebar)
+	if len(entries) == 0 {
+		m.addSystemMsg("nothing unread")
+		return m, nil
+	}
+	m.unreadPeek = &unreadPeek{entries: entries}
+	return m, nil
+}
+
+// handleUnreadPeekKey moves the selection; enter opens the selected
+// message's room (marking it read) and esc or q closes the overlay.
+func (m *model) handleUnreadPeekKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.unreadPeek
+	switch msg.String() {
+	case "esc", "q":
+		m.unreadPeek = nil
+	case "up", "k":
+		p.sel = max(p.sel-1, 0)
+	case "down", "j":
+		p.sel = min(p.sel+1, len(p.entries)-1)
+	case "pgup":
+		p.sel = max(p.sel-10, 0)
+	case "pgdown":
+		p.sel = min(p.sel+10, len(p.entries)-1)
+	case "g", "home":
+		p.sel = 0
+	case "G", "end":
+		p.sel = len(p.entries) - 1
+	case "enter":
+		m.unreadPeek = nil
+		return m.openPeekEntry(p.entries[p.sel])
+	}
+	return m, nil
+}
+
+// openPeekEntry switches to e's room and scrolls to the message.
+func (m *model) openPeekEntry(e peekEntry) (tea.Model, tea.Cmd) {
+	for i, it := range m.sidebar {
+		if it.ItemID() != e.room {
+			continue
+		}
+		m.activeItem = i
+		m.clearUnread()
+		m.updateViewport()
+		if !m.scrollToMessage(e.msg.EventID) {
+			m.addSystemMsg("that message is no longer loaded here")
+		}
+		return m, nil
+	}
+	m.addSystemMsg("that conversation is no longer in the sidebar")
+	return m, nil
+}
+
+// viewUnreadPeek renders the overlay: a header per room, then its messages,
+// scrolled to keep the selection in view.
+func (m *model) viewUnreadPeek() string {
+	p := m.unreadPeek
+	width := min(max(m.width-8, 20), 100)
+	names := make(map[string]string)
+	counts := make(map[string]int)
+	for _, it := range m.sidebar {
+		names[it.ItemID()] = it.Prefix() + it.DisplayName()
+	}
+	for _, e := range p.entries {
+		counts[e.room]++
+	}
+
+	var lines []string
+	selLine := 0
+	for i, e := range p.entries {
+		if i == 0 || p.entries[i-1].room != e.room {
+			if i > 0 {
+				lines = append(lines, "")
+			}
+			lines = append(lines, qrTitleStyle.Render(fmt.Sprintf("%s (%d unread)", names[e.room], counts[e.room])))
+		}
+		author := e.msg.Author
+		if e.msg.PubKey != "" {
+			author = m.resolveAuthor(e.msg.PubKey)
+		}
+		content := e.msg.Content
+		if m.isConcealed(e.msg) {
+			content = cwNotice(e.msg.ContentWarning)
+		}
+		text := fmt.Sprintf("%s %s: %s", e.msg.Timestamp.Time().Format("15:04"), author, strings.Join(strings.Fields(content), " "))
+		line := " " + truncateRunes(text, width-2)
+		if i == p.sel {
+			selLine = len(lines)
+			line = acSelectedStyle.Render(line)
+		}
+		lines = append(lines, line)
+	}
+
+	height := max(m.height-4, 1) // title and footer
+	start := peekScroll(len(lines), selLine, height)
+	lines = lines[start:min(start+height, len(lines))]
+
+	var b strings.Builder
+	b.WriteString(qrTitleStyle.Render(fmt.Sprintf("Unread: %d messages", len(p.entries))) + "\n\n")
+	b.WriteString(strings.Join(lines, "\n") + "\n")
+	b.WriteString(chatSystemStyle.Render("↑/↓ select · enter opens the room · esc closes (rooms stay unread)"))
+	return lipgloss.NewStyle().Width(width).Render(b.String())
+}
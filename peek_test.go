@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestUnreadPeekEntries(t *testing.T) {
+	m := newTestModel(2, 1, 1)
+	m.msgs = map[string][]ChatMessage{
+		"ch0": {
+			{EventID: "a", Content: "read", Timestamp: 10},
+			{EventID: "b", Content: "new", Timestamp: 20},
+			{EventID: "c", Content: "mine", Timestamp: 21, IsMine: true},
+			{Author: "system", Content: "joined", Timestamp: 22},
+		},
+		"ch1":                     {{EventID: "d", Content: "not unread", Timestamp: 30}},
+		groupKey("wss://r", "g0"): {{EventID: "e", Content: "group", Timestamp: 5}},
+		"pk0":                     {{EventID: "f", Content: "dm", Timestamp: 40}},
+	}
+	m.unread = map[string]bool{"ch0": true, groupKey("wss://r", "g0"): true, "pk0": true}
+	m.unreadFrom = map[string]nostr.Timestamp{"ch0": 20, groupKey("wss://r", "g0"): 5, "pk0": 40}
+
+	got := m.unreadPeekEntries(m.sidebar)
+	var ids []string
+	for _, e := range got {
+		ids = append(ids, e.room+":"+e.msg.EventID)
+	}
+	want := []string{"ch0:b", groupKey("wss://r", "g0") + ":e", "pk0:f"}
+	if len(ids) != len(want) {
+		t.Fatalf("got %v, want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Errorf("entry %d = %s, want %s", i, ids[i], want[i])
+		}
+	}
+}
+
+func TestPeekScroll(t *testing.T) {
+	tests := []struct{ total, sel, height, want int }{
+		{5, 4, 10, 0},   // fits
+		{100, 2, 10, 0}, // near the top
+		{100, 50, 10, 45},
+		{100, 99, 10, 90}, // near the bottom
+	}
+	for _, tt := range tests {
+		if got := peekScroll(tt.total, tt.sel, tt.height); got != tt.want {
+			t.Errorf("peekScroll(%d, %d, %d) = %d, want %d", tt.total, tt.sel, tt.height, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"fiatjaf.com/nostr"
+)
+
+// pingTimeout is how long /ping waits for a relay before calling it unreachable.
+const pingTimeout = 5 * time.Second
+
+// relayPing is the outcome of pinging one relay.
+type relayPing struct {
+	URL string
+	RTT time.Duration // REQ → EOSE round trip; zero if Err is set
+	Err error
+}
+
+// pingResultMsg carries the results of /ping, sorted fastest first.
+type pingResultMsg struct {
+	results []relayPing
+}
+
+// pingRelay measures a REQ/EOSE round trip for an event ID that cannot
+// exist, so the relay answers from its index without sending anything. The
+// connection is established first so its setup isn't counted.
+func pingRelay(pool *nostr.Pool, url string) relayPing {
+	r, err := pool.EnsureRelay(url)
+	if err != nil {
+		return relayPing{URL: url, Err: err}
+	}
+	var id nostr.ID
+	_, _ = rand.Read(id[:])
+
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	start := time.Now()
+	sub, err := r.Subscribe(ctx, nostr.Filter{IDs: []nostr.ID{id}, Limit: 1}, nostr.SubscriptionOptions{Label: "ping"})
+	if err != nil {
+		return relayPing{URL: url, Err: err}
+	}
+	defer sub.Unsub()
+	select {
+	case <-sub.EndOfStoredEvents:
+		return relayPing{URL: url, RTT: time.Since(start)}
+	case reason := <-sub.ClosedReason:
+		return relayPing{URL: url, Err: fmt.Errorf("closed: %s", reason)}
+	case <-ctx.Done():
+		return relayPing{URL: url, Err: fmt.Errorf("timeout after %s", pingTimeout)}
+	}
+}
+
+// sortPings orders reachable relays fastest first, then unreachable ones by URL.
+func sortPings(results []relayPing) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if (a.Err == nil) != (b.Err == nil) {
+			return a.Err == nil
+		}
+		if a.Err == nil && a.RTT != b.RTT {
+			return a.RTT < b.RTT
+		}
+		return a.URL < b.URL
+	})
+}
+
+// pingRelays pings all relays concurrently, sorted fastest first.
+func pingRelays(pool *nostr.Pool, relays []string) []relayPing {
+	results := make([]relayPing, len(relays))
+	var wg sync.WaitGroup
+	for i, url := range relays {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			results[i] = pingRelay(pool, url)
+			log.Printf("ping: %s rtt=%s err=%v", url, results[i].RTT, results[i].Err)
+		}(i, url)
+	}
+	wg.Wait()
+	sortPings(results)
+	return results
+}
+
+// pingRelaysCmd pings all relays for /ping.
+func pingRelaysCmd(pool *nostr.Pool, relays []string) tea.Cmd {
+	return func() tea.Msg {
+		return pingResultMsg{results: pingRelays(pool, relays)}
+	}
+}
+
+// renderPingResults formats /ping results for the overlay.
+func renderPingResults(results []relayPing) string {
+	var b strings.Builder
+	b.WriteString(qrTitleStyle.Render("Relay latency"))
+	b.WriteString("\n\n")
+	for _, r := range results {
+		if r.Err != nil {
+			b.WriteString(chatSystemStyle.Render(fmt.Sprintf("%12s  %s  (%v)", "unreachable", r.URL, r.Err)))
+		} else {
+			fmt.Fprintf(&b, "%12s  %s", r.RTT.Round(time.Millisecond), r.URL)
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// recordLatency stores the round-trip times of results; unreachable
+// relays lose theirs.
+func (m *model) recordLatency(results []relayPing) {
+	for _, r := range results {
+		if r.Err != nil {
+			delete(m.relayLatency, r.URL)
+		} else {
+			m.relayLatency[r.URL] = r.RTT
+		}
+	}
+}
+
+func (m *model) handlePingResult(msg pingResultMsg) (tea.Model, tea.Cmd) {
+	m.recordLatency(msg.results)
+	m.qrOverlay = renderPingResults(msg.results)
+	return m, nil
+}
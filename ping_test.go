@@ -0,0 +1,38 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSortPings(t *testing.T) {
+	results := []relayPing{
+		{URL: "wss://slow", RTT: 300 * time.Millisecond},
+		{URL: "wss://down-b", Err: errors.New("timeout")},
+		{URL: "wss://fast", RTT: 20 * time.Millisecond},
+		{URL: "wss://down-a", Err: errors.New("refused")},
+		{URL: "wss://mid", RTT: 80 * time.Millisecond},
+	}
+	sortPings(results)
+	want := []string{"wss://fast", "wss://mid", "wss://slow", "wss://down-a", "wss://down-b"}
+	for i, w := range want {
+		if results[i].URL != w {
+			t.Errorf("results[%d] = %s, want %s", i, results[i].URL, w)
+		}
+	}
+}
+
+func TestRenderPingResults(t *testing.T) {
+	out := renderPingResults([]relayPing{
+		{URL: "wss://fast", RTT: 21400 * time.Microsecond},
+		{URL: "wss://down", Err: errors.New("timeout after 5s")},
+	})
+	if !strings.Contains(out, "21ms") || !strings.Contains(out, "wss://fast") {
+		t.Errorf("missing latency line:\n%s", out)
+	}
+	if !strings.Contains(out, "unreachable") || !strings.Contains(out, "timeout after 5s") {
+		t.Errorf("missing unreachable line:\n%s", out)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/reflow/wordwrap"
+	"github.com/muesli/reflow/wrap"
+)
+
+// previewDebounce is how long typing must pause before the compose preview
+// is rendered again.
+const previewDebounce = 150 * time.Millisecond
+
+// previewTickMsg fires previewDebounce after an input change; only the
+// newest one (matching seq) renders.
+type previewTickMsg struct {
+	seq int
+}
+
+// renderPreview renders text as its message would be shown, wrapped to width
+// and cut to the last lines lines, where the cursor usually is. Commands and
+// input longer than maxChars aren't rendered.
+func renderPreview(r *glamour.TermRenderer, text string, width, lines, maxChars int) []string {
+	text = strings.TrimSpace(text)
+	switch {
+	case text == "":
+		return []string{chatSystemStyle.Render("nothing to preview")}
+	case strings.HasPrefix(text, "/"):
+		return []string{chatSystemStyle.Render("commands aren't previewed")}
+	case len([]rune(text)) > maxChars:
+		return []string{chatSystemStyle.Render(fmt.Sprintf("no preview for messages over %d characters", maxChars))}
+	}
+	rendered := strings.Split(renderMarkdown(r, hardLineBreaks(replacePaymentTokens(text))), "\n")
+	for len(rendered) > 0 && strings.TrimSpace(ansi.Strip(rendered[0])) == "" {
+		rendered = rendered[1:]
+	}
+	for len(rendered) > 0 && strings.TrimSpace(ansi.Strip(rendered[len(rendered)-1])) == "" {
+		rendered = rendered[:len(rendered)-1]
+	}
+	var out []string
+	for _, l := range rendered {
+		out = append(out, strings.Split(wrap.String(wordwrap.String(l, width), width), "\n")...)
+	}
+	if len(out) > lines {
+		out = out[len(out)-lines:]
+	}
+	return out
+}
+
+// togglePreview handles ctrl+g: it opens or closes the compose preview pane.
+func (m *model) togglePreview() (tea.Model, tea.Cmd) {
+	m.preview = !m.preview
+	m.previewSrc = ""
+	m.refreshPreview()
+	m.updateLayout()
+	return m, nil
+}
+
+// schedulePreview starts the debounce timer after the input changed, if the
+// preview is open.
+func (m *model) schedulePreview() tea.Cmd {
+	if !m.preview || m.input.Value() == m.previewSrc {
+		return nil
+	}
+	m.previewSeq++
+	seq := m.previewSeq
+	return tea.Tick(previewDebounce, func(time.Time) tea.Msg { return previewTickMsg{seq: seq} })
+}
+
+// refreshPreview renders the current input into the preview pane.
+func (m *model) refreshPreview() {
+	if !m.preview {
+		return
+	}
+	lines, maxChars := m.cfg.PreviewLimits()
+	m.previewSrc = m.input.Value()
+	m.previewLines = renderPreview(m.mdRender, m.previewSrc, max(m.viewport.Width, 1), lines, maxChars)
+}
+
+func (m *model) handlePreviewTick(msg previewTickMsg) (tea.Model, tea.Cmd) {
+	if msg.seq != m.previewSeq || !m.preview {
+		return m, nil
+	}
+	m.refreshPreview()
+	return m, nil
+}
+
+// viewPreview renders the preview pane at a fixed height, so the chat above
+// doesn't jump while typing. It is empty when the preview is closed.
+func (m *model) viewPreview() string {
+	if !m.preview {
+		return ""
+	}
+	lines, _ := m.cfg.PreviewLimits()
+	body := make([]string, lines)
+	copy(body, m.previewLines)
+	return previewStyle.Width(m.viewport.Width).Render(strings.Join(body, "\n"))
+}
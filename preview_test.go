@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestRenderPreview(t *testing.T) {
+	r := newMarkdownRenderer("dark")
+
+	got := renderPreview(r, "**bold** text", 40, 6, 100)
+	if len(got) != 1 || !strings.Contains(ansi.Strip(got[0]), "bold text") {
+		t.Errorf("got %q, want one line with the rendered text", got)
+	}
+
+	got = renderPreview(r, "/join #foo", 40, 6, 100)
+	if len(got) != 1 || !strings.Contains(got[0], "aren't previewed") {
+		t.Errorf("command: got %q", got)
+	}
+
+	got = renderPreview(r, strings.Repeat("x", 101), 40, 6, 100)
+	if len(got) != 1 || !strings.Contains(got[0], "over 100 characters") {
+		t.Errorf("long input: got %q", got)
+	}
+
+	// Long input is wrapped to width and only the last lines are kept.
+	got = renderPreview(r, "line1\nline2\nline3\nline4 "+strings.Repeat("word ", 20), 20, 3, 1000)
+	if len(got) != 3 {
+		t.Fatalf("got %d lines, want 3: %q", len(got), got)
+	}
+	for _, l := range got {
+		if w := ansi.StringWidth(l); w > 20 {
+			t.Errorf("line %q is %d wide, want at most 20", l, w)
+		}
+	}
+	if strings.Contains(ansi.Strip(strings.Join(got, "\n")), "line1") {
+		t.Errorf("got %q, want the first lines cut off", got)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"fiatjaf.com/nostr"
+)
+
+// queryWaitLog is how long a query must queue for a slot before the wait is
+// logged, to see what max_concurrent_queries costs on a big join.
+const queryWaitLog = 100 * time.Millisecond
+
+// queryLimiter bounds how many profile and metadata queries run at once;
+// the rest queue. A nil limiter doesn't limit.
+type queryLimiter struct {
+	slots chan struct{}
+}
+
+// newQueryLimiter returns a limiter for n concurrent queries, or nil if n
+// isn't positive.
+func newQueryLimiter(n int) *queryLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return &queryLimiter{slots: make(chan struct{}, n)}
+}
+
+// acquire waits for a free slot and returns the function that frees it.
+func (l *queryLimiter) acquire(what string) func() {
+	if l == nil {
+		return func() {}
+	}
+	start := time.Now()
+	l.slots <- struct{}{}
+	if wait := time.Since(start); wait >= queryWaitLog {
+		log.Printf("queryLimiter: %s queued %s (%d/%d slots busy)", what, wait.Round(time.Millisecond), len(l.slots), cap(l.slots))
+	}
+	return func() { <-l.slots }
+}
+
+// queryReplaceableLimited runs queryReplaceable once a slot is free. The
+// timeout starts then, so queueing doesn't eat into it.
+func queryReplaceableLimited(limit *queryLimiter, what string, pool *nostr.Pool, relays []string, filter nostr.Filter, wait, timeout time.Duration) *nostr.RelayEvent {
+	defer limit.acquire(what)()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return queryReplaceable(ctx, pool, relays, filter, wait)
+}
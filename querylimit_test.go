@@ -0,0 +1,43 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueryLimiterBounds(t *testing.T) {
+	l := newQueryLimiter(3)
+	var running, peak atomic.Int32
+	var wg sync.WaitGroup
+	for range 12 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer l.acquire("test")()
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+		}()
+	}
+	wg.Wait()
+	if got := peak.Load(); got != 3 {
+		t.Errorf("peak concurrency = %d, want 3", got)
+	}
+}
+
+func TestQueryLimiterUnlimited(t *testing.T) {
+	if l := newQueryLimiter(0); l != nil {
+		t.Fatalf("newQueryLimiter(0) = %v, want nil", l)
+	}
+	var l *queryLimiter
+	release := l.acquire("test")
+	release()
+}
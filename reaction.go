@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fiatjaf.com/nostr"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// reactionHistoryLimit is how many past reactions a room subscription asks
+// each relay for. Reactions have their own filter so that in a busy room
+// they don't use up the history limit meant for chat messages.
+const reactionHistoryLimit = 200
+
+// reactionFilter returns the filter for the NIP-25 reactions in a room
+// (tags selects it, like the room's message filter), with rf's since and
+// author overrides. It has no kinds if reactions are excluded.
+func reactionFilter(rf subFilter, tags nostr.TagMap) nostr.Filter {
+	f := rf.apply(nostr.Filter{Kinds: []nostr.Kind{nostr.KindReaction}, Tags: tags})
+	f.Limit = reactionHistoryLimit
+	return f
+}
+
+// reactionMsg carries a NIP-25 reaction received on a room subscription.
+type reactionMsg struct {
+	roomKey    string // channel ID or groupKey the reaction arrived on
+	reactionID string // hex ID of the kind-7 event, for deduplication
+	targetID   string // hex ID of the message reacted to
+	emoji      string
+}
+
+// parseReaction returns the reacted-to event ID and the emoji of a kind-7
+// reaction. Per NIP-25 the target is the last "e" tag; "+" and empty
+// content mean a like, "-" a dislike. ignoreID skips a room's root tag
+// (the channel ID) so it is never taken as the target.
+func parseReaction(evt nostr.Event, ignoreID string) (targetID, emoji string, ok bool) {
+	if evt.Kind != nostr.KindReaction {
+		return "", "", false
+	}
+	for _, tag := range evt.Tags {
+		if len(tag) >= 2 && tag[0] == "e" && tag[1] != ignoreID {
+			targetID = tag[1]
+		}
+	}
+	if targetID == "" {
+		return "", "", false
+	}
+	switch evt.Content {
+	case "", "+":
+		emoji = "👍"
+	case "-":
+		emoji = "👎"
+	default:
+		emoji = evt.Content
+	}
+	return targetID, emoji, true
+}
+
+// handleReaction counts a reaction against its target message and keeps
+// reading the room subscription.
+func (m *model) handleReaction(msg reactionMsg) (tea.Model, tea.Cmd) {
+	m.touchRoomSub(msg.roomKey)
+	if !m.seenReactions[msg.reactionID] {
+		m.seenReactions[msg.reactionID] = true
+		if m.reactions[msg.targetID] == nil {
+			m.reactions[msg.targetID] = make(map[string]int)
+		}
+		m.reactions[msg.targetID][msg.emoji]++
+		if item := m.activeSidebarItem(); item != nil && item.ItemID() == msg.roomKey {
+			m.updateViewport()
+		}
+	}
+	return m, waitForRoomSub(m.roomSubs[msg.roomKey], m.keys)
+}
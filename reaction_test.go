@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestParseReaction(t *testing.T) {
+	chanID := "aaaa"
+	tests := []struct {
+		name       string
+		evt        nostr.Event
+		wantTarget string
+		wantEmoji  string
+		wantOK     bool
+	}{
+		{"like", nostr.Event{Kind: nostr.KindReaction, Content: "+", Tags: nostr.Tags{{"e", "bbbb"}}}, "bbbb", "👍", true},
+		{"dislike", nostr.Event{Kind: nostr.KindReaction, Content: "-", Tags: nostr.Tags{{"e", "bbbb"}}}, "bbbb", "👎", true},
+		{"emoji, last e wins", nostr.Event{Kind: nostr.KindReaction, Content: "🔥", Tags: nostr.Tags{{"e", "cccc"}, {"e", "bbbb"}}}, "bbbb", "🔥", true},
+		{"channel root ignored", nostr.Event{Kind: nostr.KindReaction, Content: "+", Tags: nostr.Tags{{"e", "bbbb"}, {"e", chanID, "", "root"}}}, "bbbb", "👍", true},
+		{"only root", nostr.Event{Kind: nostr.KindReaction, Content: "+", Tags: nostr.Tags{{"e", chanID}}}, "", "", false},
+		{"not a reaction", nostr.Event{Kind: nostr.KindChannelMessage, Tags: nostr.Tags{{"e", "bbbb"}}}, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, emoji, ok := parseReaction(tt.evt, chanID)
+			if target != tt.wantTarget || emoji != tt.wantEmoji || ok != tt.wantOK {
+				t.Errorf("parseReaction = (%q, %q, %v), want (%q, %q, %v)", target, emoji, ok, tt.wantTarget, tt.wantEmoji, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestReactionFilter(t *testing.T) {
+	rf := subFilter{Limit: 20, Since: 1000}
+	f := reactionFilter(rf, nostr.TagMap{"h": {"grp1"}})
+	if len(f.Kinds) != 1 || f.Kinds[0] != nostr.KindReaction {
+		t.Errorf("kinds = %v, want only reactions", f.Kinds)
+	}
+	if f.Limit != reactionHistoryLimit || f.Since != 1000 {
+		t.Errorf("limit = %d, since = %d; want its own limit and the room's since", f.Limit, f.Since)
+	}
+	rf.ExcludeKinds = []nostr.Kind{nostr.KindReaction}
+	if f := reactionFilter(rf, nil); len(f.Kinds) != 0 {
+		t.Errorf("kinds = %v with reactions excluded, want none", f.Kinds)
+	}
+}
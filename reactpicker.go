@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"fiatjaf.com/nostr"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// commonReactions fill the /react picker below the recently used row.
+var commonReactions = []string{
+	"👍", "❤️", "😂", "🎉", "🙏", "🔥", "👀", "🤔",
+	"😮", "😢", "😡", "✅", "💯", "🚀", "🤙", "👎",
+}
+
+// reactPickerCols is the number of emoji per picker row, and also how many
+// recently used reactions are kept.
+const reactPickerCols = 8
+
+// reactPicker is the emoji grid opened by /react [n] without an emoji.
+type reactPicker struct {
+	n      int         // message number, for the title
+	target ChatMessage // message to react to
+	rows   [][]string
+	row    int
+	col    int
+}
+
+// reactionPublishedMsg is returned after a /react reaction was published.
+type reactionPublishedMsg struct {
+	roomKey  string
+	evt      nostr.Event
+	targetID string
+	emoji    string
+	accepted int
+	total    int
+}
+
+// reactionRecentsPath returns the path of the recently used reactions file,
+// next to the config.
+func reactionRecentsPath(cfgFlagPath string) string {
+	return filepath.Join(filepath.Dir(configPath(cfgFlagPath)), "recent_reactions")
+}
+
+// loadRecentReactions reads the recent reactions file: one emoji per line,
+// newest first. A missing file is empty.
+func loadRecentReactions(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	var recents []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if e := strings.TrimSpace(sc.Text()); e != "" && len(recents) < reactPickerCols {
+			recents = append(recents, e)
+		}
+	}
+	return recents, sc.Err()
+}
+
+// saveRecentReactions rewrites the recent reactions file.
+func saveRecentReactions(path string, recents []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(strings.Join(recents, "\n")+"\n"), 0644)
+}
+
+// pushRecentReaction moves emoji to the front of recents, keeping at most
+// reactPickerCols entries.
+func pushRecentReaction(recents []string, emoji string) []string {
+	out := []string{emoji}
+	for _, e := range recents {
+		if e != emoji && len(out) < reactPickerCols {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// reactPickerRows lays out the picker: recently used reactions on the first
+// row (when there are any), then commonReactions.
+func reactPickerRows(recents []string) [][]string {
+	var rows [][]string
+	if len(recents) > 0 {
+		rows = append(rows, recents)
+	}
+	for i := 0; i < len(commonReactions); i += reactPickerCols {
+		rows = append(rows, commonReactions[i:min(i+reactPickerCols, len(commonReactions))])
+	}
+	return rows
+}
+
+// moveInGrid returns the cursor after an arrow (or h/j/k/l) key; other keys
+// leave it unchanged. The column is clamped to the length of the new row.
+func moveInGrid(rows [][]string, row, col int, key string) (int, int) {
+	switch key {
+	case "left", "h":
+		col = max(col-1, 0)
+	case "right", "l":
+		col = min(col+1, len(rows[row])-1)
+	case "up", "k":
+		row = max(row-1, 0)
+	case "down", "j":
+		row = min(row+1, len(rows)-1)
+	}
+	return row, min(col, len(rows[row])-1)
+}
+
+// buildReactionEvent builds a NIP-25 reaction to target. roomTags scope it
+// to the room (the channel root "e" tag or the NIP-29 "h" tag) and come
+// first, so the target stays the last "e" tag as parseReaction expects.
+func buildReactionEvent(target ChatMessage, emoji string, roomTags nostr.Tags, keys Keys) (nostr.Event, error) {
+	tags := append(slices.Clone(roomTags), nostr.Tag{"e", target.EventID})
+	if target.PubKey != "" {
+		tags = append(tags, nostr.Tag{"p", target.PubKey})
+	}
+	evt := nostr.Event{
+		Kind:      nostr.KindReaction,
+		CreatedAt: nostr.Now(),
+		Tags:      tags,
+		Content:   emoji,
+	}
+	if err := evt.Sign(keys.SK); err != nil {
+		return evt, err
+	}
+	return evt, nil
+}
+
+// publishReactionCmd publishes a reaction event to relays.
+func publishReactionCmd(pool *nostr.Pool, relays []string, roomKey, targetID, emoji string, evt nostr.Event) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		logEventOut(roomKey, evt)
+		results := collectPublishResults(ctx, pool.PublishMany(ctx, relays, evt))
+		accepted := 0
+		for _, r := range results {
+			if r == "ok" {
+				accepted++
+			}
+		}
+		return reactionPublishedMsg{roomKey: roomKey, evt: evt, targetID: targetID, emoji: emoji, accepted: accepted, total: len(results)}
+	}
+}
+
+// react handles /react [n] [emoji]: it reacts to the nth most recent message
+// with emoji, or opens the picker when no emoji is given.
+func (m *model) react(arg string) (tea.Model, tea.Cmd) {
+	n := 1
+	emoji := strings.TrimSpace(arg)
+	if first, rest, _ := strings.Cut(emoji, " "); first != "" {
+		if v, err := strconv.Atoi(first); err == nil {
+			if v < 1 {
+				m.addSystemMsg("usage: /react [n] [emoji]")
+				return m, nil
+			}
+			n, emoji = v, strings.TrimSpace(rest)
+		}
+	}
+	switch m.activeSidebarItem().(type) {
+	case ChannelItem, GroupItem:
+	default:
+		m.addSystemMsg("/react only works in a channel or group")
+		return m, nil
+	}
+	msg, _, ok := m.nthRecentMessage(n)
+	if !ok || msg.EventID == "" {
+		m.addSystemMsg(fmt.Sprintf("no message #%d in this conversation", n))
+		return m, nil
+	}
+	if emoji == "" {
+		m.reactPicker = &reactPicker{n: n, target: msg, rows: reactPickerRows(m.recentReactions)}
+		return m, nil
+	}
+	return m, m.sendReaction(msg, emoji)
+}
+
+// sendReaction publishes a reaction to msg in the active room and records
+// emoji as recently used.
+func (m *model) sendReaction(msg ChatMessage, emoji string) tea.Cmd {
+	var relays []string
+	var roomTags nostr.Tags
+	switch it := m.activeSidebarItem().(type) {
+	case ChannelItem:
+		relays = it.Channel.RelaysWith(m.relays)
+		roomTags = nostr.Tags{{"e", it.Channel.ID, "", "root"}}
+	case GroupItem:
+		relays = it.Group.Relays()
+		roomTags = nostr.Tags{{"h", it.Group.GroupID}}
+	default:
+		return nil
+	}
+	evt, err := buildReactionEvent(msg, emoji, roomTags, m.keys)
+	if err != nil {
+		m.addSystemMsg("react: " + err.Error())
+		return nil
+	}
+	m.recentReactions = pushRecentReaction(m.recentReactions, emoji)
+	if err := saveRecentReactions(reactionRecentsPath(m.cfgFlagPath), m.recentReactions); err != nil {
+		log.Printf("react: saving recent reactions: %v", err)
+	}
+	return publishReactionCmd(m.pool, relays, m.activeRoomKey(), msg.EventID, emoji, evt)
+}
+
+// handleReactionPublished counts our reaction right away; the copy the room
+// subscription delivers later is then skipped as already seen.
+func (m *model) handleReactionPublished(msg reactionPublishedMsg) (tea.Model, tea.Cmd) {
+	if msg.accepted == 0 {
+		m.addSystemMsg(fmt.Sprintf("react: no relay accepted the reaction (0/%d)", msg.total))
+		return m, nil
+	}
+	id := msg.evt.ID.Hex()
+	if !m.seenReactions[id] {
+		m.seenReactions[id] = true
+		if m.reactions[msg.targetID] == nil {
+			m.reactions[msg.targetID] = make(map[string]int)
+		}
+		m.reactions[msg.targetID][msg.emoji]++
+		if m.activeRoomKey() == msg.roomKey {
+			m.updateViewport()
+		}
+	}
+	return m, nil
+}
+
+// handleReactPickerKey moves the picker cursor; enter reacts with the
+// selected emoji and esc or q closes the picker.
+func (m *model) handleReactPickerKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.reactPicker
+	switch msg.String() {
+	case "esc", "q":
+		m.reactPicker = nil
+		return m, nil
+	case "enter":
+		m.reactPicker = nil
+		return m, m.sendReaction(p.target, p.rows[p.row][p.col])
+	}
+	p.row, p.col = moveInGrid(p.rows, p.row, p.col, msg.String())
+	return m, nil
+}
+
+// viewReactPicker renders the emoji grid with the target message above it.
+func (m *model) viewReactPicker() string {
+	p := m.reactPicker
+	var b strings.Builder
+	b.WriteString(qrTitleStyle.Render(fmt.Sprintf("React to message #%d", p.n)))
+	b.WriteString("\n")
+	preview := truncateRunes(strings.Join(strings.Fields(p.target.Content), " "), 50)
+	b.WriteString(chatSystemStyle.Render(m.resolveAuthor(p.target.PubKey) + ": " + preview))
+	b.WriteString("\n\n")
+	for r, row := range p.rows {
+		if r == 0 && len(m.recentReactions) > 0 {
+			b.WriteString(chatSystemStyle.Render("recent") + "\n")
+		} else if r == 1 && len(m.recentReactions) > 0 {
+			b.WriteString(chatSystemStyle.Render("common") + "\n")
+		}
+		cells := make([]string, len(row))
+		for c, e := range row {
+			// Pad to two cells so narrow emoji (❤️ without VS16 support) align.
+			cell := " " + e + strings.Repeat(" ", max(3-lipgloss.Width(e), 1))
+			if r == p.row && c == p.col {
+				cells[c] = acSelectedStyle.Render(cell)
+			} else {
+				cells[c] = acSuggestionStyle.Render(cell)
+			}
+		}
+		b.WriteString(strings.Join(cells, "") + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(chatSystemStyle.Render("arrows move · enter reacts · esc closes"))
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestPushRecentReaction(t *testing.T) {
+	got := pushRecentReaction([]string{"👍", "🔥", "❤️"}, "🔥")
+	if want := []string{"🔥", "👍", "❤️"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("pushRecentReaction = %v, want %v", got, want)
+	}
+	if got := pushRecentReaction(commonReactions[:reactPickerCols], "🤯"); len(got) != reactPickerCols || got[0] != "🤯" {
+		t.Errorf("pushRecentReaction on a full list = %v", got)
+	}
+}
+
+func TestRecentReactionsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "recent_reactions")
+	if got, err := loadRecentReactions(path); err != nil || got != nil {
+		t.Fatalf("missing file: %v, %v", got, err)
+	}
+	want := []string{"🎉", "👍"}
+	if err := saveRecentReactions(path, want); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := loadRecentReactions(path); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("loadRecentReactions = %v, %v; want %v", got, err, want)
+	}
+}
+
+func TestMoveInGrid(t *testing.T) {
+	rows := reactPickerRows([]string{"🎉", "👍"})
+	if len(rows) != 3 || len(rows[0]) != 2 {
+		t.Fatalf("rows = %v", rows)
+	}
+	tests := []struct {
+		row, col     int
+		key          string
+		wantR, wantC int
+	}{
+		{1, 5, "up", 0, 1}, // clamped to the shorter recents row
+		{0, 0, "up", 0, 0},
+		{0, 1, "right", 0, 1},
+		{1, 0, "left", 1, 0},
+		{1, 3, "down", 2, 3},
+		{2, 3, "j", 2, 3},
+		{1, 3, "l", 1, 4},
+		{1, 3, "x", 1, 3},
+	}
+	for _, tt := range tests {
+		r, c := moveInGrid(rows, tt.row, tt.col, tt.key)
+		if r != tt.wantR || c != tt.wantC {
+			t.Errorf("moveInGrid(%d, %d, %q) = %d, %d; want %d, %d", tt.row, tt.col, tt.key, r, c, tt.wantR, tt.wantC)
+		}
+	}
+}
+
+func TestBuildReactionEvent(t *testing.T) {
+	sk := nostr.Generate()
+	keys := Keys{SK: sk, PK: sk.Public()}
+	target := ChatMessage{EventID: "aa", PubKey: "bb"}
+	evt, err := buildReactionEvent(target, "🔥", nostr.Tags{{"e", "chan", "", "root"}}, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targetID, emoji, ok := parseReaction(evt, "chan")
+	if !ok || targetID != "aa" || emoji != "🔥" {
+		t.Errorf("parseReaction = %q, %q, %v", targetID, emoji, ok)
+	}
+	if !evt.VerifySignature() {
+		t.Error("reaction not signed")
+	}
+}
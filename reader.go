@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/nip19"
+)
+
+// article is a NIP-23 long-form post (kind 30023).
+type article struct {
+	Title     string
+	Summary   string
+	Image     string
+	Author    string // hex pubkey
+	Published nostr.Timestamp
+	Content   string // markdown
+}
+
+// articleReader is the full-screen /read overlay.
+type articleReader struct {
+	naddr   string
+	article *article // nil while loading
+	status  string   // shown instead of the article while loading or on error
+	vp      viewport.Model
+}
+
+type articleMsg struct {
+	naddr   string
+	article *article
+	err     error
+}
+
+// parseArticleAddr decodes an naddr (optionally nostr:-prefixed) that points
+// to a long-form article.
+func parseArticleAddr(s string) (nostr.EntityPointer, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "nostr:")
+	prefix, val, err := nip19.Decode(s)
+	if err != nil || prefix != "naddr" {
+		return nostr.EntityPointer{}, fmt.Errorf("not an naddr")
+	}
+	ptr := val.(nostr.EntityPointer)
+	if ptr.Kind != nostr.KindArticle {
+		return ptr, fmt.Errorf("naddr points to kind %d, not a long-form article (%d)", ptr.Kind, nostr.KindArticle)
+	}
+	return ptr, nil
+}
+
+// parseArticle reads the NIP-23 metadata tags of a kind-30023 event.
+func parseArticle(evt nostr.Event) *article {
+	a := &article{Author: evt.PubKey.Hex(), Content: evt.Content, Published: evt.CreatedAt}
+	for _, tag := range evt.Tags {
+		if len(tag) < 2 {
+			continue
+		}
+		switch tag[0] {
+		case "title":
+			a.Title = tag[1]
+		case "summary":
+			a.Summary = tag[1]
+		case "image":
+			a.Image = tag[1]
+		case "published_at":
+			if ts, err := strconv.ParseInt(tag[1], 10, 64); err == nil {
+				a.Published = nostr.Timestamp(ts)
+			}
+		}
+	}
+	return a
+}
+
+// fetchArticleCmd fetches the newest version of the article at ptr from the
+// naddr's relay hints and our own relays.
+func fetchArticleCmd(pool *nostr.Pool, relays []string, naddr string, ptr nostr.EntityPointer, wait time.Duration) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		urls := append(append([]string{}, ptr.Relays...), relays...)
+		re := queryReplaceable(ctx, pool, urls, nostr.Filter{
+			Kinds:   []nostr.Kind{ptr.Kind},
+			Authors: []nostr.PubKey{ptr.PublicKey},
+			Tags:    nostr.TagMap{"d": {ptr.Identifier}},
+		}, wait)
+		if re == nil {
+			return articleMsg{naddr: naddr, err: fmt.Errorf("article not found on %d relays", len(urls))}
+		}
+		log.Printf("fetchArticle: %q by %s from %s", ptr.Identifier, shortPK(ptr.PublicKey.Hex()), re.Relay.URL)
+		return articleMsg{naddr: naddr, article: parseArticle(re.Event)}
+	}
+}
+
+// openReader handles /read <naddr>.
+func (m *model) openReader(arg string) (tea.Model, tea.Cmd) {
+	ptr, err := parseArticleAddr(arg)
+	if err != nil {
+		m.addSystemMsg("usage: /read <naddr> — " + err.Error())
+		return m, nil
+	}
+	m.reader = &articleReader{naddr: arg, status: "loading article …", vp: viewport.New(m.width, m.height)}
+	m.renderReader()
+	return m, tea.Batch(
+		fetchArticleCmd(m.pool, m.relays, arg, ptr, m.cfg.ReplaceableWait()),
+		m.maybeRequestProfile(ptr.PublicKey.Hex()),
+	)
+}
+
+func (m *model) handleArticle(msg articleMsg) (tea.Model, tea.Cmd) {
+	if m.reader == nil || m.reader.naddr != msg.naddr {
+		return m, nil
+	}
+	if msg.err != nil {
+		m.reader.status = msg.err.Error()
+	} else {
+		m.reader.article = msg.article
+	}
+	m.renderReader()
+	return m, nil
+}
+
+// handleReaderKey scrolls the reader; q or esc closes it.
+func (m *model) handleReaderKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc":
+		m.reader = nil
+		return m, nil
+	case "g", "home":
+		m.reader.vp.GotoTop()
+		return m, nil
+	case "G", "end":
+		m.reader.vp.GotoBottom()
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.reader.vp, cmd = m.reader.vp.Update(msg)
+	return m, cmd
+}
+
+// renderReader lays out the article for the current window size.
+func (m *model) renderReader() {
+	r := m.reader
+	width := m.width
+	if width > 100 {
+		width = 100 // keep lines readable on wide terminals
+	}
+	r.vp.Width = width
+	r.vp.Height = m.height - 1 // footer
+	if r.article == nil {
+		r.vp.SetContent(chatSystemStyle.Render(r.status))
+		return
+	}
+	a := r.article
+	var b strings.Builder
+	title := a.Title
+	if title == "" {
+		title = "(untitled)"
+	}
+	b.WriteString(qrTitleStyle.Render(title) + "\n")
+	b.WriteString(chatSystemStyle.Render(fmt.Sprintf("by %s · %s", m.resolveAuthor(a.Author), a.Published.Time().Format("2006-01-02"))) + "\n")
+	if a.Summary != "" {
+		b.WriteString("\n" + lipgloss.NewStyle().Italic(true).Width(width).Render(a.Summary) + "\n")
+	}
+	if a.Image != "" {
+		b.WriteString(chatSystemStyle.Render("image: "+a.Image) + "\n")
+	}
+	b.WriteString(chatSystemStyle.Render(strings.Repeat("─", width)) + "\n")
+
+	body := a.Content
+	if rdr, err := glamour.NewTermRenderer(glamour.WithStylePath(m.mdStyle), glamour.WithWordWrap(width-4)); err == nil {
+		if out, err := rdr.Render(a.Content); err == nil {
+			body = out
+		}
+	}
+	b.WriteString(body)
+	r.vp.SetContent(b.String())
+}
+
+// viewReader renders the reader overlay with a scroll-position footer.
+func (m *model) viewReader() string {
+	r := m.reader
+	footer := chatSystemStyle.Render(fmt.Sprintf("↑/↓ pgup/pgdn scroll · g/G top/bottom · q/esc close · %3.0f%%", r.vp.ScrollPercent()*100))
+	page := lipgloss.JoinVertical(lipgloss.Left, r.vp.View(), footer)
+	return lipgloss.PlaceHorizontal(m.width, lipgloss.Center, page)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/nip19"
+)
+
+func TestParseArticleAddr(t *testing.T) {
+	pk := nostr.GetPublicKey(nostr.Generate())
+	naddr := nip19.EncodeNaddr(pk, nostr.KindArticle, "my-post", []string{"wss://relay.example"})
+
+	for _, in := range []string{naddr, "nostr:" + naddr} {
+		ptr, err := parseArticleAddr(in)
+		if err != nil {
+			t.Fatalf("parseArticleAddr(%q): %v", in, err)
+		}
+		if ptr.PublicKey != pk || ptr.Identifier != "my-post" || len(ptr.Relays) != 1 {
+			t.Errorf("parseArticleAddr(%q) = %+v", in, ptr)
+		}
+	}
+
+	if _, err := parseArticleAddr(nip19.EncodeNaddr(pk, nostr.KindSimpleGroupMetadata, "g", nil)); err == nil {
+		t.Error("expected error for a non-article naddr")
+	}
+	if _, err := parseArticleAddr(nip19.EncodeNpub(pk)); err == nil {
+		t.Error("expected error for an npub")
+	}
+}
+
+func TestParseArticle(t *testing.T) {
+	evt := nostr.Event{
+		CreatedAt: 2000,
+		Content:   "# Hello\n\nbody",
+		Tags: nostr.Tags{
+			{"d", "my-post"},
+			{"title", "Hello"},
+			{"summary", "A short post"},
+			{"image", "https://example.com/a.png"},
+			{"published_at", "1000"},
+		},
+	}
+	a := parseArticle(evt)
+	if a.Title != "Hello" || a.Summary != "A short post" || a.Image != "https://example.com/a.png" {
+		t.Errorf("metadata = %+v", a)
+	}
+	if a.Published != 1000 {
+		t.Errorf("Published = %d, want published_at 1000 rather than created_at", a.Published)
+	}
+	if a.Content != evt.Content {
+		t.Errorf("Content = %q", a.Content)
+	}
+
+	// Without published_at, fall back to created_at.
+	if a := parseArticle(nostr.Event{CreatedAt: 2000}); a.Published != 2000 {
+		t.Errorf("Published = %d, want created_at 2000", a.Published)
+	}
+}
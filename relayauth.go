@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"fiatjaf.com/nostr"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// RelayConfig is a [[relay]] entry: connection options for one relay.
+type RelayConfig struct {
+	URL   string `toml:"url"`
+	Auth  string `toml:"auth"`  // "" = NIP-42 only when the relay asks; "nip42" = authenticate before any REQ
+	Token string `toml:"token"` // sent as "Authorization: Bearer <token>" when connecting
+}
+
+// validate checks the auth mode and URL of a [[relay]] entry.
+func (rc RelayConfig) validate() error {
+	if rc.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	switch rc.Auth {
+	case "", "nip42":
+	default:
+		return fmt.Errorf("unknown auth %q (want nip42 or empty)", rc.Auth)
+	}
+	return nil
+}
+
+// header returns the websocket request header for the relay.
+func (rc RelayConfig) header() http.Header {
+	if rc.Token == "" {
+		return nil
+	}
+	return http.Header{"Authorization": {"Bearer " + rc.Token}}
+}
+
+// relayAuthTimeout bounds authenticating to a relay before its
+// subscriptions are opened. Dialing has its own timeout.
+const relayAuthTimeout = 10 * time.Second
+
+// relayAccess connects the relays that have [[relay]] options itself, so
+// their request headers are set and, with auth = "nip42", AUTH has
+// succeeded before anything is subscribed. The connection is stored in the
+// pool, which reuses it for every subscription and publish while it is up.
+// The pool's own RelayOptions apply to every relay alike, so they can't
+// carry a per-relay token; instead a dropped connection is re-established
+// here right away, before the pool's reconnect (which waits a few seconds)
+// would open one without the header.
+type relayAccess struct {
+	pool    *nostr.Pool
+	configs map[string]RelayConfig // by normalized URL
+	sign    func(context.Context, *nostr.Event) error
+
+	// ctx ends with the pool or when stop is called, and with it every
+	// watch this relayAccess started.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu    sync.Mutex
+	ready map[string]*nostr.Relay // connections we set up, by normalized URL
+	locks map[string]*sync.Mutex  // serializes ensure per relay
+}
+
+func newRelayAccess(pool *nostr.Pool, configs []RelayConfig, sign func(context.Context, *nostr.Event) error) *relayAccess {
+	a := &relayAccess{pool: pool, configs: make(map[string]RelayConfig), sign: sign,
+		ready: make(map[string]*nostr.Relay), locks: make(map[string]*sync.Mutex)}
+	a.ctx, a.cancel = context.WithCancel(pool.Context)
+	for _, rc := range configs {
+		a.configs[nostr.NormalizeURL(rc.URL)] = rc
+	}
+	return a
+}
+
+// stop ends the reconnect watches, for when a reload replaces a with a
+// relayAccess for new [[relay]] options. The connections stay up until the
+// new one replaces them.
+func (a *relayAccess) stop() {
+	a.cancel()
+}
+
+// prepare connects (and authenticates) every relay in urls that has
+// [[relay]] options and isn't ready yet, in parallel.
+func (a *relayAccess) prepare(ctx context.Context, urls []string) {
+	var wg sync.WaitGroup
+	for _, url := range urls {
+		rc, ok := a.configs[nostr.NormalizeURL(url)]
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := a.ensure(ctx, rc); err != nil {
+				log.Printf("relayAccess: %s: %v", rc.URL, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// ensure sets up the connection to rc.URL unless ours is still up. A
+// connection the pool opened on its own (without our header) is replaced.
+func (a *relayAccess) ensure(ctx context.Context, rc RelayConfig) error {
+	nm := nostr.NormalizeURL(rc.URL)
+	a.mu.Lock()
+	lock := a.locks[nm]
+	if lock == nil {
+		lock = new(sync.Mutex)
+		a.locks[nm] = lock
+	}
+	a.mu.Unlock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	a.mu.Lock()
+	r := a.ready[nm]
+	a.mu.Unlock()
+	if r != nil && r.IsConnected() && r.Context().Err() == nil {
+		return nil // IsConnected lags a moment behind a closed connection
+	}
+	if other, ok := a.pool.Relays.Load(nm); ok && other != nil && other != r {
+		other.Close()
+	}
+
+	// The connection lives as long as the context it was opened with, so
+	// that is the pool's; the dial itself times out on its own.
+	r = nostr.NewRelay(a.pool.Context, rc.URL, nostr.RelayOptions{RequestHeader: rc.header()})
+	if err := r.Connect(a.pool.Context); err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, relayAuthTimeout)
+	defer cancel()
+	if rc.Auth == "nip42" {
+		if err := authenticate(ctx, r, a.sign); err != nil {
+			r.Close()
+			return err
+		}
+		log.Printf("relayAccess: authenticated to %s", rc.URL)
+	}
+	a.pool.Relays.Store(nm, r)
+	a.mu.Lock()
+	a.ready[nm] = r
+	a.mu.Unlock()
+	go a.watch(rc, r)
+	return nil
+}
+
+// relayRewatchMax caps the wait between attempts to reconnect a relay with
+// [[relay]] options.
+const relayRewatchMax = time.Minute
+
+// watch waits for our connection r to drop and then reconnects rc.URL
+// with its options, retrying with backoff until it succeeds, the pool
+// shuts down or a is stopped. The new connection gets its own watch.
+func (a *relayAccess) watch(rc RelayConfig, r *nostr.Relay) {
+	select {
+	case <-r.Context().Done():
+	case <-a.ctx.Done():
+		return
+	}
+	delay := time.Second
+	for a.ctx.Err() == nil {
+		err := a.ensure(a.ctx, rc)
+		if err == nil {
+			log.Printf("relayAccess: reconnected %s", rc.URL)
+			return
+		}
+		log.Printf("relayAccess: reconnect %s: %v (retrying in %s)", rc.URL, err, delay)
+		select {
+		case <-time.After(delay):
+		case <-a.ctx.Done():
+			return
+		}
+		delay = min(2*delay, relayRewatchMax)
+	}
+}
+
+// authenticate retries NIP-42 AUTH until the relay accepts it or ctx ends.
+// Right after connecting the challenge may not have arrived yet, which the
+// relay rejects; the retry then answers the real challenge.
+func authenticate(ctx context.Context, r *nostr.Relay, sign func(context.Context, *nostr.Event) error) error {
+	for {
+		err := r.Auth(ctx, sign)
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("nip42 auth: %w", err)
+		case <-time.After(300 * time.Millisecond):
+		}
+	}
+}
+
+// afterRelayAccess runs cmd once the relays in urls with [[relay]] options
+// are connected and authenticated. The relayAccess is captured now, as a
+// reload may replace m.access before cmd runs.
+func (m *model) afterRelayAccess(urls []string, cmd tea.Cmd) tea.Cmd {
+	a := m.access
+	if a == nil || len(a.configs) == 0 {
+		return cmd
+	}
+	prepare := func() tea.Msg {
+		a.prepare(context.Background(), urls)
+		return nil
+	}
+	return tea.Sequence(prepare, cmd)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/khatru"
+)
+
+func TestRelayConfigValidate(t *testing.T) {
+	for _, tt := range []struct {
+		rc RelayConfig
+		ok bool
+	}{
+		{RelayConfig{URL: "wss://r"}, true},
+		{RelayConfig{URL: "wss://r", Auth: "nip42"}, true},
+		{RelayConfig{URL: "wss://r", Auth: "bearer"}, false},
+		{RelayConfig{Auth: "nip42"}, false},
+	} {
+		if err := tt.rc.validate(); (err == nil) != tt.ok {
+			t.Errorf("validate(%+v) = %v, want ok=%v", tt.rc, err, tt.ok)
+		}
+	}
+}
+
+func TestRelayAccessAuthenticatesBeforeUse(t *testing.T) {
+	relay := khatru.NewRelay()
+	relay.OnConnect = func(ctx context.Context) { khatru.RequestAuth(ctx) }
+	var authHeader atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "" {
+			authHeader.Store(r.Header.Get("Authorization"))
+		}
+		relay.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	sk := nostr.Generate()
+	pool := nostr.NewPool(nostr.PoolOptions{})
+	defer pool.Close("test done")
+	a := newRelayAccess(pool, []RelayConfig{{URL: url, Auth: "nip42", Token: "s3cret"}},
+		func(ctx context.Context, evt *nostr.Event) error { return evt.Sign(sk) })
+
+	if err := a.ensure(context.Background(), a.configs[nostr.NormalizeURL(url)]); err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+	if got, _ := authHeader.Load().(string); got != "Bearer s3cret" {
+		t.Errorf("Authorization header = %q", got)
+	}
+	r, ok := pool.Relays.Load(nostr.NormalizeURL(url))
+	if !ok || r != a.ready[nostr.NormalizeURL(url)] {
+		t.Fatal("pool does not reuse the authenticated connection")
+	}
+	got, err := pool.EnsureRelay(url)
+	if err != nil || got != r {
+		t.Errorf("EnsureRelay = %p, %v; want the prepared relay %p", got, err, r)
+	}
+}
+
+func TestRelayAccessReconnectsWithToken(t *testing.T) {
+	relay := khatru.NewRelay()
+	var withToken atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "" && r.Header.Get("Authorization") == "Bearer s3cret" {
+			withToken.Add(1)
+		}
+		relay.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	nm := nostr.NormalizeURL(url)
+
+	pool := nostr.NewPool(nostr.PoolOptions{})
+	defer pool.Close("test done")
+	a := newRelayAccess(pool, []RelayConfig{{URL: url, Token: "s3cret"}}, nil)
+	if err := a.ensure(context.Background(), a.configs[nm]); err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+	first, _ := pool.Relays.Load(nm)
+	first.Close() // the connection drops
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		r, ok := pool.Relays.Load(nm)
+		if ok && r != first && r.IsConnected() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("relay was not reconnected")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if n := withToken.Load(); n != 2 {
+		t.Errorf("%d connections carried the token, want both", n)
+	}
+}
+
+func TestRelayAccessStopEndsWatch(t *testing.T) {
+	relay := khatru.NewRelay()
+	srv := httptest.NewServer(relay)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	nm := nostr.NormalizeURL(url)
+
+	pool := nostr.NewPool(nostr.PoolOptions{})
+	defer pool.Close("test done")
+	a := newRelayAccess(pool, []RelayConfig{{URL: url, Token: "s3cret"}}, nil)
+	if err := a.ensure(context.Background(), a.configs[nm]); err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+	a.stop()
+	first, _ := pool.Relays.Load(nm)
+	first.Close()
+
+	time.Sleep(500 * time.Millisecond)
+	if r, ok := pool.Relays.Load(nm); ok && r != first {
+		t.Error("a stopped relayAccess reconnected the relay")
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"fiatjaf.com/nostr"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// slowRelayLatency is the round trip above which the status bar shows the
+// relays as degraded even when all are connected.
+const slowRelayLatency = time.Second
+
+// statusPingTickMsg fires on the status-bar ping ticker.
+type statusPingTickMsg struct{}
+
+// statusPingMsg carries the results of a background ping for the status bar.
+type statusPingMsg struct {
+	results []relayPing
+}
+
+// statusPingTickCmd schedules the next background ping.
+func statusPingTickCmd(every time.Duration) tea.Cmd {
+	return tea.Tick(every, func(time.Time) tea.Msg { return statusPingTickMsg{} })
+}
+
+// worstLatency returns the slowest known round trip among relays, and
+// whether any is known.
+func worstLatency(relays []string, latency map[string]time.Duration) (time.Duration, bool) {
+	var worst time.Duration
+	known := false
+	for _, url := range relays {
+		if rtt, ok := latency[url]; ok {
+			worst = max(worst, rtt)
+			known = true
+		}
+	}
+	return worst, known
+}
+
+// relayIndicator renders the status bar's relay count: green when every
+// relay is connected and answering quickly, yellow when some are down or
+// slow, red when none is connected. The worst latency is appended once
+// a ping has measured it.
+func relayIndicator(connected, total int, worst time.Duration, known bool) string {
+	text := fmt.Sprintf("● %d/%d relays", connected, total)
+	if known && connected > 0 {
+		text += fmt.Sprintf(" (%s)", worst.Round(time.Millisecond))
+	}
+	var style lipgloss.Style
+	switch {
+	case connected == 0:
+		style = statusDownStyle
+	case connected < total || (known && worst >= slowRelayLatency):
+		style = statusDegradedStyle
+	default:
+		style = statusConnectedStyle
+	}
+	return style.Render(text)
+}
+
+// statusPingCmd pings all relays for the status bar.
+func statusPingCmd(pool *nostr.Pool, relays []string) tea.Cmd {
+	return func() tea.Msg {
+		return statusPingMsg{results: pingRelays(pool, relays)}
+	}
+}
+
+func (m *model) handleStatusPingTick() (tea.Model, tea.Cmd) {
+	return m, statusPingCmd(m.pool, m.relays)
+}
+
+// handleStatusPing records the latencies and schedules the next ping,
+// unless /reload turned the background ping off.
+func (m *model) handleStatusPing(msg statusPingMsg) (tea.Model, tea.Cmd) {
+	m.recordLatency(msg.results)
+	if m.cfg.StatusPingInterval() <= 0 {
+		return m, nil
+	}
+	return m, statusPingTickCmd(m.cfg.StatusPingInterval())
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorstLatency(t *testing.T) {
+	latency := map[string]time.Duration{
+		"wss://a":     40 * time.Millisecond,
+		"wss://b":     120 * time.Millisecond,
+		"wss://other": 900 * time.Millisecond, // not one of ours
+	}
+	worst, known := worstLatency([]string{"wss://a", "wss://b", "wss://down"}, latency)
+	if !known || worst != 120*time.Millisecond {
+		t.Errorf("worstLatency = %s, %v; want 120ms, true", worst, known)
+	}
+	if _, known := worstLatency([]string{"wss://down"}, latency); known {
+		t.Error("worstLatency reported a latency for a relay never measured")
+	}
+}
+
+func TestRelayIndicator(t *testing.T) {
+	tests := []struct {
+		name             string
+		connected, total int
+		worst            time.Duration
+		known            bool
+		want             string
+		style            string
+	}{
+		{"all up", 5, 5, 120 * time.Millisecond, true, "● 5/5 relays (120ms)", statusConnectedStyle.Render("x")},
+		{"not measured", 5, 5, 0, false, "● 5/5 relays", statusConnectedStyle.Render("x")},
+		{"some down", 3, 5, 80 * time.Millisecond, true, "● 3/5 relays (80ms)", statusDegradedStyle.Render("x")},
+		{"slow", 2, 2, 1500 * time.Millisecond, true, "● 2/2 relays (1.5s)", statusDegradedStyle.Render("x")},
+		{"all down", 0, 5, 80 * time.Millisecond, true, "● 0/5 relays", statusDownStyle.Render("x")},
+	}
+	for _, tt := range tests {
+		got := relayIndicator(tt.connected, tt.total, tt.worst, tt.known)
+		want := strings.Replace(tt.style, "x", tt.want, 1)
+		if got != want {
+			t.Errorf("%s: relayIndicator = %q, want %q", tt.name, got, want)
+		}
+	}
+}
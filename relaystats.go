@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"fiatjaf.com/nostr"
+)
+
+// relayURLOf returns the URL of the relay that delivered re, or "" if unknown.
+func relayURLOf(re nostr.RelayEvent) string {
+	if re.Relay == nil {
+		return ""
+	}
+	return re.Relay.URL
+}
+
+// relayDuplicates counts, per relay, the events the pool dropped because
+// another relay had delivered them first. Those copies never reach the
+// rooms, so countRelayDelivery doesn't see them. The pool reports them from
+// its own goroutines (PoolOptions.DuplicateMiddleware).
+type relayDuplicates struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (d *relayDuplicates) record(relay string, _ nostr.ID) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.counts == nil {
+		d.counts = make(map[string]int)
+	}
+	d.counts[relay]++
+}
+
+// snapshot returns a copy of the counts; a nil counter has none.
+func (d *relayDuplicates) snapshot() map[string]int {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return maps.Clone(d.counts)
+}
+
+// countRelayDelivery records that relay delivered an event for room. Only
+// the first copy of an event is counted here; later copies from other
+// relays are counted per relay in m.relayDups.
+func (m *model) countRelayDelivery(room, relay string) {
+	if relay == "" {
+		return // local echo
+	}
+	if m.relayStats == nil {
+		m.relayStats = make(map[string]map[string]int)
+	}
+	if m.relayStats[room] == nil {
+		m.relayStats[room] = make(map[string]int)
+	}
+	m.relayStats[room][relay]++
+}
+
+// formatRelayCounts lists relays by descending event count, then by URL.
+func formatRelayCounts(counts map[string]int) string {
+	urls := make([]string, 0, len(counts))
+	for url := range counts {
+		urls = append(urls, url)
+	}
+	sort.Slice(urls, func(i, j int) bool {
+		if counts[urls[i]] != counts[urls[j]] {
+			return counts[urls[i]] > counts[urls[j]]
+		}
+		return urls[i] < urls[j]
+	})
+	parts := make([]string, len(urls))
+	for i, url := range urls {
+		parts[i] = fmt.Sprintf("%s %d", url, counts[url])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// silentRelays returns the configured relays that delivered nothing this
+// session: no room event, and not even a copy of one another relay
+// delivered first.
+func (m *model) silentRelays() []string {
+	dups := m.relayDups.snapshot()
+	var out []string
+	for _, url := range m.relays {
+		delivered := dups[url] > 0
+		for _, counts := range m.relayStats {
+			if counts[url] > 0 {
+				delivered = true
+				break
+			}
+		}
+		if !delivered {
+			out = append(out, url)
+		}
+	}
+	return out
+}
+
+// showRelayStats prints, per channel and group, which relays delivered its
+// events this session and how many. DMs are not included: gift wraps
+// arrive already unwrapped, without the relay that sent them.
+func (m *model) showRelayStats() (tea.Model, tea.Cmd) {
+	received := false
+	for _, it := range m.sidebar {
+		if it.Kind() == SidebarDM {
+			continue
+		}
+		counts := m.relayStats[it.ItemID()]
+		if len(counts) == 0 {
+			m.addSystemMsg(fmt.Sprintf("%s%s: no events yet", it.Prefix(), it.DisplayName()))
+			continue
+		}
+		received = true
+		m.addSystemMsg(fmt.Sprintf("%s%s: %s", it.Prefix(), it.DisplayName(), formatRelayCounts(counts)))
+	}
+	if !received {
+		m.addSystemMsg("no room events received yet")
+		return m, nil
+	}
+	if dups := m.relayDups.snapshot(); len(dups) > 0 {
+		m.addSystemMsg("copies dropped as duplicates (all subscriptions): " + formatRelayCounts(dups))
+	}
+	if silent := m.silentRelays(); len(silent) > 0 {
+		m.addSystemMsg("no events for any room from: " + strings.Join(silent, ", "))
+	}
+	return m, nil
+}
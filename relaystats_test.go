@@ -0,0 +1,44 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestFormatRelayCounts(t *testing.T) {
+	got := formatRelayCounts(map[string]int{"wss://b": 3, "wss://a": 3, "wss://c": 10})
+	if want := "wss://c 10, wss://a 3, wss://b 3"; got != want {
+		t.Errorf("formatRelayCounts = %q, want %q", got, want)
+	}
+}
+
+func TestSilentRelays(t *testing.T) {
+	m := newTestModel(2, 0, 0)
+	m.relays = []string{"wss://a", "wss://b", "wss://c"}
+	m.countRelayDelivery("ch0", "wss://a")
+	m.countRelayDelivery("ch1", "wss://c")
+	m.countRelayDelivery("ch1", "") // local echo, not counted
+	if got := m.silentRelays(); !slices.Equal(got, []string{"wss://b"}) {
+		t.Errorf("silentRelays = %v, want [wss://b]", got)
+	}
+	if m.relayStats["ch1"]["wss://c"] != 1 || len(m.relayStats["ch1"]) != 1 {
+		t.Errorf("ch1 stats = %v", m.relayStats["ch1"])
+	}
+}
+
+func TestSilentRelaysCountsDuplicates(t *testing.T) {
+	m := newTestModel(1, 0, 0)
+	m.relays = []string{"wss://a", "wss://b"}
+	m.relayDups = &relayDuplicates{}
+	m.countRelayDelivery("ch0", "wss://a")
+	// wss://b only ever delivered copies of events wss://a sent first.
+	m.relayDups.record("wss://b", nostr.ID{})
+	if got := m.silentRelays(); len(got) != 0 {
+		t.Errorf("silentRelays = %v, want none: wss://b delivered duplicates", got)
+	}
+	if got := m.relayDups.snapshot()["wss://b"]; got != 1 {
+		t.Errorf("duplicates from wss://b = %d, want 1", got)
+	}
+}
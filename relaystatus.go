@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"fiatjaf.com/nostr"
+)
+
+// relayProbedMsg reports the outcome of the startup connection attempt to a relay.
+type relayProbedMsg struct {
+	url string
+	err error
+}
+
+// relayStatusKey is the StatusKey of a relay's "connecting to ..." line.
+func relayStatusKey(url string) string {
+	return "relay:" + url
+}
+
+// probeRelayCmd connects to a relay so its startup status line can show
+// whether the connection succeeded.
+func probeRelayCmd(pool *nostr.Pool, url string) tea.Cmd {
+	return func() tea.Msg {
+		_, err := pool.EnsureRelay(url)
+		return relayProbedMsg{url: url, err: err}
+	}
+}
+
+func (m *model) handleRelayProbed(msg relayProbedMsg) (tea.Model, tea.Cmd) {
+	text := fmt.Sprintf("connected to %s", msg.url)
+	if msg.err != nil {
+		log.Printf("relayProbedMsg: %s: %v", msg.url, msg.err)
+		text = fmt.Sprintf("failed: %s: %v", msg.url, msg.err)
+	}
+	m.updateStatusMsg(relayStatusKey(msg.url), text)
+	return m, nil
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/nip11"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// relayProbeTimeout bounds each probe of /relay-test.
+const relayProbeTimeout = 5 * time.Second
+
+// checkResult is the outcome of one /relay-test check.
+type checkResult int
+
+const (
+	checkPass checkResult = iota
+	checkFail
+	checkSkip // couldn't be tested
+)
+
+// relayCheck is one line of the /relay-test report.
+type relayCheck struct {
+	Feature string
+	Result  checkResult
+	Detail  string
+}
+
+// relayTestMsg carries the /relay-test report for a relay.
+type relayTestMsg struct {
+	url    string
+	name   string // from NIP-11, may be empty
+	checks []relayCheck
+}
+
+// nipAdvertised reports whether a NIP-11 supported_nips list contains n.
+// Relays send numbers, some as strings.
+func nipAdvertised(nips []any, n int) bool {
+	for _, v := range nips {
+		switch v := v.(type) {
+		case float64:
+			if int(v) == n {
+				return true
+			}
+		case int:
+			if v == n {
+				return true
+			}
+		case string:
+			if i, err := strconv.Atoi(v); err == nil && i == n {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// advertisedNote describes whether the relay lists nips in NIP-11.
+func advertisedNote(nips []any, hasInfo bool, n ...int) string {
+	if !hasInfo {
+		return "no NIP-11 document"
+	}
+	var listed []string
+	for _, x := range n {
+		if nipAdvertised(nips, x) {
+			listed = append(listed, strconv.Itoa(x))
+		}
+	}
+	if len(listed) == 0 {
+		return "not in supported_nips"
+	}
+	return "NIP-" + strings.Join(listed, ", NIP-") + " advertised"
+}
+
+// probeRead runs filter on r and counts the stored events up to EOSE.
+func probeRead(r *nostr.Relay, filter nostr.Filter) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), relayProbeTimeout)
+	defer cancel()
+	sub, err := r.Subscribe(ctx, filter, nostr.SubscriptionOptions{Label: "relay-test"})
+	if err != nil {
+		return 0, err
+	}
+	defer sub.Unsub()
+	n := 0
+	for {
+		select {
+		case <-sub.Events:
+			n++
+		case <-sub.EndOfStoredEvents:
+			return n, nil
+		case reason := <-sub.ClosedReason:
+			return n, fmt.Errorf("closed: %s", reason)
+		case <-ctx.Done():
+			return n, fmt.Errorf("no answer within %s", relayProbeTimeout)
+		}
+	}
+}
+
+// probeWrite publishes evt to r.
+func probeWrite(r *nostr.Relay, evt nostr.Event) error {
+	ctx, cancel := context.WithTimeout(context.Background(), relayProbeTimeout)
+	defer cancel()
+	logEventOut("", evt)
+	return r.Publish(ctx, evt)
+}
+
+// throwawayGiftWrap builds a kind-1059 event as NIP-59 does: signed by a
+// random key, addressed to a random key nobody holds, with junk content.
+// It expires a minute later (NIP-40), so relays may drop it right away.
+func throwawayGiftWrap() (nostr.Event, error) {
+	junk := make([]byte, 256)
+	if _, err := rand.Read(junk); err != nil {
+		return nostr.Event{}, err
+	}
+	return throwawayEvent(nostr.KindGiftWrap, base64.StdEncoding.EncodeToString(junk),
+		nostr.Tag{"p", nostr.GetPublicKey(nostr.Generate()).Hex()})
+}
+
+// throwawayEvent builds an event of kind signed by a random key, expiring a
+// minute later (NIP-40). The write probes use these rather than the user's
+// own events, so testing a relay doesn't hand it the user's profile or
+// relay lists.
+func throwawayEvent(kind nostr.Kind, content string, tags ...nostr.Tag) (nostr.Event, error) {
+	evt := nostr.Event{
+		Kind:      kind,
+		CreatedAt: nostr.Now(),
+		Tags:      append(nostr.Tags(tags), nostr.Tag{"expiration", strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)}),
+		Content:   content,
+	}
+	err := evt.Sign(nostr.Generate())
+	return evt, err
+}
+
+// relayChallengeWait is how long /relay-test waits for a NIP-42
+// challenge after connecting. Relays that want AUTH send it right away.
+const relayChallengeWait = 2 * time.Second
+
+// errChallengeNoted is what the /relay-test AuthHandler returns: it only
+// reports the challenge, which relayTestCmd then answers itself to see the
+// relay's verdict.
+var errChallengeNoted = errors.New("challenge answered by the relay test")
+
+// challengeNotifier returns an AuthHandler that signals challenged when the
+// relay sends a challenge instead of answering it.
+func challengeNotifier(challenged chan<- struct{}) func(context.Context, *nostr.Relay, *nostr.Event) error {
+	return func(context.Context, *nostr.Relay, *nostr.Event) error {
+		select {
+		case challenged <- struct{}{}:
+		default:
+		}
+		return errChallengeNoted
+	}
+}
+
+// readCheck turns a read probe into a check.
+func readCheck(feature, note string, n int, err error, what string) relayCheck {
+	if err != nil {
+		return relayCheck{feature, checkFail, note + "; " + err.Error()}
+	}
+	return relayCheck{feature, checkPass, fmt.Sprintf("%s; %d %s found", note, n, what)}
+}
+
+// relayTestCmd checks what url supports of the NIPs nitrous uses: NIP-11
+// supported_nips, and probes that read channels, groups, and gift wraps,
+// authenticate when the relay asks to, and accept kind 0/10002/10050
+// events. Written events are signed by throwaway keys. header is sent when
+// connecting, as for a relay with [[relay]] options.
+func relayTestCmd(url string, header http.Header, sign func(context.Context, *nostr.Event) error) tea.Cmd {
+	return func() tea.Msg {
+		msg := relayTestMsg{url: url}
+		ctx, cancel := context.WithTimeout(context.Background(), relayProbeTimeout)
+		info, infoErr := nip11.Fetch(ctx, url)
+		cancel()
+		hasInfo := infoErr == nil
+		msg.name = info.Name
+		if hasInfo {
+			msg.checks = append(msg.checks, relayCheck{"NIP-11 info", checkPass, fmt.Sprintf("%d NIPs listed", len(info.SupportedNIPs))})
+		} else {
+			msg.checks = append(msg.checks, relayCheck{"NIP-11 info", checkFail, infoErr.Error()})
+		}
+
+		// A connection of its own, so the probes neither reuse nor leave
+		// behind one in the pool. Ending the context it is opened with
+		// drops it, so that is not bounded; the dial times out on its own.
+		challenged := make(chan struct{}, 1)
+		r, err := nostr.RelayConnect(context.Background(), url, nostr.RelayOptions{AuthHandler: challengeNotifier(challenged), RequestHeader: header})
+		if err != nil {
+			msg.checks = append(msg.checks, relayCheck{"connection", checkFail, err.Error()})
+			return msg
+		}
+		defer r.Close()
+		msg.checks = append(msg.checks, relayCheck{"connection", checkPass, "connected"})
+
+		// Authenticate first, so relays that gate reading don't fail the
+		// probes below. Relays that send no challenge don't want AUTH.
+		authNote := advertisedNote(info.SupportedNIPs, hasInfo, 42)
+		select {
+		case <-challenged:
+			ctx, cancel = context.WithTimeout(context.Background(), relayProbeTimeout)
+			err = r.Auth(ctx, sign)
+			cancel()
+			if err != nil {
+				msg.checks = append(msg.checks, relayCheck{"NIP-42 auth", checkFail, authNote + "; AUTH rejected: " + err.Error()})
+			} else {
+				msg.checks = append(msg.checks, relayCheck{"NIP-42 auth", checkPass, authNote + "; authenticated"})
+			}
+		case <-time.After(relayChallengeWait):
+			msg.checks = append(msg.checks, relayCheck{"NIP-42 auth", checkSkip, authNote + "; not requested by relay"})
+		}
+
+		n, err := probeRead(r, nostr.Filter{Kinds: []nostr.Kind{nostr.KindChannelCreation}, Limit: 5})
+		msg.checks = append(msg.checks, readCheck("NIP-28 channels", advertisedNote(info.SupportedNIPs, hasInfo, 28), n, err, "channels"))
+
+		n, err = probeRead(r, nostr.Filter{Kinds: []nostr.Kind{nostr.KindSimpleGroupMetadata}, Limit: 5})
+		groups := readCheck("NIP-29 groups", advertisedNote(info.SupportedNIPs, hasInfo, 29), n, err, "groups")
+		if groups.Result == checkPass && n == 0 && !nipAdvertised(info.SupportedNIPs, 29) {
+			groups.Result = checkFail
+			groups.Detail += " (not a group relay)"
+		}
+		msg.checks = append(msg.checks, groups)
+
+		dmNote := advertisedNote(info.SupportedNIPs, hasInfo, 17, 59)
+		if gw, err := throwawayGiftWrap(); err != nil {
+			msg.checks = append(msg.checks, relayCheck{"NIP-17 gift wraps", checkSkip, err.Error()})
+		} else if err := probeWrite(r, gw); err != nil {
+			msg.checks = append(msg.checks, relayCheck{"NIP-17 gift wraps", checkFail, dmNote + "; kind 1059 rejected: " + err.Error()})
+		} else {
+			msg.checks = append(msg.checks, relayCheck{"NIP-17 gift wraps", checkPass, dmNote + "; kind 1059 accepted"})
+		}
+
+		for _, k := range []struct {
+			kind    nostr.Kind
+			feature string
+			content string
+			tag     nostr.Tag
+		}{
+			{nostr.KindProfileMetadata, "write kind 0 (profile)", `{"name":"nitrous relay test"}`, nil},
+			{nostr.KindRelayListMetadata, "write kind 10002 (relay list)", "", nostr.Tag{"r", url}},
+			{nostr.KindDMRelayList, "write kind 10050 (DM relays)", "", nostr.Tag{"relay", url}},
+		} {
+			var tags []nostr.Tag
+			if k.tag != nil {
+				tags = append(tags, k.tag)
+			}
+			if evt, err := throwawayEvent(k.kind, k.content, tags...); err != nil {
+				msg.checks = append(msg.checks, relayCheck{k.feature, checkSkip, err.Error()})
+			} else if err := probeWrite(r, evt); err != nil {
+				msg.checks = append(msg.checks, relayCheck{k.feature, checkFail, "test event rejected: " + err.Error()})
+			} else {
+				msg.checks = append(msg.checks, relayCheck{k.feature, checkPass, "test event accepted"})
+			}
+		}
+		log.Printf("relayTest: %s: %d checks", url, len(msg.checks))
+		return msg
+	}
+}
+
+// renderRelayTest formats the /relay-test report for the overlay.
+func renderRelayTest(msg relayTestMsg) string {
+	title := "Relay test: " + msg.url
+	if msg.name != "" {
+		title += " (" + msg.name + ")"
+	}
+	var b strings.Builder
+	b.WriteString(qrTitleStyle.Render(title) + "\n\n")
+	width := 0
+	for _, c := range msg.checks {
+		width = max(width, len(c.Feature))
+	}
+	for _, c := range msg.checks {
+		var mark string
+		switch c.Result {
+		case checkPass:
+			mark = statusConnectedStyle.Render("✓")
+		case checkFail:
+			mark = chatFailedStyle.Render("✗")
+		default:
+			mark = chatSystemStyle.Render("–")
+		}
+		fmt.Fprintf(&b, "%s %-*s  %s\n", mark, width, c.Feature, chatSystemStyle.Render(c.Detail))
+	}
+	b.WriteString("\n" + chatSystemStyle.Render("press any key to close"))
+	return b.String()
+}
+
+// relayTest handles /relay-test <url>.
+func (m *model) relayTest(arg string) (tea.Model, tea.Cmd) {
+	url := normalizeRelayURL(arg)
+	if url == "" {
+		m.addSystemMsg("usage: /relay-test wss://relay.example")
+		return m, nil
+	}
+	var header http.Header
+	for _, rc := range m.cfg.RelayOptions {
+		if nostr.NormalizeURL(rc.URL) == nostr.NormalizeURL(url) {
+			header = rc.header()
+		}
+	}
+	m.addSystemMsg("testing " + url + " ...")
+	return m, relayTestCmd(url, header, m.kr.SignEvent)
+}
+
+func (m *model) handleRelayTest(msg relayTestMsg) (tea.Model, tea.Cmd) {
+	m.qrOverlay = renderRelayTest(msg)
+	return m, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/khatru"
+)
+
+func TestNIPAdvertised(t *testing.T) {
+	nips := []any{float64(1), float64(11), "42", float64(29)}
+	for n, want := range map[int]bool{1: true, 11: true, 42: true, 29: true, 17: false, 28: false} {
+		if got := nipAdvertised(nips, n); got != want {
+			t.Errorf("nipAdvertised(%d) = %v, want %v", n, got, want)
+		}
+	}
+	if nipAdvertised(nil, 1) {
+		t.Error("empty list advertises NIP-1")
+	}
+}
+
+func TestAdvertisedNote(t *testing.T) {
+	nips := []any{float64(17), float64(59)}
+	if got := advertisedNote(nips, true, 17, 59); got != "NIP-17, NIP-59 advertised" {
+		t.Errorf("got %q", got)
+	}
+	if got := advertisedNote(nips, true, 28); got != "not in supported_nips" {
+		t.Errorf("got %q", got)
+	}
+	if got := advertisedNote(nil, false, 28); got != "no NIP-11 document" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestThrowawayGiftWrap(t *testing.T) {
+	evt, err := throwawayGiftWrap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evt.Kind != nostr.KindGiftWrap || !evt.CheckID() || !evt.VerifySignature() {
+		t.Errorf("bad gift wrap: kind %d", evt.Kind)
+	}
+	if evt.Tags.Find("expiration") == nil || evt.Tags.Find("p") == nil {
+		t.Errorf("missing tags: %v", evt.Tags)
+	}
+}
+
+func TestChallengeNotifier(t *testing.T) {
+	challenged := make(chan struct{}, 1)
+	notify := challengeNotifier(challenged)
+	for range 2 { // a second challenge must not block
+		if err := notify(context.Background(), nil, &nostr.Event{}); !errors.Is(err, errChallengeNoted) {
+			t.Errorf("err = %v, want errChallengeNoted", err)
+		}
+	}
+	select {
+	case <-challenged:
+	default:
+		t.Error("challenge not signalled")
+	}
+}
+
+func TestRenderRelayTest(t *testing.T) {
+	out := renderRelayTest(relayTestMsg{url: "wss://r", name: "Relay", checks: []relayCheck{
+		{"NIP-28 channels", checkPass, "3 channels found"},
+		{"NIP-42 auth", checkFail, "no AUTH challenge answered"},
+		{"write kind 0 (profile)", checkSkip, "none of ours"},
+	}})
+	for _, want := range []string{"wss://r (Relay)", "✓", "✗", "NIP-28 channels", "no AUTH challenge"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRelayTestCmdAuth(t *testing.T) {
+	for _, requestAuth := range []bool{true, false} {
+		relay := khatru.NewRelay()
+		if requestAuth {
+			relay.OnConnect = func(ctx context.Context) { khatru.RequestAuth(ctx) }
+		}
+		srv := httptest.NewServer(relay)
+		url := "ws" + strings.TrimPrefix(srv.URL, "http")
+		sk := nostr.Generate()
+		msg := relayTestCmd(url, nil, func(ctx context.Context, evt *nostr.Event) error { return evt.Sign(sk) })().(relayTestMsg)
+		srv.Close()
+
+		want := checkSkip
+		if requestAuth {
+			want = checkPass
+		}
+		for _, c := range msg.checks {
+			if c.Feature == "NIP-42 auth" && c.Result != want {
+				t.Errorf("requestAuth=%v: auth check = %+v, want result %d", requestAuth, c, want)
+			}
+		}
+	}
+}
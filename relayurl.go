@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"fiatjaf.com/nostr"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// normalizeRelayURL canonicalizes a relay URL so that equivalent spellings
+// compare equal: ws(s) scheme, lower-case host, no default port (443 for
+// wss, 80 for ws), and no trailing slash. Unparseable input is returned
+// trimmed, so the error surfaces when connecting.
+func normalizeRelayURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	// NormalizeURL only recognizes lower-case schemes.
+	if scheme, rest, ok := strings.Cut(raw, "://"); ok {
+		raw = strings.ToLower(scheme) + "://" + rest
+	}
+	raw = strings.TrimRight(raw, "/") // NormalizeURL rejects "host//"
+	n := nostr.NormalizeURL(raw)
+	if n == "" {
+		return raw
+	}
+	u, err := url.Parse(n)
+	if err != nil {
+		return n
+	}
+	if (u.Scheme == "wss" && u.Port() == "443") || (u.Scheme == "ws" && u.Port() == "80") {
+		host := u.Hostname()
+		if strings.Contains(host, ":") {
+			host = "[" + host + "]" // IPv6 literal
+		}
+		u.Host = host
+	}
+	return u.String()
+}
+
+// mergeRelayURLs normalizes urls and drops duplicates, keeping the first
+// occurrence's position. dups counts the entries dropped per kept URL.
+func mergeRelayURLs(urls []string) (kept []string, dups map[string]int) {
+	dups = make(map[string]int)
+	seen := make(map[string]bool)
+	for _, raw := range urls {
+		n := normalizeRelayURL(raw)
+		if seen[n] {
+			dups[n]++
+			continue
+		}
+		seen[n] = true
+		kept = append(kept, n)
+	}
+	return kept, dups
+}
+
+// mergeRelays runs /mergerelays: it collapses equivalent URLs in the relay
+// list and reports what was merged.
+func (m *model) mergeRelays() (tea.Model, tea.Cmd) {
+	kept, dups := mergeRelayURLs(m.relays)
+	if len(kept) == len(m.relays) {
+		m.relays = kept
+		m.addSystemMsg(fmt.Sprintf("%d relays, no duplicates", len(kept)))
+		return m, nil
+	}
+	for _, url := range kept {
+		if n := dups[url]; n > 0 {
+			m.addSystemMsg(fmt.Sprintf("  %s: %d entries merged into one", url, n+1))
+		}
+	}
+	m.addSystemMsg(fmt.Sprintf("merged %d relays into %d; update relays in the config to keep this", len(m.relays), len(kept)))
+	m.relays = kept
+	return m, nil
+}
+
+// migrateGroupHistory moves history stored under a group's key as listed
+// before relay URLs were normalized to its normalized key, so old logs keep
+// loading.
+func (m *model) migrateGroupHistory(sg SavedGroup) {
+	if m.history == nil || sg.listedRelayURL == "" || sg.listedRelayURL == sg.RelayURL {
+		return
+	}
+	if err := m.history.Rename("group", groupKey(sg.listedRelayURL, sg.GroupID), groupKey(sg.RelayURL, sg.GroupID)); err != nil {
+		log.Printf("history: migrate group %s: %v", sg.GroupID, err)
+	}
+}
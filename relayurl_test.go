@@ -0,0 +1,69 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestNormalizeRelayURL(t *testing.T) {
+	tests := map[string]string{
+		"wss://nos.lol":                 "wss://nos.lol",
+		"wss://nos.lol/":                "wss://nos.lol",
+		"WSS://Nos.LOL//":               "wss://nos.lol",
+		"  wss://nos.lol  ":             "wss://nos.lol",
+		"nos.lol":                       "wss://nos.lol",
+		"https://relay.example.com":     "wss://relay.example.com",
+		"wss://relay.example.com:443":   "wss://relay.example.com",
+		"ws://relay.example.com:80/":    "ws://relay.example.com",
+		"wss://relay.example.com:80":    "wss://relay.example.com:80",
+		"ws://localhost:7777":           "ws://localhost:7777",
+		"wss://relay.example.com/Path/": "wss://relay.example.com/Path",
+	}
+	for in, want := range tests {
+		if got := normalizeRelayURL(in); got != want {
+			t.Errorf("normalizeRelayURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMergeRelayURLs(t *testing.T) {
+	kept, dups := mergeRelayURLs([]string{"wss://nos.lol/", "wss://a.com", "WSS://NOS.LOL", "wss://nos.lol:443", "wss://a.com/"})
+	if want := []string{"wss://nos.lol", "wss://a.com"}; !reflect.DeepEqual(kept, want) {
+		t.Errorf("kept = %v, want %v", kept, want)
+	}
+	if dups["wss://nos.lol"] != 2 || dups["wss://a.com"] != 1 {
+		t.Errorf("dups = %v", dups)
+	}
+}
+
+func TestParseGroupInputNormalizesRelay(t *testing.T) {
+	relay, id, err := parseGroupInput("Groups.Example.com:443/'abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if relay != "wss://groups.example.com" || id != "abc" {
+		t.Errorf("got %q %q", relay, id)
+	}
+}
+
+func TestMigrateGroupHistory(t *testing.T) {
+	m := newTestModel(0, 0, 0)
+	m.history = fileHistoryStore{dir: t.TempDir()}
+	m.history.Append("group", groupKey("wss://Groups.Example.com/", "abc"), ChatMessage{Timestamp: 1, EventID: "a", Content: "old"}, "alice")
+
+	groups := parseSimpleGroupsListEvent(&nostr.Event{Tags: nostr.Tags{{"group", "abc", "wss://Groups.Example.com/", "Test"}}})
+	if len(groups) != 1 {
+		t.Fatalf("parsed %d groups", len(groups))
+	}
+	m.migrateGroupHistory(groups[0])
+
+	got, err := m.history.Load("group", groupKey("wss://groups.example.com", "abc"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Content != "old" {
+		t.Errorf("normalized key history = %+v, want the old log", got)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// localState holds the files nitrous keeps next to the config, read at
+// startup and again by /reload. Files that fail to load are logged and left
+// empty.
+type localState struct {
+	drafts          map[string]string
+	plainRooms      map[string]bool
+	whitelistRooms  map[string]bool
+	invites         []groupInvite
+	recentReactions []string
+	displayPrefs    map[string]bool
+}
+
+// loadLocalState reads the state files next to the config.
+func loadLocalState(cfgFlagPath string) localState {
+	var s localState
+	var err error
+	if s.drafts, err = loadDrafts(draftsPath(cfgFlagPath)); err != nil {
+		log.Printf("loadLocalState: loading drafts: %v", err)
+	}
+	if s.plainRooms, err = loadRoomSet(plainRoomsPath(cfgFlagPath)); err != nil {
+		log.Printf("loadLocalState: loading plain rooms: %v", err)
+	}
+	if s.whitelistRooms, err = loadRoomSet(whitelistPath(cfgFlagPath)); err != nil {
+		log.Printf("loadLocalState: loading whitelist rooms: %v", err)
+	}
+	if s.invites, err = loadInvites(invitesPath(cfgFlagPath)); err != nil {
+		log.Printf("loadLocalState: loading invites: %v", err)
+	}
+	if s.recentReactions, err = loadRecentReactions(reactionRecentsPath(cfgFlagPath)); err != nil {
+		log.Printf("loadLocalState: loading recent reactions: %v", err)
+	}
+	if s.displayPrefs, err = loadDisplayPrefs(displayPath(cfgFlagPath)); err != nil {
+		log.Printf("loadLocalState: loading display options: %v", err)
+	}
+	return s
+}
+
+// mutedWordSet returns the muted_words as a set of lower-case words.
+func mutedWordSet(words []string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range words {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			set[w] = true
+		}
+	}
+	return set
+}
+
+// diffRelays returns the relays in next that aren't in prev, and the other
+// way round.
+func diffRelays(prev, next []string) (added, removed []string) {
+	for _, r := range next {
+		if !slices.Contains(prev, r) {
+			added = append(added, r)
+		}
+	}
+	for _, r := range prev {
+		if !slices.Contains(next, r) {
+			removed = append(removed, r)
+		}
+	}
+	return added, removed
+}
+
+// restartSettings lists the settings that changed between prev and next
+// but only take effect after a restart.
+func restartSettings(prev, next Config) []string {
+	var changed []string
+	if prev.PrivateKeyFile != next.PrivateKeyFile {
+		changed = append(changed, "private_key_file")
+	}
+	if prev.LoggingEnabled() != next.LoggingEnabled() || prev.LogDir != next.LogDir {
+		changed = append(changed, "logging")
+	}
+	if prev.HistoryBackend != next.HistoryBackend {
+		changed = append(changed, "history_backend")
+	}
+	return changed
+}
+
+// trimMessages drops the oldest messages of every buffer beyond max.
+func (m *model) trimMessages(max int) {
+	for room, msgs := range m.msgs {
+		if len(msgs) > max {
+			m.msgs[room] = msgs[len(msgs)-max:]
+		}
+	}
+	if len(m.globalMsgs) > max {
+		m.globalMsgs = m.globalMsgs[len(m.globalMsgs)-max:]
+	}
+}
+
+// dropUnlistedContacts removes the DM peers that are neither in contacts
+// nor (with follows_in_sidebar) followed from the sidebar, for a contacts
+// file edited outside nitrous. Group DMs stay.
+func (m *model) dropUnlistedContacts(contacts []Contact) int {
+	keep := make(map[string]bool)
+	for _, c := range contacts {
+		keep[c.PubKey] = true
+	}
+	if m.cfg.FollowsAsDMs {
+		for _, f := range m.follows {
+			keep[f.PubKey] = true
+		}
+	}
+	dropped := 0
+	for i := len(m.sidebar) - 1; i >= 0; i-- {
+		if di, ok := m.sidebar[i].(DMItem); ok && !keep[di.PubKey] {
+			m.removeSidebarItem(i)
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// reload handles /reload: it reads the config and the state files again and
+// applies them without a restart. Relay changes resubscribe the DMs and
+// channels; the channel, group, and contact lists are fetched again from
+// the relays (or the contacts file with sync_contacts = false), keeping the
+// active conversation selected when it is still listed.
+func (m *model) reload() (tea.Model, tea.Cmd) {
+	cfg, err := LoadConfig(m.cfgFlagPath)
+	if err != nil {
+		m.addSystemMsg("reload: " + err.Error() + " (nothing changed)")
+		return m, nil
+	}
+	state := loadLocalState(m.cfgFlagPath)
+	applyDisplayPrefs(&cfg, state.displayPrefs)
+
+	activeID := ""
+	if item := m.activeSidebarItem(); item != nil {
+		activeID = item.ItemID()
+	}
+	prev := m.cfg
+	m.cfg = cfg
+	m.drafts = state.drafts
+	m.plainRooms = state.plainRooms
+	m.whitelistRooms = state.whitelistRooms
+	m.invites = state.invites
+	m.recentReactions = state.recentReactions
+	m.mutedWords = mutedWordSet(cfg.MutedWords)
+	configureInput(&m.input, cfg)
+	if cfg.MaxQueries() != prev.MaxQueries() {
+		m.queries = newQueryLimiter(cfg.MaxQueries())
+	}
+	if !slices.Equal(cfg.RelayOptions, prev.RelayOptions) {
+		if m.access != nil {
+			m.access.stop()
+		}
+		m.access = newRelayAccess(m.pool, cfg.RelayOptions, m.kr.SignEvent)
+	}
+
+	var notes []string
+	var cmds []tea.Cmd
+	if cfg.MaxMessages < prev.MaxMessages {
+		m.trimMessages(cfg.MaxMessages)
+	}
+	if cfg.MaxMessages != prev.MaxMessages {
+		notes = append(notes, fmt.Sprintf("max_messages %d → %d", prev.MaxMessages, cfg.MaxMessages))
+	}
+
+	if added, removed := diffRelays(m.relays, cfg.Relays); len(added) > 0 || len(removed) > 0 {
+		m.relays = cfg.Relays
+		notes = append(notes, fmt.Sprintf("relays +%d -%d", len(added), len(removed)))
+		for _, r := range removed {
+			m.addStatusMsg(relayStatusKey(r), r+" removed from the relays")
+			delete(m.relayLatency, r)
+		}
+		for _, r := range added {
+			m.addStatusMsg(relayStatusKey(r), fmt.Sprintf("connecting to %s ...", r))
+			cmds = append(cmds, probeRelayCmd(m.pool, r))
+		}
+		if m.dmCancel != nil {
+			m.dmCancel()
+			m.dmCancel = nil
+		}
+		m.dmEvents = nil
+		cmds = append(cmds,
+			m.afterRelayAccess(m.relays, subscribeDMCmd(m.pool, m.relays, m.kr, m.lastDMSeen, m.cfg.DMLookback())),
+			publishDMRelaysCmd(m.pool, m.relays, m.keys),
+		)
+		for _, ch := range m.allChannels() {
+			if _, ok := m.roomSubs[ch.ID]; ok {
+				m.cancelRoomSub(ch.ID)
+				cmds = append(cmds, m.subscribeChannel(ch.ID))
+			}
+		}
+	}
+
+	if cfg.Profile != prev.Profile {
+		notes = append(notes, "profile")
+		if name := cmp.Or(cfg.Profile.DisplayName, cfg.Profile.Name); name != "" {
+			m.profiles[m.keys.PK.Hex()] = name
+		}
+		if cfg.Profile != (ProfileConfig{}) {
+			cmds = append(cmds, publishProfileCmd(m.pool, m.relays, cfg.Profile, m.keys))
+		}
+	}
+	if prev.StatusPingInterval() <= 0 && cfg.StatusPingInterval() > 0 {
+		cmds = append(cmds, statusPingCmd(m.pool, m.relays))
+	}
+	if prev.StaleSubInterval() <= 0 && cfg.StaleSubInterval() > 0 {
+		cmds = append(cmds, staleCheckCmd(cfg.StaleSubInterval()))
+	}
+
+	if !cfg.SyncContactsEnabled() {
+		contacts, err := loadContactsFile(contactsPath(m.cfgFlagPath))
+		if err != nil {
+			m.addSystemMsg("loading contacts: " + err.Error())
+		} else if n := m.dropUnlistedContacts(contacts); n > 0 {
+			notes = append(notes, fmt.Sprintf("%d contacts removed", n))
+		}
+		cmds = append(cmds, m.loadLocalContacts()...)
+	}
+	cmds = append(cmds, m.afterRelayAccess(m.relays, fetchNIP51ListsCmd(m.pool, m.relays, m.keys, m.kr, cfg.SyncContactsEnabled(), cfg.ReplaceableWait())))
+	m.selectItem(activeID)
+	m.updateLayout()
+	m.updateViewport()
+	summary := "reloaded the config and state files"
+	if len(notes) > 0 {
+		summary += ": " + strings.Join(notes, ", ")
+	}
+	m.addSystemMsg(summary + "; fetching lists from relays ...")
+	if later := restartSettings(prev, cfg); len(later) > 0 {
+		m.addSystemMsg("restart nitrous to apply " + strings.Join(later, ", "))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// selectItem makes the sidebar item with ID id active, or keeps the active
+// index in range when it is gone.
+func (m *model) selectItem(id string) {
+	if i := slices.IndexFunc(m.sidebar, func(it SidebarItem) bool { return it.ItemID() == id }); i >= 0 {
+		m.activeItem = i
+		return
+	}
+	m.activeItem = min(m.activeItem, max(m.sidebarTotal()-1, 0))
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/keyer"
+)
+
+func TestDiffRelays(t *testing.T) {
+	added, removed := diffRelays([]string{"wss://a", "wss://b"}, []string{"wss://b", "wss://c"})
+	if !slices.Equal(added, []string{"wss://c"}) || !slices.Equal(removed, []string{"wss://a"}) {
+		t.Errorf("added %v, removed %v", added, removed)
+	}
+	if added, removed := diffRelays([]string{"wss://a"}, []string{"wss://a"}); added != nil || removed != nil {
+		t.Errorf("unchanged relays: added %v, removed %v", added, removed)
+	}
+}
+
+func TestRestartSettings(t *testing.T) {
+	prev := Config{PrivateKeyFile: "a", MaxMessages: 500}
+	next := prev
+	next.MaxMessages = 100
+	if got := restartSettings(prev, next); got != nil {
+		t.Errorf("max_messages needs no restart, got %v", got)
+	}
+	off := false
+	next.Logging = &off
+	next.HistoryBackend = "sqlite"
+	if got := restartSettings(prev, next); !slices.Equal(got, []string{"logging", "history_backend"}) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestMutedWordSet(t *testing.T) {
+	set := mutedWordSet([]string{" Spoiler ", "", "crypto"})
+	if len(set) != 2 || !set["spoiler"] || !set["crypto"] {
+		t.Errorf("set = %v", set)
+	}
+}
+
+func TestDropUnlistedContacts(t *testing.T) {
+	m := newTestModel(1, 0, 3)
+	m.cfg.FollowsAsDMs = true
+	m.follows = []Follow{{PubKey: "pk2"}}
+	n := m.dropUnlistedContacts([]Contact{{PubKey: "pk0"}})
+	if n != 1 || m.findDMPeerIdx("pk1") >= 0 || m.findDMPeerIdx("pk0") < 0 || m.findDMPeerIdx("pk2") < 0 {
+		t.Errorf("dropped %d, sidebar %v", n, m.sidebar)
+	}
+}
+
+func TestSelectItem(t *testing.T) {
+	m := newTestModel(2, 0, 2)
+	m.selectItem("pk1")
+	if m.activeItem != 3 {
+		t.Errorf("activeItem = %d, want 3", m.activeItem)
+	}
+	m.removeSidebarItem(3)
+	m.selectItem("pk1")
+	if m.activeItem != 2 {
+		t.Errorf("activeItem = %d after removal, want 2", m.activeItem)
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.toml")
+	write := func(s string) {
+		if err := os.WriteFile(cfgPath, []byte(s), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("relays = [\"wss://a\"]\nmax_messages = 10\n")
+	cfg, err := LoadConfig(cfgPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := testKeys(t)
+	kr := keyer.NewPlainKeySigner(keys.SK)
+	pool := nostr.NewPool(nostr.PoolOptions{})
+	defer pool.Close("test done")
+	m := newModel(cfg, cfgPath, keys, pool, &kr, nil, "")
+	m.sidebar = append(m.sidebar, DMItem{PubKey: "pk0"}, DMItem{PubKey: "pk1"})
+	m.activeItem = 1
+	for range 10 {
+		m.msgs["pk1"] = append(m.msgs["pk1"], ChatMessage{Content: "x"})
+	}
+
+	write("relays = [\"wss://a\", \"wss://b\"]\nmax_messages = 4\nmuted_words = [\"Spam\"]\n")
+	if err := os.WriteFile(draftsPath(cfgPath), []byte("hi\thello there\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m.reload()
+	if !slices.Equal(m.relays, []string{"wss://a", "wss://b"}) {
+		t.Errorf("relays = %v", m.relays)
+	}
+	if len(m.msgs["pk1"]) != 4 {
+		t.Errorf("%d messages kept, want 4", len(m.msgs["pk1"]))
+	}
+	if !m.mutedWords["spam"] {
+		t.Errorf("muted words = %v", m.mutedWords)
+	}
+	if m.drafts["hi"] != "hello there" {
+		t.Errorf("drafts = %v", m.drafts)
+	}
+	if m.activeSidebarItem().ItemID() != "pk1" {
+		t.Errorf("active item = %s, want pk1", m.activeSidebarItem().ItemID())
+	}
+
+	write("relays = \"not a list\"\n")
+	m.reload()
+	if m.cfg.MaxMessages != 4 {
+		t.Errorf("a broken config was applied: max_messages = %d", m.cfg.MaxMessages)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"fiatjaf.com/nostr"
+)
+
+// boostPublishedMsg is returned after a /boost repost has been published.
+type boostPublishedMsg struct {
+	origAuthor string // hex pubkey of the reposted message's author
+	accepted   int
+	total      int
+}
+
+// buildRepostEvent builds a NIP-18 repost of orig: kind 6 for kind-1 notes,
+// kind 16 (generic repost, with a "k" tag) for everything else. The original
+// event JSON goes in content. extraTags scope the repost to a room (e.g. the
+// NIP-29 "h" tag) and follow the NIP-18 tags. When they carry an "e" tag of
+// their own (a channel's root), the reposted event's tag is marked
+// "mention" so clients can tell the two apart.
+func buildRepostEvent(orig nostr.Event, relayURL string, extraTags nostr.Tags, keys Keys) (nostr.Event, error) {
+	raw, err := json.Marshal(orig)
+	if err != nil {
+		return nostr.Event{}, fmt.Errorf("marshal original: %w", err)
+	}
+
+	kind := nostr.KindGenericRepost
+	target := nostr.Tag{"e", orig.ID.Hex(), relayURL}
+	if slices.ContainsFunc(extraTags, func(t nostr.Tag) bool { return len(t) >= 2 && t[0] == "e" }) {
+		target = append(target, "mention")
+	}
+	tags := nostr.Tags{
+		target,
+		{"p", orig.PubKey.Hex()},
+	}
+	if orig.Kind == nostr.KindTextNote {
+		kind = nostr.KindRepost
+	} else {
+		tags = append(tags, nostr.Tag{"k", strconv.Itoa(int(orig.Kind))})
+	}
+	tags = append(tags, extraTags...)
+
+	evt := nostr.Event{
+		Kind:      kind,
+		CreatedAt: nostr.Now(),
+		Tags:      tags,
+		Content:   string(raw),
+	}
+	if err := evt.Sign(keys.SK); err != nil {
+		return evt, err
+	}
+	return evt, nil
+}
+
+// parseRepost extracts the embedded original from a kind 6/16 repost. It
+// returns false if evt is not a repost or the embedded event is missing or
+// doesn't verify.
+func parseRepost(evt nostr.Event) (nostr.Event, bool) {
+	if evt.Kind != nostr.KindRepost && evt.Kind != nostr.KindGenericRepost {
+		return nostr.Event{}, false
+	}
+	var orig nostr.Event
+	if err := json.Unmarshal([]byte(evt.Content), &orig); err != nil {
+		return nostr.Event{}, false
+	}
+	if !orig.CheckID() || !orig.VerifySignature() {
+		return nostr.Event{}, false
+	}
+	return orig, true
+}
+
+// applyRepost turns cm into a repost display if evt is a repost: Content
+// becomes the original's content and RepostOf its author. Reposts without
+// a valid embedded event keep the raw content.
+func applyRepost(cm *ChatMessage, evt nostr.Event) {
+	if orig, ok := parseRepost(evt); ok {
+		cm.Content = orig.Content
+		cm.RepostOf = orig.PubKey.Hex()
+	}
+}
+
+// boostCmd fetches the original event by ID from relays and publishes a
+// repost of it to the same relays.
+func boostCmd(pool *nostr.Pool, relays []string, eventID string, extraTags nostr.Tags, keys Keys) tea.Cmd {
+	return func() tea.Msg {
+		id, err := nostr.IDFromHex(eventID)
+		if err != nil {
+			return nostrErrMsg{fmt.Errorf("boost: invalid event id: %w", err)}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		re := pool.QuerySingle(ctx, relays, nostr.Filter{IDs: []nostr.ID{id}}, nostr.SubscriptionOptions{})
+		if re == nil {
+			return nostrErrMsg{fmt.Errorf("boost: original event not found on relays")}
+		}
+
+		evt, err := buildRepostEvent(re.Event, re.Relay.URL, extraTags, keys)
+		if err != nil {
+			return nostrErrMsg{fmt.Errorf("boost: %w", err)}
+		}
+		logEventOut("", evt)
+		results := collectPublishResults(ctx, pool.PublishMany(ctx, relays, evt))
+		accepted := 0
+		for _, r := range results {
+			if r == "ok" {
+				accepted++
+			}
+		}
+		log.Printf("boostCmd: reposted %s as kind %d to %d/%d relays", shortPK(eventID), evt.Kind, accepted, len(results))
+		return boostPublishedMsg{origAuthor: re.PubKey.Hex(), accepted: accepted, total: len(results)}
+	}
+}
+
+// boostMessage handles /boost [n]: repost the nth most recent message in the
+// active channel or group into the same room.
+func (m *model) boostMessage(arg string) (tea.Model, tea.Cmd) {
+	n := 1
+	if arg != "" {
+		v, err := strconv.Atoi(arg)
+		if err != nil || v < 1 {
+			m.addSystemMsg("usage: /boost [n]")
+			return m, nil
+		}
+		n = v
+	}
+	msg, _, ok := m.nthRecentMessage(n)
+	if !ok {
+		m.addSystemMsg(fmt.Sprintf("no message #%d in this conversation", n))
+		return m, nil
+	}
+
+	switch it := m.activeSidebarItem().(type) {
+	case ChannelItem:
+		// The channel's root "e" tag lets channel subscriptions pick it up.
+		extra := nostr.Tags{{"e", it.Channel.ID, "", "root"}}
+		m.addSystemMsg(fmt.Sprintf("boosting message #%d by %s", n, m.resolveAuthor(msg.PubKey)))
+		return m, boostCmd(m.pool, it.Channel.RelaysWith(m.relays), msg.EventID, extra, m.keys)
+	case GroupItem:
+		gk := groupKey(it.Group.RelayURL, it.Group.GroupID)
+		extra := nostr.Tags{{"h", it.Group.GroupID}}
+		extra = append(extra, pickPreviousTags(m.groupRecentIDs[gk])...)
+		m.addSystemMsg(fmt.Sprintf("boosting message #%d by %s", n, m.resolveAuthor(msg.PubKey)))
+		return m, boostCmd(m.pool, it.Group.Relays(), msg.EventID, extra, m.keys)
+	default:
+		m.addSystemMsg("/boost only works in a channel or group")
+		return m, nil
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestBuildRepostEvent(t *testing.T) {
+	keys := testKeys(t)
+	orig, err := buildGroupMessageEvent("grp1", "hello group", nil, nil, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evt, err := buildRepostEvent(orig, "wss://relay.example.com", nostr.Tags{{"h", "grp1"}}, keys)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if evt.Kind != nostr.KindGenericRepost {
+		t.Errorf("Kind = %d, want %d for a non-note original", evt.Kind, nostr.KindGenericRepost)
+	}
+	if !hasTag(evt, "e", orig.ID.Hex()) {
+		t.Error("missing e tag for the original event")
+	}
+	if !hasTag(evt, "p", orig.PubKey.Hex()) {
+		t.Error("missing p tag for the original author")
+	}
+	if !hasTag(evt, "k", "9") {
+		t.Error("missing k tag with the original kind")
+	}
+	if !hasTag(evt, "h", "grp1") {
+		t.Error("missing extra h tag")
+	}
+	var embedded nostr.Event
+	if err := json.Unmarshal([]byte(evt.Content), &embedded); err != nil || embedded.ID != orig.ID {
+		t.Errorf("content should embed the original event JSON, err=%v", err)
+	}
+	if !evt.VerifySignature() {
+		t.Error("invalid signature")
+	}
+
+	note := nostr.Event{Kind: nostr.KindTextNote, CreatedAt: nostr.Now(), Content: "a note"}
+	if err := note.Sign(keys.SK); err != nil {
+		t.Fatal(err)
+	}
+	evt, err = buildRepostEvent(note, "", nil, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evt.Kind != nostr.KindRepost {
+		t.Errorf("Kind = %d, want %d for a kind-1 original", evt.Kind, nostr.KindRepost)
+	}
+	if tag := evt.Tags[0]; len(tag) != 3 {
+		t.Errorf("e tag = %v, want no marker without a room e tag", tag)
+	}
+
+	// In a channel the root tag is a second "e" tag; the target is marked.
+	chanID := "c0ffee"
+	evt, err = buildRepostEvent(orig, "wss://relay.example.com", nostr.Tags{{"e", chanID, "", "root"}}, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var marks []string
+	for _, tag := range evt.Tags {
+		if len(tag) >= 4 && tag[0] == "e" {
+			marks = append(marks, tag[1]+" "+tag[3])
+		}
+	}
+	if len(marks) != 2 || marks[0] != orig.ID.Hex()+" mention" || marks[1] != chanID+" root" {
+		t.Errorf("e tags = %v, want the target marked mention and the channel root", marks)
+	}
+}
+
+func TestParseRepost(t *testing.T) {
+	keys := testKeys(t)
+	orig, _ := buildGroupMessageEvent("grp1", "original text", nil, nil, keys)
+	repost, _ := buildRepostEvent(orig, "", nil, keys)
+
+	cm := ChatMessage{Content: repost.Content}
+	applyRepost(&cm, repost)
+	if cm.Content != "original text" || cm.RepostOf != orig.PubKey.Hex() {
+		t.Errorf("applyRepost: content=%q repostOf=%q", cm.Content, cm.RepostOf)
+	}
+
+	// Tampered embedded event is rejected.
+	orig.Content = "forged"
+	raw, _ := json.Marshal(orig)
+	repost.Content = string(raw)
+	if _, ok := parseRepost(repost); ok {
+		t.Error("expected tampered embedded event to be rejected")
+	}
+
+	if _, ok := parseRepost(orig); ok {
+		t.Error("non-repost kinds should not parse as reposts")
+	}
+}
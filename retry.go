@@ -0,0 +1,139 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"fiatjaf.com/nostr"
+)
+
+// failedDMEcho is the local copy of a DM that could not be sent, kept in
+// the conversation marked failed so /retry can send it again.
+func failedDMEcho(msg dmSendErrMsg, keys Keys) ChatMessage {
+	ts := nostr.Now()
+	h := sha256.Sum256([]byte(fmt.Sprintf("failed:%s:%s:%d:%s", keys.PK.Hex(), msg.peerPK, ts, msg.content)))
+	cm := ChatMessage{
+		Author:    shortPK(keys.PK.Hex()),
+		PubKey:    msg.peerPK,
+		Content:   msg.content,
+		Timestamp: ts,
+		EventID:   hex.EncodeToString(h[:]),
+		IsMine:    true,
+		Failed:    true,
+		DMMembers: msg.members,
+		Expires:   msg.expires,
+	}
+	if msg.members != nil {
+		cm.PubKey = keys.PK.Hex()
+	}
+	return cm
+}
+
+// failedIndexes returns the buffer indexes of failed messages, oldest first.
+func failedIndexes(msgs []ChatMessage) []int {
+	var out []int
+	for i, msg := range msgs {
+		if msg.Failed {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// retryCmd sends the failed message at index i of room roomKey again.
+// Channel and group messages are re-published unchanged and keep their
+// place in the buffer. DMs are gift-wrapped anew, so the failed copy is
+// dropped and the new one is appended (or marked failed again). A failed
+// /timed DM past its expiration is dropped without sending it.
+func (m *model) retryCmd(roomKey string, i int) tea.Cmd {
+	msgs := m.msgs[roomKey]
+	msg := msgs[i]
+	if msg.SentEvent != nil {
+		relays := m.channelRelays(msg.ChannelID)
+		if msg.GroupKey != "" {
+			relays = m.groupRelays(msg.GroupKey)
+		}
+		msgs[i].Failed = false
+		msgs[i].Deliveries = nil
+		publish := publishEventCmd(m.pool, relays, roomKey, *msg.SentEvent)
+		if msg.GroupKey == "" {
+			return publish
+		}
+		msgs[i].Echo = echoPending
+		m.trackGroupEcho(msgs[i])
+		return tea.Batch(publish, groupEchoTimeoutCmd(msg.EventID))
+	}
+	m.msgs[roomKey] = append(msgs[:i:i], msgs[i+1:]...)
+	if isExpired(msg, nostr.Now()) {
+		return nil
+	}
+	var extra nostr.Tags
+	if msg.Expires != 0 {
+		extra = nostr.Tags{expirationTag(msg.Expires)}
+	}
+	if msg.DMMembers != nil {
+		return sendGroupDM(m.pool, m.relays, msg.DMMembers, msg.Content, extra, m.keys, m.kr)
+	}
+	return sendDM(m.pool, m.relays, msg.PubKey, msg.Content, extra, m.keys, m.kr)
+}
+
+// retryFailed re-sends every failed message in the active room, or in the
+// open thread.
+func (m *model) retryFailed() (tea.Model, tea.Cmd) {
+	if m.activeSidebarItem() == nil {
+		m.addSystemMsg("no active conversation")
+		return m, nil
+	}
+	roomKey := m.activeRoomKey()
+	failed := failedIndexes(m.msgs[roomKey])
+	if len(failed) == 0 {
+		m.addSystemMsg("no failed messages here")
+		return m, nil
+	}
+	// Newest first, so dropping a failed DM doesn't shift the rest.
+	var cmds []tea.Cmd
+	now := nostr.Now()
+	expired := 0
+	for j := len(failed) - 1; j >= 0; j-- {
+		if isExpired(m.msgs[roomKey][failed[j]], now) {
+			expired++
+		}
+		cmds = append(cmds, m.retryCmd(roomKey, failed[j]))
+	}
+	if expired > 0 {
+		m.addSystemMsg(fmt.Sprintf("dropped %d expired /timed message(s)", expired))
+	}
+	if n := len(failed) - expired; n > 0 {
+		m.addSystemMsg(fmt.Sprintf("retrying %d failed message(s)…", n))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// retryMessage re-sends the nth most recent message if it failed.
+func (m *model) retryMessage(arg string) (tea.Model, tea.Cmd) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		m.addSystemMsg("usage: /retry <n>")
+		return m, nil
+	}
+	msg, i, ok := m.nthRecentMessage(n)
+	if !ok {
+		m.addSystemMsg(fmt.Sprintf("no message #%d in this conversation", n))
+		return m, nil
+	}
+	if !msg.Failed {
+		m.addSystemMsg(fmt.Sprintf("message #%d did not fail", n))
+		return m, nil
+	}
+	if isExpired(msg, nostr.Now()) {
+		m.retryCmd(m.activeRoomKey(), i)
+		m.addSystemMsg(fmt.Sprintf("message #%d has expired; dropped it instead of sending", n))
+		return m, nil
+	}
+	cmd := m.retryCmd(m.activeRoomKey(), i)
+	m.addSystemMsg(fmt.Sprintf("retrying message #%d…", n))
+	return m, cmd
+}
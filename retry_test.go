@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestFailedIndexes(t *testing.T) {
+	msgs := []ChatMessage{{Content: "a", Failed: true}, {Content: "b"}, {Content: "c", Failed: true}}
+	got := failedIndexes(msgs)
+	if len(got) != 2 || got[0] != 0 || got[1] != 2 {
+		t.Errorf("failedIndexes = %v, want [0 2]", got)
+	}
+}
+
+func TestFailedDMEcho(t *testing.T) {
+	keys := Keys{PK: nostr.MustPubKeyFromHex("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")}
+	cm := failedDMEcho(dmSendErrMsg{peerPK: "peer", content: "hi"}, keys)
+	if !cm.Failed || !cm.IsMine || cm.PubKey != "peer" || cm.Content != "hi" {
+		t.Errorf("1:1 echo = %+v", cm)
+	}
+	if dmRoomKey(cm) != "peer" {
+		t.Errorf("1:1 echo room = %q, want peer", dmRoomKey(cm))
+	}
+
+	members := []string{"a", "b"}
+	cm = failedDMEcho(dmSendErrMsg{peerPK: dmGroupKey(members), content: "hi", members: members}, keys)
+	if cm.PubKey != keys.PK.Hex() || dmRoomKey(cm) != dmGroupKey(members) {
+		t.Errorf("group echo = %+v, room %q", cm, dmRoomKey(cm))
+	}
+}
+
+func TestRetryCmdKeepsChannelMessage(t *testing.T) {
+	m := newTestModel(1, 0, 0)
+	evt := nostr.Event{Kind: nostr.KindChannelMessage, Content: "hi"}
+	m.msgs = map[string][]ChatMessage{"ch0": {{Content: "hi", IsMine: true, Failed: true, SentEvent: &evt, Deliveries: map[string]string{"wss://r": "timeout"}}}}
+	if m.retryCmd("ch0", 0) == nil {
+		t.Fatal("no publish command")
+	}
+	got := m.msgs["ch0"]
+	if len(got) != 1 || got[0].Failed || got[0].Deliveries != nil {
+		t.Errorf("after retry: %+v", got)
+	}
+}
+
+func TestRetryCmdDropsExpiredTimedDM(t *testing.T) {
+	m := newTestModel(0, 0, 1)
+	m.msgs = map[string][]ChatMessage{"pk0": {{Content: "the code is 1234", PubKey: "pk0", IsMine: true, Failed: true, Expires: nostr.Now() - 1}}}
+	if cmd := m.retryCmd("pk0", 0); cmd != nil {
+		t.Error("an expired /timed DM was sent again")
+	}
+	if len(m.msgs["pk0"]) != 0 {
+		t.Errorf("expired failed DM kept: %+v", m.msgs["pk0"])
+	}
+}
+
+func TestRetryFailedReplyInOpenThread(t *testing.T) {
+	m := newTestModel(0, 1, 0)
+	gk := groupKey("wss://r", "g0")
+	reply := nostr.Event{Kind: nostr.KindSimpleGroupThreadedReply, Content: "reply"}
+	m.groupThread = map[string]string{gk: "root"}
+	m.msgs = map[string][]ChatMessage{
+		gk:                {{Content: "root", EventID: "root", ThreadID: "root", ThreadTitle: "Plans", GroupKey: gk}, {Content: "other", GroupKey: gk}},
+		threadKey("root"): {{Content: "reply", EventID: "r1", ThreadID: "root", GroupKey: gk, IsMine: true, Failed: true, SentEvent: &reply}},
+	}
+
+	if _, cmd := m.retryMessage("1"); cmd == nil {
+		t.Fatal("/retry 1 in the thread sent nothing")
+	}
+	if got := m.msgs[threadKey("root")][0]; got.Failed {
+		t.Errorf("thread reply still failed: %+v", got)
+	}
+	if got := m.msgs[gk][1]; got.Echo != echoNone || got.Failed {
+		t.Errorf("unrelated group message touched: %+v", got)
+	}
+
+	m.msgs[threadKey("root")][0].Failed = true
+	if _, cmd := m.retryFailed(); cmd == nil || m.msgs[threadKey("root")][0].Failed {
+		t.Error("/retry-failed didn't find the failed thread reply")
+	}
+}
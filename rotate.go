@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/keyer"
+	"fiatjaf.com/nostr/nip19"
+	"fiatjaf.com/nostr/nip49"
+)
+
+// migrationPublishedMsg is returned after the old key announced the move to
+// the new one.
+type migrationPublishedMsg struct {
+	accepted int
+	total    int
+}
+
+// confirmCode is what /nsec-rotate and /clear-history all must be given to
+// proceed: the last six characters of the current npub, so running them
+// takes reading the warning.
+func confirmCode(npub string) string {
+	if len(npub) < 6 {
+		return npub
+	}
+	return npub[len(npub)-6:]
+}
+
+// rotateKeyFile moves the key file at path aside to a timestamped backup and
+// writes a freshly generated key in its place: an nsec, or with a non-empty
+// pass a NIP-49 ncryptsec. It returns the new keys and the backup path.
+func rotateKeyFile(path string, now time.Time, pass string) (Keys, string, error) {
+	backup := path + ".old-" + now.Format("20060102-150405")
+	if _, err := os.Stat(backup); err == nil {
+		return Keys{}, "", fmt.Errorf("backup %s already exists", backup)
+	}
+	sk := nostr.Generate()
+	stored := nip19.EncodeNsec(sk)
+	if pass != "" {
+		enc, err := nip49.Encrypt(sk, pass, keyEncryptionLogN, nip49.ClientDoesNotTrackThisData)
+		if err != nil {
+			return Keys{}, "", fmt.Errorf("encrypt new key: %w", err)
+		}
+		stored = enc
+	}
+	if err := os.Rename(path, backup); err != nil {
+		return Keys{}, "", fmt.Errorf("back up key file: %w", err)
+	}
+	if err := writeKeyFile(path, stored); err != nil {
+		// Put the old key back so the next start still works.
+		if rerr := os.Rename(backup, path); rerr != nil {
+			log.Printf("rotateKeyFile: restore %s: %v", backup, rerr)
+		}
+		return Keys{}, "", fmt.Errorf("write new key file: %w", err)
+	}
+	pk := nostr.GetPublicKey(sk)
+	return Keys{SK: sk, PK: pk, NPub: nip19.EncodeNpub(pk)}, backup, nil
+}
+
+// buildMigrationEvent builds a kind-1 note, signed by the old key, pointing
+// followers at the new one. There is no finalized key-migration NIP, so this
+// is a plain announcement that clients render and users can act on.
+func buildMigrationEvent(oldKeys Keys, newPK nostr.PubKey) (nostr.Event, error) {
+	evt := nostr.Event{
+		Kind:      nostr.KindTextNote,
+		CreatedAt: nostr.Now(),
+		Tags:      nostr.Tags{{"p", newPK.Hex()}},
+		Content:   "This account has moved to nostr:" + nip19.EncodeNpub(newPK) + " — please follow the new key.",
+	}
+	if err := evt.Sign(oldKeys.SK); err != nil {
+		return evt, fmt.Errorf("buildMigrationEvent: sign: %w", err)
+	}
+	return evt, nil
+}
+
+// publishMigrationCmd publishes the old key's pointer to the new key.
+func publishMigrationCmd(pool *nostr.Pool, relays []string, oldKeys Keys, newPK nostr.PubKey) tea.Cmd {
+	return func() tea.Msg {
+		evt, err := buildMigrationEvent(oldKeys, newPK)
+		if err != nil {
+			return nostrErrMsg{fmt.Errorf("nsec-rotate: %w", err)}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		logEventOut("", evt)
+		results := collectPublishResults(ctx, pool.PublishMany(ctx, relays, evt))
+		accepted := 0
+		for _, r := range results {
+			if r == "ok" {
+				accepted++
+			}
+		}
+		log.Printf("publishMigration: announced move to %s on %d/%d relays", shortPK(newPK.Hex()), accepted, len(results))
+		return migrationPublishedMsg{accepted: accepted, total: len(results)}
+	}
+}
+
+// rotateKey handles /nsec-rotate [code [migrate]]. Without the confirmation
+// code it only explains what rotation does. An encrypted key file gets an
+// encrypted new key, so it first asks for the new key's passphrase.
+func (m *model) rotateKey(args []string) (tea.Model, tea.Cmd) {
+	if m.cfg.PrivateKeyFile == "" {
+		m.addSystemMsg("nsec-rotate: needs private_key_file in the config (keys from NOSTR_PRIVATE_KEY can't be rotated in place)")
+		return m, nil
+	}
+	code := confirmCode(m.keys.NPub)
+	if len(args) == 0 || args[0] != code {
+		if len(args) > 0 {
+			m.addSystemMsg("nsec-rotate: wrong confirmation code, nothing changed")
+		}
+		m.addSystemMsg("⚠ /nsec-rotate replaces your identity with a brand-new key.")
+		m.addSystemMsg("  kept: your key file is backed up (an encrypted key stays encrypted); contacts, follows, channels, groups, DM relays and [profile] are republished under the new key")
+		m.addSystemMsg("  lost: followers, reactions and replies stay with the old npub; DMs sent to it won't reach you; group memberships and admin roles must be re-granted")
+		m.addSystemMsg(fmt.Sprintf("  to proceed: /nsec-rotate %s            (add \"migrate\" to have the old key post a note pointing to the new one)", code))
+		return m, nil
+	}
+	migrate := len(args) > 1 && args[1] == "migrate"
+
+	raw, err := os.ReadFile(expandKeyPath(m.cfg.PrivateKeyFile))
+	if err != nil {
+		m.addSystemMsg("nsec-rotate: " + err.Error())
+		return m, nil
+	}
+	if isEncryptedKey(string(raw)) {
+		m.openPassPrompt("nsec-rotate", "", false)
+		m.passPrompt.migrate = migrate
+		return m, textinput.Blink
+	}
+	return m.finishRotate(migrate, "")
+}
+
+// finishRotate replaces the key file with a new key (encrypted with pass
+// when it is non-empty), switches to it, and republishes our lists under it.
+func (m *model) finishRotate(migrate bool, pass string) (tea.Model, tea.Cmd) {
+	oldKeys := m.keys
+	newKeys, backup, err := rotateKeyFile(expandKeyPath(m.cfg.PrivateKeyFile), time.Now(), pass)
+	if err != nil {
+		m.addSystemMsg("nsec-rotate: " + err.Error())
+		return m, nil
+	}
+	log.Printf("rotateKey: %s -> %s, old key backed up to %s", shortPK(oldKeys.PK.Hex()), shortPK(newKeys.PK.Hex()), backup)
+
+	m.keys = newKeys
+	// Swap the signer in place when possible so the pool's NIP-42 auth
+	// handler, which holds the same signer, signs with the new key too.
+	ks := keyer.NewPlainKeySigner(newKeys.SK)
+	if p, ok := m.kr.(*keyer.KeySigner); ok {
+		*p = ks
+	} else {
+		m.kr = &ks
+	}
+
+	m.addSystemMsg("key rotated — new npub: " + newKeys.NPub)
+	m.addSystemMsg("old key backed up to " + backup)
+	if pass != "" {
+		m.addSystemMsg("the new key is encrypted with the passphrase you just entered")
+	}
+
+	if m.dmCancel != nil {
+		m.dmCancel()
+		m.dmCancel = nil
+	}
+	m.dmEvents = nil
+	cmds := []tea.Cmd{
+		m.afterRelayAccess(m.relays, subscribeDMCmd(m.pool, m.relays, m.kr, m.lastDMSeen, m.cfg.DMLookback())),
+		publishDMRelaysCmd(m.pool, m.relays, m.keys),
+		m.syncContacts(),
+		publishPublicChatsListCmd(m.pool, m.relays, m.allChannels(), m.keys),
+		publishSimpleGroupsListCmd(m.pool, m.relays, m.allGroups(), m.keys),
+	}
+	// Only a follow list we actually received is republished; otherwise the
+	// new key would start out with an empty one.
+	if m.followsLoaded {
+		cmds = append(cmds, publishFollowListCmd(m.pool, m.relays, m.follows, m.followsContent, m.keys))
+	}
+	if m.cfg.Profile != (ProfileConfig{}) {
+		cmds = append(cmds, publishProfileCmd(m.pool, m.relays, m.cfg.Profile, m.keys))
+	}
+	if migrate {
+		cmds = append(cmds, publishMigrationCmd(m.pool, m.relays, oldKeys, newKeys.PK))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+func (m *model) handleMigrationPublished(msg migrationPublishedMsg) (tea.Model, tea.Cmd) {
+	m.addSystemMsg(fmt.Sprintf("old key announced the move (accepted by %d/%d relays)", msg.accepted, msg.total))
+	return m, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/nip19"
+	"fiatjaf.com/nostr/nip49"
+)
+
+func TestConfirmCode(t *testing.T) {
+	if got := confirmCode("npub1abcdefxyz123"); got != "xyz123" {
+		t.Errorf("confirmCode = %q, want xyz123", got)
+	}
+}
+
+func TestRotateKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nsec")
+	oldSK := nostr.Generate()
+	oldRaw := nip19.EncodeNsec(oldSK) + "\n"
+	if err := os.WriteFile(path, []byte(oldRaw), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	keys, backup, err := rotateKeyFile(path, now, "")
+	if err != nil {
+		t.Fatalf("rotateKeyFile: %v", err)
+	}
+	if want := path + ".old-20260102-030405"; backup != want {
+		t.Errorf("backup = %q, want %q", backup, want)
+	}
+	if data, _ := os.ReadFile(backup); string(data) != oldRaw {
+		t.Error("backup does not hold the old key")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sk, err := parseSecretKey(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatalf("new key file unreadable: %v", err)
+	}
+	if sk != keys.SK || sk == oldSK {
+		t.Error("key file does not hold the new key")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("key file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// A second rotation in the same second must not clobber the backup.
+	if _, _, err := rotateKeyFile(path, now, ""); err == nil {
+		t.Error("expected error when backup already exists")
+	}
+}
+
+func TestRotateKeyFileEncrypted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nsec")
+	if err := os.WriteFile(path, []byte("ncryptsec1old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keys, _, err := rotateKeyFile(path, time.Now(), "hunter2")
+	if err != nil {
+		t.Fatalf("rotateKeyFile: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := strings.TrimSpace(string(data))
+	if !isEncryptedKey(raw) {
+		t.Fatalf("new key file = %q, want an ncryptsec", raw)
+	}
+	sk, err := nip49.Decrypt(raw, "hunter2")
+	if err != nil || sk != keys.SK {
+		t.Errorf("new key does not decrypt to the new key: %v", err)
+	}
+}
+
+func TestRotateKeyAsksPassphraseForEncryptedKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nsec")
+	if err := os.WriteFile(path, []byte("ncryptsec1old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m := newTestModel(1, 0, 0)
+	m.keys = testKeys(t)
+	m.cfg.PrivateKeyFile = path
+
+	m.rotateKey([]string{confirmCode(m.keys.NPub), "migrate"})
+	if m.passPrompt == nil || m.passPrompt.action != "nsec-rotate" || !m.passPrompt.migrate {
+		t.Fatalf("passPrompt = %+v, want an nsec-rotate prompt remembering migrate", m.passPrompt)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "ncryptsec1old\n" {
+		t.Error("key file changed before the passphrase was entered")
+	}
+}
+
+func TestBuildMigrationEvent(t *testing.T) {
+	oldSK := nostr.Generate()
+	oldKeys := Keys{SK: oldSK, PK: nostr.GetPublicKey(oldSK)}
+	newPK := nostr.GetPublicKey(nostr.Generate())
+
+	evt, err := buildMigrationEvent(oldKeys, newPK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evt.PubKey != oldKeys.PK || !evt.VerifySignature() {
+		t.Error("migration note not signed by the old key")
+	}
+	if tag := evt.Tags.Find("p"); tag == nil || tag[1] != newPK.Hex() {
+		t.Errorf("p tag = %v, want new pubkey", tag)
+	}
+	if !strings.Contains(evt.Content, nip19.EncodeNpub(newPK)) {
+		t.Errorf("content %q does not name the new npub", evt.Content)
+	}
+}
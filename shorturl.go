@@ -0,0 +1,117 @@
+package main
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// webURLRe matches http(s) URLs in message text, optionally in the <...> of
+// a markdown autolink.
+var webURLRe = regexp.MustCompile(`<?https?://[^\s<>()\[\]"']+>?`)
+
+// shortURL is a URL shown shortened in the chat.
+type shortURL struct {
+	placeholder string // put in place of the URL before rendering
+	label       string // shown once the message is laid out
+	full        string
+}
+
+// shortenURL returns the label shown for raw: its host followed by "/…"
+// when anything comes after the host.
+func shortenURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return truncateRunes(raw, 24)
+	}
+	label := strings.TrimPrefix(u.Host, "www.")
+	if strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.Fragment != "" {
+		label += "/…"
+	}
+	return label
+}
+
+// shortenURLs replaces the http(s) URLs in content longer than limit
+// characters with their shortenURL label, outside fenced code blocks, and
+// returns the URLs replaced in order of appearance. Trailing punctuation
+// isn't taken as part of a URL. The destination of a markdown link keeps
+// its scheme, since glamour resolves scheme-less ones as paths;
+// hyperlinkShortURLs drops it after wrapping.
+func shortenURLs(content string, limit int) (string, []shortURL) {
+	if limit <= 0 {
+		return content, nil
+	}
+	var links []shortURL
+	lines := strings.Split(content, "\n")
+	inCode := false
+	for n, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, loc := range webURLRe.FindAllStringIndex(line, -1) {
+			i, j := loc[0], loc[1]
+			angled := line[i] == '<' && line[j-1] == '>'
+			raw := strings.Trim(line[i:j], "<>")
+			full := strings.TrimRight(raw, ".,;:!?")
+			if len([]rune(full)) <= limit {
+				continue
+			}
+			label := shortenURL(full)
+			link := shortURL{placeholder: label, label: label, full: full}
+			if strings.HasSuffix(line[:i], "](") {
+				u, _ := url.Parse(full)
+				link.placeholder = u.Scheme + "://" + label
+			}
+			links = append(links, link)
+			b.WriteString(line[last:i])
+			if angled {
+				b.WriteString(link.placeholder)
+			} else {
+				b.WriteString(strings.Replace(line[i:j], full, link.placeholder, 1))
+			}
+			last = j
+		}
+		b.WriteString(line[last:])
+		lines[n] = b.String()
+	}
+	return strings.Join(lines, "\n"), links
+}
+
+// hyperlinkShortURLs replaces the placeholders of links found in line, from
+// links[next] on, with their label in an OSC 8 hyperlink to the full URL, so
+// the short text can still be opened in terminals that support them. It
+// returns the index of the next link to look for. Placeholders split across
+// lines by wrapping are left as they are.
+func hyperlinkShortURLs(line string, links []shortURL, next int) (string, int) {
+	var b strings.Builder
+	rest := line
+	for next < len(links) {
+		found := false
+		for j := next; j < len(links); j++ {
+			i := strings.Index(rest, links[j].placeholder)
+			if i < 0 {
+				continue
+			}
+			end := i + len(links[j].placeholder)
+			b.WriteString(rest[:i])
+			b.WriteString(ansi.SetHyperlink(links[j].full) + links[j].label + ansi.ResetHyperlink())
+			rest = rest[end:]
+			next = j + 1
+			found = true
+			break
+		}
+		if !found {
+			break
+		}
+	}
+	b.WriteString(rest)
+	return b.String(), next
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+func TestShortenURL(t *testing.T) {
+	for raw, want := range map[string]string{
+		"https://example.com/very/long/path": "example.com/…",
+		"https://www.example.com/a?b=c":      "example.com/…",
+		"https://example.com/":               "example.com",
+		"http://example.com#top":             "example.com/…",
+	} {
+		if got := shortenURL(raw); got != want {
+			t.Errorf("shortenURL(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestShortenURLs(t *testing.T) {
+	long := "https://example.com/very/long/path/to/a/page"
+	tests := []struct {
+		in, want string
+	}{
+		{"see " + long + ", thanks", "see example.com/…, thanks"},
+		{"<" + long + ">.", "example.com/…."},
+		{"[docs](" + long + ")", "[docs](https://example.com/…)"},
+		{"short https://example.com/a stays", "short https://example.com/a stays"},
+		{"```\n" + long + "\n```", "```\n" + long + "\n```"},
+	}
+	for _, tt := range tests {
+		got, _ := shortenURLs(tt.in, 30)
+		if got != tt.want {
+			t.Errorf("shortenURLs(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if got, links := shortenURLs("see "+long, 0); got != "see "+long || links != nil {
+		t.Errorf("limit 0 changed %q", got)
+	}
+	_, links := shortenURLs(long+" and "+long+"/more.", 30)
+	if len(links) != 2 || links[0].full != long || links[1].full != long+"/more" {
+		t.Errorf("links = %+v", links)
+	}
+}
+
+func TestHyperlinkShortURLs(t *testing.T) {
+	links := []shortURL{
+		{placeholder: "a.com/…", label: "a.com/…", full: "https://a.com/x"},
+		{placeholder: "https://b.com/…", label: "b.com/…", full: "https://b.com/y"},
+		{placeholder: "c.com/…", label: "c.com/…", full: "https://c.com/z"},
+	}
+	line, next := hyperlinkShortURLs("go to a.com/… or https://b.com/…", links, 0)
+	if next != 2 {
+		t.Errorf("next = %d, want 2", next)
+	}
+	if got := ansi.Strip(line); got != "go to a.com/… or b.com/…" {
+		t.Errorf("shown %q", got)
+	}
+	if !strings.Contains(line, ansi.SetHyperlink("https://b.com/y")) {
+		t.Errorf("no hyperlink to the full URL in %q", line)
+	}
+	// A placeholder split by wrapping is skipped.
+	if line, next = hyperlinkShortURLs("c.com/…", links, 0); next != 3 || ansi.Strip(line) != "c.com/…" {
+		t.Errorf("next = %d, line %q", next, line)
+	}
+}
@@ -1,5 +1,7 @@
 package main
 
+import "fmt"
+
 // SidebarKind identifies the type of a sidebar item.
 type SidebarKind int
 
@@ -120,6 +122,7 @@ func (m *model) appendChannelItem(ch Channel) int {
 // appendGroupItem inserts a GroupItem at the end of the group section.
 // Returns the sidebar index where it was inserted.
 func (m *model) appendGroupItem(g Group) int {
+	g.RelayURL = normalizeRelayURL(g.RelayURL)
 	idx := m.groupEndIdx()
 	m.sidebar = append(m.sidebar, nil)
 	copy(m.sidebar[idx+1:], m.sidebar[idx:])
@@ -148,6 +151,7 @@ func (m *model) findChannelIdx(id string) int {
 
 // findGroupIdx finds a group by relay URL and group ID. Returns sidebar index or -1.
 func (m *model) findGroupIdx(relayURL, groupID string) int {
+	relayURL = normalizeRelayURL(relayURL)
 	for i, it := range m.sidebar {
 		if gi, ok := it.(GroupItem); ok && gi.Group.RelayURL == relayURL && gi.Group.GroupID == groupID {
 			return i
@@ -156,6 +160,25 @@ func (m *model) findGroupIdx(relayURL, groupID string) int {
 	return -1
 }
 
+// groupRelays returns the relay set of the group with key gk: its home
+// relay plus mirrors, or just the home relay if it isn't in the sidebar.
+func (m *model) groupRelays(gk string) []string {
+	relayURL, groupID := splitGroupKey(gk)
+	if idx := m.findGroupIdx(relayURL, groupID); idx >= 0 {
+		return m.sidebar[idx].(GroupItem).Group.Relays()
+	}
+	return []string{relayURL}
+}
+
+// channelRelays returns the relays of the channel with ID id: the global
+// relays plus its relay hints.
+func (m *model) channelRelays(id string) []string {
+	if idx := m.findChannelIdx(id); idx >= 0 {
+		return m.sidebar[idx].(ChannelItem).Channel.RelaysWith(m.relays)
+	}
+	return m.relays
+}
+
 // findDMPeerIdx finds a DM peer by pubkey. Returns sidebar index or -1.
 func (m *model) findDMPeerIdx(pubkey string) int {
 	for i, it := range m.sidebar {
@@ -265,10 +288,11 @@ func (m *model) replaceGroups(groups []Group) {
 }
 
 // replaceDMPeers replaces all DM items in the sidebar with the given contacts.
+// Group DMs aren't in the contacts list, so they are kept.
 func (m *model) replaceDMPeers(contacts []Contact) {
 	// Remove existing DM items (iterate backwards).
 	for i := len(m.sidebar) - 1; i >= 0; i-- {
-		if m.sidebar[i].Kind() == SidebarDM {
+		if _, ok := m.sidebar[i].(DMItem); ok {
 			m.sidebar = append(m.sidebar[:i], m.sidebar[i+1:]...)
 		}
 	}
@@ -300,6 +324,18 @@ func (m *model) updateGroupName(relayURL, groupID, name string) {
 	}
 }
 
+// updateGroupRoles records our roles from the group's kind 39001 admins list.
+func (m *model) updateGroupRoles(relayURL, groupID string, roles []string) {
+	for i, it := range m.sidebar {
+		if gi, ok := it.(GroupItem); ok && gi.Group.RelayURL == relayURL && gi.Group.GroupID == groupID {
+			gi.Group.Roles = roles
+			gi.Group.AdminsKnown = true
+			m.sidebar[i] = gi
+			return
+		}
+	}
+}
+
 // updateGroupRelayPubKey updates the relay pubkey of a group in the sidebar.
 func (m *model) updateGroupRelayPubKey(relayURL, groupID, relayPubKey string) {
 	for i, it := range m.sidebar {
@@ -310,3 +346,83 @@ func (m *model) updateGroupRelayPubKey(relayURL, groupID, relayPubKey string) {
 		}
 	}
 }
+
+// --- Section layout ---
+
+// sidebarSections lists the sidebar sections and their headers in display order.
+var sidebarSections = []struct {
+	kind  SidebarKind
+	title string
+}{
+	{SidebarChannel, "CHANNELS"},
+	{SidebarGroup, "GROUPS"},
+	{SidebarDM, "DMS"},
+}
+
+// sidebarRow is one line of the sidebar: the header of section kind when
+// item is -1, otherwise the sidebar item at index item.
+type sidebarRow struct {
+	kind SidebarKind
+	item int
+}
+
+// sidebarRows lays out the sidebar line by line. Empty sections are left
+// out with compact_sidebar; folded sections show only their header, plus
+// the active item if it is in that section. viewSidebar and the mouse
+// hit-testing both use this, so they always agree.
+func (m *model) sidebarRows() []sidebarRow {
+	var rows []sidebarRow
+	for _, sec := range sidebarSections {
+		var items []sidebarRow
+		for i, it := range m.sidebar {
+			if it.Kind() == sec.kind {
+				items = append(items, sidebarRow{kind: sec.kind, item: i})
+			}
+		}
+		if len(items) == 0 && m.cfg.CompactSidebar {
+			continue
+		}
+		rows = append(rows, sidebarRow{kind: sec.kind, item: -1})
+		for _, r := range items {
+			if !m.foldedSections[sec.kind] || r.item == m.activeItem {
+				rows = append(rows, r)
+			}
+		}
+	}
+	return rows
+}
+
+// renderSectionHeader renders a section header. A folded header shows how
+// many items it hides and is styled as unread when any of them is.
+func (m *model) renderSectionHeader(kind SidebarKind) string {
+	var title string
+	for _, sec := range sidebarSections {
+		if sec.kind == kind {
+			title = sec.title
+		}
+	}
+	if !m.foldedSections[kind] {
+		return sidebarSectionStyle.Render(title)
+	}
+	hidden, unread := 0, false
+	for i, it := range m.sidebar {
+		if it.Kind() != kind || i == m.activeItem {
+			continue
+		}
+		hidden++
+		unread = unread || m.unread[it.ItemID()] || m.highlights[it.ItemID()]
+	}
+	title = fmt.Sprintf("%s +%d", title, hidden)
+	if unread {
+		return sidebarUnreadStyle.Render(title)
+	}
+	return sidebarSectionStyle.Render(title)
+}
+
+// setSectionFolded folds or unfolds a sidebar section.
+func (m *model) setSectionFolded(kind SidebarKind, folded bool) {
+	if m.foldedSections == nil {
+		m.foldedSections = make(map[SidebarKind]bool)
+	}
+	m.foldedSections[kind] = folded
+}
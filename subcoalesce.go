@@ -0,0 +1,465 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"fiatjaf.com/nostr"
+)
+
+// With max_subs_per_relay set, room subscriptions are not opened one by one:
+// requests are queued for subCoalesceDelay and then opened as one combined
+// subscription (all channel IDs in one #e filter, all of a relay's groups in
+// one #h and one #d filter). A roomMux routes the combined stream back to
+// per-room event channels, so each room keeps its own roomSub, cancel func,
+// and reconnect handling exactly as with a dedicated subscription.
+//
+// A REQ could carry one filter per room, but the relay pool sends a single
+// filter per subscription. So the combined subscription only carries new
+// events (since now, limit 0), and each room's history is fetched by a
+// one-shot query with the room's own filter and limit. Those queries close
+// at EOSE and go through max_concurrent_queries, so they don't hold
+// subscriptions open, and a busy room can't crowd out a quiet one.
+
+// subCoalesceDelay is how long subscription requests are collected before
+// they are opened together.
+const subCoalesceDelay = 300 * time.Millisecond
+
+// subFlushMsg opens the queued room subscriptions.
+type subFlushMsg struct{}
+
+// roomSubsStartedMsg reports the rooms of a combined subscription.
+type roomSubsStartedMsg struct {
+	channels []channelSubStartedMsg
+	groups   []groupSubStartedMsg
+}
+
+// subBatchSeq numbers combined subscriptions for budget accounting.
+var subBatchSeq atomic.Int64
+
+// roomMux fans one upstream subscription out to per-room event channels.
+// The room set is fixed at creation; detaching the last room cancels the
+// upstream subscription.
+type roomMux struct {
+	rooms map[string]*muxRoom
+
+	mu       sync.Mutex
+	attached int
+	cancel   context.CancelFunc
+}
+
+type muxRoom struct {
+	in   chan nostr.RelayEvent
+	done chan struct{}
+	once sync.Once
+}
+
+func newRoomMux(rooms []string, cancel context.CancelFunc) *roomMux {
+	x := &roomMux{rooms: make(map[string]*muxRoom, len(rooms)), cancel: cancel}
+	for _, id := range rooms {
+		if _, ok := x.rooms[id]; !ok {
+			x.rooms[id] = &muxRoom{in: make(chan nostr.RelayEvent), done: make(chan struct{})}
+			x.attached++
+		}
+	}
+	return x
+}
+
+// run forwards each upstream event to the room route picks, dropping events
+// for unknown or detached rooms. When upstream ends, every room's channel is
+// closed so each room sees its subscription end and reconnects.
+func (x *roomMux) run(upstream <-chan nostr.RelayEvent, route func(nostr.RelayEvent) string) {
+	defer func() {
+		for _, r := range x.rooms {
+			close(r.in)
+		}
+	}()
+	for re := range upstream {
+		r, ok := x.rooms[route(re)]
+		if !ok {
+			continue
+		}
+		select {
+		case <-r.done:
+			continue
+		default:
+		}
+		select {
+		case r.in <- re:
+		case <-r.done:
+		}
+	}
+}
+
+// events returns the event channel of room id.
+func (x *roomMux) events(id string) <-chan nostr.RelayEvent {
+	return x.rooms[id].in
+}
+
+// detacher returns the cancel func of room id: it stops delivery to that
+// room, and cancels the upstream subscription once no room is left.
+func (x *roomMux) detacher(id string) context.CancelFunc {
+	r := x.rooms[id]
+	return func() {
+		r.once.Do(func() {
+			close(r.done)
+			x.mu.Lock()
+			x.attached--
+			last := x.attached == 0
+			x.mu.Unlock()
+			if last {
+				x.cancel()
+			}
+		})
+	}
+}
+
+// routeByTag returns a router picking the room from the first tag named
+// one of names whose value is in ids; key maps that value to the room key.
+func routeByTag(ids []string, key func(string) string, names ...string) func(nostr.RelayEvent) string {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return func(re nostr.RelayEvent) string {
+		for _, tag := range re.Tags {
+			if len(tag) < 2 || !set[tag[1]] {
+				continue
+			}
+			for _, n := range names {
+				if tag[0] == n {
+					return key(tag[1])
+				}
+			}
+		}
+		return ""
+	}
+}
+
+// roomHistoryTimeout bounds one room history query of a combined
+// subscription, counted from when it gets a query slot.
+const roomHistoryTimeout = 15 * time.Second
+
+// liveOnly turns a history filter into one for new events only.
+func liveOnly(f nostr.Filter) nostr.Filter {
+	f.Since = nostr.Now()
+	f.Until = 0
+	f.Limit = 0
+	f.LimitZero = true
+	return f
+}
+
+// withHistory merges the events of live and of the one-shot history
+// queries, as they arrive. Each query waits for a slot of limit. The
+// returned channel closes once live has and the queries are done, so a room
+// still reconnects when its combined subscription ends.
+func withHistory(ctx context.Context, pool *nostr.Pool, limit *queryLimiter, live <-chan nostr.RelayEvent, queries []nostr.DirectedFilter) <-chan nostr.RelayEvent {
+	out := make(chan nostr.RelayEvent)
+	forward := func(events <-chan nostr.RelayEvent) {
+		for re := range events {
+			select {
+			case out <- re:
+			case <-ctx.Done(): // drain until the sender closes
+			}
+		}
+	}
+	var wg sync.WaitGroup
+	wg.Add(1 + len(queries))
+	go func() {
+		defer wg.Done()
+		forward(live)
+	}()
+	for _, q := range queries {
+		go func() {
+			defer wg.Done()
+			defer limit.acquire("room history on " + q.Relay)()
+			qctx, cancel := context.WithTimeout(ctx, roomHistoryTimeout)
+			defer cancel()
+			forward(pool.FetchMany(qctx, []string{q.Relay}, q.Filter, nostr.SubscriptionOptions{}))
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// channelBatchFilters returns the filters of a combined channel
+// subscription: per relay, live filters for all channelIDs, and a history
+// filter per channel with the channel's own limit.
+func channelBatchFilters(relays []string, channelIDs []string, cfg Config) (live, history []nostr.DirectedFilter) {
+	for _, url := range relays {
+		rf := subFilter{}.forRelay(cfg, url)
+		for _, f := range []nostr.Filter{
+			rf.apply(nostr.Filter{Kinds: []nostr.Kind{nostr.KindChannelMessage, nostr.KindGenericRepost}, Tags: nostr.TagMap{"e": channelIDs}}),
+			reactionFilter(rf, nostr.TagMap{"e": channelIDs}),
+		} {
+			if len(f.Kinds) > 0 {
+				live = append(live, nostr.DirectedFilter{Relay: url, Filter: liveOnly(f)})
+			}
+		}
+		for _, id := range channelIDs {
+			tags := nostr.TagMap{"e": {id}}
+			for _, f := range []nostr.Filter{
+				rf.apply(nostr.Filter{Kinds: []nostr.Kind{nostr.KindChannelMessage, nostr.KindGenericRepost}, Tags: tags}),
+				reactionFilter(rf, tags),
+			} {
+				if len(f.Kinds) > 0 {
+					history = append(history, nostr.DirectedFilter{Relay: url, Filter: f})
+				}
+			}
+		}
+	}
+	return live, history
+}
+
+// subscribeChannelBatchCmd opens one live subscription per relay covering
+// all channelIDs, and fetches each channel's history separately.
+func subscribeChannelBatchCmd(pool *nostr.Pool, limit *queryLimiter, relays []string, channelIDs []string, cfg Config, closed chan<- subClosedMsg) tea.Cmd {
+	return func() tea.Msg {
+		batch := fmt.Sprintf("channels#%d", subBatchSeq.Add(1))
+		log.Printf("subscribeChannelBatchCmd: %s with %d channels", batch, len(channelIDs))
+		ctx, cancel := context.WithCancel(context.Background())
+		live, history := channelBatchFilters(relays, channelIDs, cfg)
+		mux := newRoomMux(channelIDs, cancel)
+		events, closedBy := pool.BatchedSubscribeManyNotifyClosed(ctx, live, nostr.SubscriptionOptions{})
+		go forwardClosed(ctx, closedBy, channelIDs, closed)
+		go mux.run(withHistory(ctx, pool, limit, events, history), routeByTag(channelIDs, func(id string) string { return id }, "e"))
+
+		var msg roomSubsStartedMsg
+		for id := range mux.rooms {
+			msg.channels = append(msg.channels, channelSubStartedMsg{channelID: id, events: mux.events(id), cancel: mux.detacher(id), batch: batch})
+		}
+		return msg
+	}
+}
+
+// subscribeGroupBatchCmd opens subscriptions on relayURL covering all
+// groupIDs: live chat messages and reactions by #h, and metadata plus
+// admins by #d. Each group's chat history is fetched separately.
+func subscribeGroupBatchCmd(pool *nostr.Pool, limit *queryLimiter, relayURL string, groupIDs []string, cfg Config, closed chan<- subClosedMsg) tea.Cmd {
+	return func() tea.Msg {
+		batch := fmt.Sprintf("groups#%d", subBatchSeq.Add(1))
+		log.Printf("subscribeGroupBatchCmd: %s with %d groups on %s", batch, len(groupIDs), relayURL)
+		rf := subFilter{}.forRelay(cfg, relayURL)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		chatKinds := []nostr.Kind{nostr.KindSimpleGroupChatMessage, nostr.KindSimpleGroupThreadedReply, nostr.KindSimpleGroupThread, nostr.KindSimpleGroupReply, nostr.KindGenericRepost}
+		var filters []nostr.Filter
+		for _, f := range []nostr.Filter{
+			rf.apply(nostr.Filter{Kinds: chatKinds, Tags: nostr.TagMap{"h": groupIDs}}),
+			reactionFilter(rf, nostr.TagMap{"h": groupIDs}),
+		} {
+			if len(f.Kinds) > 0 {
+				filters = append(filters, liveOnly(f))
+			}
+		}
+		var history []nostr.DirectedFilter
+		for _, id := range groupIDs {
+			tags := nostr.TagMap{"h": {id}}
+			for _, f := range []nostr.Filter{
+				rf.apply(nostr.Filter{Kinds: chatKinds, Tags: tags}),
+				reactionFilter(rf, tags),
+			} {
+				if len(f.Kinds) > 0 {
+					history = append(history, nostr.DirectedFilter{Relay: relayURL, Filter: f})
+				}
+			}
+		}
+		var metaKinds []nostr.Kind
+		for _, k := range []nostr.Kind{nostr.KindSimpleGroupMetadata, nostr.KindSimpleGroupAdmins} {
+			if !rf.excludes(k) {
+				metaKinds = append(metaKinds, k)
+			}
+		}
+		if len(metaKinds) > 0 {
+			filters = append(filters, nostr.Filter{
+				Kinds: metaKinds,
+				Tags:  nostr.TagMap{"d": groupIDs},
+				Limit: len(metaKinds) * len(groupIDs),
+			})
+		}
+
+		gks := make([]string, len(groupIDs))
+		for i, id := range groupIDs {
+			gks[i] = groupKey(relayURL, id)
+		}
+
+		upstream := make(chan nostr.RelayEvent)
+		var wg sync.WaitGroup
+		for _, f := range filters {
+			wg.Add(1)
+			go func(f nostr.Filter) {
+				defer wg.Done()
+				events, closedBy := pool.SubscribeManyNotifyClosed(ctx, []string{relayURL}, f, nostr.SubscriptionOptions{})
+				go forwardClosed(ctx, closedBy, gks, closed)
+				for re := range events {
+					upstream <- re
+				}
+			}(f)
+		}
+		go func() {
+			wg.Wait()
+			close(upstream)
+		}()
+
+		mux := newRoomMux(gks, cancel)
+		go mux.run(withHistory(ctx, pool, limit, upstream, history), routeByTag(groupIDs, func(id string) string { return groupKey(relayURL, id) }, "h", "d"))
+
+		var msg roomSubsStartedMsg
+		for gk := range mux.rooms {
+			msg.groups = append(msg.groups, groupSubStartedMsg{groupKey: gk, events: mux.events(gk), cancel: mux.detacher(gk), batch: batch})
+		}
+		return msg
+	}
+}
+
+// coalescesSubs reports whether roomID's subscription goes through the
+// queue. Rooms with /filter overrides keep a dedicated subscription, since
+// their filter can't be shared.
+func (m *model) coalescesSubs(roomID string) bool {
+	if m.cfg.MaxSubsPerRelay <= 0 {
+		return false
+	}
+	if len(m.groupRelays(roomID)) > 1 {
+		return false // mirrored groups span relays; batches are per relay
+	}
+	if len(m.channelRelays(roomID)) > len(m.relays) {
+		return false // relay hints reach beyond the relays batches use
+	}
+	_, filtered := m.roomFilters[roomID]
+	return !filtered
+}
+
+// queueSubscribe queues roomID (a channel ID or groupKey) for the next
+// combined subscription, scheduling a flush if none is pending.
+func (m *model) queueSubscribe(roomID string) tea.Cmd {
+	m.subQueue[roomID] = true
+	if m.subFlushPending {
+		return nil
+	}
+	m.subFlushPending = true
+	return tea.Tick(subCoalesceDelay, func(time.Time) tea.Msg { return subFlushMsg{} })
+}
+
+// subBatchCaps returns how many combined channel subscriptions and, per
+// relay, group subscriptions fit in max_subs_per_relay. Half the budget goes
+// to channels; group batches cost two subscriptions each.
+func subBatchCaps(budget int) (channels, groups int) {
+	return max(1, budget/2), max(1, budget/4)
+}
+
+// liveSubBatches groups the active subscriptions of kind (for groups, on
+// relay) by batch, leaving out rooms in skip.
+func (m *model) liveSubBatches(kind SidebarKind, relay string, skip map[string]bool) map[string][]string {
+	live := make(map[string][]string)
+	for id, sub := range m.roomSubs {
+		if sub.kind != kind || skip[id] {
+			continue
+		}
+		if kind == SidebarGroup {
+			if r, _ := splitGroupKey(id); r != relay {
+				continue
+			}
+		}
+		b := sub.batch
+		if b == "" {
+			b = id
+		}
+		live[b] = append(live[b], id)
+	}
+	return live
+}
+
+// planSubBatch returns the rooms for a new combined subscription: pending,
+// plus the rooms of the smallest existing batches when opening another
+// subscription would exceed limit. Those rooms are resubscribed in the new
+// batch, replacing their old subscription.
+func (m *model) planSubBatch(kind SidebarKind, relay string, pending []string, limit int) []string {
+	skip := make(map[string]bool, len(pending))
+	for _, id := range pending {
+		skip[id] = true
+	}
+	live := m.liveSubBatches(kind, relay, skip)
+	for len(live)+1 > limit {
+		smallest := ""
+		for b, rooms := range live {
+			if !m.coalescesSubs(rooms[0]) {
+				continue // a /filter room; can't be merged
+			}
+			if smallest == "" || len(rooms) < len(live[smallest]) || (len(rooms) == len(live[smallest]) && b < smallest) {
+				smallest = b
+			}
+		}
+		if smallest == "" {
+			log.Printf("planSubBatch: %d subscriptions exceed max_subs_per_relay, nothing left to merge", len(live)+1)
+			break
+		}
+		pending = append(pending, live[smallest]...)
+		delete(live, smallest)
+	}
+	sort.Strings(pending)
+	return pending
+}
+
+// handleSubFlush opens the queued subscriptions: one combined subscription
+// for the channels and one per relay for the groups.
+func (m *model) handleSubFlush() (tea.Model, tea.Cmd) {
+	m.subFlushPending = false
+	var channels []string
+	groups := make(map[string][]string) // relay URL → group keys
+	for id := range m.subQueue {
+		if relay, _ := splitGroupKey(id); relay != "" {
+			groups[relay] = append(groups[relay], id)
+		} else {
+			channels = append(channels, id)
+		}
+	}
+	m.subQueue = make(map[string]bool)
+	chCap, grCap := subBatchCaps(m.cfg.MaxSubsPerRelay)
+
+	var cmds []tea.Cmd
+	if len(channels) > 0 {
+		ids := m.planSubBatch(SidebarChannel, "", channels, chCap)
+		if len(ids) == 1 {
+			cmds = append(cmds, m.afterRelayAccess(m.relays, subscribeChannelCmd(m.pool, m.relays, ids[0], m.cfg, subFilter{}, m.subClosed)))
+		} else {
+			cmds = append(cmds, m.afterRelayAccess(m.relays, subscribeChannelBatchCmd(m.pool, m.queries, m.relays, ids, m.cfg, m.subClosed)))
+		}
+	}
+	for relay, gks := range groups {
+		gks = m.planSubBatch(SidebarGroup, relay, gks, grCap)
+		if len(gks) == 1 {
+			_, gid := splitGroupKey(gks[0])
+			cmds = append(cmds, m.afterRelayAccess([]string{relay}, subscribeGroupCmd(m.pool, []string{relay}, gid, m.cfg, subFilter{}, m.subClosed)))
+			continue
+		}
+		ids := make([]string, len(gks))
+		for i, gk := range gks {
+			_, ids[i] = splitGroupKey(gk)
+		}
+		cmds = append(cmds, m.afterRelayAccess([]string{relay}, subscribeGroupBatchCmd(m.pool, m.queries, relay, ids, m.cfg, m.subClosed)))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// handleRoomSubsStarted registers every room of a combined subscription.
+func (m *model) handleRoomSubsStarted(msg roomSubsStartedMsg) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+	for _, c := range msg.channels {
+		_, cmd := m.handleChannelSubStarted(c)
+		cmds = append(cmds, cmd)
+	}
+	for _, g := range msg.groups {
+		_, cmd := m.handleGroupSubStarted(g)
+		cmds = append(cmds, cmd)
+	}
+	return m, tea.Batch(cmds...)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestRouteByTag(t *testing.T) {
+	route := routeByTag([]string{"a", "b"}, func(id string) string { return "room:" + id }, "h", "d")
+	for _, tt := range []struct {
+		tags nostr.Tags
+		want string
+	}{
+		{nostr.Tags{{"h", "b"}}, "room:b"},
+		{nostr.Tags{{"d", "a"}}, "room:a"},
+		{nostr.Tags{{"e", "a"}, {"h", "b"}}, "room:b"},
+		{nostr.Tags{{"h", "c"}}, ""},
+		{nostr.Tags{{"h"}}, ""},
+	} {
+		re := nostr.RelayEvent{Event: nostr.Event{Tags: tt.tags}}
+		if got := route(re); got != tt.want {
+			t.Errorf("route(%v) = %q, want %q", tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestRoomMuxDemux(t *testing.T) {
+	upstream := make(chan nostr.RelayEvent)
+	cancelled := make(chan struct{})
+	x := newRoomMux([]string{"a", "b"}, func() { close(cancelled) })
+	go x.run(upstream, routeByTag([]string{"a", "b"}, func(id string) string { return id }, "e"))
+
+	ev := func(id string) nostr.RelayEvent {
+		return nostr.RelayEvent{Event: nostr.Event{Content: id, Tags: nostr.Tags{{"e", id}}}}
+	}
+	go func() { upstream <- ev("b") }()
+	if got := <-x.events("b"); got.Content != "b" {
+		t.Fatalf("room b got %q", got.Content)
+	}
+
+	// A detached room no longer blocks delivery to the others.
+	x.detacher("a")()
+	go func() {
+		upstream <- ev("a")
+		upstream <- ev("b")
+	}()
+	if got := <-x.events("b"); got.Content != "b" {
+		t.Fatalf("room b got %q after detaching a", got.Content)
+	}
+	select {
+	case <-cancelled:
+		t.Fatal("upstream cancelled while room b is attached")
+	default:
+	}
+
+	x.detacher("b")()
+	x.detacher("b")() // idempotent
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("upstream not cancelled after last detach")
+	}
+
+	close(upstream)
+	if _, ok := <-x.events("b"); ok {
+		t.Error("room channel not closed after upstream ended")
+	}
+}
+
+func TestPlanSubBatchMergesSmallest(t *testing.T) {
+	m := newTestModel(0, 0, 0)
+	m.cfg.MaxSubsPerRelay = 4
+	m.roomFilters = map[string]subFilter{"f": {Limit: 5}}
+	m.roomSubs = map[string]*roomSub{
+		"a": {kind: SidebarChannel, batch: "b1"},
+		"b": {kind: SidebarChannel, batch: "b1"},
+		"c": {kind: SidebarChannel},
+		"f": {kind: SidebarChannel},
+		"g": {kind: SidebarGroup, roomID: groupKey("wss://r", "g")},
+	}
+	// Limit 2: "f" (filtered) and "c" are single subscriptions, "b1" has
+	// two rooms. Opening one more means merging "c" and then "b1".
+	got := m.planSubBatch(SidebarChannel, "", []string{"x"}, 2)
+	if want := []string{"a", "b", "c", "x"}; !slices.Equal(got, want) {
+		t.Errorf("planSubBatch = %v, want %v", got, want)
+	}
+
+	got = m.planSubBatch(SidebarChannel, "", []string{"x"}, 4)
+	if want := []string{"x"}; !slices.Equal(got, want) {
+		t.Errorf("planSubBatch within budget = %v, want %v", got, want)
+	}
+}
+
+func TestQueueSubscribeSchedulesOnce(t *testing.T) {
+	m := newTestModel(0, 0, 0)
+	m.subQueue = make(map[string]bool)
+	if m.queueSubscribe("a") == nil {
+		t.Fatal("first queued room did not schedule a flush")
+	}
+	if m.queueSubscribe("b") != nil {
+		t.Error("second queued room scheduled another flush")
+	}
+	if len(m.subQueue) != 2 {
+		t.Errorf("queue has %d rooms, want 2", len(m.subQueue))
+	}
+}
+
+func TestChannelBatchFiltersPerRoomHistory(t *testing.T) {
+	cfg := Config{History: HistoryConfig{Limit: 30}}
+	live, history := channelBatchFilters([]string{"wss://a", "wss://b"}, []string{"c1", "c2"}, cfg)
+
+	// Per relay: one live message filter and one live reaction filter.
+	if len(live) != 4 {
+		t.Fatalf("got %d live filters, want 4", len(live))
+	}
+	for _, df := range live {
+		f := df.Filter
+		if !f.LimitZero || f.Limit != 0 || f.Since == 0 || len(f.Tags["e"]) != 2 {
+			t.Errorf("live filter %+v should cover both channels with no history", f)
+		}
+	}
+
+	// Per relay and channel: a message and a reaction history query.
+	if len(history) != 8 {
+		t.Fatalf("got %d history filters, want 8", len(history))
+	}
+	for _, df := range history {
+		f := df.Filter
+		if len(f.Tags["e"]) != 1 || f.LimitZero {
+			t.Errorf("history filter %+v should cover one channel", f)
+		}
+		if slices.Contains(f.Kinds, nostr.KindChannelMessage) && f.Limit != 30 {
+			t.Errorf("history limit = %d, want the room's own 30", f.Limit)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"fiatjaf.com/nostr"
+)
+
+// defaultHistoryLimit is how many past messages a room subscription asks
+// each relay for when history.limit is not configured.
+const defaultHistoryLimit = 50
+
+// subFilter holds the history and author overrides applied to a room
+// subscription. The zero value means "use the configured defaults".
+type subFilter struct {
+	Limit        int             // max past messages per relay; 0 = default
+	Since        nostr.Timestamp // only messages newer than this; 0 = no bound
+	Authors      []string        // hex pubkeys; empty = everyone
+	ExcludeKinds []nostr.Kind    // kinds never subscribed to
+}
+
+// forRelay merges the global and per-relay [history] config with the room's
+// live /filter overrides for one relay. Room overrides win.
+func (sf subFilter) forRelay(cfg Config, relay string) subFilter {
+	out := subFilter{Limit: defaultHistoryLimit, Authors: sf.Authors}
+	apply := func(h HistoryConfig) {
+		if h.Limit > 0 {
+			out.Limit = h.Limit
+		}
+		if d, err := time.ParseDuration(h.Since); err == nil && d > 0 {
+			out.Since = nostr.Timestamp(time.Now().Add(-d).Unix())
+		}
+		for _, k := range h.ExcludeKinds {
+			out.ExcludeKinds = append(out.ExcludeKinds, nostr.Kind(k))
+		}
+	}
+	apply(cfg.History)
+	if h, ok := cfg.RelayHistory[relay]; ok {
+		apply(h)
+	}
+	if sf.Limit > 0 {
+		out.Limit = sf.Limit
+	}
+	if sf.Since > 0 {
+		out.Since = sf.Since
+	}
+	return out
+}
+
+// excludes reports whether kind is configured to be skipped.
+func (sf subFilter) excludes(kind nostr.Kind) bool {
+	return slices.Contains(sf.ExcludeKinds, kind)
+}
+
+// apply copies the limit, since, and author overrides onto a message filter
+// and drops its excluded kinds. A filter left without kinds is not to be
+// subscribed.
+func (sf subFilter) apply(f nostr.Filter) nostr.Filter {
+	f.Kinds = slices.DeleteFunc(slices.Clone(f.Kinds), sf.excludes)
+	f.Limit = sf.Limit
+	if sf.Since > 0 {
+		f.Since = sf.Since
+	}
+	for _, a := range sf.Authors {
+		if pk, err := nostr.PubKeyFromHex(a); err == nil {
+			f.Authors = append(f.Authors, pk)
+		}
+	}
+	return f
+}
+
+// String describes the room overrides for /filter.
+func (sf subFilter) String() string {
+	var parts []string
+	if len(sf.Authors) > 0 {
+		parts = append(parts, fmt.Sprintf("authors: %d contacts", len(sf.Authors)))
+	}
+	if sf.Since > 0 {
+		parts = append(parts, "since: "+sf.Since.Time().Format("2006-01-02 15:04"))
+	}
+	if sf.Limit > 0 {
+		parts = append(parts, "limit: "+strconv.Itoa(sf.Limit))
+	}
+	if len(parts) == 0 {
+		return "none (config defaults)"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// roomSubFilter returns the live /filter overrides for a room.
+func (m *model) roomSubFilter(roomID string) subFilter {
+	return m.roomFilters[roomID]
+}
+
+// subscribeChannel subscribes to a channel with its room filter applied,
+// or queues it for a combined subscription under max_subs_per_relay.
+func (m *model) subscribeChannel(channelID string) tea.Cmd {
+	if m.coalescesSubs(channelID) {
+		return m.queueSubscribe(channelID)
+	}
+	relays := m.channelRelays(channelID)
+	return m.afterRelayAccess(relays, subscribeChannelCmd(m.pool, relays, channelID, m.cfg, m.roomSubFilter(channelID), m.subClosed))
+}
+
+// subscribeGroup subscribes to a group with its room filter applied, or
+// queues it for a combined subscription under max_subs_per_relay.
+func (m *model) subscribeGroup(relayURL, groupID string) tea.Cmd {
+	gk := groupKey(relayURL, groupID)
+	if m.coalescesSubs(gk) {
+		return m.queueSubscribe(gk)
+	}
+	relays := m.groupRelays(gk)
+	return m.afterRelayAccess(relays, subscribeGroupCmd(m.pool, relays, groupID, m.cfg, m.roomSubFilter(gk), m.subClosed))
+}
+
+// contactPubKeys returns our pubkey plus all follows and DM peers.
+func (m *model) contactPubKeys() []string {
+	seen := map[string]bool{m.keys.PK.Hex(): true}
+	out := []string{m.keys.PK.Hex()}
+	add := func(pk string) {
+		if !seen[pk] {
+			seen[pk] = true
+			out = append(out, pk)
+		}
+	}
+	for _, f := range m.follows {
+		add(f.PubKey)
+	}
+	for _, pk := range m.allDMPeers() {
+		add(pk)
+	}
+	return out
+}
+
+// isRoomFilteredOut reports whether msg is excluded by its room's /filter
+// author restriction (used to collapse already-loaded messages).
+func (m *model) isRoomFilteredOut(msg ChatMessage) bool {
+	if msg.Author == "system" || msg.IsMine || msg.PubKey == "" {
+		return false
+	}
+	roomID := msg.ChannelID
+	if roomID == "" {
+		roomID = msg.GroupKey
+	}
+	sf, ok := m.roomFilters[roomID]
+	if !ok || len(sf.Authors) == 0 {
+		return false
+	}
+	return !slices.Contains(sf.Authors, msg.PubKey)
+}
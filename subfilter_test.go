@@ -0,0 +1,102 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestSubFilterForRelay(t *testing.T) {
+	cfg := Config{
+		History: HistoryConfig{Limit: 30, ExcludeKinds: []int{39001}},
+		RelayHistory: map[string]HistoryConfig{
+			"wss://big.example.com": {Limit: 10, Since: "24h"},
+		},
+	}
+
+	rf := subFilter{}.forRelay(cfg, "wss://small.example.com")
+	if rf.Limit != 30 || rf.Since != 0 {
+		t.Errorf("global config: got limit=%d since=%d, want 30, 0", rf.Limit, rf.Since)
+	}
+	if !rf.excludes(nostr.KindSimpleGroupAdmins) || rf.excludes(nostr.KindSimpleGroupChatMessage) {
+		t.Error("expected only kind 39001 to be excluded")
+	}
+
+	rf = subFilter{}.forRelay(cfg, "wss://big.example.com")
+	if rf.Limit != 10 {
+		t.Errorf("relay override: limit = %d, want 10", rf.Limit)
+	}
+	wantSince := time.Now().Add(-24 * time.Hour).Unix()
+	if d := int64(rf.Since) - wantSince; d < -5 || d > 5 {
+		t.Errorf("relay override: since = %d, want ~%d", rf.Since, wantSince)
+	}
+
+	// Room overrides win over config.
+	rf = subFilter{Limit: 5, Since: 1000}.forRelay(cfg, "wss://big.example.com")
+	if rf.Limit != 5 || rf.Since != 1000 {
+		t.Errorf("room override: got limit=%d since=%d, want 5, 1000", rf.Limit, rf.Since)
+	}
+
+	if rf := (subFilter{}).forRelay(Config{}, "wss://x"); rf.Limit != defaultHistoryLimit {
+		t.Errorf("default limit = %d, want %d", rf.Limit, defaultHistoryLimit)
+	}
+}
+
+func TestSubFilterApply(t *testing.T) {
+	pk := "aaaa1111bbbb2222cccc3333dddd4444aaaa1111bbbb2222cccc3333dddd4444"
+	f := subFilter{Limit: 7, Since: 1234, Authors: []string{pk, "not-hex"}}.apply(nostr.Filter{
+		Kinds: []nostr.Kind{nostr.KindChannelMessage},
+	})
+	if f.Limit != 7 || f.Since != 1234 {
+		t.Errorf("got limit=%d since=%d, want 7, 1234", f.Limit, f.Since)
+	}
+	if len(f.Authors) != 1 || f.Authors[0].Hex() != pk {
+		t.Errorf("authors = %v, want only the valid pubkey", f.Authors)
+	}
+	if len(f.Kinds) != 1 {
+		t.Error("apply should keep the original kinds")
+	}
+
+	// Every excluded kind is dropped, not just the first.
+	kinds := []nostr.Kind{nostr.KindChannelMessage, nostr.KindGenericRepost, nostr.KindReaction}
+	f = subFilter{ExcludeKinds: []nostr.Kind{nostr.KindReaction, nostr.KindGenericRepost}}.apply(nostr.Filter{Kinds: kinds})
+	if !slices.Equal(f.Kinds, []nostr.Kind{nostr.KindChannelMessage}) {
+		t.Errorf("kinds = %v, want only %d", f.Kinds, nostr.KindChannelMessage)
+	}
+	if len(kinds) != 3 {
+		t.Error("apply modified the caller's kinds")
+	}
+}
+
+func TestIsRoomFilteredOut(t *testing.T) {
+	m := &model{roomFilters: map[string]subFilter{"ch1": {Authors: []string{"alice"}}}}
+	if m.isRoomFilteredOut(ChatMessage{ChannelID: "ch1", PubKey: "alice", Author: "alice"}) {
+		t.Error("allowed author should not be filtered")
+	}
+	if !m.isRoomFilteredOut(ChatMessage{ChannelID: "ch1", PubKey: "bob", Author: "bob"}) {
+		t.Error("other author should be filtered")
+	}
+	if m.isRoomFilteredOut(ChatMessage{ChannelID: "ch2", PubKey: "bob", Author: "bob"}) {
+		t.Error("rooms without a filter should not filter")
+	}
+	if m.isRoomFilteredOut(ChatMessage{ChannelID: "ch1", PubKey: "bob", Author: "bob", IsMine: true}) {
+		t.Error("own messages should never be filtered")
+	}
+}
+
+func TestFilterContactsWithoutContacts(t *testing.T) {
+	m := newTestModel(1, 0, 0)
+	m.roomFilters = map[string]subFilter{}
+	m.activeItem = 0
+	m.handleFilterCommand("contacts")
+	if _, ok := m.roomFilters["ch0"]; ok {
+		t.Error("/filter contacts without contacts set a filter")
+	}
+	last := m.msgs["ch0"][len(m.msgs["ch0"])-1].Content
+	if !strings.Contains(last, "no contacts") {
+		t.Errorf("report = %q, want it to say there are no contacts", last)
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// testDMPrefix starts the content of every /test-dm message. Self-DMs with
+// this prefix are consumed by the test and never shown, including copies
+// from earlier runs that the subscription delivers again.
+const testDMPrefix = "nitrous /test-dm "
+
+// testDMTimeout is how long /test-dm waits for the gift wrap to come back.
+const testDMTimeout = 30 * time.Second
+
+// dmSelfTest is the state of a running (or finished) /test-dm.
+type dmSelfTest struct {
+	seq      int
+	content  string
+	started  time.Time
+	sent     bool
+	received bool
+}
+
+// testDMSentMsg reports the publish step of a /test-dm.
+type testDMSentMsg struct {
+	seq        int
+	deliveries map[string]string
+	err        error
+}
+
+// testDMTimeoutMsg fires testDMTimeout after a /test-dm started.
+type testDMTimeoutMsg struct {
+	seq int
+}
+
+func (t *dmSelfTest) sendKey() string { return fmt.Sprintf("testdm:%d:send", t.seq) }
+func (t *dmSelfTest) recvKey() string { return fmt.Sprintf("testdm:%d:recv", t.seq) }
+
+// isTestDM reports whether content is a /test-dm message.
+func isTestDM(content string) bool {
+	return strings.HasPrefix(content, testDMPrefix)
+}
+
+// testDMSendCmd publishes the test message to ourselves through sendDM and
+// turns its result into a testDMSentMsg, so the local echo isn't shown.
+func testDMSendCmd(send tea.Cmd, seq int) tea.Cmd {
+	return func() tea.Msg {
+		switch msg := send().(type) {
+		case dmSendErrMsg:
+			return testDMSentMsg{seq: seq, err: msg.err}
+		case dmEventMsg:
+			return testDMSentMsg{seq: seq, deliveries: msg.Deliveries}
+		default:
+			return testDMSentMsg{seq: seq, err: fmt.Errorf("unexpected result %T", msg)}
+		}
+	}
+}
+
+// formatDeliveries lists per-relay publish outcomes, sorted by URL.
+func formatDeliveries(deliveries map[string]string) string {
+	urls := make([]string, 0, len(deliveries))
+	for url := range deliveries {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+	parts := make([]string, len(urls))
+	for i, url := range urls {
+		parts[i] = url + ": " + deliveries[url]
+	}
+	return strings.Join(parts, ", ")
+}
+
+// testDM runs /test-dm: it sends a DM to ourselves and waits for the DM
+// subscription to deliver and unwrap it, reporting each step.
+func (m *model) testDM() (tea.Model, tea.Cmd) {
+	if m.dmEvents == nil {
+		m.addSystemMsg("test-dm: the DM subscription is not running (still connecting, or no relay accepted it); try again shortly")
+		return m, nil
+	}
+	nonce := make([]byte, 8)
+	_, _ = rand.Read(nonce)
+	seq := 1
+	if m.dmTest != nil {
+		seq = m.dmTest.seq + 1
+	}
+	m.dmTest = &dmSelfTest{
+		seq:     seq,
+		content: testDMPrefix + hex.EncodeToString(nonce),
+		started: time.Now(),
+	}
+	m.addSystemMsg("test-dm: sending a DM to yourself")
+	m.addStatusMsg(m.dmTest.sendKey(), "  publish: gift-wrapping and sending…")
+	m.addStatusMsg(m.dmTest.recvKey(), "  receive: waiting for the gift wrap to come back…")
+	send := sendDM(m.pool, m.relays, m.keys.PK.Hex(), m.dmTest.content, nil, m.keys, m.kr)
+	return m, tea.Batch(
+		testDMSendCmd(send, seq),
+		tea.Tick(testDMTimeout, func(time.Time) tea.Msg { return testDMTimeoutMsg{seq: seq} }),
+	)
+}
+
+func (m *model) handleTestDMSent(msg testDMSentMsg) (tea.Model, tea.Cmd) {
+	t := m.dmTest
+	if t == nil || t.seq != msg.seq {
+		return m, nil
+	}
+	if msg.err != nil {
+		m.updateStatusMsg(t.sendKey(), "  publish: FAILED: "+msg.err.Error())
+		m.updateStatusMsg(t.recvKey(), "  receive: skipped, nothing was sent")
+		if len(msg.deliveries) > 0 {
+			m.addSystemMsg("  relays: " + formatDeliveries(msg.deliveries))
+		}
+		return m, nil
+	}
+	t.sent = true
+	m.updateStatusMsg(t.sendKey(), "  publish: ok ("+formatDeliveries(msg.deliveries)+")")
+	return m, nil
+}
+
+// consumeTestDM handles a /test-dm message arriving on the DM subscription
+// and reports whether it was one (and so must not be shown).
+func (m *model) consumeTestDM(cm ChatMessage) bool {
+	if !cm.IsMine || dmRoomKey(cm) != m.keys.PK.Hex() || !isTestDM(cm.Content) {
+		return false
+	}
+	t := m.dmTest
+	if t == nil || t.received || cm.Content != t.content {
+		return true // an earlier run's copy
+	}
+	t.received = true
+	m.updateStatusMsg(t.recvKey(), fmt.Sprintf("  receive: ok, unwrapped and content matches (%s round-trip)",
+		time.Since(t.started).Round(100*time.Millisecond)))
+	m.addSystemMsg("test-dm: DMs are working")
+	return true
+}
+
+func (m *model) handleTestDMTimeout(msg testDMTimeoutMsg) (tea.Model, tea.Cmd) {
+	t := m.dmTest
+	// A failed publish already reported itself (sendDM gives up after 10s).
+	if t == nil || t.seq != msg.seq || !t.sent || t.received {
+		return m, nil
+	}
+	m.updateStatusMsg(t.recvKey(), fmt.Sprintf("  receive: FAILED: nothing came back within %s", testDMTimeout))
+	m.addSystemMsg("test-dm: check that the relays in your DM relay list (kind 10050) are in your relays config and accept your key (NIP-42 auth); see the log file for details")
+	return m, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatDeliveries(t *testing.T) {
+	got := formatDeliveries(map[string]string{"wss://b": "ok", "wss://a": "auth-required: no"})
+	if want := "wss://a: auth-required: no, wss://b: ok"; got != want {
+		t.Errorf("formatDeliveries = %q, want %q", got, want)
+	}
+}
+
+func TestConsumeTestDM(t *testing.T) {
+	m := newTestModel(1, 0, 0)
+	m.dmTest = &dmSelfTest{seq: 1, content: testDMPrefix + "abc", started: time.Now(), sent: true}
+	self := m.keys.PK.Hex()
+
+	if m.consumeTestDM(ChatMessage{PubKey: self, Content: "hello", IsMine: true}) {
+		t.Error("consumed an ordinary self-DM")
+	}
+	if m.consumeTestDM(ChatMessage{PubKey: "peer", Content: testDMPrefix + "abc", IsMine: true}) {
+		t.Error("consumed a DM to someone else")
+	}
+	if !m.consumeTestDM(ChatMessage{PubKey: self, Content: testDMPrefix + "old", IsMine: true}) {
+		t.Error("an earlier run's test DM should be hidden")
+	}
+	if m.dmTest.received {
+		t.Error("an earlier run's test DM completed the test")
+	}
+	if !m.consumeTestDM(ChatMessage{PubKey: self, Content: testDMPrefix + "abc", IsMine: true}) || !m.dmTest.received {
+		t.Error("matching test DM not consumed")
+	}
+	if n := len(m.msgs["ch0"]); n != 1 || m.msgs["ch0"][0].Content != "test-dm: DMs are working" {
+		t.Errorf("messages after success = %+v", m.msgs["ch0"])
+	}
+}
+
+func TestTestDMSendFailure(t *testing.T) {
+	m := newTestModel(1, 0, 0)
+	m.dmTest = &dmSelfTest{seq: 2, content: testDMPrefix + "abc", started: time.Now()}
+	m.addStatusMsg(m.dmTest.sendKey(), "sending")
+	m.addStatusMsg(m.dmTest.recvKey(), "waiting")
+
+	m.handleTestDMSent(testDMSentMsg{seq: 2, err: errors.New("send DM: no relays")})
+	m.handleTestDMTimeout(testDMTimeoutMsg{seq: 2})
+
+	msgs := m.msgs["ch0"]
+	if !strings.Contains(msgs[0].Content, "FAILED: send DM: no relays") {
+		t.Errorf("publish line = %q", msgs[0].Content)
+	}
+	if !strings.Contains(msgs[1].Content, "skipped") {
+		t.Errorf("receive line = %q, the timeout should not overwrite it", msgs[1].Content)
+	}
+}
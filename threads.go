@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"fiatjaf.com/nostr"
+)
+
+// NIP-29 threads: a kind-11 event opens a thread (with a "title" tag), and
+// kind-12 replies (or kind-10 threaded replies of older relays) point at it
+// with an "e" root tag. Thread roots show up in the group's chat; their
+// replies are kept in a separate buffer and history log, keyed by
+// threadKey, which the group view shows after /thread open.
+
+// threadKey is the m.msgs key of the replies to thread root rootID.
+func threadKey(rootID string) string {
+	return "thread:" + rootID
+}
+
+// threadRootID returns the root event ID a kind-10 or kind-12 reply points
+// at, or "".
+func threadRootID(evt nostr.Event) string {
+	var first string
+	for _, tag := range evt.Tags {
+		if len(tag) < 2 || tag[0] != "e" {
+			continue
+		}
+		if len(tag) >= 4 && tag[3] == "root" {
+			return tag[1]
+		}
+		if first == "" {
+			first = tag[1]
+		}
+	}
+	return first
+}
+
+// threadTitle returns the title of a kind-11 thread root, falling back to
+// the first line of its content.
+func threadTitle(evt nostr.Event) string {
+	if t := evt.Tags.Find("title"); len(t) >= 2 && t[1] != "" {
+		return t[1]
+	}
+	title, _, _ := strings.Cut(evt.Content, "\n")
+	return title
+}
+
+// applyThread fills in the thread fields of cm from a group event.
+func applyThread(cm *ChatMessage, evt nostr.Event) {
+	switch evt.Kind {
+	case nostr.KindSimpleGroupThread:
+		cm.ThreadID = evt.ID.Hex()
+		cm.ThreadTitle = threadTitle(evt)
+	case nostr.KindSimpleGroupReply, nostr.KindSimpleGroupThreadedReply:
+		cm.ThreadID = threadRootID(evt)
+	}
+}
+
+// isThreadReply reports whether cm belongs in a thread rather than the
+// group's chat.
+func isThreadReply(cm ChatMessage) bool {
+	return cm.ThreadID != "" && cm.ThreadID != cm.EventID
+}
+
+// buildGroupThreadEvent builds a kind-11 thread root with the given title.
+func buildGroupThreadEvent(groupID, title string, previousIDs []string, keys Keys) (nostr.Event, error) {
+	tags := nostr.Tags{{"h", groupID}, {"title", title}}
+	tags = append(tags, pickPreviousTags(previousIDs)...)
+	evt := nostr.Event{
+		Kind:      nostr.KindSimpleGroupThread,
+		CreatedAt: nostr.Now(),
+		Tags:      tags,
+		Content:   title,
+	}
+	if err := evt.Sign(keys.SK); err != nil {
+		return evt, err
+	}
+	return evt, nil
+}
+
+// buildGroupReplyEvent builds a kind-12 reply to thread root rootID.
+func buildGroupReplyEvent(groupID, rootID, content string, previousIDs []string, keys Keys) (nostr.Event, error) {
+	tags := nostr.Tags{{"h", groupID}, {"e", rootID, "", "root"}}
+	tags = append(tags, pickPreviousTags(previousIDs)...)
+	evt := nostr.Event{
+		Kind:      nostr.KindSimpleGroupReply,
+		CreatedAt: nostr.Now(),
+		Tags:      tags,
+		Content:   content,
+	}
+	if err := evt.Sign(keys.SK); err != nil {
+		return evt, err
+	}
+	return evt, nil
+}
+
+// threadRoots returns the thread roots of a group, newest first.
+func threadRoots(msgs []ChatMessage) []ChatMessage {
+	var roots []ChatMessage
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].ThreadTitle != "" {
+			roots = append(roots, msgs[i])
+		}
+	}
+	return roots
+}
+
+// activeThread returns the root of the thread open in the active group.
+func (m *model) activeThread() (ChatMessage, bool) {
+	gk := m.activeGroupKey()
+	if gk == "" || m.groupThread[gk] == "" {
+		return ChatMessage{}, false
+	}
+	for _, msg := range m.msgs[gk] {
+		if msg.EventID == m.groupThread[gk] {
+			return msg, true
+		}
+	}
+	return ChatMessage{ThreadID: m.groupThread[gk]}, true
+}
+
+// activeRoomKey returns the m.msgs key shown in the chat view: the open
+// thread of the active group, or the active sidebar item.
+func (m *model) activeRoomKey() string {
+	if root, ok := m.activeThread(); ok {
+		return threadKey(root.ThreadID)
+	}
+	if item := m.activeSidebarItem(); item != nil {
+		return item.ItemID()
+	}
+	return ""
+}
+
+// handleThreadCommand runs /thread [list|new <title>|open <n>|close].
+func (m *model) handleThreadCommand(arg string) (tea.Model, tea.Cmd) {
+	item := m.activeSidebarItem()
+	gi, ok := item.(GroupItem)
+	if !ok {
+		m.addSystemMsg("threads are only available in NIP-29 groups")
+		return m, nil
+	}
+	gk := gi.ItemID()
+	sub, rest, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	rest = strings.TrimSpace(rest)
+
+	switch sub {
+	case "", "list":
+		roots := threadRoots(m.msgs[gk])
+		if len(roots) == 0 {
+			m.addSystemMsg("no threads in this group — start one with /thread new <title>")
+			return m, nil
+		}
+		for i, r := range roots {
+			m.addSystemMsg(fmt.Sprintf("%d. %s — %s, %s, %d replies", i+1, r.ThreadTitle,
+				m.resolveAuthor(r.PubKey), r.Timestamp.Time().Format("01-02 15:04"), len(m.msgs[threadKey(r.ThreadID)])))
+		}
+		m.addSystemMsg("use /thread open <n> to read and reply")
+		return m, nil
+
+	case "new":
+		if rest == "" {
+			m.addSystemMsg("usage: /thread new <title>")
+			return m, nil
+		}
+		evt, err := buildGroupThreadEvent(gi.Group.GroupID, rest, m.groupRecentIDs[gk], m.keys)
+		if err != nil {
+			m.addSystemMsg(fmt.Sprintf("thread: %v", err))
+			return m, nil
+		}
+		return m, publishGroupEventCmd(m.pool, gi.Group.Relays(), gk, evt, m.keys)
+
+	case "open":
+		n, err := strconv.Atoi(rest)
+		roots := threadRoots(m.msgs[gk])
+		if err != nil || n < 1 || n > len(roots) {
+			m.addSystemMsg("usage: /thread open <n> (see /thread list)")
+			return m, nil
+		}
+		if m.groupThread == nil {
+			m.groupThread = make(map[string]string)
+		}
+		m.groupThread[gk] = roots[n-1].ThreadID
+		m.updateLayout()
+		m.addSystemMsg(fmt.Sprintf("in thread %q — messages are sent as replies; /thread close to leave", roots[n-1].ThreadTitle))
+		return m, nil
+
+	case "close":
+		if m.groupThread[gk] == "" {
+			m.addSystemMsg("no thread open")
+			return m, nil
+		}
+		delete(m.groupThread, gk)
+		m.updateLayout()
+		return m, nil
+	}
+	m.addSystemMsg("usage: /thread [list|new <title>|open <n>|close]")
+	return m, nil
+}
+
+// sendThreadReply posts text as a kind-12 reply to thread rootID of g.
+func (m *model) sendThreadReply(g Group, rootID, text string) tea.Cmd {
+	gk := groupKey(g.RelayURL, g.GroupID)
+	evt, err := buildGroupReplyEvent(g.GroupID, rootID, text, m.groupRecentIDs[gk], m.keys)
+	if err != nil {
+		return func() tea.Msg { return nostrErrMsg{err} }
+	}
+	return publishGroupEventCmd(m.pool, g.Relays(), gk, evt, m.keys)
+}
+
+// handleThreadReply files a thread reply under its thread and logs it. The
+// group is marked unread unless that thread is being viewed.
+func (m *model) handleThreadReply(cm ChatMessage, sub *roomSub) (tea.Model, tea.Cmd) {
+	key := threadKey(cm.ThreadID)
+	m.msgs[key] = appendMessage(m.msgs[key], cm, m.cfg.MaxMessages)
+	m.history.Append("thread", key, cm, m.resolveAuthor(cm.PubKey))
+	m.applyEarlyReport(cm.EventID)
+	switch {
+	case m.activeRoomKey() == key:
+		m.updateViewport()
+	case cm.GroupKey == m.activeGroupKey():
+		m.updateViewport() // refresh the root's reply count
+	default:
+		m.markUnread(cm.GroupKey, cm.Timestamp)
+	}
+	var cmds []tea.Cmd
+	if cmd := m.maybeRequestProfile(cm.PubKey); cmd != nil {
+		cmds = append(cmds, cmd)
+	}
+	cmds = append(cmds, waitForRoomSub(sub, m.keys))
+	return m, tea.Batch(cmds...)
+}
+
+// renderThreadRoot is the chat body of a thread root: its title, content
+// if it differs, and the reply count.
+func (m *model) renderThreadRoot(msg ChatMessage) string {
+	body := "🧵 **" + msg.ThreadTitle + "**"
+	if c := strings.TrimSpace(msg.Content); c != "" && c != msg.ThreadTitle {
+		body += "\n\n" + c
+	}
+	return body + fmt.Sprintf("\n\n_%d replies — /thread list_", len(m.msgs[threadKey(msg.ThreadID)]))
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestThreadRootID(t *testing.T) {
+	evt := nostr.Event{Tags: nostr.Tags{{"h", "g"}, {"e", "prev"}, {"e", "root", "", "root"}}}
+	if got := threadRootID(evt); got != "root" {
+		t.Errorf("threadRootID = %q, want root", got)
+	}
+	evt = nostr.Event{Tags: nostr.Tags{{"e", "only"}}}
+	if got := threadRootID(evt); got != "only" {
+		t.Errorf("threadRootID without marker = %q, want only", got)
+	}
+}
+
+func TestThreadTitle(t *testing.T) {
+	evt := nostr.Event{Content: "body", Tags: nostr.Tags{{"title", "Release plan"}}}
+	if got := threadTitle(evt); got != "Release plan" {
+		t.Errorf("threadTitle = %q", got)
+	}
+	evt = nostr.Event{Content: "first line\nrest"}
+	if got := threadTitle(evt); got != "first line" {
+		t.Errorf("threadTitle fallback = %q", got)
+	}
+}
+
+func TestGroupChatMessageThreads(t *testing.T) {
+	sk := nostr.Generate()
+	keys := Keys{SK: sk, PK: nostr.GetPublicKey(sk)}
+
+	root, err := buildGroupThreadEvent("g", "Plans", nil, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm := groupChatMessage(root, "gk", keys)
+	if cm.ThreadTitle != "Plans" || cm.ThreadID != root.ID.Hex() || isThreadReply(cm) {
+		t.Errorf("root message = %+v", cm)
+	}
+
+	reply, err := buildGroupReplyEvent("g", root.ID.Hex(), "sounds good", nil, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cm = groupChatMessage(reply, "gk", keys)
+	if cm.ThreadID != root.ID.Hex() || !isThreadReply(cm) {
+		t.Errorf("reply message = %+v", cm)
+	}
+
+	// Older relays use kind 10 for threaded replies.
+	reply.Kind = nostr.KindSimpleGroupThreadedReply
+	if err := reply.Sign(sk); err != nil {
+		t.Fatal(err)
+	}
+	cm = groupChatMessage(reply, "gk", keys)
+	if cm.ThreadID != root.ID.Hex() || !isThreadReply(cm) {
+		t.Errorf("kind-10 reply message = %+v", cm)
+	}
+}
+
+func TestThreadReplyBuffer(t *testing.T) {
+	m := newTestModel(0, 1, 0)
+	m.unread = make(map[string]bool)
+	m.history = fileHistoryStore{dir: t.TempDir()}
+	gk := groupKey("wss://r", "g0")
+	m.msgs[gk] = []ChatMessage{{EventID: "root", ThreadID: "root", ThreadTitle: "Plans", PubKey: "pk"}}
+
+	m.handleThreadReply(ChatMessage{EventID: "r1", ThreadID: "root", GroupKey: gk, Content: "hi", Timestamp: 1}, nil)
+	if len(m.msgs[threadKey("root")]) != 1 || len(m.msgs[gk]) != 1 {
+		t.Fatalf("reply not filed under its thread: %v", m.msgs)
+	}
+	if got, _ := m.history.Load("thread", threadKey("root"), 10); len(got) != 1 || got[0].EventID != "r1" {
+		t.Errorf("thread history = %+v, want the reply logged", got)
+	}
+
+	m.groupThread = map[string]string{gk: "root"}
+	if got := m.activeRoomKey(); got != threadKey("root") {
+		t.Errorf("activeRoomKey = %q, want the thread", got)
+	}
+	if root, ok := m.activeThread(); !ok || root.ThreadTitle != "Plans" {
+		t.Errorf("activeThread = %+v, %v", root, ok)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"fiatjaf.com/nostr"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// /timed sends a DM with a NIP-40 expiration: relays that honor it drop the
+// gift wraps after the timeout, and nitrous removes the message from the
+// conversation once it has expired and never writes it to the history.
+// Relays that ignore NIP-40, and the recipient's client, may keep it, so
+// this is best-effort.
+
+// timedRefreshInterval is how often the "⏳ expires in" labels are redrawn
+// while /timed DMs are shown.
+const timedRefreshInterval = time.Minute
+
+// timedExpiredMsg fires when a /timed DM shown in a room expires.
+type timedExpiredMsg struct {
+	room string
+}
+
+// timedRefreshMsg fires to redraw the expiry labels of /timed DMs.
+type timedRefreshMsg struct{}
+
+// expirationTag returns the NIP-40 expiration tag for at.
+func expirationTag(at nostr.Timestamp) nostr.Tag {
+	return nostr.Tag{"expiration", strconv.FormatInt(int64(at), 10)}
+}
+
+// expirationOf returns the NIP-40 expiration in tags, or 0.
+func expirationOf(tags nostr.Tags) nostr.Timestamp {
+	if tag := tags.Find("expiration"); tag != nil {
+		if at, err := strconv.ParseInt(tag[1], 10, 64); err == nil && at > 0 {
+			return nostr.Timestamp(at)
+		}
+	}
+	return 0
+}
+
+// parseTimedArgs splits "/timed <duration> <text>". The duration is a Go
+// duration or a number of days ("2d").
+func parseTimedArgs(arg string) (time.Duration, string, error) {
+	durStr, text, _ := strings.Cut(strings.TrimSpace(arg), " ")
+	text = strings.TrimSpace(text)
+	if durStr == "" || text == "" {
+		return 0, "", fmt.Errorf("usage: /timed <duration> <text>, e.g. /timed 10m see you at 5")
+	}
+	var d time.Duration
+	if days, ok := strings.CutSuffix(durStr, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, "", fmt.Errorf("invalid duration %q", durStr)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(durStr); err != nil {
+			return 0, "", fmt.Errorf("invalid duration %q", durStr)
+		}
+	}
+	if d < time.Minute {
+		return 0, "", fmt.Errorf("the duration must be at least 1m (got %s)", durStr)
+	}
+	return d, text, nil
+}
+
+// expiryLabel is the marker shown after a /timed DM.
+func expiryLabel(expires, now nostr.Timestamp) string {
+	left := time.Duration(expires-now) * time.Second
+	switch {
+	case left <= 0:
+		return "⏳ expired"
+	case left < time.Minute:
+		return "⏳ expires in <1m"
+	case left < time.Hour:
+		return fmt.Sprintf("⏳ expires in %dm", int(left.Round(time.Minute).Minutes()))
+	case left < 48*time.Hour:
+		return fmt.Sprintf("⏳ expires in %dh", int(left.Round(time.Hour).Hours()))
+	}
+	return fmt.Sprintf("⏳ expires in %dd", int(left.Round(24*time.Hour).Hours()/24))
+}
+
+// isExpired reports whether msg is a /timed DM past its expiration.
+func isExpired(msg ChatMessage, now nostr.Timestamp) bool {
+	return msg.Expires != 0 && msg.Expires <= now
+}
+
+// timedExpiryCmd fires timedExpiredMsg for room when expires is reached.
+func timedExpiryCmd(room string, expires nostr.Timestamp) tea.Cmd {
+	return tea.Tick(time.Until(expires.Time()), func(time.Time) tea.Msg { return timedExpiredMsg{room: room} })
+}
+
+// hasTimedMessages reports whether any room shows a /timed DM.
+func (m *model) hasTimedMessages() bool {
+	for _, msgs := range m.msgs {
+		if slices.ContainsFunc(msgs, func(cm ChatMessage) bool { return cm.Expires != 0 }) {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduleTimedRefresh starts redrawing the expiry labels every
+// timedRefreshInterval unless that is running already.
+func (m *model) scheduleTimedRefresh() tea.Cmd {
+	if m.timedRefreshing {
+		return nil
+	}
+	m.timedRefreshing = true
+	return tea.Tick(timedRefreshInterval, func(time.Time) tea.Msg { return timedRefreshMsg{} })
+}
+
+// handleTimedRefresh redraws the expiry labels and keeps doing so while
+// /timed DMs are left.
+func (m *model) handleTimedRefresh() (tea.Model, tea.Cmd) {
+	m.timedRefreshing = false
+	if !m.hasTimedMessages() {
+		return m, nil
+	}
+	m.updateViewport()
+	return m, m.scheduleTimedRefresh()
+}
+
+// sendTimed handles /timed <duration> <text> in a DM or group DM.
+func (m *model) sendTimed(arg string) (tea.Model, tea.Cmd) {
+	d, text, err := parseTimedArgs(arg)
+	if err != nil {
+		m.addSystemMsg(err.Error())
+		return m, nil
+	}
+	expires := nostr.Now() + nostr.Timestamp(d/time.Second)
+	tags := nostr.Tags{expirationTag(expires)}
+	switch it := m.activeSidebarItem().(type) {
+	case DMItem:
+		return m, sendDM(m.pool, m.relays, it.PubKey, text, tags, m.keys, m.kr)
+	case GroupDMItem:
+		return m, sendGroupDM(m.pool, m.relays, it.Members, text, tags, m.keys, m.kr)
+	}
+	m.addSystemMsg("/timed only works in a DM")
+	return m, nil
+}
+
+// handleTimedExpired drops the expired /timed DMs of a room.
+func (m *model) handleTimedExpired(msg timedExpiredMsg) (tea.Model, tea.Cmd) {
+	now := nostr.Now()
+	m.msgs[msg.room] = slices.DeleteFunc(m.msgs[msg.room], func(cm ChatMessage) bool { return isExpired(cm, now) })
+	if m.activeRoomKey() == msg.room {
+		m.updateViewport()
+	}
+	return m, nil
+}
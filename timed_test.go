@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestParseTimedArgs(t *testing.T) {
+	tests := []struct {
+		arg  string
+		d    time.Duration
+		text string
+		ok   bool
+	}{
+		{"10m see you at 5", 10 * time.Minute, "see you at 5", true},
+		{"1h30m  hi", 90 * time.Minute, "hi", true},
+		{"2d the code is 1234", 48 * time.Hour, "the code is 1234", true},
+		{"30s too short", 0, "", false},
+		{"soon hi", 0, "", false},
+		{"10m", 0, "", false},
+		{"", 0, "", false},
+	}
+	for _, tt := range tests {
+		d, text, err := parseTimedArgs(tt.arg)
+		if (err == nil) != tt.ok || d != tt.d || text != tt.text {
+			t.Errorf("parseTimedArgs(%q) = %s, %q, %v", tt.arg, d, text, err)
+		}
+	}
+}
+
+func TestExpirationTag(t *testing.T) {
+	tags := nostr.Tags{{"p", "abc"}, expirationTag(1700000000)}
+	if got := expirationOf(tags); got != 1700000000 {
+		t.Errorf("expirationOf = %d", got)
+	}
+	if got := expirationOf(nostr.Tags{{"p", "abc"}}); got != 0 {
+		t.Errorf("expirationOf without tag = %d", got)
+	}
+	if got := expirationOf(nostr.Tags{{"expiration", "soon"}}); got != 0 {
+		t.Errorf("expirationOf with a bad tag = %d", got)
+	}
+}
+
+func TestExpiryLabel(t *testing.T) {
+	now := nostr.Timestamp(1000000)
+	for left, want := range map[nostr.Timestamp]string{
+		-5:        "⏳ expired",
+		0:         "⏳ expired",
+		30:        "⏳ expires in <1m",
+		10 * 60:   "⏳ expires in 10m",
+		3 * 3600:  "⏳ expires in 3h",
+		3 * 86400: "⏳ expires in 3d",
+	} {
+		if got := expiryLabel(now+left, now); got != want {
+			t.Errorf("expiryLabel(+%ds) = %q, want %q", left, got, want)
+		}
+	}
+}
+
+func TestHandleTimedExpired(t *testing.T) {
+	m := newTestModel(0, 0, 1)
+	m.msgs = map[string][]ChatMessage{"pk0": {
+		{Content: "kept", EventID: "a"},
+		{Content: "gone", EventID: "b", Expires: nostr.Now() - 1},
+		{Content: "later", EventID: "c", Expires: nostr.Now() + 3600},
+	}}
+	m.activeItem = -1
+	m.handleTimedExpired(timedExpiredMsg{room: "pk0"})
+	msgs := m.msgs["pk0"]
+	if len(msgs) != 2 || msgs[0].EventID != "a" || msgs[1].EventID != "c" {
+		t.Errorf("msgs after expiry = %+v", msgs)
+	}
+}
+
+func TestTimedRefresh(t *testing.T) {
+	m := newTestModel(0, 0, 1)
+	m.msgs = map[string][]ChatMessage{"pk0": {{Content: "later", EventID: "c", Expires: nostr.Now() + 3600}}}
+	m.activeItem = -1
+	if m.scheduleTimedRefresh() == nil || m.scheduleTimedRefresh() != nil {
+		t.Fatal("want one refresh ticker at a time")
+	}
+	if _, cmd := m.handleTimedRefresh(); cmd == nil {
+		t.Error("refresh stopped while a /timed DM is shown")
+	}
+
+	m.msgs["pk0"] = nil
+	m.timedRefreshing = true
+	if _, cmd := m.handleTimedRefresh(); cmd != nil || m.timedRefreshing {
+		t.Error("refresh kept running without /timed DMs")
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/hex"
+	"strings"
 
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
@@ -17,6 +18,8 @@ var (
 	colorStatusBg  = lipgloss.Color("#24283B")
 	colorWhite     = lipgloss.Color("#C0CAF5")
 	colorGreen     = lipgloss.Color("#9ECE6A")
+	colorYellow    = lipgloss.Color("#E0AF68")
+	colorRed       = lipgloss.Color("#F7768E")
 )
 
 // Distinct author colors — chosen for readability on dark backgrounds.
@@ -85,8 +88,10 @@ const (
 	minSidebarWidth = 12
 	sidebarPadding  = 3 // "#", "~", or "@" prefix + left/right padding
 	sidebarBorder   = 1 // right border on sidebar
-	inputMinHeight  = 1
-	inputMaxHeight  = 8
+	inputMinHeight  = 1 // default input_min_height
+	inputMaxHeight  = 8 // default input_max_height
+	previewHeight   = 6    // default compose_preview_height
+	previewMaxChars = 2000 // default compose_preview_max_chars
 )
 
 // Styles
@@ -105,6 +110,11 @@ var (
 		Bold(true).
 		Padding(0, 1)
 
+	sidebarHighlightStyle = lipgloss.NewStyle().
+		Foreground(colorYellow).
+		Bold(true).
+		Padding(0, 1)
+
 	sidebarSelectedStyle = lipgloss.NewStyle().
 		Foreground(colorHighlight).
 		Background(colorSecondary).
@@ -135,9 +145,32 @@ var (
 	statusConnectedStyle = lipgloss.NewStyle().
 		Foreground(colorGreen)
 
+	statusDegradedStyle = lipgloss.NewStyle().
+		Foreground(colorYellow)
+
+	statusDownStyle = lipgloss.NewStyle().
+		Foreground(colorRed)
+
 	chatSystemStyle = lipgloss.NewStyle().
 		Foreground(colorMuted)
 
+	previewStyle = lipgloss.NewStyle().
+		BorderTop(true).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(colorSecondary)
+
+	chatFailedStyle = lipgloss.NewStyle().
+		Foreground(colorRed).
+		Bold(true)
+
+	chatUnconfirmedStyle = lipgloss.NewStyle().
+		Foreground(colorYellow)
+
+	chatHighlightStyle = lipgloss.NewStyle().
+		Foreground(colorStatusBg).
+		Background(colorYellow).
+		Bold(true)
+
 	qrTitleStyle = lipgloss.NewStyle().
 		Foreground(colorPrimary).
 		Bold(true)
@@ -177,6 +210,27 @@ func newMarkdownRenderer(style string) *glamour.TermRenderer {
 	return r
 }
 
+// hardLineBreaks turns single newlines into markdown hard line breaks (two
+// trailing spaces) so chat-style line breaks survive rendering, where
+// markdown would otherwise join the lines into one paragraph. Lines inside
+// fenced code blocks and lines followed by a blank line are left alone, so
+// lists, quotes, and code keep their structure.
+func hardLineBreaks(s string) string {
+	lines := strings.Split(s, "\n")
+	inCode := false
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inCode = !inCode
+			continue
+		}
+		if inCode || i == len(lines)-1 || strings.TrimSpace(line) == "" || strings.TrimSpace(lines[i+1]) == "" {
+			continue
+		}
+		lines[i] = strings.TrimRight(line, " ") + "  "
+	}
+	return strings.Join(lines, "\n")
+}
+
 // renderMarkdown renders markdown content to terminal-styled text.
 // Falls back to plain text if the renderer is nil or rendering fails.
 func renderMarkdown(r *glamour.TermRenderer, content string) string {
@@ -1,7 +1,10 @@
 package main
 
 import (
+	"strings"
 	"testing"
+
+	"github.com/charmbracelet/x/ansi"
 )
 
 func TestColorForPubkey(t *testing.T) {
@@ -87,3 +90,85 @@ func TestNewMarkdownRenderer(t *testing.T) {
 		}
 	})
 }
+
+func TestHardLineBreaks(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"chat lines", "one\ntwo\nthree", "one  \ntwo  \nthree"},
+		{"blank line kept as paragraph", "one\n\ntwo", "one\n\ntwo"},
+		{"code fence untouched", "look:\n```\na\nb\n```\nafter", "look:  \n```\na\nb\n```\nafter"},
+		{"existing trailing spaces normalized", "one   \ntwo", "one  \ntwo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hardLineBreaks(tt.in); got != tt.want {
+				t.Errorf("hardLineBreaks(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+// renderedLines renders content like the chat view does and returns the
+// non-blank plain-text lines with their common indentation removed.
+func renderedLines(t *testing.T, content string) []string {
+	t.Helper()
+	r := newMarkdownRenderer("notty")
+	if r == nil {
+		t.Skip("could not create markdown renderer")
+	}
+	var lines []string
+	for _, l := range strings.Split(ansi.Strip(renderMarkdown(r, hardLineBreaks(content))), "\n") {
+		if strings.TrimSpace(l) != "" {
+			lines = append(lines, strings.TrimRight(l, " "))
+		}
+	}
+	return lines
+}
+
+func TestMarkdownStructurePreserved(t *testing.T) {
+	t.Run("plain lines stay on separate lines", func(t *testing.T) {
+		got := renderedLines(t, "hey\nhow are you?")
+		if len(got) != 2 || strings.TrimSpace(got[0]) != "hey" || strings.TrimSpace(got[1]) != "how are you?" {
+			t.Errorf("got %q", got)
+		}
+	})
+
+	t.Run("numbered list is one tight list", func(t *testing.T) {
+		got := renderedLines(t, "1. first\n2. second\n3. third")
+		want := []string{"1. first", "2. second", "3. third"}
+		if len(got) != len(want) {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+		for i := range want {
+			if strings.TrimSpace(got[i]) != want[i] {
+				t.Errorf("line %d = %q, want %q", i, got[i], want[i])
+			}
+		}
+	})
+
+	t.Run("code block keeps its lines verbatim", func(t *testing.T) {
+		got := renderedLines(t, "see:\n```\nif x {\n    y()\n}\n```")
+		joined := strings.Join(got, "\n")
+		if !strings.Contains(joined, "if x {") || !strings.Contains(joined, "    y()") {
+			t.Errorf("code block mangled:\n%s", joined)
+		}
+		if strings.Contains(joined, "if x {  ") {
+			t.Errorf("hard breaks leaked into code:\n%s", joined)
+		}
+	})
+
+	t.Run("blockquote lines stay in one quote", func(t *testing.T) {
+		got := renderedLines(t, "> quoted one\n> quoted two")
+		if len(got) != 2 {
+			t.Fatalf("got %q, want two quoted lines", got)
+		}
+		for i, w := range []string{"quoted one", "quoted two"} {
+			if !strings.Contains(got[i], w) || !strings.HasPrefix(strings.TrimSpace(got[i]), "|") {
+				t.Errorf("line %d = %q, want quote marker and %q", i, got[i], w)
+			}
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -28,6 +29,7 @@ func (m *model) markSeenEvent(eventID string) {
 		for k, ts := range m.seenEvents {
 			if now.Sub(ts) > seenEventsTTL {
 				delete(m.seenEvents, k)
+				m.seenEvicted++
 			}
 		}
 		m.seenEventsClean = now
@@ -35,6 +37,15 @@ func (m *model) markSeenEvent(eventID string) {
 }
 
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := m.dispatch(msg)
+	if rc := m.readReceiptCmd(); rc != nil {
+		cmd = tea.Batch(cmd, rc)
+	}
+	return model, cmd
+}
+
+// dispatch routes a message to its handler.
+func (m *model) dispatch(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		return m.handleWindowSize(msg)
@@ -50,6 +61,8 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleDMSubStarted(msg)
 	case channelEventMsg:
 		return m.handleChannelEvent(msg)
+	case dmReceiptMsg:
+		return m.handleDMReceipt(msg)
 	case dmEventMsg:
 		return m.handleDMEvent(msg)
 	case dmSubEndedMsg:
@@ -64,12 +77,38 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleGroupSubStarted(msg)
 	case groupEventMsg:
 		return m.handleGroupEvent(msg)
+	case reactionMsg:
+		return m.handleReaction(msg)
+	case whoisProfileMsg:
+		return m.handleWhoisProfile(msg)
+	case whoisRelaysMsg:
+		return m.handleWhoisRelays(msg)
+	case whoisNIP05Msg:
+		return m.handleWhoisNIP05(msg)
+	case staleCheckMsg:
+		return m.handleStaleCheck()
+	case keepaliveMsg:
+		return m.handleKeepalive(msg)
+	case pingResultMsg:
+		return m.handlePingResult(msg)
+	case statusPingTickMsg:
+		return m.handleStatusPingTick()
+	case statusPingMsg:
+		return m.handleStatusPing(msg)
+	case relayProbedMsg:
+		return m.handleRelayProbed(msg)
 	case groupSubEndedMsg:
 		return m.handleGroupSubEnded(msg)
 	case groupReconnectMsg:
 		return m.handleGroupReconnect(msg)
+	case groupAdminsMsg:
+		return m.handleGroupAdmins(msg)
 	case groupMetaMsg:
 		return m.handleGroupMeta(msg)
+	case groupMetaMissingMsg:
+		return m.handleGroupMetaMissing(msg)
+	case metaRetryMsg:
+		return m.handleMetaRetry(msg)
 	case groupCreatedMsg:
 		return m.handleGroupCreated(msg)
 	case groupInviteCreatedMsg:
@@ -98,6 +137,55 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.handleNIP51PublishResult(msg)
 	case clipboardCopiedMsg:
 		return m, nil
+	case editorFinishedMsg:
+		return m.handleEditorFinished(msg)
+	case boostPublishedMsg:
+		return m.handleBoostPublished(msg)
+	case subFlushMsg:
+		return m.handleSubFlush()
+	case roomSubsStartedMsg:
+		return m.handleRoomSubsStarted(msg)
+	case articleMsg:
+		return m.handleArticle(msg)
+	case migrationPublishedMsg:
+		return m.handleMigrationPublished(msg)
+	case deliveryReportMsg:
+		return m.handleDeliveryReport(msg)
+	case groupEchoTimeoutMsg:
+		return m.handleGroupEchoTimeout(msg)
+	case digestMsg:
+		return m.handleDigest(msg)
+	case inviteRevokedMsg:
+		return m.handleInviteRevoked(msg)
+	case reactionPublishedMsg:
+		return m.handleReactionPublished(msg)
+	case testDMSentMsg:
+		return m.handleTestDMSent(msg)
+	case testDMTimeoutMsg:
+		return m.handleTestDMTimeout(msg)
+	case previewTickMsg:
+		return m.handlePreviewTick(msg)
+	case subClosedMsg:
+		return m.handleSubClosed(msg)
+	case relayAuthedMsg:
+		return m.handleRelayAuthed(msg)
+	case embedMsg:
+		return m.handleEmbed(msg)
+	case backupDoneMsg:
+		return m.handleBackupDone(msg)
+	case restoreReadMsg:
+		return m.handleRestoreRead(msg)
+	case restoreDoneMsg:
+		return m.handleRestoreDone(msg)
+	case relayTestMsg:
+		return m.handleRelayTest(msg)
+	case timedExpiredMsg:
+		return m.handleTimedExpired(msg)
+	case timedRefreshMsg:
+		return m.handleTimedRefresh()
+	case bellDoneMsg:
+		m.bell = false
+		return m, nil
 	case tea.KeyMsg:
 		return m.handleKeyMsg(msg)
 	}
@@ -109,10 +197,19 @@ func (m *model) handleWindowSize(msg tea.WindowSizeMsg) (tea.Model, tea.Cmd) {
 	m.width = msg.Width
 	m.height = msg.Height
 	m.updateLayout()
+	m.refreshPreview() // rewrap to the new width
+	if m.reader != nil {
+		m.renderReader()
+	}
 	return m, tea.ClearScreen
 }
 
 func (m *model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
+	if m.reader != nil {
+		var cmd tea.Cmd
+		m.reader.vp, cmd = m.reader.vp.Update(msg)
+		return m, cmd
+	}
 	switch msg.Button {
 	case tea.MouseButtonWheelUp:
 		m.viewport.ScrollUp(3)
@@ -128,6 +225,8 @@ func (m *model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 					m.activeItem = idx
 					m.clearUnread()
 					m.updateViewport()
+				} else if kind, ok := m.sidebarHeaderAt(msg.Y); ok {
+					m.setSectionFolded(kind, !m.foldedSections[kind])
 				}
 			} else {
 				m.selecting = true
@@ -160,17 +259,25 @@ func (m *model) handleMouse(msg tea.MouseMsg) (tea.Model, tea.Cmd) {
 
 func (m *model) handleChannelCreated(msg channelCreatedMsg) (tea.Model, tea.Cmd) {
 	log.Printf("channelCreatedMsg: id=%s name=%q", msg.ID, msg.Name)
-	idx := m.appendChannelItem(Channel(msg))
+	idx := m.appendChannelItem(Channel{ID: msg.ID, Name: msg.Name})
 	m.activeItem = idx
 	m.updateViewport()
 	return m, tea.Batch(
-		subscribeChannelCmd(m.pool, m.relays, msg.ID),
+		m.subscribeChannel(msg.ID),
 		publishPublicChatsListCmd(m.pool, m.relays, m.allChannels(), m.keys),
 	)
 }
 
 func (m *model) handleChannelMeta(msg channelMetaMsg) (tea.Model, tea.Cmd) {
 	log.Printf("channelMetaMsg: id=%s name=%q", msg.ID, msg.Name)
+	if msg.NotFound {
+		// Keep whatever name the room has; retry while it is a placeholder.
+		if idx := m.findChannelIdx(msg.ID); idx >= 0 && isPlaceholderName(m.sidebar[idx].(ChannelItem).Channel.Name, msg.ID) {
+			return m, m.scheduleMetaRetry(msg.ID)
+		}
+		return m, nil
+	}
+	delete(m.metaAttempts, msg.ID)
 	m.updateChannelName(msg.ID, msg.Name)
 	return m, nil
 }
@@ -179,7 +286,7 @@ func (m *model) handleChannelSubStarted(msg channelSubStartedMsg) (tea.Model, te
 	log.Printf("channelSubStartedMsg: channel=%s", shortPK(msg.channelID))
 	// Cancel any existing subscription for this channel (e.g. reconnect).
 	m.cancelRoomSub(msg.channelID)
-	sub := &roomSub{kind: SidebarChannel, roomID: msg.channelID, events: msg.events, cancel: msg.cancel}
+	sub := &roomSub{kind: SidebarChannel, roomID: msg.channelID, events: msg.events, cancel: msg.cancel, batch: msg.batch, lastActivity: time.Now()}
 	m.roomSubs[msg.channelID] = sub
 	// Load log history if no messages are loaded yet.
 	if len(m.msgs[msg.channelID]) == 0 {
@@ -202,22 +309,32 @@ func (m *model) handleChannelEvent(msg channelEventMsg) (tea.Model, tea.Cmd) {
 	cm := ChatMessage(msg)
 	log.Printf("channelEventMsg: author=%s channel=%s id=%s content=%q", cm.Author, cm.ChannelID, cm.EventID, cm.Content)
 	sub := m.roomSubs[cm.ChannelID]
+	m.countRelayDelivery(cm.ChannelID, cm.Relay)
+	if cm.Relay != "" {
+		m.touchRoomSub(cm.ChannelID)
+		m.resetAuthRetries(cm.ChannelID, cm.Relay)
+	}
 	if m.isSeenEvent(cm.EventID) {
 		return m, waitForRoomSub(sub, m.keys)
 	}
 	m.markSeenEvent(cm.EventID)
 	chID := cm.ChannelID
 	m.msgs[chID] = appendMessage(m.msgs[chID], cm, m.cfg.MaxMessages)
-	appendLogEntry(m.logDir, "channel", chID, cm, m.resolveAuthor(cm.PubKey))
+	m.history.Append("channel", chID, cm, m.resolveAuthor(cm.PubKey))
+	m.applyEarlyReport(cm.EventID)
 	if chID == m.activeChannelID() {
 		m.updateViewport()
 	} else {
-		m.unread[chID] = true
+		m.markUnread(chID, cm.Timestamp)
 	}
 	var batchCmds []tea.Cmd
+	if cmd := m.notifyHighlight(chID, cm, chID == m.activeChannelID()); cmd != nil {
+		batchCmds = append(batchCmds, cmd)
+	}
 	if profileCmd := m.maybeRequestProfile(cm.PubKey); profileCmd != nil {
 		batchCmds = append(batchCmds, profileCmd)
 	}
+	batchCmds = append(batchCmds, m.maybeFetchEmbeds(cm.Content, m.channelRelays(chID)))
 	batchCmds = append(batchCmds, waitForRoomSub(sub, m.keys))
 	return m, tea.Batch(batchCmds...)
 }
@@ -225,6 +342,9 @@ func (m *model) handleChannelEvent(msg channelEventMsg) (tea.Model, tea.Cmd) {
 func (m *model) handleDMEvent(msg dmEventMsg) (tea.Model, tea.Cmd) {
 	cm := ChatMessage(msg)
 	log.Printf("dmEventMsg: author=%s id=%s mine=%v content=%q", cm.Author, cm.EventID, cm.IsMine, cm.Content)
+	if m.cfg.DMGrouping == "peer" {
+		cm = collapseGroupDM(cm)
+	}
 	if m.isSeenEvent(cm.EventID) {
 		if m.dmEvents != nil {
 			return m, waitForDMEvent(m.dmEvents, m.keys)
@@ -232,12 +352,19 @@ func (m *model) handleDMEvent(msg dmEventMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 	m.markSeenEvent(cm.EventID)
+	if m.consumeTestDM(cm) || isExpired(cm, nostr.Now()) {
+		if m.dmEvents != nil {
+			return m, waitForDMEvent(m.dmEvents, m.keys)
+		}
+		return m, nil
+	}
 
 	// Content-based dedup for our own DMs: the local echo from sendDM and
 	// the relay echo from the subscription have different synthetic EventIDs,
 	// so seenEvents can't catch the duplicate. Track by peer+content instead.
+	peer := dmRoomKey(cm)
 	if cm.IsMine {
-		echoKey := cm.PubKey + ":" + cm.Content
+		echoKey := peer + ":" + cm.Content
 		if _, ok := m.localDMEchoes[echoKey]; ok {
 			log.Printf("dmEventMsg: skipping relay echo (already have local echo)")
 			delete(m.localDMEchoes, echoKey)
@@ -257,12 +384,19 @@ func (m *model) handleDMEvent(msg dmEventMsg) (tea.Model, tea.Cmd) {
 		m.localDMEchoes[echoKey] = now
 	}
 
-	peer := cm.PubKey
 	m.msgs[peer] = appendMessage(m.msgs[peer], cm, m.cfg.MaxMessages)
-	appendLogEntry(m.logDir, "dm", peer, cm, m.resolveAuthor(cm.PubKey))
+	if cm.Expires == 0 {
+		m.history.Append("dm", peer, cm, m.resolveAuthor(cm.PubKey))
+	}
 
 	newPeer := false
-	if !m.containsDMPeer(peer) {
+	if len(cm.DMMembers) > 0 {
+		// Group DMs aren't in the contacts list, so replayed history is
+		// what brings them back after a restart.
+		if m.findGroupDMIdx(peer) < 0 {
+			m.appendGroupDMItem(cm.DMMembers)
+		}
+	} else if !m.containsDMPeer(peer) {
 		// Only auto-add unknown peers for genuinely new DMs, not replayed
 		// history. Replayed messages from peers the user previously left
 		// (removed from NIP-51 contacts) are stored but the peer is not
@@ -283,17 +417,30 @@ func (m *model) handleDMEvent(msg dmEventMsg) (tea.Model, tea.Cmd) {
 			log.Printf("dmEventMsg: failed to save last DM seen: %v", err)
 		}
 	}
-	if m.isDMSelected() && peer == m.activeDMPeerPK() {
+	active := m.isDMSelected() && peer == m.activeSidebarItem().ItemID()
+	if active {
 		m.updateViewport()
 	} else if cm.Timestamp > m.dmSeenAtStart {
-		m.unread[peer] = true
+		m.markUnread(peer, cm.Timestamp)
 	}
 	var batchCmds []tea.Cmd
-	if profileCmd := m.maybeRequestProfile(peer); profileCmd != nil {
-		batchCmds = append(batchCmds, profileCmd)
+	if cmd := m.notifyHighlight(peer, cm, active); cmd != nil {
+		batchCmds = append(batchCmds, cmd)
+	}
+	for _, pk := range append([]string{cm.PubKey}, cm.DMMembers...) {
+		if profileCmd := m.maybeRequestProfile(pk); profileCmd != nil {
+			batchCmds = append(batchCmds, profileCmd)
+		}
+	}
+	batchCmds = append(batchCmds, m.maybeFetchEmbeds(cm.Content, m.relays))
+	if cm.Expires != 0 {
+		batchCmds = append(batchCmds, timedExpiryCmd(peer, cm.Expires), m.scheduleTimedRefresh())
 	}
 	if newPeer {
-		batchCmds = append(batchCmds, publishContactsListCmd(m.pool, m.relays, contactsFromModel(m.allDMPeers(), m.profiles), m.keys, m.kr))
+		batchCmds = append(batchCmds, m.syncContacts())
+	}
+	if cmd := m.maybeAutoReply(cm, newPeer); cmd != nil {
+		batchCmds = append(batchCmds, cmd)
 	}
 	if m.dmEvents != nil {
 		batchCmds = append(batchCmds, waitForDMEvent(m.dmEvents, m.keys))
@@ -302,6 +449,11 @@ func (m *model) handleDMEvent(msg dmEventMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m *model) handleDMSubEnded(msg dmSubEndedMsg) (tea.Model, tea.Cmd) {
+	// Ignore the end of a subscription we replaced (e.g. after /nsec-rotate).
+	if msg.events != m.dmEvents {
+		log.Println("dmSubEndedMsg: ignoring stale message")
+		return m, nil
+	}
 	log.Println("dmSubEndedMsg: DM subscription ended, scheduling reconnect")
 	m.dmEvents = nil
 	m.addSystemMsg("DM subscription lost, reconnecting...")
@@ -310,13 +462,13 @@ func (m *model) handleDMSubEnded(msg dmSubEndedMsg) (tea.Model, tea.Cmd) {
 
 func (m *model) handleDMReconnect(msg dmReconnectMsg) (tea.Model, tea.Cmd) {
 	log.Println("dmReconnectMsg: reconnecting DM subscription")
-	return m, subscribeDMCmd(m.pool, m.relays, m.kr, m.lastDMSeen)
+	return m, m.afterRelayAccess(m.relays, subscribeDMCmd(m.pool, m.relays, m.kr, m.lastDMSeen, m.cfg.DMLookback()))
 }
 
 func (m *model) handleChannelSubEnded(msg channelSubEndedMsg) (tea.Model, tea.Cmd) {
 	log.Printf("channelSubEndedMsg: channel %s subscription ended", shortPK(msg.channelID))
-	// Ignore stale messages from a previously canceled subscription.
-	if _, ok := m.roomSubs[msg.channelID]; !ok {
+	// Ignore stale messages from a previously canceled or replaced subscription.
+	if sub, ok := m.roomSubs[msg.channelID]; !ok || sub.events != msg.events {
 		log.Printf("channelSubEndedMsg: ignoring stale message for %s", shortPK(msg.channelID))
 		return m, nil
 	}
@@ -333,7 +485,7 @@ func (m *model) handleChannelReconnect(msg channelReconnectMsg) (tea.Model, tea.
 	}
 	// Only reconnect if the channel is still in the sidebar.
 	if m.findChannelIdx(msg.channelID) >= 0 {
-		return m, subscribeChannelCmd(m.pool, m.relays, msg.channelID)
+		return m, m.subscribeChannel(msg.channelID)
 	}
 	return m, nil
 }
@@ -342,7 +494,7 @@ func (m *model) handleGroupSubStarted(msg groupSubStartedMsg) (tea.Model, tea.Cm
 	log.Printf("groupSubStartedMsg: group=%s", msg.groupKey)
 	// Cancel any existing subscription for this group (e.g. reconnect).
 	m.cancelRoomSub(msg.groupKey)
-	sub := &roomSub{kind: SidebarGroup, roomID: msg.groupKey, events: msg.events, cancel: msg.cancel}
+	sub := &roomSub{kind: SidebarGroup, roomID: msg.groupKey, events: msg.events, cancel: msg.cancel, batch: msg.batch, lastActivity: time.Now()}
 	m.roomSubs[msg.groupKey] = sub
 	if _, ok := m.groupRecentIDs[msg.groupKey]; !ok {
 		m.groupRecentIDs[msg.groupKey] = nil
@@ -359,10 +511,21 @@ func (m *model) handleGroupEvent(msg groupEventMsg) (tea.Model, tea.Cmd) {
 	log.Printf("groupEventMsg: author=%s group=%s id=%s", cm.Author, cm.GroupKey, cm.EventID)
 	gk := cm.GroupKey
 	sub := m.roomSubs[gk]
+	m.countRelayDelivery(gk, cm.Relay)
+	if cm.Relay != "" {
+		m.touchRoomSub(gk)
+		m.resetAuthRetries(gk, cm.Relay)
+	}
 	if m.isSeenEvent(cm.EventID) {
+		if cm.Relay != "" {
+			m.confirmGroupEcho(cm.EventID)
+		}
 		return m, waitForRoomSub(sub, m.keys)
 	}
 	m.markSeenEvent(cm.EventID)
+	if cm.Echo == echoPending {
+		m.trackGroupEcho(cm)
+	}
 	// Track recent event IDs for NIP-29 "previous" tags.
 	ids := m.groupRecentIDs[gk]
 	ids = append(ids, cm.EventID)
@@ -370,25 +533,39 @@ func (m *model) handleGroupEvent(msg groupEventMsg) (tea.Model, tea.Cmd) {
 		ids = ids[len(ids)-50:]
 	}
 	m.groupRecentIDs[gk] = ids
+	if isThreadReply(cm) {
+		return m.handleThreadReply(cm, sub)
+	}
 	m.msgs[gk] = appendMessage(m.msgs[gk], cm, m.cfg.MaxMessages)
-	appendLogEntry(m.logDir, "group", gk, cm, m.resolveAuthor(cm.PubKey))
+	if cm.ThreadTitle == "" {
+		m.history.Append("group", gk, cm, m.resolveAuthor(cm.PubKey))
+	} else if len(m.msgs[threadKey(cm.ThreadID)]) == 0 {
+		// The log has no room for a thread title, so roots always come from
+		// the relay; their replies are logged per thread.
+		m.loadHistory("thread", threadKey(cm.ThreadID))
+	}
+	m.applyEarlyReport(cm.EventID)
 	if gk == m.activeGroupKey() {
 		m.updateViewport()
 	} else {
-		m.unread[gk] = true
+		m.markUnread(gk, cm.Timestamp)
 	}
 	var batchCmds []tea.Cmd
+	if cmd := m.notifyHighlight(gk, cm, gk == m.activeGroupKey()); cmd != nil {
+		batchCmds = append(batchCmds, cmd)
+	}
 	if profileCmd := m.maybeRequestProfile(cm.PubKey); profileCmd != nil {
 		batchCmds = append(batchCmds, profileCmd)
 	}
+	batchCmds = append(batchCmds, m.maybeFetchEmbeds(cm.Content, mergeRelayHints(m.relays, m.groupRelays(gk))))
 	batchCmds = append(batchCmds, waitForRoomSub(sub, m.keys))
 	return m, tea.Batch(batchCmds...)
 }
 
 func (m *model) handleGroupSubEnded(msg groupSubEndedMsg) (tea.Model, tea.Cmd) {
 	log.Printf("groupSubEndedMsg: group %s subscription ended", msg.groupKey)
-	// Ignore stale messages from a previously canceled subscription.
-	if _, ok := m.roomSubs[msg.groupKey]; !ok {
+	// Ignore stale messages from a previously canceled or replaced subscription.
+	if sub, ok := m.roomSubs[msg.groupKey]; !ok || sub.events != msg.events {
 		log.Printf("groupSubEndedMsg: ignoring stale message for %s", msg.groupKey)
 		return m, nil
 	}
@@ -406,13 +583,25 @@ func (m *model) handleGroupReconnect(msg groupReconnectMsg) (tea.Model, tea.Cmd)
 	// Only reconnect if the group is still in the sidebar.
 	relayURL, groupID := splitGroupKey(msg.groupKey)
 	if m.findGroupIdx(relayURL, groupID) >= 0 {
-		return m, subscribeGroupCmd(m.pool, relayURL, groupID)
+		return m, m.subscribeGroup(relayURL, groupID)
+	}
+	return m, nil
+}
+
+func (m *model) handleGroupAdmins(msg groupAdminsMsg) (tea.Model, tea.Cmd) {
+	log.Printf("groupAdminsMsg: relay=%s group=%s roles=%v", msg.RelayURL, msg.GroupID, msg.Roles)
+	m.updateGroupRoles(msg.RelayURL, msg.GroupID, msg.Roles)
+	gk := groupKey(msg.RelayURL, msg.GroupID)
+	if sub, ok := m.roomSubs[gk]; ok {
+		sub.lastActivity = time.Now()
+		return m, waitForRoomSub(sub, m.keys)
 	}
 	return m, nil
 }
 
 func (m *model) handleGroupMeta(msg groupMetaMsg) (tea.Model, tea.Cmd) {
 	log.Printf("groupMetaMsg: relay=%s group=%s name=%q", msg.RelayURL, msg.GroupID, msg.Name)
+	delete(m.metaAttempts, groupKey(msg.RelayURL, msg.GroupID))
 	m.updateGroupName(msg.RelayURL, msg.GroupID, msg.Name)
 	if msg.RelayPubKey != "" {
 		m.updateGroupRelayPubKey(msg.RelayURL, msg.GroupID, msg.RelayPubKey)
@@ -425,6 +614,7 @@ func (m *model) handleGroupMeta(msg groupMetaMsg) (tea.Model, tea.Cmd) {
 	if msg.FromSub {
 		gk := groupKey(msg.RelayURL, msg.GroupID)
 		if sub, ok := m.roomSubs[gk]; ok {
+			sub.lastActivity = time.Now()
 			metaCmds = append(metaCmds, waitForRoomSub(sub, m.keys))
 		}
 	}
@@ -438,7 +628,7 @@ func (m *model) handleGroupCreated(msg groupCreatedMsg) (tea.Model, tea.Cmd) {
 		m.activeItem = idx
 		m.updateViewport()
 		g := m.sidebar[idx].(GroupItem).Group
-		return m, subscribeGroupCmd(m.pool, g.RelayURL, g.GroupID)
+		return m, m.subscribeGroup(g.RelayURL, g.GroupID)
 	}
 	idx := m.appendGroupItem(Group{RelayURL: msg.RelayURL, GroupID: msg.GroupID, Name: msg.Name})
 	m.activeItem = idx
@@ -446,7 +636,7 @@ func (m *model) handleGroupCreated(msg groupCreatedMsg) (tea.Model, tea.Cmd) {
 	m.updateViewport()
 	gk := groupKey(msg.RelayURL, msg.GroupID)
 	return m, tea.Batch(
-		subscribeGroupCmd(m.pool, msg.RelayURL, msg.GroupID),
+		m.subscribeGroup(msg.RelayURL, msg.GroupID),
 		// Kind 9007 (create) doesn't set metadata on most relays;
 		// publish a kind 9002 (edit metadata) to set the name.
 		editGroupMetadataCmd(m.pool, msg.RelayURL, msg.GroupID, map[string]string{"name": msg.Name}, m.groupRecentIDs[gk], m.keys),
@@ -456,13 +646,6 @@ func (m *model) handleGroupCreated(msg groupCreatedMsg) (tea.Model, tea.Cmd) {
 	)
 }
 
-func (m *model) handleGroupInviteCreated(msg groupInviteCreatedMsg) (tea.Model, tea.Cmd) {
-	log.Printf("groupInviteCreatedMsg: relay=%s group=%s code=%s", msg.RelayURL, msg.GroupID, msg.Code)
-	host := strings.TrimPrefix(msg.RelayURL, "wss://")
-	m.addSystemMsg(fmt.Sprintf("invite code: %s  join with: /join %s'%s", msg.Code, host, msg.GroupID))
-	return m, nil
-}
-
 func (m *model) handleGroupJoined(msg groupJoinedMsg) (tea.Model, tea.Cmd) {
 	log.Printf("groupJoinedMsg: relay=%s group=%s", msg.RelayURL, msg.GroupID)
 	// Check if already in list
@@ -470,7 +653,7 @@ func (m *model) handleGroupJoined(msg groupJoinedMsg) (tea.Model, tea.Cmd) {
 		m.activeItem = idx
 		m.updateViewport()
 		g := m.sidebar[idx].(GroupItem).Group
-		return m, subscribeGroupCmd(m.pool, g.RelayURL, g.GroupID)
+		return m, m.subscribeGroup(g.RelayURL, g.GroupID)
 	}
 	name := msg.Name
 	if name == "" {
@@ -480,8 +663,8 @@ func (m *model) handleGroupJoined(msg groupJoinedMsg) (tea.Model, tea.Cmd) {
 	m.activeItem = idx
 	m.updateViewport()
 	return m, tea.Batch(
-		subscribeGroupCmd(m.pool, msg.RelayURL, msg.GroupID),
-		fetchGroupMetaCmd(m.pool, msg.RelayURL, msg.GroupID),
+		m.subscribeGroup(msg.RelayURL, msg.GroupID),
+		fetchGroupMetaCmd(m.pool, m.queries, msg.RelayURL, msg.GroupID, m.cfg.ReplaceableWait()),
 		publishSimpleGroupsListCmd(m.pool, m.relays, m.allGroups(), m.keys),
 	)
 }
@@ -490,16 +673,28 @@ func (m *model) handleProfileResolved(msg profileResolvedMsg) (tea.Model, tea.Cm
 	log.Printf("profileResolvedMsg: %s -> %q", shortPK(msg.PubKey), msg.DisplayName)
 	m.profiles[msg.PubKey] = msg.DisplayName
 	delete(m.profilePending, msg.PubKey)
+	m.refreshGroupDMNames(msg.PubKey)
 	if m.containsDMPeer(msg.PubKey) {
 		m.updateDMItemName(msg.PubKey, msg.DisplayName)
 		m.updateViewport()
-		return m, publishContactsListCmd(m.pool, m.relays, contactsFromModel(m.allDMPeers(), m.profiles), m.keys, m.kr)
+		return m, m.syncContacts()
 	}
 	m.updateViewport()
 	return m, nil
 }
 
 func (m *model) handleNIP05Resolved(msg nip05ResolvedMsg) (tea.Model, tea.Cmd) {
+	if msg.Verify {
+		switch {
+		case msg.Err != nil:
+			m.addSystemMsg(fmt.Sprintf("NIP-05 %s: lookup failed: %v", msg.Identifier, msg.Err))
+		case msg.PubKey == m.keys.PK.Hex():
+			m.addSystemMsg(fmt.Sprintf("NIP-05 %s: ✓ verified, points to your pubkey", msg.Identifier))
+		default:
+			m.addSystemMsg(fmt.Sprintf("NIP-05 %s: ✗ points to %s, not your pubkey", msg.Identifier, shortPK(msg.PubKey)))
+		}
+		return m, nil
+	}
 	if msg.Err != nil {
 		m.addSystemMsg(fmt.Sprintf("NIP-05 error: %v", msg.Err))
 		return m, nil
@@ -523,9 +718,64 @@ func (m *model) handleDMSendErr(msg dmSendErrMsg) (tea.Model, tea.Cmd) {
 		Timestamp: nostr.Now(),
 	}
 	m.msgs[msg.peerPK] = appendMessage(m.msgs[msg.peerPK], errMsg, m.cfg.MaxMessages)
-	if m.activeDMPeerPK() == msg.peerPK {
+	if msg.content != "" {
+		m.msgs[msg.peerPK] = appendMessage(m.msgs[msg.peerPK], failedDMEcho(msg, m.keys), m.cfg.MaxMessages)
+	}
+	if item := m.activeSidebarItem(); item != nil && item.ItemID() == msg.peerPK {
 		m.updateViewport()
 	}
+	if msg.expires != 0 {
+		return m, tea.Batch(timedExpiryCmd(msg.peerPK, msg.expires), m.scheduleTimedRefresh())
+	}
+	return m, nil
+}
+
+func (m *model) handleDeliveryReport(msg deliveryReportMsg) (tea.Model, tea.Cmd) {
+	ok := 0
+	for _, status := range msg.deliveries {
+		if status == "ok" {
+			ok++
+		}
+	}
+	log.Printf("deliveryReportMsg: room=%s id=%s accepted=%d/%d", shortPK(msg.roomKey), shortPK(msg.eventID), ok, len(msg.deliveries))
+	msgs := m.msgs[msg.roomKey]
+	i := slices.IndexFunc(msgs, func(cm ChatMessage) bool { return cm.EventID == msg.eventID })
+	if i < 0 && !m.isSeenEvent(msg.eventID) {
+		// The publish finished before the batched echo was handled; keep
+		// the report for when the echo arrives.
+		if m.earlyReports == nil {
+			m.earlyReports = make(map[string]deliveryReportMsg)
+		}
+		m.earlyReports[msg.eventID] = msg
+		return m, nil
+	}
+	if i >= 0 {
+		msgs[i].Deliveries = msg.deliveries
+		msgs[i].Failed = ok == 0
+		if ok == 0 && msgs[i].Echo == echoPending {
+			msgs[i].Echo = echoNone
+			delete(m.pendingEchoes, msg.eventID)
+		}
+	}
+	if ok == 0 {
+		m.addRoomSystemMsg(msg.roomKey, "message not accepted by any relay; /retry-failed to send it again")
+	} else if m.activeRoomKey() == msg.roomKey {
+		m.updateViewport()
+	}
+	return m, nil
+}
+
+// applyEarlyReport applies a delivery report that came in before the echo
+// of eventID was appended.
+func (m *model) applyEarlyReport(eventID string) {
+	if r, ok := m.earlyReports[eventID]; ok {
+		delete(m.earlyReports, eventID)
+		m.handleDeliveryReport(r)
+	}
+}
+
+func (m *model) handleBoostPublished(msg boostPublishedMsg) (tea.Model, tea.Cmd) {
+	m.addSystemMsg(fmt.Sprintf("boosted %s's message (accepted by %d/%d relays)", m.resolveAuthor(msg.origAuthor), msg.accepted, msg.total))
 	return m, nil
 }
 
@@ -545,9 +795,13 @@ func (m *model) handleBlossomUploadErr(msg blossomUploadErrMsg) (tea.Model, tea.
 }
 
 func (m *model) handleNIP51ListsFetched(msg nip51ListsFetchedMsg) (tea.Model, tea.Cmd) {
-	log.Printf("nip51ListsFetchedMsg: contacts=%d (ts=%d) channels=%d (ts=%d) groups=%d (ts=%d)",
-		len(msg.contacts), msg.contactsTS, len(msg.channels), msg.channelsTS, len(msg.groups), msg.groupsTS)
+	log.Printf("nip51ListsFetchedMsg: contacts=%d (ts=%d) channels=%d (ts=%d) groups=%d (ts=%d) follows=%d (ts=%d)",
+		len(msg.contacts), msg.contactsTS, len(msg.channels), msg.channelsTS, len(msg.groups), msg.groupsTS, len(msg.follows), msg.followsTS)
 	var fetchCmds []tea.Cmd
+	activeID := ""
+	if item := m.activeSidebarItem(); item != nil {
+		activeID = item.ItemID()
+	}
 
 	// Contacts: if relay data is newer, replace in-memory state.
 	if msg.contactsTS > m.contactsListTS && msg.contacts != nil {
@@ -564,6 +818,34 @@ func (m *model) handleNIP51ListsFetched(msg nip51ListsFetchedMsg) (tea.Model, te
 		}
 	}
 
+	// Follows (kind 3): if relay data is newer, replace the follow list.
+	// Only a list actually received unlocks /follow: when the query timed
+	// out, publishing from an empty list would wipe the real one.
+	if msg.followsTS > 0 {
+		m.followsLoaded = true
+	}
+	if msg.followsTS > m.followsListTS {
+		m.followsListTS = msg.followsTS
+		m.follows = msg.follows
+		m.followsContent = msg.followsRaw
+		for _, f := range msg.follows {
+			if f.Petname != "" {
+				if _, ok := m.profiles[f.PubKey]; !ok {
+					m.profiles[f.PubKey] = f.Petname
+				}
+			}
+		}
+	}
+	// With follows_in_sidebar, every follow also appears as a DM peer.
+	for _, f := range m.follows {
+		if m.cfg.FollowsAsDMs && !m.containsDMPeer(f.PubKey) {
+			m.appendDMItem(f.PubKey, m.resolveAuthor(f.PubKey))
+			if cmd := m.maybeRequestProfile(f.PubKey); cmd != nil {
+				fetchCmds = append(fetchCmds, cmd)
+			}
+		}
+	}
+
 	// Channels: if relay data is newer, replace in-memory state and rewrite cache.
 	if msg.channelsTS > m.channelsListTS && msg.channels != nil {
 		m.channelsListTS = msg.channelsTS
@@ -581,9 +863,9 @@ func (m *model) handleNIP51ListsFetched(msg nip51ListsFetchedMsg) (tea.Model, te
 		// Subscribe to new channels and fetch metadata.
 		for _, ch := range msg.channels {
 			if _, ok := m.roomSubs[ch.ID]; !ok {
-				fetchCmds = append(fetchCmds, subscribeChannelCmd(m.pool, m.relays, ch.ID))
+				fetchCmds = append(fetchCmds, m.subscribeChannel(ch.ID))
 			}
-			fetchCmds = append(fetchCmds, fetchChannelMetaCmd(m.pool, m.relays, ch.ID))
+			fetchCmds = append(fetchCmds, fetchChannelMetaCmd(m.pool, m.queries, ch.RelaysWith(m.relays), ch.ID))
 		}
 	}
 
@@ -603,26 +885,22 @@ func (m *model) handleNIP51ListsFetched(msg nip51ListsFetchedMsg) (tea.Model, te
 		}
 		var groups []Group
 		for _, sg := range msg.groups {
-			groups = append(groups, Group{RelayURL: sg.RelayURL, GroupID: sg.GroupID, Name: sg.Name})
+			m.migrateGroupHistory(sg)
+			groups = append(groups, Group{RelayURL: sg.RelayURL, GroupID: sg.GroupID, Name: sg.Name, Mirrors: sg.Mirrors})
 		}
 		m.replaceGroups(groups)
 		// Subscribe to new groups and fetch metadata.
 		for _, sg := range msg.groups {
 			gk := groupKey(sg.RelayURL, sg.GroupID)
 			if _, ok := m.roomSubs[gk]; !ok {
-				fetchCmds = append(fetchCmds, subscribeGroupCmd(m.pool, sg.RelayURL, sg.GroupID))
+				fetchCmds = append(fetchCmds, m.subscribeGroup(sg.RelayURL, sg.GroupID))
 			}
-			fetchCmds = append(fetchCmds, fetchGroupMetaCmd(m.pool, sg.RelayURL, sg.GroupID))
+			fetchCmds = append(fetchCmds, fetchGroupMetaCmd(m.pool, m.queries, sg.RelayURL, sg.GroupID, m.cfg.ReplaceableWait()))
 		}
 	}
 
-	// Clamp activeItem to valid range after list replacement.
-	total := m.sidebarTotal()
-	if total == 0 {
-		m.activeItem = 0
-	} else if m.activeItem >= total {
-		m.activeItem = total - 1
-	}
+	// Keep the active item selected after list replacement.
+	m.selectItem(activeID)
 	m.updateViewport()
 	if len(fetchCmds) > 0 {
 		return m, tea.Batch(fetchCmds...)
@@ -652,6 +930,25 @@ func (m *model) handleNIP51PublishResult(msg nip51PublishResultMsg) (tea.Model,
 }
 
 func (m *model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.reader != nil && msg.String() != "ctrl+c" {
+		return m.handleReaderKey(msg)
+	}
+	if m.reactPicker != nil && msg.String() != "ctrl+c" {
+		return m.handleReactPickerKey(msg)
+	}
+	if m.unreadPeek != nil && msg.String() != "ctrl+c" {
+		return m.handleUnreadPeekKey(msg)
+	}
+	if m.displayMenu != nil && msg.String() != "ctrl+c" {
+		return m.handleDisplayMenuKey(msg)
+	}
+	if m.passPrompt != nil && msg.String() != "ctrl+c" {
+		return m.handlePassPromptKey(msg)
+	}
+	if m.pendingRestore != nil && msg.String() != "ctrl+c" {
+		return m.handleRestoreConfirmKey(msg)
+	}
+
 	// Dismiss QR overlay on any key (except ctrl+c which still quits).
 	if m.qrOverlay != "" {
 		if msg.String() == "ctrl+c" {
@@ -662,6 +959,23 @@ func (m *model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 		}
 		m.qrOverlay = ""
+		m.whois = nil
+		return m, nil
+	}
+
+	if m.pendingSend != "" {
+		return m.handleConfirmSendKey(msg)
+	}
+
+	if m.paletteOpen {
+		if msg.String() == "ctrl+c" {
+			m.paletteOpen = false
+			return m, nil
+		}
+		return m.handlePaletteKey(msg)
+	}
+	if msg.String() == "ctrl+p" {
+		m.openPalette()
 		return m, nil
 	}
 
@@ -718,7 +1032,7 @@ func (m *model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		m.input.SetValue(m.inputHistory[m.historyIndex])
 		m.syncInputHeight()
-		return m, nil
+		return m, m.schedulePreview()
 	}
 	if msg.String() == "down" && m.input.Line() == m.input.LineCount()-1 && m.historyIndex >= 0 {
 		if m.historyIndex < len(m.inputHistory)-1 {
@@ -731,7 +1045,24 @@ func (m *model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			m.historySaved = ""
 		}
 		m.syncInputHeight()
-		return m, nil
+		return m, m.schedulePreview()
+	}
+
+	if msg.String() == m.cfg.EditorKeyBinding() {
+		return m.openExternalEditor()
+	}
+
+	if msg.String() == m.cfg.SendKeyBinding() {
+		text := strings.TrimSpace(m.input.Value())
+		if text == "" {
+			m.revealLatest()
+			return m, nil
+		}
+		if !strings.HasPrefix(text, "/") && m.isLargeMessage(text) {
+			m.pendingSend = text
+			return m, nil
+		}
+		return m.submitInput(text)
 	}
 
 	switch msg.String() {
@@ -766,6 +1097,27 @@ func (m *model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case "ctrl+g":
+		return m.togglePreview()
+
+	case "alt+-":
+		if item := m.activeSidebarItem(); item != nil {
+			m.setSectionFolded(item.Kind(), true)
+		}
+		return m, nil
+
+	case "alt++", "alt+=":
+		if item := m.activeSidebarItem(); item != nil {
+			m.setSectionFolded(item.Kind(), false)
+		}
+		return m, nil
+
+	case "alt+up":
+		return m.moveActiveItem("up")
+
+	case "alt+down":
+		return m.moveActiveItem("down")
+
 	case "pgup":
 		m.viewport.ScrollUp(10)
 		return m, nil
@@ -773,43 +1125,53 @@ func (m *model) handleKeyMsg(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "pgdown":
 		m.viewport.ScrollDown(10)
 		return m, nil
+	}
 
-	case "enter":
-		text := strings.TrimSpace(m.input.Value())
-		if text == "" {
-			return m, nil
-		}
-		m.inputHistory = append(m.inputHistory, text)
-		m.historyIndex = -1
-		m.historySaved = ""
-		m.input.Reset()
-		m.acSuggestions = nil
-		m.acIndex = 0
-		m.input.SetHeight(inputMinHeight)
-		m.lastInputHeight = inputMinHeight
-		m.updateLayout()
+	return m.handleInputUpdate(msg)
+}
 
-		// Slash commands
-		if strings.HasPrefix(text, "/") {
-			return m.handleCommand(text)
-		}
+// submitInput clears the input box and runs text as a command or sends it
+// as a message to the active conversation.
+func (m *model) submitInput(text string) (tea.Model, tea.Cmd) {
+	m.inputHistory = append(m.inputHistory, text)
+	m.historyIndex = -1
+	m.historySaved = ""
+	m.input.Reset()
+	m.acSuggestions = nil
+	m.acIndex = 0
+	m.lastInputHeight = inputHeight(m.cfg, 1, true)
+	m.input.SetHeight(m.lastInputHeight)
+	m.refreshPreview()
+	m.updateLayout()
 
-		// Regular message
-		if item := m.activeSidebarItem(); item != nil {
-			switch it := item.(type) {
-			case ChannelItem:
-				return m, publishChannelMessage(m.pool, m.relays, it.Channel.ID, text, m.keys)
-			case GroupItem:
-				gk := groupKey(it.Group.RelayURL, it.Group.GroupID)
-				return m, publishGroupMessage(m.pool, it.Group.RelayURL, it.Group.GroupID, text, m.groupRecentIDs[gk], m.keys)
-			case DMItem:
-				return m, sendDM(m.pool, m.relays, it.PubKey, text, m.keys, m.kr)
-			}
+	// Slash commands
+	if strings.HasPrefix(text, "/") {
+		return m.handleCommand(text)
+	}
+
+	// Regular message
+	if it, ok := m.activeSidebarItem().(GroupItem); ok {
+		if root, ok := m.activeThread(); ok {
+			return m, m.sendThreadReply(it.Group, root.ThreadID, text)
 		}
-		return m, nil
 	}
+	return m, m.sendToItem(m.activeSidebarItem(), text)
+}
 
-	return m.handleInputUpdate(msg)
+// sendToItem sends text as a message to the room of a sidebar item.
+func (m *model) sendToItem(item SidebarItem, text string) tea.Cmd {
+	switch it := item.(type) {
+	case ChannelItem:
+		return publishChannelMessage(m.pool, it.Channel.RelaysWith(m.relays), it.Channel.ID, text, nil, m.keys)
+	case GroupItem:
+		gk := groupKey(it.Group.RelayURL, it.Group.GroupID)
+		return publishGroupMessage(m.pool, it.Group.Relays(), it.Group.GroupID, text, nil, m.groupRecentIDs[gk], m.keys)
+	case DMItem:
+		return sendDM(m.pool, m.relays, it.PubKey, text, nil, m.keys, m.kr)
+	case GroupDMItem:
+		return sendGroupDM(m.pool, m.relays, it.Members, text, nil, m.keys, m.kr)
+	}
+	return nil
 }
 
 func (m *model) handleInputUpdate(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -818,11 +1180,8 @@ func (m *model) handleInputUpdate(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Pre-grow textarea before newline insertion so the internal viewport
 	// calculates its scroll offset with the correct height.
 	if keyMsg, ok := msg.(tea.KeyMsg); ok {
-		if s := keyMsg.String(); s == "alt+enter" || s == "ctrl+j" {
-			target := m.input.LineCount() + 1
-			if target > inputMaxHeight {
-				target = inputMaxHeight
-			}
+		if slices.Contains(m.cfg.NewlineKeys(), keyMsg.String()) {
+			target := inputHeight(m.cfg, m.input.LineCount()+1, false)
 			if target != m.lastInputHeight {
 				m.input.SetHeight(target)
 				m.lastInputHeight = target
@@ -843,6 +1202,7 @@ func (m *model) handleInputUpdate(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	// Shrink textarea when lines are removed (e.g. backspace joining lines).
 	m.syncInputHeight()
+	cmds = append(cmds, m.schedulePreview())
 
 	return m, tea.Batch(cmds...)
 }
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
@@ -15,47 +14,30 @@ import (
 // Returns the unified activeItem index and true if the row is a clickable item,
 // or 0 and false if it's a section header or out of bounds.
 func (m *model) sidebarItemAt(y int) (int, bool) {
-	row := 0
-	// CHANNELS header
-	row++ // "CHANNELS"
-	for i, it := range m.sidebar {
-		if it.Kind() != SidebarChannel {
-			break
-		}
-		if y == row {
-			return i, true
-		}
-		row++
-	}
-	// GROUPS header
-	row++ // "GROUPS"
-	for i, it := range m.sidebar {
-		if it.Kind() != SidebarGroup {
-			continue
-		}
-		if y == row {
-			return i, true
-		}
-		row++
+	rows := m.sidebarRows()
+	if y < 0 || y >= len(rows) || rows[y].item < 0 {
+		return 0, false
 	}
-	// DMS header
-	row++ // "DMS"
-	for i, it := range m.sidebar {
-		if it.Kind() != SidebarDM {
-			continue
-		}
-		if y == row {
-			return i, true
-		}
-		row++
+	return rows[y].item, true
+}
+
+// sidebarHeaderAt maps a Y coordinate to the section whose header is there.
+func (m *model) sidebarHeaderAt(y int) (SidebarKind, bool) {
+	rows := m.sidebarRows()
+	if y < 0 || y >= len(rows) || rows[y].item >= 0 {
+		return 0, false
 	}
-	return 0, false
+	return rows[y].kind, true
 }
 
 func (m *model) sidebarWidth() int {
 	longest := 0
 	for _, it := range m.sidebar {
-		if n := lipgloss.Width(it.DisplayName()); n > longest {
+		n := lipgloss.Width(it.DisplayName())
+		if _, ok := it.(DMItem); ok && m.cfg.Avatars {
+			n += avatarWidth - 1 // the avatar replaces the "@" prefix
+		}
+		if n > longest {
 			longest = n
 		}
 	}
@@ -71,6 +53,12 @@ func (m *model) renderTitleBar() string {
 	var title string
 	if item := m.activeSidebarItem(); item != nil {
 		title = item.Prefix() + item.DisplayName()
+		if gi, ok := item.(GroupItem); ok && gi.Group.isAdmin() {
+			title += " [" + strings.Join(gi.Group.Roles, ", ") + "]"
+		}
+		if root, ok := m.activeThread(); ok {
+			title += " › 🧵 " + root.ThreadTitle
+		}
 	}
 	return lipgloss.NewStyle().Bold(true).Foreground(colorPrimary).Padding(0, 1).Render(title)
 }
@@ -93,8 +81,12 @@ func (m *model) updateLayout() {
 	if len(m.acSuggestions) > 0 {
 		acHeight = lipgloss.Height(m.viewAutocomplete())
 	}
+	paneHeight := 0
+	if m.preview {
+		paneHeight = lipgloss.Height(m.viewPreview())
+	}
 
-	contentHeight := m.height - titleHeight - statusHeight - inputHeight - acHeight
+	contentHeight := m.height - titleHeight - statusHeight - inputHeight - acHeight - paneHeight
 	if contentHeight < 1 {
 		contentHeight = 1
 	}
@@ -107,7 +99,7 @@ func (m *model) updateViewport() {
 	m.clearUnread()
 	var msgs []ChatMessage
 	if item := m.activeSidebarItem(); item != nil {
-		msgs = m.msgs[item.ItemID()]
+		msgs = m.msgs[m.activeRoomKey()]
 	} else {
 		msgs = m.globalMsgs
 	}
@@ -116,10 +108,15 @@ func (m *model) updateViewport() {
 	type resolvedMsg struct {
 		msg         ChatMessage
 		displayName string
+		hidden      bool // collapsed by a filter (e.g. muted words)
 	}
 	var resolved []resolvedMsg
 	maxNameW := 0
 	for _, msg := range msgs {
+		if m.isFilteredMessage(msg) {
+			resolved = append(resolved, resolvedMsg{msg: msg, hidden: true})
+			continue
+		}
 		if msg.Author == "system" {
 			resolved = append(resolved, resolvedMsg{msg: msg})
 			continue
@@ -139,13 +136,43 @@ func (m *model) updateViewport() {
 		resolved = append(resolved, resolvedMsg{msg: msg, displayName: displayName})
 	}
 
+	format := m.cfg.MessageFormatString()
+	if !m.cfg.ShowTimestamps() {
+		format = dropFormatToken(format, "{time}")
+	}
+	prefixTmpl, suffixTmpl := splitMessageFormat(format)
+	seenID := ""
+	if peer := m.activeDMPeerPK(); peer != "" {
+		seenID = m.seenMarkerID(peer, msgs)
+	}
+	// Rooms toggled with /toggle-markdown show raw text, only wrapped.
+	plain := !m.cfg.MarkdownEnabled()
+	if item := m.activeSidebarItem(); item != nil && m.plainRooms[item.ItemID()] {
+		plain = true
+	}
 	var lines []string
+	m.msgLines = make(map[string]int)
+	hiddenRun := 0
+	var prev *ChatMessage // previous message shown with an author, for group_by_author
 	for _, rm := range resolved {
+		// Collapse consecutive filtered messages into a single summary line.
+		if rm.hidden {
+			hiddenRun++
+			prev = nil
+			continue
+		}
+		if hiddenRun > 0 {
+			lines = append(lines, renderHiddenSummary(hiddenRun))
+			hiddenRun = 0
+		}
 		msg := rm.msg
 		if msg.Author == "system" {
 			lines = append(lines, chatSystemStyle.Render("  "+msg.Content))
+			prev = nil
 			continue
 		}
+		grouped := m.cfg.GroupByAuthor && prev != nil && sameAuthorRun(*prev, msg)
+		prev = &rm.msg
 		var authorStyle lipgloss.Style
 		if msg.IsMine {
 			authorStyle = chatOwnAuthorStyle
@@ -161,13 +188,66 @@ func (m *model) updateViewport() {
 		if nameW < maxNameW {
 			namePad = strings.Repeat(" ", maxNameW-nameW)
 		}
-		ts := chatTimestampStyle.Render(msg.Timestamp.Time().Format("15:04"))
+		tsStyle := chatTimestampStyle
+		if m.isHighlightedMessage(msg) {
+			tsStyle = chatHighlightStyle
+		}
+		ts := tsStyle.Render(msg.Timestamp.Time().Format("15:04"))
 		author := namePad + authorStyle.Render(displayName)
-		// Convert single newlines to paragraph breaks for glamour,
-		// but leave newlines inside fenced code blocks untouched.
-		mdContent := doubleNewlinesOutsideCode(msg.Content)
-		content := renderMarkdown(m.mdRender, mdContent)
-		prefix := fmt.Sprintf("%s %s: ", ts, author)
+		if m.cfg.Avatars {
+			pk := msg.PubKey
+			if msg.IsMine {
+				pk = m.keys.PK.Hex()
+			}
+			if pk != "" {
+				author = namePad + renderAvatar(displayName, pk) + " " + authorStyle.Render(displayName)
+			} else {
+				author = strings.Repeat(" ", avatarWidth+1) + author
+			}
+		}
+		if grouped {
+			author = strings.Repeat(" ", lipgloss.Width(author))
+		}
+		shortID := strings.Repeat(" ", 8)
+		if len(msg.EventID) >= 8 {
+			shortID = chatTimestampStyle.Render(msg.EventID[:8])
+		}
+		tokens := map[string]string{
+			"{time}":      ts,
+			"{author}":    author,
+			"{id}":        shortID,
+			"{reactions}": formatReactions(m.reactions[msg.EventID]),
+		}
+		// Keep chat-style single newlines as line breaks without
+		// breaking up lists, quotes, or code blocks.
+		body := expandNoteRefs(replacePaymentTokens(msg.Content), m.noteCard)
+		if msg.RepostOf != "" {
+			body = "🔁 reposted @" + m.resolveAuthor(msg.RepostOf) + ":\n\n" + body
+		}
+		if msg.ThreadTitle != "" {
+			body = m.renderThreadRoot(msg)
+		}
+		body, links := shortenURLs(body, m.cfg.ShortURLsOver)
+		content := body
+		switch {
+		case m.isConcealed(msg):
+			content = chatSystemStyle.Render(cwNotice(msg.ContentWarning))
+		case !plain:
+			content = renderMarkdown(m.mdRender, hardLineBreaks(body))
+		}
+		prefix := expandMessageFormat(prefixTmpl, tokens)
+		suffix := expandMessageFormat(suffixTmpl, tokens)
+		if seenID != "" && msg.EventID == seenID {
+			suffix += " " + chatSystemStyle.Render("✓ seen")
+		}
+		if msg.Expires != 0 {
+			suffix += " " + chatSystemStyle.Render(expiryLabel(msg.Expires, nostr.Now()))
+		}
+		if msg.Failed {
+			suffix += " " + chatFailedStyle.Render("✗ failed")
+		} else if label := echoLabel(msg.Echo); label != "" {
+			suffix += " " + label
+		}
 		prefixW := lipgloss.Width(prefix)
 		pad := strings.Repeat(" ", prefixW)
 		wrapWidth := m.viewport.Width - prefixW
@@ -207,10 +287,29 @@ func (m *model) updateViewport() {
 				}
 			}
 		}
+		if len(links) > 0 {
+			next := 0
+			for i := range contentLines {
+				contentLines[i].text, next = hyperlinkShortURLs(contentLines[i].text, links, next)
+			}
+		}
 		if len(contentLines) == 0 {
 			contentLines = []cLine{{text: ""}}
 		}
+		// Append the suffix to the last line, or give it its own line when
+		// it would overflow.
+		if strings.TrimSpace(ansi.Strip(suffix)) != "" {
+			last := &contentLines[len(contentLines)-1]
+			if lipgloss.Width(last.text)+lipgloss.Width(suffix) <= wrapWidth {
+				last.text += suffix
+			} else {
+				contentLines = append(contentLines, cLine{text: strings.TrimLeft(suffix, " ")})
+			}
+		}
 		first := prefix + contentLines[0].text
+		if msg.EventID != "" {
+			m.msgLines[msg.EventID] = len(lines)
+		}
 		lines = append(lines, first)
 		for _, cl := range contentLines[1:] {
 			if cl.hardWrap {
@@ -221,18 +320,80 @@ func (m *model) updateViewport() {
 		}
 	}
 
+	if hiddenRun > 0 {
+		lines = append(lines, renderHiddenSummary(hiddenRun))
+	}
+
 	m.viewport.SetContent(strings.Join(lines, "\n"))
 	m.viewport.GotoBottom()
 }
 
+// authorRunGap is the longest pause between two messages by the same
+// author that group_by_author still shows as one run.
+const authorRunGap = 5 * 60 // seconds
+
+// sameAuthorRun reports whether cur continues prev's run of messages by the
+// same author, so its author can be left blank.
+func sameAuthorRun(prev, cur ChatMessage) bool {
+	if prev.IsMine != cur.IsMine || (!cur.IsMine && (cur.PubKey == "" || prev.PubKey != cur.PubKey)) {
+		return false
+	}
+	if cur.RepostOf != "" || cur.ThreadTitle != "" {
+		return false
+	}
+	return cur.Timestamp >= prev.Timestamp && cur.Timestamp-prev.Timestamp <= authorRunGap
+}
+
+// scrollToMessage scrolls the viewport so the message with eventID is at
+// the top. It reports false if the message isn't rendered in the current view.
+func (m *model) scrollToMessage(eventID string) bool {
+	line, ok := m.msgLines[eventID]
+	if !ok {
+		return false
+	}
+	m.viewport.SetYOffset(line)
+	return true
+}
+
 func (m *model) View() string {
+	if m.bell {
+		return m.view() + "\a" // zero width; see ringBell
+	}
+	return m.view()
+}
+
+func (m *model) view() string {
 	if m.width == 0 {
 		return "Loading..."
 	}
 
+	if m.reader != nil {
+		return m.viewReader()
+	}
 	if m.qrOverlay != "" {
 		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.qrOverlay)
 	}
+	if m.reactPicker != nil {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.viewReactPicker())
+	}
+	if m.unreadPeek != nil {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.viewUnreadPeek())
+	}
+	if m.displayMenu != nil {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.viewDisplayMenu())
+	}
+	if m.passPrompt != nil {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.viewPassPrompt())
+	}
+	if m.pendingRestore != nil {
+		return lipgloss.Place(m.width, m.height, lipgloss.Center, lipgloss.Center, m.viewRestoreConfirm())
+	}
+	if m.pendingSend != "" {
+		return m.viewConfirmSend()
+	}
+	if m.paletteOpen {
+		return m.viewPalette()
+	}
 
 	sidebar := m.viewSidebar()
 	content := m.viewContent()
@@ -248,61 +409,31 @@ func (m *model) viewSidebar() string {
 	sw := m.sidebarWidth()
 	var items []string
 
-	// CHANNELS section
-	items = append(items, sidebarSectionStyle.Render("CHANNELS"))
-	for i, it := range m.sidebar {
-		if it.Kind() != SidebarChannel {
-			break
-		}
-		name := it.Prefix() + it.DisplayName()
-		if lipgloss.Width(name) > sw-2 {
-			name = ansi.Truncate(name, sw-2, "")
-		}
-		if i == m.activeItem {
-			items = append(items, sidebarSelectedStyle.Render(name))
-		} else if m.unread[it.ItemID()] {
-			items = append(items, sidebarUnreadStyle.Render(name))
-		} else {
-			items = append(items, sidebarItemStyle.Render(name))
-		}
-	}
-
-	// GROUPS section
-	items = append(items, sidebarSectionStyle.Render("GROUPS"))
-	for i, it := range m.sidebar {
-		if it.Kind() != SidebarGroup {
+	for _, row := range m.sidebarRows() {
+		if row.item < 0 {
+			items = append(items, m.renderSectionHeader(row.kind))
 			continue
 		}
+		it := m.sidebar[row.item]
 		name := it.Prefix() + it.DisplayName()
-		if lipgloss.Width(name) > sw-2 {
-			name = ansi.Truncate(name, sw-2, "")
-		}
-		if i == m.activeItem {
-			items = append(items, sidebarSelectedStyle.Render(name))
-		} else if m.unread[it.ItemID()] {
-			items = append(items, sidebarUnreadStyle.Render(name))
-		} else {
-			items = append(items, sidebarItemStyle.Render(name))
-		}
-	}
-
-	// DMS section
-	items = append(items, sidebarSectionStyle.Render("DMS"))
-	for i, it := range m.sidebar {
-		if it.Kind() != SidebarDM {
-			continue
-		}
-		name := it.Prefix() + it.DisplayName()
-		if lipgloss.Width(name) > sw-2 {
-			name = ansi.Truncate(name, sw-2, "")
-		}
-		if i == m.activeItem {
-			items = append(items, sidebarSelectedStyle.Render(name))
+		// DM peers get their avatar in place of the "@" prefix.
+		avatar := ""
+		if di, ok := it.(DMItem); ok && m.cfg.Avatars {
+			avatar = renderAvatar(di.DisplayName(), di.PubKey)
+			name = di.DisplayName()
+		}
+		if w := sw - 2 - lipgloss.Width(avatar); lipgloss.Width(name) > w {
+			name = ansi.Truncate(name, w, "")
+		}
+		style := sidebarItemStyle
+		if row.item == m.activeItem {
+			style = sidebarSelectedStyle
+		} else if m.highlights[it.ItemID()] {
+			style = sidebarHighlightStyle
 		} else if m.unread[it.ItemID()] {
-			items = append(items, sidebarUnreadStyle.Render(name))
-		} else {
-			items = append(items, sidebarItemStyle.Render(name))
+			style = sidebarUnreadStyle
 		}
+		items = append(items, avatar+style.Render(name))
 	}
 
 	content := strings.Join(items, "\n")
@@ -321,13 +452,14 @@ func (m *model) viewContent() string {
 		vp = m.applySelectionHighlight(vp)
 	}
 
-	var inner string
+	parts := []string{titleBar, vp}
 	if len(m.acSuggestions) > 0 {
-		acView := m.viewAutocomplete()
-		inner = lipgloss.JoinVertical(lipgloss.Left, titleBar, vp, acView, inputView)
-	} else {
-		inner = lipgloss.JoinVertical(lipgloss.Left, titleBar, vp, inputView)
+		parts = append(parts, m.viewAutocomplete())
+	}
+	if m.preview {
+		parts = append(parts, m.viewPreview())
 	}
+	inner := lipgloss.JoinVertical(lipgloss.Left, append(parts, inputView)...)
 
 	return lipgloss.NewStyle().Height(totalHeight).MaxHeight(totalHeight).Render(inner)
 }
@@ -344,35 +476,10 @@ func (m *model) connectedRelayCount() int {
 }
 
 func (m *model) viewStatusBar() string {
-	connected := m.connectedRelayCount()
-	total := len(m.relays)
-	bar := statusConnectedStyle.Render(fmt.Sprintf("● %d/%d relays", connected, total))
-	return statusBarStyle.Width(m.width).Render(bar)
-}
-
-// doubleNewlinesOutsideCode doubles single newlines for markdown paragraph
-// breaks, but preserves newlines inside fenced code blocks (``` ... ```).
-func doubleNewlinesOutsideCode(s string) string {
-	var b strings.Builder
-	b.Grow(len(s) * 2)
-	lines := strings.Split(s, "\n")
-	inCode := false
-	for i, line := range lines {
-		if strings.HasPrefix(strings.TrimSpace(line), "```") {
-			inCode = !inCode
-		}
-		b.WriteString(line)
-		if i < len(lines)-1 {
-			b.WriteByte('\n')
-			if !inCode {
-				b.WriteByte('\n')
-			}
-		}
+	worst, known := worstLatency(m.relays, m.relayLatency)
+	bar := relayIndicator(m.connectedRelayCount(), len(m.relays), worst, known)
+	if m.awayMsg != "" {
+		bar += chatSystemStyle.Render("  away")
 	}
-	// Collapse runs of 3+ newlines into double.
-	result := b.String()
-	for strings.Contains(result, "\n\n\n") {
-		result = strings.ReplaceAll(result, "\n\n\n", "\n\n")
-	}
-	return result
+	return statusBarStyle.Width(m.width).Render(bar)
 }
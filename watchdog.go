@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fiatjaf.com/nostr"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// A room subscription can stall without closing: the connection stays up
+// but the relay stops sending. The watchdog probes the subscriptions that
+// have been quiet for stale_subscription_after with a keepalive query (the
+// same REQ/EOSE round trip as /ping), pinging each relay once per check no
+// matter how many rooms use it, and reconnects a room when none of its
+// relays answer. Rooms that are merely quiet pass the probe and are left
+// alone.
+
+// staleCheckMsg fires on the watchdog ticker.
+type staleCheckMsg struct{}
+
+// keepaliveMsg carries the keepalive results for the quiet rooms of one
+// check.
+type keepaliveMsg struct {
+	rooms   map[string]<-chan nostr.RelayEvent // room → subscription probed, to ignore stale results
+	results []relayPing
+}
+
+// staleCheckCmd schedules the next watchdog check, a quarter of the stale
+// interval from now (at least every 15s).
+func staleCheckCmd(after time.Duration) tea.Cmd {
+	return tea.Tick(max(after/4, 15*time.Second), func(time.Time) tea.Msg { return staleCheckMsg{} })
+}
+
+// keepaliveCmd pings relays, each once, on behalf of rooms.
+func keepaliveCmd(pool *nostr.Pool, rooms map[string]<-chan nostr.RelayEvent, relays []string) tea.Cmd {
+	return func() tea.Msg {
+		results := make([]relayPing, len(relays))
+		var wg sync.WaitGroup
+		for i, url := range relays {
+			wg.Add(1)
+			go func(i int, url string) {
+				defer wg.Done()
+				results[i] = pingRelay(pool, url)
+			}(i, url)
+		}
+		wg.Wait()
+		return keepaliveMsg{rooms: rooms, results: results}
+	}
+}
+
+// staleRoomSubs returns the rooms whose subscription has received nothing
+// for after and is not being probed already, sorted.
+func staleRoomSubs(subs map[string]*roomSub, now time.Time, after time.Duration) []string {
+	var rooms []string
+	for room, sub := range subs {
+		if !sub.probing && now.Sub(sub.lastActivity) >= after {
+			rooms = append(rooms, room)
+		}
+	}
+	sort.Strings(rooms)
+	return rooms
+}
+
+// failedPings lists those of relays that didn't answer a keepalive. Relays
+// missing from results weren't probed and count as answered.
+func failedPings(relays []string, results []relayPing) []string {
+	errs := make(map[string]error, len(results))
+	for _, r := range results {
+		errs[r.URL] = r.Err
+	}
+	var failed []string
+	for _, url := range relays {
+		if err := errs[url]; err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%v)", url, err))
+		}
+	}
+	return failed
+}
+
+// touchRoomSub records that room's subscription delivered something.
+func (m *model) touchRoomSub(room string) {
+	if sub, ok := m.roomSubs[room]; ok {
+		sub.lastActivity = time.Now()
+	}
+}
+
+// roomSubRelays returns the relays a room subscription reads from.
+func (m *model) roomSubRelays(sub *roomSub) []string {
+	if sub.kind == SidebarGroup {
+		return m.groupRelays(sub.roomID)
+	}
+	return m.channelRelays(sub.roomID)
+}
+
+// keepaliveTargets marks the subscriptions of rooms as being probed and
+// returns them with the relays they read from, each relay once.
+func (m *model) keepaliveTargets(rooms []string) (map[string]<-chan nostr.RelayEvent, []string) {
+	probed := make(map[string]<-chan nostr.RelayEvent, len(rooms))
+	var relays []string
+	for _, room := range rooms {
+		sub := m.roomSubs[room]
+		sub.probing = true
+		probed[room] = sub.events
+		for _, url := range m.roomSubRelays(sub) {
+			if !slices.Contains(relays, url) {
+				relays = append(relays, url)
+			}
+		}
+	}
+	return probed, relays
+}
+
+func (m *model) handleStaleCheck() (tea.Model, tea.Cmd) {
+	after := m.cfg.StaleSubInterval()
+	if after <= 0 {
+		return m, nil // turned off by /reload
+	}
+	cmds := []tea.Cmd{staleCheckCmd(after)}
+	rooms, relays := m.keepaliveTargets(staleRoomSubs(m.roomSubs, time.Now(), after))
+	if len(rooms) > 0 {
+		log.Printf("watchdog: %d quiet rooms, probing %s", len(rooms), strings.Join(relays, ", "))
+		cmds = append(cmds, keepaliveCmd(m.pool, rooms, relays))
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// handleKeepalive keeps each probed subscription with at least one relay
+// that answered and reconnects those whose relays all failed.
+func (m *model) handleKeepalive(msg keepaliveMsg) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+	rooms := make([]string, 0, len(msg.rooms))
+	for room := range msg.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+	for _, room := range rooms {
+		sub, ok := m.roomSubs[room]
+		if !ok || sub.events != msg.rooms[room] {
+			continue // replaced or closed meanwhile
+		}
+		sub.probing = false
+		relays := m.roomSubRelays(sub)
+		failed := failedPings(relays, msg.results)
+		if len(failed) == 0 || len(failed) < len(relays) {
+			if len(failed) > 0 {
+				log.Printf("watchdog: %s: keepalive failed on %s, other relays answered", room, strings.Join(failed, ", "))
+			}
+			sub.lastActivity = time.Now()
+			continue
+		}
+		log.Printf("watchdog: %s stalled, keepalive failed on %s; reconnecting", room, strings.Join(failed, ", "))
+		m.addRoomSystemMsg(room, "subscription stalled (no answer from "+strings.Join(failed, ", ")+"), reconnecting...")
+		m.cancelRoomSub(room)
+		if sub.kind == SidebarGroup {
+			cmds = append(cmds, groupReconnectDelayCmd(room))
+		} else {
+			cmds = append(cmds, channelReconnectDelayCmd(room))
+		}
+	}
+	return m, tea.Batch(cmds...)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"fiatjaf.com/nostr"
+)
+
+func TestStaleRoomSubs(t *testing.T) {
+	now := time.Now()
+	subs := map[string]*roomSub{
+		"quiet":   {lastActivity: now.Add(-10 * time.Minute)},
+		"busy":    {lastActivity: now.Add(-time.Minute)},
+		"probing": {lastActivity: now.Add(-10 * time.Minute), probing: true},
+		"edge":    {lastActivity: now.Add(-5 * time.Minute)},
+	}
+	got := staleRoomSubs(subs, now, 5*time.Minute)
+	if want := []string{"edge", "quiet"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("staleRoomSubs = %v, want %v", got, want)
+	}
+}
+
+func TestHandleKeepalive(t *testing.T) {
+	events := make(chan nostr.RelayEvent)
+	canceled := false
+	newModel := func() *model {
+		m := newTestModel(1, 0, 0)
+		m.relays = []string{"wss://a", "wss://b"}
+		m.roomSubs = map[string]*roomSub{
+			"ch0": {kind: SidebarChannel, roomID: "ch0", events: events, cancel: func() { canceled = true }, probing: true},
+		}
+		return m
+	}
+	probed := map[string]<-chan nostr.RelayEvent{"ch0": events}
+	timeout := errors.New("timeout")
+
+	m := newModel()
+	_, cmd := m.handleKeepalive(keepaliveMsg{rooms: probed, results: []relayPing{{URL: "wss://a", RTT: time.Millisecond}, {URL: "wss://b", RTT: time.Millisecond}}})
+	sub := m.roomSubs["ch0"]
+	if cmd != nil || sub == nil || sub.probing || time.Since(sub.lastActivity) > time.Minute {
+		t.Errorf("answered keepalive: cmd=%v sub=%+v", cmd != nil, sub)
+	}
+
+	// One relay answering is enough to keep the subscription.
+	m = newModel()
+	_, cmd = m.handleKeepalive(keepaliveMsg{rooms: probed, results: []relayPing{{URL: "wss://a", Err: timeout}, {URL: "wss://b", RTT: time.Millisecond}}})
+	if cmd != nil || canceled || m.roomSubs["ch0"] == nil {
+		t.Errorf("partly failed keepalive: cmd=%v canceled=%v", cmd != nil, canceled)
+	}
+
+	m = newModel()
+	_, cmd = m.handleKeepalive(keepaliveMsg{rooms: probed, results: []relayPing{{URL: "wss://a", Err: timeout}, {URL: "wss://b", Err: timeout}}})
+	if cmd == nil || !canceled || m.roomSubs["ch0"] != nil {
+		t.Errorf("failed keepalive: cmd=%v canceled=%v sub=%v", cmd != nil, canceled, m.roomSubs["ch0"])
+	}
+
+	// Results for a subscription that was replaced meanwhile are ignored.
+	m = newModel()
+	canceled = false
+	m.handleKeepalive(keepaliveMsg{rooms: map[string]<-chan nostr.RelayEvent{"ch0": make(chan nostr.RelayEvent)}, results: []relayPing{{URL: "wss://a", Err: timeout}, {URL: "wss://b", Err: timeout}}})
+	if canceled || m.roomSubs["ch0"] == nil {
+		t.Error("stale keepalive result tore down the current subscription")
+	}
+}
+
+func TestKeepaliveTargetsProbeEachRelayOnce(t *testing.T) {
+	m := newTestModel(2, 0, 0)
+	m.relays = []string{"wss://a", "wss://b"}
+	m.roomSubs = map[string]*roomSub{
+		"ch0": {kind: SidebarChannel, roomID: "ch0"},
+		"ch1": {kind: SidebarChannel, roomID: "ch1"},
+	}
+	rooms, relays := m.keepaliveTargets([]string{"ch0", "ch1"})
+	if len(rooms) != 2 || !m.roomSubs["ch0"].probing || !m.roomSubs["ch1"].probing {
+		t.Errorf("probed rooms = %v", rooms)
+	}
+	if want := []string{"wss://a", "wss://b"}; !reflect.DeepEqual(relays, want) {
+		t.Errorf("relays = %v, want %v", relays, want)
+	}
+}
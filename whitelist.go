@@ -0,0 +1,89 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// whitelistPath returns the path of the file listing rooms in /whitelist
+// mode, next to the config.
+func whitelistPath(cfgFlagPath string) string {
+	return filepath.Join(filepath.Dir(configPath(cfgFlagPath)), "whitelist_rooms")
+}
+
+// isContact reports whether pubkey is one we follow or have a DM with.
+func (m *model) isContact(pubkey string) bool {
+	return m.containsDMPeer(pubkey) || slices.ContainsFunc(m.follows, func(f Follow) bool { return f.PubKey == pubkey })
+}
+
+// isWhitelistedOut reports whether msg is hidden by its room's /whitelist:
+// the room is in whitelist mode, not expanded, and the author isn't a
+// contact.
+func (m *model) isWhitelistedOut(msg ChatMessage) bool {
+	if msg.Author == "system" || msg.IsMine || msg.PubKey == "" {
+		return false
+	}
+	roomID := msg.ChannelID
+	if roomID == "" {
+		roomID = msg.GroupKey
+	}
+	if !m.whitelistRooms[roomID] || m.whitelistShown[roomID] {
+		return false
+	}
+	return !m.isContact(msg.PubKey)
+}
+
+// whitelistCommand handles /whitelist [show]: without an argument it turns
+// the active room's whitelist mode on or off (saved); "show" expands or
+// collapses the messages it hides.
+func (m *model) whitelistCommand(arg string) (tea.Model, tea.Cmd) {
+	var key string
+	switch it := m.activeSidebarItem().(type) {
+	case ChannelItem:
+		key = it.Channel.ID
+	case GroupItem:
+		key = groupKey(it.Group.RelayURL, it.Group.GroupID)
+	default:
+		m.addSystemMsg("/whitelist only works in a channel or group")
+		return m, nil
+	}
+	switch strings.TrimSpace(arg) {
+	case "":
+	case "show":
+		if !m.whitelistRooms[key] {
+			m.addSystemMsg("whitelist mode is off in this room")
+			return m, nil
+		}
+		m.whitelistShown[key] = !m.whitelistShown[key]
+		if m.whitelistShown[key] {
+			m.addSystemMsg("showing messages from everyone; /whitelist show hides them again")
+		} else {
+			m.addSystemMsg("hiding messages from non-contacts again")
+		}
+		m.updateViewport()
+		return m, nil
+	default:
+		m.addSystemMsg("usage: /whitelist [show]")
+		return m, nil
+	}
+
+	if m.whitelistRooms[key] {
+		delete(m.whitelistRooms, key)
+	} else {
+		m.whitelistRooms[key] = true
+	}
+	delete(m.whitelistShown, key)
+	if err := saveRoomSet(whitelistPath(m.cfgFlagPath), m.whitelistRooms); err != nil {
+		m.addSystemMsg("whitelist: " + err.Error())
+	}
+	if m.whitelistRooms[key] {
+		m.addSystemMsg("whitelist on: only messages from your contacts and follows are shown here (/whitelist show to expand)")
+	} else {
+		m.addSystemMsg("whitelist off: showing messages from everyone")
+	}
+	m.updateViewport()
+	return m, nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestIsWhitelistedOut(t *testing.T) {
+	m := newTestModel(2, 0, 1) // DM peer pk0
+	m.follows = []Follow{{PubKey: "friend"}}
+	m.whitelistRooms = map[string]bool{"ch0": true}
+	m.whitelistShown = map[string]bool{}
+
+	tests := []struct {
+		name string
+		msg  ChatMessage
+		want bool
+	}{
+		{"stranger", ChatMessage{ChannelID: "ch0", PubKey: "stranger", Author: "x"}, true},
+		{"follow", ChatMessage{ChannelID: "ch0", PubKey: "friend", Author: "f"}, false},
+		{"DM contact", ChatMessage{ChannelID: "ch0", PubKey: "pk0", Author: "p"}, false},
+		{"own", ChatMessage{ChannelID: "ch0", PubKey: "me", Author: "me", IsMine: true}, false},
+		{"system", ChatMessage{ChannelID: "ch0", Author: "system"}, false},
+		{"other room", ChatMessage{ChannelID: "ch1", PubKey: "stranger", Author: "x"}, false},
+	}
+	for _, tt := range tests {
+		if got := m.isWhitelistedOut(tt.msg); got != tt.want {
+			t.Errorf("%s: isWhitelistedOut = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	m.whitelistShown["ch0"] = true
+	if m.isWhitelistedOut(tests[0].msg) {
+		t.Error("expanded room still hides a stranger")
+	}
+}
+
+func TestWhitelistKeepsHighlights(t *testing.T) {
+	m := newTestModel(1, 0, 0)
+	m.cfg.HighlightWords = []string{"nitrous"}
+	m.whitelistRooms = map[string]bool{"ch0": true}
+
+	if !m.isFilteredMessage(ChatMessage{ChannelID: "ch0", PubKey: "stranger", Author: "x", Content: "hello"}) {
+		t.Error("stranger's message not collapsed in whitelist mode")
+	}
+	if m.isFilteredMessage(ChatMessage{ChannelID: "ch0", PubKey: "stranger", Author: "x", Content: "nitrous rocks"}) {
+		t.Error("highlighted message collapsed by the whitelist")
+	}
+}
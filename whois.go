@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"fiatjaf.com/nostr"
+	"fiatjaf.com/nostr/nip05"
+	"fiatjaf.com/nostr/nip19"
+	"fiatjaf.com/nostr/nip65"
+)
+
+// whoisRecentMessages is how many recent messages /whois lists.
+const whoisRecentMessages = 5
+
+// profileMeta is the subset of kind-0 profile fields /whois shows.
+type profileMeta struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	About       string `json:"about"`
+	Picture     string `json:"picture"`
+	NIP05       string `json:"nip05"`
+	LUD16       string `json:"lud16"`
+}
+
+// whoisReport accumulates the /whois overlay as fetches complete.
+type whoisReport struct {
+	PubKey       string
+	Profile      *profileMeta // nil while loading or if not found
+	ProfileDone  bool
+	NIP05Status  string // "" until the profile names a NIP-05 address
+	ReadRelays   []string
+	WriteRelays  []string
+	RelaysDone   bool
+}
+
+type whoisProfileMsg struct {
+	pubkey string
+	meta   *profileMeta // nil if not found
+}
+
+type whoisRelaysMsg struct {
+	pubkey      string
+	read, write []string
+}
+
+type whoisNIP05Msg struct {
+	pubkey string
+	status string
+}
+
+// fetchWhoisProfileCmd fetches the full kind-0 profile for /whois.
+func fetchWhoisProfileCmd(pool *nostr.Pool, relays []string, pubkey string, wait time.Duration) tea.Cmd {
+	return func() tea.Msg {
+		pk, err := nostr.PubKeyFromHex(pubkey)
+		if err != nil {
+			return whoisProfileMsg{pubkey: pubkey}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		re := queryReplaceable(ctx, pool, relays, nostr.Filter{
+			Kinds:   []nostr.Kind{nostr.KindProfileMetadata},
+			Authors: []nostr.PubKey{pk},
+		}, wait)
+		if re == nil {
+			return whoisProfileMsg{pubkey: pubkey}
+		}
+		var meta profileMeta
+		if err := json.Unmarshal([]byte(re.Content), &meta); err != nil {
+			return whoisProfileMsg{pubkey: pubkey}
+		}
+		return whoisProfileMsg{pubkey: pubkey, meta: &meta}
+	}
+}
+
+// fetchWhoisRelaysCmd fetches the NIP-65 relay list for /whois.
+func fetchWhoisRelaysCmd(pool *nostr.Pool, relays []string, pubkey string, wait time.Duration) tea.Cmd {
+	return func() tea.Msg {
+		pk, err := nostr.PubKeyFromHex(pubkey)
+		if err != nil {
+			return whoisRelaysMsg{pubkey: pubkey}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		re := queryReplaceable(ctx, pool, relays, nostr.Filter{
+			Kinds:   []nostr.Kind{nostr.KindRelayListMetadata},
+			Authors: []nostr.PubKey{pk},
+		}, wait)
+		if re == nil {
+			return whoisRelaysMsg{pubkey: pubkey}
+		}
+		read, write := nip65.ParseRelayList(re.Event)
+		return whoisRelaysMsg{pubkey: pubkey, read: read, write: write}
+	}
+}
+
+// verifyWhoisNIP05Cmd checks that a profile's NIP-05 address points back
+// to its pubkey.
+func verifyWhoisNIP05Cmd(pubkey, identifier string) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		pp, err := nip05.QueryIdentifier(ctx, identifier)
+		switch {
+		case err != nil:
+			return whoisNIP05Msg{pubkey: pubkey, status: "✗ " + err.Error()}
+		case pp.PublicKey.Hex() != pubkey:
+			return whoisNIP05Msg{pubkey: pubkey, status: "✗ points to " + shortPK(pp.PublicKey.Hex())}
+		}
+		return whoisNIP05Msg{pubkey: pubkey, status: "✓ verified"}
+	}
+}
+
+// showWhois handles /whois [npub|name]; with no argument it inspects the
+// current DM peer.
+func (m *model) showWhois(arg string) (tea.Model, tea.Cmd) {
+	if arg == "" {
+		arg = m.activeDMPeerPK()
+	}
+	if arg == "" {
+		m.addSystemMsg("usage: /whois <npub|name>")
+		return m, nil
+	}
+	pk, err := m.resolvePubKey(arg)
+	if err != nil {
+		m.addSystemMsg(err.Error())
+		return m, nil
+	}
+	m.whois = &whoisReport{PubKey: pk}
+	m.qrOverlay = m.renderWhois()
+	wait := m.cfg.ReplaceableWait()
+	return m, tea.Batch(
+		fetchWhoisProfileCmd(m.pool, m.relays, pk, wait),
+		fetchWhoisRelaysCmd(m.pool, m.relays, pk, wait),
+	)
+}
+
+// whoisFor returns the open report for pubkey, or nil if /whois was closed
+// or moved on to someone else.
+func (m *model) whoisFor(pubkey string) *whoisReport {
+	if m.whois == nil || m.whois.PubKey != pubkey || m.qrOverlay == "" {
+		return nil
+	}
+	return m.whois
+}
+
+func (m *model) handleWhoisProfile(msg whoisProfileMsg) (tea.Model, tea.Cmd) {
+	w := m.whoisFor(msg.pubkey)
+	if w == nil {
+		return m, nil
+	}
+	w.Profile = msg.meta
+	w.ProfileDone = true
+	var cmd tea.Cmd
+	if msg.meta != nil && msg.meta.NIP05 != "" {
+		w.NIP05Status = "checking …"
+		cmd = verifyWhoisNIP05Cmd(msg.pubkey, msg.meta.NIP05)
+	}
+	m.qrOverlay = m.renderWhois()
+	return m, cmd
+}
+
+func (m *model) handleWhoisRelays(msg whoisRelaysMsg) (tea.Model, tea.Cmd) {
+	if w := m.whoisFor(msg.pubkey); w != nil {
+		w.ReadRelays, w.WriteRelays = msg.read, msg.write
+		w.RelaysDone = true
+		m.qrOverlay = m.renderWhois()
+	}
+	return m, nil
+}
+
+func (m *model) handleWhoisNIP05(msg whoisNIP05Msg) (tea.Model, tea.Cmd) {
+	if w := m.whoisFor(msg.pubkey); w != nil {
+		w.NIP05Status = msg.status
+		m.qrOverlay = m.renderWhois()
+	}
+	return m, nil
+}
+
+// whoisRoom is a channel or group where the pubkey has posted.
+type whoisRoom struct {
+	Label string
+	Count int
+}
+
+// whoisActivity scans buffered messages for what pubkey wrote: the shared
+// channels and groups (by message count) and the most recent messages,
+// newest first, each labelled with its room.
+func (m *model) whoisActivity(pubkey string) ([]whoisRoom, []string) {
+	type labelled struct {
+		label string
+		msg   ChatMessage
+	}
+	var rooms []whoisRoom
+	var recent []labelled
+	for _, it := range m.sidebar {
+		label := it.Prefix() + it.DisplayName()
+		n := 0
+		for _, msg := range m.msgs[it.ItemID()] {
+			// In DMs PubKey is the peer for both directions.
+			if msg.Author == "system" || msg.PubKey != pubkey || (it.Kind() == SidebarDM && msg.IsMine) {
+				continue
+			}
+			n++
+			recent = append(recent, labelled{label, msg})
+		}
+		if n > 0 && it.Kind() != SidebarDM {
+			rooms = append(rooms, whoisRoom{Label: label, Count: n})
+		}
+	}
+	sort.SliceStable(rooms, func(i, j int) bool { return rooms[i].Count > rooms[j].Count })
+	sort.SliceStable(recent, func(i, j int) bool { return recent[i].msg.Timestamp > recent[j].msg.Timestamp })
+	var lines []string
+	for i := 0; i < len(recent) && i < whoisRecentMessages; i++ {
+		r := recent[i]
+		text := truncateRunes(strings.Join(strings.Fields(r.msg.Content), " "), 60)
+		lines = append(lines, fmt.Sprintf("%s %s: %s", r.msg.Timestamp.Time().Format("01-02 15:04"), r.label, text))
+	}
+	return rooms, lines
+}
+
+// renderWhois renders the /whois overlay from the current report.
+func (m *model) renderWhois() string {
+	w := m.whois
+	var b strings.Builder
+	b.WriteString(qrTitleStyle.Render("whois " + m.resolveAuthor(w.PubKey)))
+	b.WriteString("\n\n")
+	row := func(label, value string) {
+		fmt.Fprintf(&b, "%-9s %s\n", label, value)
+	}
+	loading := chatSystemStyle.Render("loading …")
+
+	if pk, err := nostr.PubKeyFromHex(w.PubKey); err == nil {
+		row("npub", nip19.EncodeNpub(pk))
+	}
+	row("hex", w.PubKey)
+
+	switch {
+	case !w.ProfileDone:
+		row("profile", loading)
+	case w.Profile == nil:
+		row("profile", chatSystemStyle.Render("no kind-0 profile found"))
+	default:
+		p := w.Profile
+		for _, f := range []struct{ label, value string }{
+			{"name", p.Name},
+			{"display", p.DisplayName},
+			{"about", truncateRunes(strings.Join(strings.Fields(p.About), " "), 120)},
+			{"picture", p.Picture},
+			{"lud16", p.LUD16},
+		} {
+			if f.value != "" {
+				row(f.label, f.value)
+			}
+		}
+		if p.NIP05 != "" {
+			row("nip05", p.NIP05+"  "+w.NIP05Status)
+		}
+	}
+
+	switch {
+	case !w.RelaysDone:
+		row("relays", loading)
+	case len(w.ReadRelays) == 0 && len(w.WriteRelays) == 0:
+		row("relays", chatSystemStyle.Render("no NIP-65 relay list"))
+	default:
+		if len(w.WriteRelays) > 0 {
+			row("write", strings.Join(w.WriteRelays, " "))
+		}
+		if len(w.ReadRelays) > 0 {
+			row("read", strings.Join(w.ReadRelays, " "))
+		}
+	}
+
+	var rel []string
+	if followIndex(m.follows, w.PubKey) >= 0 {
+		rel = append(rel, "followed")
+	}
+	if m.containsDMPeer(w.PubKey) {
+		rel = append(rel, "DM contact")
+	}
+	if w.PubKey == m.keys.PK.Hex() {
+		rel = append(rel, "you")
+	}
+	if len(rel) > 0 {
+		row("contact", strings.Join(rel, ", "))
+	}
+
+	rooms, recent := m.whoisActivity(w.PubKey)
+	if len(rooms) > 0 {
+		var parts []string
+		for _, r := range rooms {
+			parts = append(parts, fmt.Sprintf("%s (%d)", r.Label, r.Count))
+		}
+		row("rooms", strings.Join(parts, ", "))
+	}
+	if len(recent) > 0 {
+		b.WriteString("\nrecent messages\n")
+		for _, l := range recent {
+			b.WriteString("  " + l + "\n")
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
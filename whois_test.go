@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWhoisActivity(t *testing.T) {
+	m := newTestModel(2, 1, 1) // ch0, ch1, g0, pk0
+	alice := "pk0"
+	m.msgs = map[string][]ChatMessage{
+		"ch0":         {{PubKey: alice, Author: "a", Content: "hello\nchannel", Timestamp: 100}, {PubKey: "bob", Author: "b", Content: "hi", Timestamp: 150}},
+		"ch1":         {{PubKey: "bob", Author: "b", Content: "not alice", Timestamp: 200}},
+		"wss://r\tg0": {{PubKey: alice, Content: "one", Timestamp: 300}, {PubKey: alice, Content: "two", Timestamp: 400}, {Author: "system", PubKey: alice, Content: "notice", Timestamp: 500}},
+		// In DMs PubKey is the peer for both directions; only theirs count.
+		"pk0": {{PubKey: alice, Content: "dm from alice", Timestamp: 350}, {PubKey: alice, IsMine: true, Content: "my reply", Timestamp: 360}},
+	}
+
+	rooms, recent := m.whoisActivity(alice)
+	if len(rooms) != 2 || rooms[0].Label != "~grp0" || rooms[0].Count != 2 || rooms[1].Label != "#chan0" {
+		t.Errorf("rooms = %+v, want ~grp0 (2) then #chan0 (1)", rooms)
+	}
+	if len(recent) != 4 {
+		t.Fatalf("recent = %v, want 4 messages", recent)
+	}
+	if !strings.Contains(recent[0], "~grp0: two") || !strings.Contains(recent[1], "@pk0: dm from alice") {
+		t.Errorf("recent not newest-first with room labels: %v", recent)
+	}
+	if !strings.Contains(recent[3], "hello channel") {
+		t.Errorf("newlines should be flattened: %q", recent[3])
+	}
+}
+
+func TestRenderWhoisProgressive(t *testing.T) {
+	m := newTestModel(0, 0, 0)
+	m.whois = &whoisReport{PubKey: testKeys(t).PK.Hex()}
+
+	out := m.renderWhois()
+	if !strings.Contains(out, "npub1") || strings.Count(out, "loading") != 2 {
+		t.Errorf("initial report should show npub and two loading rows:\n%s", out)
+	}
+
+	m.whois.Profile = &profileMeta{Name: "alice", NIP05: "alice@example.com"}
+	m.whois.ProfileDone = true
+	m.whois.NIP05Status = "✓ verified"
+	m.whois.RelaysDone = true
+	m.whois.WriteRelays = []string{"wss://w.example"}
+	out = m.renderWhois()
+	for _, want := range []string{"alice", "alice@example.com  ✓ verified", "wss://w.example"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "loading") {
+		t.Errorf("nothing should be loading:\n%s", out)
+	}
+}